@@ -2,48 +2,129 @@ package main
 
 import (
 	"context"
-	"database/sql"
 	"errors"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"sync"
 	"syscall"
 	"time"
 
-	"strconv"
-
+	"github.com/shayesteh1hs/DrAppointment/internal/config"
 	"github.com/shayesteh1hs/DrAppointment/internal/database"
+	"github.com/shayesteh1hs/DrAppointment/internal/events"
+	"github.com/shayesteh1hs/DrAppointment/internal/health"
+	"github.com/shayesteh1hs/DrAppointment/internal/jobs"
+	"github.com/shayesteh1hs/DrAppointment/internal/logging"
+	"github.com/shayesteh1hs/DrAppointment/internal/notification"
+	"github.com/shayesteh1hs/DrAppointment/internal/payment"
+	appointmentPostgres "github.com/shayesteh1hs/DrAppointment/internal/repository/medical/appointment/postgres"
+	doctorPostgres "github.com/shayesteh1hs/DrAppointment/internal/repository/medical/doctor/postgres"
 	"github.com/shayesteh1hs/DrAppointment/internal/router"
+	appointmentService "github.com/shayesteh1hs/DrAppointment/internal/service/medical/appointment"
+	"github.com/shayesteh1hs/DrAppointment/internal/tracing"
 	"github.com/shayesteh1hs/DrAppointment/internal/utils"
+	"github.com/shayesteh1hs/DrAppointment/internal/webhook"
 )
 
 func main() {
-	dbConfig := database.Config{
-		Host:     utils.GetEnv("DB_HOST", "localhost"),
-		Port:     utils.GetEnvInt("DB_PORT", 5432),
-		User:     utils.GetEnv("DB_USER", "postgres"),
-		Password: utils.GetEnv("DB_PASSWORD", "postgres"),
-		DBName:   utils.GetEnv("DB_NAME", "drgo"),
-		SSLMode:  utils.GetEnv("DB_SSL_MODE", "disable"),
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
-	databaseCtx, databaseCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	logger, err := logging.New(os.Stdout, cfg.Log.Format, cfg.Log.Level)
+	if err != nil {
+		log.Fatalf("Failed to set up logging: %v", err)
+	}
+	slog.SetDefault(logger)
+	slog.Info("effective configuration", "config", cfg.String())
+
+	shutdownTracing, err := tracing.Setup(tracing.Config{
+		ServiceName: "drgo-api",
+		Exporter:    cfg.Tracing.Exporter,
+		FilePath:    cfg.Tracing.FilePath,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		fatal("failed to set up tracing", err)
+	}
+	flushTraces = func() {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			slog.Error("failed to flush traces", "error", err)
+		}
+	}
+	defer flushTraces()
+
+	if err := utils.SetImageBaseURL(cfg.ImageBaseURL()); err != nil {
+		fatal("failed to configure image base URL", err)
+	}
+
+	if cfg.Server.PublicBaseURL != "" {
+		if err := utils.SetPublicBaseURL(cfg.Server.PublicBaseURL); err != nil {
+			fatal("failed to configure public base URL", err)
+		}
+	}
+
+	dbConfig := cfg.Database.Connection()
+
+	databaseCtx, databaseCancel := context.WithTimeout(context.Background(), cfg.Database.ConnectTimeout)
 	defer databaseCancel()
 	db, err := database.Connect(databaseCtx, &dbConfig)
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		fatal("failed to connect to database", err)
 	}
-	defer func(db *sql.DB) {
+	defer func(db *database.DB) {
 		err := db.Close()
 		if err != nil {
-			log.Printf("Failed to close database: %v", err)
+			slog.Error("failed to close database", "error", err)
 		}
 	}(db)
 
-	r := router.SetupRouter(db)
+	healthRegistry, err := setupHealthChecks(db, cfg)
+	if err != nil {
+		fatal("failed to set up health checks", err)
+	}
+
+	outbox := newOutbox(db, cfg)
+	jobStore := newJobStore(db, cfg)
+	webhookStore := newWebhookStore(db, cfg)
+	dispatcher := webhook.NewDispatcher(webhookStore, jobStore, &http.Client{Timeout: cfg.Webhook.Timeout}, cfg.Webhook.Options())
 
-	port := utils.GetEnvInt("PORT", 8000)
+	payments, err := newPaymentProvider(cfg)
+	if err != nil {
+		fatal("failed to set up payments", err)
+	}
+
+	r, err := router.SetupRouter(db, cfg, healthRegistry, outbox, webhook.NewService(webhookStore, dispatcher), payments)
+	if err != nil {
+		fatal("failed to set up router", err)
+	}
+
+	notifier, err := newNotifier(db, cfg)
+	if err != nil {
+		fatal("failed to set up notifications", err)
+	}
+	relay := newRelay(db, cfg, outbox, jobStore, dispatcher, payments)
+	runner := newJobRunner(db, cfg, jobStore, relay, notifier, dispatcher, outbox, payments)
+
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+	workers.Go(func() { relay.Run(workerCtx) })
+	workers.Go(func() { runner.Run(workerCtx) })
+	workers.Go(func() { notifier.Run(workerCtx) })
+	workersDone := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(workersDone)
+	}()
+
+	port := cfg.Server.Port
 	server := &http.Server{
 		Addr:    ":" + strconv.Itoa(port),
 		Handler: r,
@@ -51,22 +132,166 @@ func main() {
 
 	go func() {
 		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			log.Fatalf("Failed to start server: %v", err)
+			fatal("failed to start server", err)
 		}
 	}()
 
-	log.Println("Server started on port", port)
+	slog.Info("server started", "port", port)
 
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
-	log.Println("Shutting down server...")
+	slog.Info("shutting down server")
+
+	// Fail readiness first so load balancers stop sending new requests
+	healthRegistry.SetShuttingDown()
+	time.Sleep(cfg.Server.ShutdownDrainDelay)
 
-	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 	defer shutdownCancel()
 	if err := server.Shutdown(shutdownCtx); err != nil {
-		log.Fatal("Server forced to shutdown:", err)
+		fatal("server forced to shutdown", err)
+	}
+
+	// Let running jobs finish and record their outcome; jobs cut off here
+	// are run again once their lease expires
+	stopWorkers()
+	select {
+	case <-workersDone:
+	case <-shutdownCtx.Done():
+		slog.Warn("background jobs did not finish before the shutdown timeout")
+	}
+
+	slog.Info("server exiting")
+}
+
+// flushTraces exports the spans still buffered once tracing is set up.
+var flushTraces = func() {}
+
+// fatal logs err and exits, flushing traces first since os.Exit skips the
+// deferred shutdown.
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	flushTraces()
+	os.Exit(1)
+}
+
+// newNotifier sends notifications over in-app and whichever of SMS and email
+// are configured.
+func newNotifier(db *database.DB, cfg *config.Config) (*notification.Notifier, error) {
+	zone, err := cfg.Notification.Location()
+	if err != nil {
+		return nil, err
+	}
+	templates, err := notification.LoadTemplates(zone)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		store notification.Store
+		inbox notification.Inbox
+	)
+	if cfg.Notification.Store == "memory" {
+		store, inbox = notification.NewMemoryStore(), notification.NewMemoryInbox()
+	} else {
+		store, inbox = notification.NewPostgresStore(db), notification.NewPostgresInbox(db)
+	}
+
+	providers := []notification.Provider{notification.NewInAppProvider(inbox)}
+	if cfg.SMS.BaseURL != "" {
+		sms, err := notification.NewSMSProvider(cfg.SMS.Options(), &http.Client{Timeout: cfg.Notification.SendTimeout})
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, sms)
+	}
+	if cfg.SMTP.Host != "" {
+		smtp, err := notification.NewSMTPProvider(cfg.SMTP.Options())
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, smtp)
+	}
+
+	return notification.NewNotifier(store, templates, cfg.Notification.Options(), providers...), nil
+}
+
+func newPaymentProvider(cfg *config.Config) (payment.Provider, error) {
+	if cfg.Payment.Provider == "zarinpal" {
+		return payment.NewZarinpalProvider(cfg.Payment.Zarinpal.Options(), &http.Client{Timeout: cfg.Payment.Timeout})
+	}
+	slog.Warn("payment.provider is fake, deposits are marked paid without charging anyone")
+	return payment.NewFakeProvider(), nil
+}
+
+func newJobStore(db *database.DB, cfg *config.Config) jobs.Store {
+	if cfg.Jobs.Store == "memory" {
+		return jobs.NewMemoryStore()
+	}
+	return jobs.NewPostgresStore(db)
+}
+
+func newOutbox(db *database.DB, cfg *config.Config) events.Store {
+	if cfg.Events.Store == "memory" {
+		return events.NewMemoryStore()
+	}
+	return events.NewPostgresStore(db)
+}
+
+func newWebhookStore(db *database.DB, cfg *config.Config) webhook.Store {
+	if cfg.Webhook.Store == "memory" {
+		return webhook.NewMemoryStore()
+	}
+	return webhook.NewPostgresStore(db)
+}
+
+// newRelay subscribes every consumer of domain events.
+func newRelay(db *database.DB, cfg *config.Config, outbox events.Store, queue jobs.Enqueuer, dispatcher *webhook.Dispatcher, payments payment.Provider) *events.Relay {
+	relay := events.NewRelay(outbox, queue, cfg.Events.Options())
+	relay.Subscribe(appointmentService.NotificationSubscriber, appointmentService.QueueNotifications(queue), events.AppointmentBooked)
+	relay.Subscribe(appointmentService.RefundSubscriber, appointmentService.IssueRefunds(db, payment.NewPostgresStore(db), payments), events.AppointmentCancelled)
+	relay.Subscribe(webhook.Subscriber, dispatcher.HandleEvent, events.Types...)
+	return relay
+}
+
+// newJobRunner registers every background job this server runs.
+func newJobRunner(db *database.DB, cfg *config.Config, store jobs.Store, relay *events.Relay, notifier *notification.Notifier, dispatcher *webhook.Dispatcher, outbox events.Outbox, payments payment.Provider) *jobs.Runner {
+	runner := jobs.NewRunner(store, cfg.Jobs.Options())
+	relay.Register(runner)
+	dispatcher.Register(runner)
+
+	runner.Register(appointmentService.NotificationJob, appointmentService.NotificationJobHandler(
+		appointmentPostgres.NewAppointmentRepository(db),
+		doctorPostgres.NewDoctorRepository(db),
+		notifier,
+	))
+	runner.Every(appointmentService.PaymentExpiryJob, time.Minute, appointmentService.ExpirePayments(
+		appointmentPostgres.NewAppointmentRepository(db),
+		db,
+		outbox,
+		payment.NewPostgresStore(db),
+		payments,
+	))
+
+	return runner
+}
+
+func setupHealthChecks(db *database.DB, cfg *config.Config) (*health.Registry, error) {
+	registry := health.NewRegistry(cfg.Health.CheckTimeout)
+
+	registry.AddReadinessCheck("database", health.DatabaseCheck(db))
+	if cfg.Health.MigrationCheck {
+		migrationVersion, err := database.LatestMigrationVersion()
+		if err != nil {
+			return nil, err
+		}
+		registry.AddReadinessCheck("migrations", health.MigrationCheck(db, migrationVersion))
+	}
+
+	if cfg.SMS.BaseURL != "" {
+		registry.AddReadinessCheck("sms_provider", health.HTTPCheck(&http.Client{Timeout: cfg.Health.CheckTimeout}, cfg.SMS.BaseURL))
 	}
 
-	log.Println("Server exiting")
+	return registry, nil
 }
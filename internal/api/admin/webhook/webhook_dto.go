@@ -0,0 +1,110 @@
+package webhook
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/webhook"
+)
+
+const defaultDeliveryLimit = 50
+
+type CreateRequest struct {
+	URL    string `json:"url" binding:"required,url" doc:"Endpoint deliveries are POSTed to"`
+	Secret string `json:"secret,omitempty" binding:"omitempty,min=16,max=255" doc:"HMAC key for the Webhook-Signature header; generated when omitted"`
+	// EventTypes are validated by the service against events.Types
+	EventTypes []string `json:"event_types" binding:"required,min=1" doc:"Event types to receive: appointment.booked, appointment.cancelled, doctor.updated"`
+}
+
+func (r CreateRequest) input() webhook.SubscriptionInput {
+	return webhook.SubscriptionInput{URL: r.URL, Secret: r.Secret, EventTypes: r.EventTypes}
+}
+
+// UpdateRequest changes the fields that are present.
+type UpdateRequest struct {
+	URL        *string  `json:"url,omitempty" binding:"omitempty,url"`
+	Secret     *string  `json:"secret,omitempty" binding:"omitempty,min=16,max=255"`
+	EventTypes []string `json:"event_types,omitempty" binding:"omitempty,min=1"`
+	Active     *bool    `json:"active,omitempty" doc:"Set to true to re-enable a disabled subscription"`
+}
+
+func (r UpdateRequest) update() webhook.SubscriptionUpdate {
+	return webhook.SubscriptionUpdate{URL: r.URL, Secret: r.Secret, EventTypes: r.EventTypes, Active: r.Active}
+}
+
+type DeliveryListParams struct {
+	Limit int `form:"limit" binding:"omitempty,min=1,max=200" doc:"Most recent deliveries to return, 50 by default"`
+}
+
+// SubscriptionDTO leaves out the secret, which is only returned on
+// creation.
+type SubscriptionDTO struct {
+	ID                  uuid.UUID  `json:"id"`
+	URL                 string     `json:"url"`
+	EventTypes          []string   `json:"event_types"`
+	Active              bool       `json:"active"`
+	ConsecutiveFailures int        `json:"consecutive_failures" doc:"Failed attempts since the last success"`
+	DisabledAt          *time.Time `json:"disabled_at,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
+
+func NewSubscriptionDTO(sub webhook.Subscription) SubscriptionDTO {
+	return SubscriptionDTO{
+		ID:                  sub.ID,
+		URL:                 sub.URL,
+		EventTypes:          sub.EventTypes,
+		Active:              sub.Active,
+		ConsecutiveFailures: sub.ConsecutiveFailures,
+		DisabledAt:          sub.DisabledAt,
+		CreatedAt:           sub.CreatedAt,
+		UpdatedAt:           sub.UpdatedAt,
+	}
+}
+
+type CreatedSubscriptionDTO struct {
+	SubscriptionDTO
+	Secret string `json:"secret" doc:"Shown only once; store it to verify signatures"`
+}
+
+type SubscriptionListDTO struct {
+	Items []SubscriptionDTO `json:"items"`
+}
+
+type DeliveryDTO struct {
+	ID             uuid.UUID       `json:"id"`
+	SubscriptionID uuid.UUID       `json:"subscription_id"`
+	EventID        uuid.UUID       `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload" doc:"The request body sent"`
+	Status         webhook.Status  `json:"status" doc:"pending, succeeded or failed"`
+	Attempts       int             `json:"attempts"`
+	ResponseStatus int             `json:"response_status,omitempty" doc:"HTTP status of the last attempt"`
+	LastError      string          `json:"last_error,omitempty"`
+	ReplayOf       *uuid.UUID      `json:"replay_of,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+}
+
+func NewDeliveryDTO(d webhook.Delivery) DeliveryDTO {
+	return DeliveryDTO{
+		ID:             d.ID,
+		SubscriptionID: d.SubscriptionID,
+		EventID:        d.EventID,
+		EventType:      d.EventType,
+		Payload:        d.Payload,
+		Status:         d.Status,
+		Attempts:       d.Attempts,
+		ResponseStatus: d.ResponseStatus,
+		LastError:      d.LastError,
+		ReplayOf:       d.ReplayOf,
+		CreatedAt:      d.CreatedAt,
+		DeliveredAt:    d.DeliveredAt,
+	}
+}
+
+type DeliveryListDTO struct {
+	Items []DeliveryDTO `json:"items"`
+}
@@ -0,0 +1,294 @@
+package webhook
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/api"
+	"github.com/shayesteh1hs/DrAppointment/internal/logging"
+	"github.com/shayesteh1hs/DrAppointment/internal/openapi"
+	"github.com/shayesteh1hs/DrAppointment/internal/webhook"
+)
+
+type Handler struct {
+	service webhook.Service
+}
+
+func NewHandler(service webhook.Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) CreateSubscription(c *gin.Context) {
+	var req CreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sub, err := h.service.CreateSubscription(c.Request.Context(), req.input())
+	if err != nil {
+		h.writeError(c, err, "failed to create webhook subscription")
+		return
+	}
+
+	c.JSON(http.StatusCreated, CreatedSubscriptionDTO{SubscriptionDTO: NewSubscriptionDTO(sub), Secret: sub.Secret})
+}
+
+func (h *Handler) ListSubscriptions(c *gin.Context) {
+	subs, err := h.service.ListSubscriptions(c.Request.Context())
+	if err != nil {
+		h.writeError(c, err, "failed to list webhook subscriptions")
+		return
+	}
+
+	items := make([]SubscriptionDTO, len(subs))
+	for i, sub := range subs {
+		items[i] = NewSubscriptionDTO(sub)
+	}
+	c.JSON(http.StatusOK, SubscriptionListDTO{Items: items})
+}
+
+func (h *Handler) GetSubscription(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	sub, err := h.service.GetSubscription(c.Request.Context(), id)
+	if err != nil {
+		h.writeError(c, err, "failed to fetch webhook subscription")
+		return
+	}
+
+	c.JSON(http.StatusOK, NewSubscriptionDTO(sub))
+}
+
+func (h *Handler) UpdateSubscription(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	var req UpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sub, err := h.service.UpdateSubscription(c.Request.Context(), id, req.update())
+	if err != nil {
+		h.writeError(c, err, "failed to update webhook subscription")
+		return
+	}
+
+	c.JSON(http.StatusOK, NewSubscriptionDTO(sub))
+}
+
+func (h *Handler) DeleteSubscription(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteSubscription(c.Request.Context(), id); err != nil {
+		h.writeError(c, err, "failed to delete webhook subscription")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) ListDeliveries(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	var params DeliveryListParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if params.Limit == 0 {
+		params.Limit = defaultDeliveryLimit
+	}
+
+	deliveries, err := h.service.ListDeliveries(c.Request.Context(), id, params.Limit)
+	if err != nil {
+		h.writeError(c, err, "failed to list webhook deliveries")
+		return
+	}
+
+	items := make([]DeliveryDTO, len(deliveries))
+	for i, d := range deliveries {
+		items[i] = NewDeliveryDTO(d)
+	}
+	c.JSON(http.StatusOK, DeliveryListDTO{Items: items})
+}
+
+func (h *Handler) ReplayDelivery(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	deliveryID, ok := parseID(c, "delivery_id")
+	if !ok {
+		return
+	}
+
+	replay, err := h.service.Replay(c.Request.Context(), id, deliveryID)
+	if err != nil {
+		h.writeError(c, err, "failed to replay webhook delivery")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, NewDeliveryDTO(replay))
+}
+
+func parseID(c *gin.Context, param string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param(param))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func (h *Handler) writeError(c *gin.Context, err error, msg string) {
+	switch {
+	case errors.Is(err, webhook.ErrSubscriptionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook subscription not found"})
+	case errors.Is(err, webhook.ErrDeliveryNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook delivery not found"})
+	case errors.Is(err, webhook.ErrInvalidSubscription):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	case errors.Is(err, webhook.ErrSubscriptionDisabled):
+		c.JSON(http.StatusConflict, gin.H{"error": "Webhook subscription is disabled; enable it before replaying"})
+	default:
+		logging.FromContext(c.Request.Context()).Error(msg, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+	}
+}
+
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
+	webhookRoutes := router.Group("/webhooks")
+	{
+		webhookRoutes.POST("", h.CreateSubscription)
+		webhookRoutes.GET("", h.ListSubscriptions)
+		webhookRoutes.GET("/:id", h.GetSubscription)
+		webhookRoutes.PATCH("/:id", h.UpdateSubscription)
+		webhookRoutes.DELETE("/:id", h.DeleteSubscription)
+		webhookRoutes.GET("/:id/deliveries", h.ListDeliveries)
+		webhookRoutes.POST("/:id/deliveries/:delivery_id/replay", h.ReplayDelivery)
+	}
+}
+
+// Operations documents the routes added by RegisterRoutes.
+func (h *Handler) Operations() []openapi.Operation {
+	idParam := map[string]any{"id": uuid.UUID{}}
+	return []openapi.Operation{
+		{
+			Method:      http.MethodPost,
+			Path:        "/webhooks",
+			ID:          "createWebhook",
+			Summary:     "Subscribe an endpoint to events",
+			Description: "Deliveries are POSTed as JSON with a Webhook-Signature header of the form t=<unix time>,v1=<hex HMAC-SHA256 of \"<t>.<body>\" keyed with the secret>.",
+			Tags:        []string{"Webhooks"},
+			Body:        CreateRequest{},
+			Responses: map[int]any{
+				http.StatusCreated:             CreatedSubscriptionDTO{},
+				http.StatusBadRequest:          api.ErrorDTO{},
+				http.StatusUnprocessableEntity: api.ErrorDTO{},
+				http.StatusInternalServerError: api.ErrorDTO{},
+			},
+		},
+		{
+			Method:  http.MethodGet,
+			Path:    "/webhooks",
+			ID:      "listWebhooks",
+			Summary: "List webhook subscriptions",
+			Tags:    []string{"Webhooks"},
+			Responses: map[int]any{
+				http.StatusOK:                  SubscriptionListDTO{},
+				http.StatusInternalServerError: api.ErrorDTO{},
+			},
+		},
+		{
+			Method:     http.MethodGet,
+			Path:       "/webhooks/:id",
+			ID:         "getWebhook",
+			Summary:    "Get a webhook subscription",
+			Tags:       []string{"Webhooks"},
+			PathParams: idParam,
+			Responses: map[int]any{
+				http.StatusOK:                  SubscriptionDTO{},
+				http.StatusBadRequest:          api.ErrorDTO{},
+				http.StatusNotFound:            api.ErrorDTO{},
+				http.StatusInternalServerError: api.ErrorDTO{},
+			},
+		},
+		{
+			Method:      http.MethodPatch,
+			Path:        "/webhooks/:id",
+			ID:          "updateWebhook",
+			Summary:     "Update a webhook subscription",
+			Description: "Changes the fields given. Setting active to true re-enables a subscription disabled after repeated failures.",
+			Tags:        []string{"Webhooks"},
+			PathParams:  idParam,
+			Body:        UpdateRequest{},
+			Responses: map[int]any{
+				http.StatusOK:                  SubscriptionDTO{},
+				http.StatusBadRequest:          api.ErrorDTO{},
+				http.StatusNotFound:            api.ErrorDTO{},
+				http.StatusUnprocessableEntity: api.ErrorDTO{},
+				http.StatusInternalServerError: api.ErrorDTO{},
+			},
+		},
+		{
+			Method:     http.MethodDelete,
+			Path:       "/webhooks/:id",
+			ID:         "deleteWebhook",
+			Summary:    "Delete a webhook subscription and its delivery log",
+			Tags:       []string{"Webhooks"},
+			PathParams: idParam,
+			Responses: map[int]any{
+				http.StatusNoContent:           nil,
+				http.StatusBadRequest:          api.ErrorDTO{},
+				http.StatusNotFound:            api.ErrorDTO{},
+				http.StatusInternalServerError: api.ErrorDTO{},
+			},
+		},
+		{
+			Method:     http.MethodGet,
+			Path:       "/webhooks/:id/deliveries",
+			ID:         "listWebhookDeliveries",
+			Summary:    "List a subscription's deliveries, newest first",
+			Tags:       []string{"Webhooks"},
+			PathParams: idParam,
+			Query:      []any{DeliveryListParams{}},
+			Responses: map[int]any{
+				http.StatusOK:                  DeliveryListDTO{},
+				http.StatusBadRequest:          api.ErrorDTO{},
+				http.StatusNotFound:            api.ErrorDTO{},
+				http.StatusInternalServerError: api.ErrorDTO{},
+			},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/webhooks/:id/deliveries/:delivery_id/replay",
+			ID:          "replayWebhookDelivery",
+			Summary:     "Send a delivery again",
+			Description: "Queues a new delivery with the same payload and event ID.",
+			Tags:        []string{"Webhooks"},
+			PathParams:  map[string]any{"id": uuid.UUID{}, "delivery_id": uuid.UUID{}},
+			Responses: map[int]any{
+				http.StatusAccepted:            DeliveryDTO{},
+				http.StatusBadRequest:          api.ErrorDTO{},
+				http.StatusNotFound:            api.ErrorDTO{},
+				http.StatusConflict:            api.ErrorDTO{},
+				http.StatusInternalServerError: api.ErrorDTO{},
+			},
+		},
+	}
+}
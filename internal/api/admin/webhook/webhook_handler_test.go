@@ -0,0 +1,94 @@
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/jobs"
+	"github.com/shayesteh1hs/DrAppointment/internal/webhook"
+)
+
+func newRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	store := webhook.NewMemoryStore()
+	dispatcher := webhook.NewDispatcher(store, jobs.NewMemoryStore(), http.DefaultClient, webhook.DefaultOptions())
+	router := gin.New()
+	NewHandler(webhook.NewService(store, dispatcher)).RegisterRoutes(router.Group("/"))
+	return router
+}
+
+func serve(t *testing.T, router *gin.Engine, method, target, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req, err := http.NewRequest(method, target, strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestHandler_SubscriptionLifecycle(t *testing.T) {
+	router := newRouter()
+
+	w := serve(t, router, http.MethodPost, "/webhooks", `{"url":"https://clinic.example.com/hooks","event_types":["appointment.booked"]}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var created CreatedSubscriptionDTO
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.True(t, strings.HasPrefix(created.Secret, "whsec_"))
+	assert.True(t, created.Active)
+
+	path := "/webhooks/" + created.ID.String()
+	w = serve(t, router, http.MethodGet, path, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), created.Secret, "the secret is only shown on creation")
+
+	w = serve(t, router, http.MethodPatch, path, `{"active":false}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var updated SubscriptionDTO
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &updated))
+	assert.False(t, updated.Active)
+	assert.NotNil(t, updated.DisabledAt)
+
+	w = serve(t, router, http.MethodGet, path+"/deliveries?limit=10", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"items":[]}`, w.Body.String())
+
+	w = serve(t, router, http.MethodDelete, path, "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = serve(t, router, http.MethodGet, path, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestHandler_Errors(t *testing.T) {
+	router := newRouter()
+	unknown := "/webhooks/" + uuid.NewString()
+
+	tests := []struct {
+		name         string
+		method, path string
+		body         string
+		status       int
+	}{
+		{"invalid json", http.MethodPost, "/webhooks", `{`, http.StatusBadRequest},
+		{"missing url", http.MethodPost, "/webhooks", `{"event_types":["appointment.booked"]}`, http.StatusBadRequest},
+		{"unknown event type", http.MethodPost, "/webhooks", `{"url":"https://clinic.example.com","event_types":["patient.created"]}`, http.StatusUnprocessableEntity},
+		{"invalid id", http.MethodGet, "/webhooks/not-a-uuid", "", http.StatusBadRequest},
+		{"unknown subscription", http.MethodPatch, unknown, `{"active":true}`, http.StatusNotFound},
+		{"limit out of range", http.MethodGet, unknown + "/deliveries?limit=1000", "", http.StatusBadRequest},
+		{"unknown delivery", http.MethodPost, unknown + "/deliveries/" + uuid.NewString() + "/replay", "", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(t, router, tt.method, tt.path, tt.body)
+			assert.Equal(t, tt.status, w.Code, w.Body.String())
+		})
+	}
+}
@@ -1,6 +1,20 @@
 package api
 
+import "github.com/google/uuid"
+
 type PageEntityDTO interface {
 	IsPageEntityDTO() bool // Use in CreatePaginationResult
 	GetID() string         // Use in CursorPagination
 }
+
+// ErrorDTO is the body handlers return when a request fails.
+type ErrorDTO struct {
+	Error string `json:"error"`
+}
+
+// BatchResult answers a lookup of several IDs at once. Items follow the
+// requested order; IDs that matched nothing are listed in Missing.
+type BatchResult[T any] struct {
+	Items   []T         `json:"items"`
+	Missing []uuid.UUID `json:"missing"`
+}
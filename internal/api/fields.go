@@ -0,0 +1,161 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var _ PageEntityDTO = SparseItem(nil)
+
+// ExpandParams are the query parameters shared by endpoints that support
+// sparse fieldsets and embedded relations.
+type ExpandParams struct {
+	Fields  string `form:"fields" doc:"Comma-separated top-level fields to return, e.g. id,name,avatar_url. The id is always returned."`
+	Include string `form:"include" doc:"Comma-separated related resources to embed in each item"`
+}
+
+// Fieldset is the set of top-level JSON fields a client asked for. A nil
+// Fieldset selects every field.
+type Fieldset map[string]struct{}
+
+// ParseFieldset parses a comma-separated ?fields= value and checks every name
+// against the JSON fields of dto. The id is always selected so paginated and
+// cached responses stay addressable.
+func ParseFieldset(raw string, dto any) (Fieldset, error) {
+	names := splitList(raw)
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	known := jsonFields(reflect.TypeOf(dto))
+	fs := Fieldset{"id": {}}
+	for _, name := range names {
+		if _, ok := known[name]; !ok {
+			return nil, fmt.Errorf("unknown field %q", name)
+		}
+		fs[name] = struct{}{}
+	}
+	return fs, nil
+}
+
+// Add selects extra fields, e.g. embedded relations. It is a no-op on a nil
+// Fieldset, which already selects everything.
+func (f Fieldset) Add(names ...string) {
+	if f == nil {
+		return
+	}
+	for _, name := range names {
+		f[name] = struct{}{}
+	}
+}
+
+// Includes is the set of relations a client asked to embed with ?include=.
+type Includes map[string]struct{}
+
+// ParseIncludes parses a comma-separated ?include= value, rejecting any
+// relation not listed in supported.
+func ParseIncludes(raw string, supported ...string) (Includes, error) {
+	includes := Includes{}
+	for _, name := range splitList(raw) {
+		found := false
+		for _, s := range supported {
+			if s == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unsupported include %q, supported: %s", name, strings.Join(supported, ", "))
+		}
+		includes[name] = struct{}{}
+	}
+	return includes, nil
+}
+
+func (i Includes) Has(name string) bool {
+	_, ok := i[name]
+	return ok
+}
+
+// SparseItem is a DTO reduced to the fields of a Fieldset. It keeps the
+// encoded values so the output matches the full DTO field for field.
+type SparseItem map[string]json.RawMessage
+
+func (s SparseItem) IsPageEntityDTO() bool { return true }
+
+func (s SparseItem) GetID() string {
+	var id string
+	_ = json.Unmarshal(s["id"], &id)
+	return id
+}
+
+// SelectFields reduces every item to the fields in fs.
+func SelectFields[T any](items []T, fs Fieldset) ([]SparseItem, error) {
+	sparse := make([]SparseItem, 0, len(items))
+	for _, item := range items {
+		s, err := SelectItemFields(item, fs)
+		if err != nil {
+			return nil, err
+		}
+		sparse = append(sparse, s)
+	}
+	return sparse, nil
+}
+
+// SelectItemFields reduces a single DTO to the fields in fs.
+func SelectItemFields(item any, fs Fieldset) (SparseItem, error) {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return nil, err
+	}
+
+	var all SparseItem
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+	if fs == nil {
+		return all, nil
+	}
+
+	for name := range all {
+		if _, ok := fs[name]; !ok {
+			delete(all, name)
+		}
+	}
+	return all, nil
+}
+
+func jsonFields(t reflect.Type) map[string]struct{} {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	fields := map[string]struct{}{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = struct{}{}
+	}
+	return fields
+}
+
+func splitList(raw string) []string {
+	var items []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
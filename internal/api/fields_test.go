@@ -0,0 +1,74 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fieldsTestDTO struct {
+	ID        string  `json:"id"`
+	Name      string  `json:"name"`
+	AvatarURL string  `json:"avatar_url,omitempty"`
+	Nested    *string `json:"nested,omitempty"`
+	Hidden    string  `json:"-"`
+}
+
+func TestParseFieldset(t *testing.T) {
+	fs, err := ParseFieldset("", fieldsTestDTO{})
+	require.NoError(t, err)
+	assert.Nil(t, fs)
+
+	fs, err = ParseFieldset(" name, avatar_url ,", fieldsTestDTO{})
+	require.NoError(t, err)
+	assert.Equal(t, Fieldset{"id": {}, "name": {}, "avatar_url": {}}, fs)
+
+	_, err = ParseFieldset("name,Hidden", fieldsTestDTO{})
+	assert.EqualError(t, err, `unknown field "Hidden"`)
+}
+
+func TestFieldset_AddOnNilSelectsEverything(t *testing.T) {
+	var fs Fieldset
+	fs.Add("nested")
+	assert.Nil(t, fs)
+}
+
+func TestParseIncludes(t *testing.T) {
+	includes, err := ParseIncludes("specialty", "specialty")
+	require.NoError(t, err)
+	assert.True(t, includes.Has("specialty"))
+
+	includes, err = ParseIncludes("", "specialty")
+	require.NoError(t, err)
+	assert.False(t, includes.Has("specialty"))
+
+	_, err = ParseIncludes("specialty,clinics", "specialty")
+	assert.EqualError(t, err, `unsupported include "clinics", supported: specialty`)
+}
+
+func TestSelectFields(t *testing.T) {
+	items := []fieldsTestDTO{
+		{ID: "a", Name: "Dr. A", AvatarURL: "https://example.com/a.png"},
+		{ID: "b", Name: "Dr. B"},
+	}
+
+	sparse, err := SelectFields(items, Fieldset{"id": {}, "avatar_url": {}})
+	require.NoError(t, err)
+	require.Len(t, sparse, 2)
+
+	data, err := json.Marshal(sparse)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[{"id":"a","avatar_url":"https://example.com/a.png"},{"id":"b"}]`, string(data))
+	assert.Equal(t, "a", sparse[0].GetID())
+}
+
+func TestSelectItemFields_NilFieldsetKeepsEverything(t *testing.T) {
+	sparse, err := SelectItemFields(fieldsTestDTO{ID: "a", Name: "Dr. A"}, nil)
+	require.NoError(t, err)
+
+	data, err := json.Marshal(sparse)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id":"a","name":"Dr. A"}`, string(data))
+}
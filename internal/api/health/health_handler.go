@@ -0,0 +1,53 @@
+package health
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shayesteh1hs/DrAppointment/internal/health"
+	"github.com/shayesteh1hs/DrAppointment/internal/openapi"
+)
+
+type Handler struct {
+	registry *health.Registry
+}
+
+func NewHandler(registry *health.Registry) *Handler {
+	return &Handler{
+		registry: registry,
+	}
+}
+
+func (h *Handler) Liveness(c *gin.Context) {
+	respond(c, h.registry.Liveness(c.Request.Context()))
+}
+
+func (h *Handler) Readiness(c *gin.Context) {
+	respond(c, h.registry.Readiness(c.Request.Context()))
+}
+
+func respond(c *gin.Context, report health.Report) {
+	c.Header("Cache-Control", "no-store")
+	if !report.Healthy() {
+		c.JSON(http.StatusServiceUnavailable, report)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/livez", h.Liveness)
+	router.GET("/readyz", h.Readiness)
+}
+
+// Operations documents the routes added by RegisterRoutes.
+func (h *Handler) Operations() []openapi.Operation {
+	responses := map[int]any{
+		http.StatusOK:                 health.Report{},
+		http.StatusServiceUnavailable: health.Report{},
+	}
+	return []openapi.Operation{
+		{Method: http.MethodGet, Path: "/livez", ID: "liveness", Summary: "Liveness probe", Tags: []string{"Health"}, Responses: responses},
+		{Method: http.MethodGet, Path: "/readyz", ID: "readiness", Summary: "Readiness probe with per-dependency status", Tags: []string{"Health"}, Responses: responses},
+	}
+}
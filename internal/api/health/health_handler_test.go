@@ -0,0 +1,51 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/health"
+)
+
+func setupHealthRouter(registry *health.Registry) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	NewHandler(registry).RegisterRoutes(&router.RouterGroup)
+	return router
+}
+
+func TestHealthHandler_Readiness_Failing(t *testing.T) {
+	registry := health.NewRegistry(time.Second)
+	registry.AddReadinessCheck("database", func(ctx context.Context) error { return errors.New("connection refused") })
+	router := setupHealthRouter(registry)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	var report health.Report
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(t, health.StatusFail, report.Status)
+	assert.Equal(t, "connection refused", report.Checks["database"].Error)
+}
+
+func TestHealthHandler_Liveness_IgnoresDependencies(t *testing.T) {
+	registry := health.NewRegistry(time.Second)
+	registry.AddReadinessCheck("database", func(ctx context.Context) error { return errors.New("connection refused") })
+	router := setupHealthRouter(registry)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/livez", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,176 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/api"
+	"github.com/shayesteh1hs/DrAppointment/internal/entity"
+	"github.com/shayesteh1hs/DrAppointment/internal/logging"
+	"github.com/shayesteh1hs/DrAppointment/internal/media"
+	"github.com/shayesteh1hs/DrAppointment/internal/openapi"
+	"github.com/shayesteh1hs/DrAppointment/internal/storage"
+)
+
+// SignedLinkParams are the query parameters of links to private objects.
+type SignedLinkParams struct {
+	Expires   string `form:"expires" doc:"Unix time the link expires at; required for keys under private/"`
+	Signature string `form:"signature" doc:"HMAC of the key and expiry; required for keys under private/"`
+}
+
+// Uploaded objects get a fresh key on every upload, so they never change.
+const immutableCacheControl = "public, max-age=31536000, immutable"
+
+type Handler struct {
+	store  storage.Storage
+	signer *media.Signer
+	// variants deduplicates concurrent on-demand generation of a variant
+	variants singleflight.Group
+}
+
+func NewHandler(store storage.Storage, signer *media.Signer) *Handler {
+	return &Handler{
+		store:  store,
+		signer: signer,
+	}
+}
+
+// GetObject streams a stored object with support for range and conditional
+// requests. Private objects require a signed link. Missing resized variants
+// are generated from their original on first request.
+func (h *Handler) GetObject(c *gin.Context) {
+	ctx := c.Request.Context()
+	key := strings.TrimPrefix(c.Param("key"), "/")
+
+	cacheControl := immutableCacheControl
+	if entity.IsPrivateKey(key) {
+		expiry, err := h.signer.Verify(key, c.Request.URL.Query())
+		if err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		// Shared caches must not serve the file past the link's expiry
+		cacheControl = "private, max-age=" + strconv.Itoa(int(time.Until(expiry).Seconds()))
+	}
+
+	obj, err := h.store.Get(ctx, key)
+	if errors.Is(err, storage.ErrNotFound) {
+		obj, err = h.loadVariant(c, key)
+	}
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+			return
+		}
+		logging.FromContext(ctx).Error("failed to read media object", "key", key, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+		return
+	}
+	defer func() { _ = obj.Body.Close() }()
+
+	c.Header("Cache-Control", cacheControl)
+	if obj.ContentType != "" {
+		c.Header("Content-Type", obj.ContentType)
+	}
+
+	if rs, ok := obj.Body.(io.ReadSeeker); ok {
+		http.ServeContent(c.Writer, c.Request, "", obj.ModTime, rs)
+		return
+	}
+
+	if !obj.ModTime.IsZero() {
+		c.Header("Last-Modified", obj.ModTime.UTC().Format(http.TimeFormat))
+	}
+	if obj.Size >= 0 {
+		c.Header("Content-Length", strconv.FormatInt(obj.Size, 10))
+	}
+	c.Status(http.StatusOK)
+	if c.Request.Method == http.MethodHead {
+		return
+	}
+	if _, err := io.Copy(c.Writer, obj.Body); err != nil {
+		logging.FromContext(ctx).Warn("failed to stream media object", "key", key, "error", err)
+	}
+}
+
+type variant struct {
+	data        []byte
+	contentType string
+	created     time.Time
+}
+
+func (h *Handler) loadVariant(c *gin.Context, key string) (*storage.Object, error) {
+	if _, _, _, ok := media.ParseVariantKey(key); !ok {
+		return nil, storage.ErrNotFound
+	}
+
+	// Generation outlives a disconnecting client so other waiters and the
+	// stored copy still get it
+	ctx := context.WithoutCancel(c.Request.Context())
+	v, err, _ := h.variants.Do(key, func() (any, error) {
+		data, f, err := media.LoadVariant(ctx, h.store, key)
+		if err != nil {
+			return nil, err
+		}
+		return variant{data: data, contentType: f.ContentType, created: time.Now()}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	generated := v.(variant)
+	return &storage.Object{
+		Body:        readSeekNopCloser{bytes.NewReader(generated.data)},
+		ContentType: generated.contentType,
+		Size:        int64(len(generated.data)),
+		ModTime:     generated.created,
+	}, nil
+}
+
+type readSeekNopCloser struct {
+	io.ReadSeeker
+}
+
+func (readSeekNopCloser) Close() error { return nil }
+
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/media/*key", h.GetObject)
+	router.HEAD("/media/*key", h.GetObject)
+}
+
+// Operations documents the routes added by RegisterRoutes.
+func (h *Handler) Operations() []openapi.Operation {
+	op := func(method, id, summary string) openapi.Operation {
+		return openapi.Operation{
+			Method:     method,
+			Path:       "/media/*key",
+			ID:         id,
+			Summary:    summary,
+			Tags:       []string{"Media"},
+			PathParams: map[string]any{"key": ""},
+			Query:      []any{SignedLinkParams{}},
+			Responses: map[int]any{
+				http.StatusOK:                           openapi.Response{Description: "The stored file", ContentType: "application/octet-stream", Body: ""},
+				http.StatusPartialContent:               openapi.Response{Description: "The requested byte range of a local file", ContentType: "application/octet-stream", Body: ""},
+				http.StatusNotModified:                  nil,
+				http.StatusForbidden:                    api.ErrorDTO{},
+				http.StatusNotFound:                     api.ErrorDTO{},
+				http.StatusRequestedRangeNotSatisfiable: nil,
+				http.StatusInternalServerError:          api.ErrorDTO{},
+			},
+		}
+	}
+	return []openapi.Operation{
+		op(http.MethodGet, "getMedia", "Download an uploaded file"),
+		op(http.MethodHead, "headMedia", "Check an uploaded file"),
+	}
+}
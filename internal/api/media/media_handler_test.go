@@ -0,0 +1,133 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/media"
+	"github.com/shayesteh1hs/DrAppointment/internal/storage"
+)
+
+// streamStorage serves objects whose bodies cannot seek, like S3 responses.
+type streamStorage struct {
+	storage.Storage
+	objects map[string]string
+}
+
+func (s streamStorage) Get(ctx context.Context, key string) (*storage.Object, error) {
+	data, ok := s.objects[key]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return &storage.Object{Body: io.NopCloser(strings.NewReader(data)), ContentType: "image/webp", Size: int64(len(data))}, nil
+}
+
+var testSigner = media.NewSigner([]byte("0123456789abcdef0123456789abcdef"), time.Minute)
+
+func setupMediaRouter(store storage.Storage) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	NewHandler(store, testSigner).RegisterRoutes(&router.RouterGroup)
+	return router
+}
+
+func TestMediaHandler_StreamsNonSeekableObjects(t *testing.T) {
+	router := setupMediaRouter(streamStorage{objects: map[string]string{"doctors/a/b.webp": "RIFF....WEBP"}})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/media/doctors/a/b.webp", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/webp", w.Header().Get("Content-Type"))
+	assert.Equal(t, "12", w.Header().Get("Content-Length"))
+	assert.Equal(t, immutableCacheControl, w.Header().Get("Cache-Control"))
+	assert.Equal(t, "RIFF....WEBP", w.Body.String())
+}
+
+func TestMediaHandler_NotFound(t *testing.T) {
+	router := setupMediaRouter(streamStorage{})
+
+	for _, path := range []string{"/media/doctors/missing.png", "/media/../etc/passwd"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusNotFound, w.Code, path)
+	}
+}
+
+func TestMediaHandler_LocalRangeRequest(t *testing.T) {
+	store := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, store.Put(context.Background(), "specialties/a.png", strings.NewReader("0123456789"), 10, "image/png"))
+	router := setupMediaRouter(store)
+
+	req := httptest.NewRequest(http.MethodGet, "/media/specialties/a.png", nil)
+	req.Header.Set("Range", "bytes=2-5")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusPartialContent, w.Code)
+	assert.Equal(t, "2345", w.Body.String())
+}
+
+func TestMediaHandler_GeneratesMissingVariants(t *testing.T) {
+	store := storage.NewLocalStorage(t.TempDir())
+	var src bytes.Buffer
+	require.NoError(t, png.Encode(&src, image.NewRGBA(image.Rect(0, 0, 300, 150))))
+	require.NoError(t, store.Put(context.Background(), "specialties/legacy.png", bytes.NewReader(src.Bytes()), int64(src.Len()), "image/png"))
+	router := setupMediaRouter(store)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/media/specialties/legacy.png.128.jpg", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/jpeg", w.Header().Get("Content-Type"))
+	cfg, err := jpeg.DecodeConfig(w.Body)
+	require.NoError(t, err)
+	assert.Equal(t, 128, cfg.Width)
+
+	obj, err := store.Get(context.Background(), "specialties/legacy.png.128.jpg")
+	require.NoError(t, err)
+	_ = obj.Body.Close()
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/media/specialties/other.png.128.jpg", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestMediaHandler_PrivateObjectsRequireSignedLink(t *testing.T) {
+	store := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, store.Put(context.Background(), "private/labs/a.pdf", strings.NewReader("%PDF-1.7 results"), 16, "application/pdf"))
+	router := setupMediaRouter(store)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/media/private/labs/a.pdf", nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// A link signed for another object is refused, even if that one is missing
+	other := testSigner.Sign(&url.URL{Path: "/media/private/labs/b.pdf"}, "private/labs/b.pdf")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/media/private/labs/a.pdf?"+other.RawQuery, nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	link := testSigner.Sign(&url.URL{Path: "/media/private/labs/a.pdf"}, "private/labs/a.pdf")
+	req := httptest.NewRequest(http.MethodGet, link.String(), nil)
+	req.Header.Set("Range", "bytes=9-")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusPartialContent, w.Code)
+	assert.Equal(t, "results", w.Body.String())
+	assert.Equal(t, "bytes 9-15/16", w.Header().Get("Content-Range"))
+	assert.Regexp(t, `^private, max-age=(59|60)$`, w.Header().Get("Cache-Control"))
+}
@@ -0,0 +1,204 @@
+package appointment
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shayesteh1hs/DrAppointment/internal/entity/medical"
+	"github.com/shayesteh1hs/DrAppointment/internal/money"
+	"github.com/shayesteh1hs/DrAppointment/internal/payment"
+)
+
+const defaultDurationMinutes = 30
+
+type BookRequest struct {
+	DoctorID     uuid.UUID `json:"doctor_id" binding:"required"`
+	PatientName  string    `json:"patient_name" binding:"required,max=100"`
+	PatientPhone string    `json:"patient_phone" binding:"required,max=20"`
+	PatientEmail string    `json:"patient_email,omitempty" binding:"omitempty,email,max=255"`
+	Locale       string    `json:"locale,omitempty" binding:"omitempty,oneof=fa en" doc:"Language of notifications, fa (default) or en"`
+	StartsAt     time.Time `json:"starts_at" binding:"required"`
+	// DurationMinutes defaults to 30
+	DurationMinutes int `json:"duration_minutes,omitempty" binding:"omitempty,min=5,max=480" doc:"Length of the visit, 30 minutes by default"`
+}
+
+func (r BookRequest) appointment() medical.Appointment {
+	duration := r.DurationMinutes
+	if duration == 0 {
+		duration = defaultDurationMinutes
+	}
+	locale := r.Locale
+	if locale == "" {
+		locale = "fa"
+	}
+	return medical.Appointment{
+		DoctorID:     r.DoctorID,
+		PatientName:  r.PatientName,
+		PatientPhone: r.PatientPhone,
+		PatientEmail: r.PatientEmail,
+		Locale:       locale,
+		StartsAt:     r.StartsAt,
+		EndsAt:       r.StartsAt.Add(time.Duration(duration) * time.Minute),
+	}
+}
+
+type DetailDTO struct {
+	ID           uuid.UUID                 `json:"id"`
+	DoctorID     uuid.UUID                 `json:"doctor_id"`
+	PatientName  string                    `json:"patient_name"`
+	PatientPhone string                    `json:"patient_phone"`
+	PatientEmail string                    `json:"patient_email,omitempty"`
+	Locale       string                    `json:"locale"`
+	StartsAt     time.Time                 `json:"starts_at"`
+	EndsAt       time.Time                 `json:"ends_at"`
+	Status       medical.AppointmentStatus `json:"status" doc:"pending_payment, booked or cancelled"`
+	CancelledAt  *time.Time                `json:"cancelled_at,omitempty"`
+	CreatedAt    time.Time                 `json:"created_at"`
+}
+
+func NewDetailDTO(appt medical.Appointment) DetailDTO {
+	return DetailDTO{
+		ID:           appt.ID,
+		DoctorID:     appt.DoctorID,
+		PatientName:  appt.PatientName,
+		PatientPhone: appt.PatientPhone,
+		PatientEmail: appt.PatientEmail,
+		Locale:       appt.Locale,
+		StartsAt:     appt.StartsAt,
+		EndsAt:       appt.EndsAt,
+		Status:       appt.Status,
+		CancelledAt:  appt.CancelledAt,
+		CreatedAt:    appt.CreatedAt,
+	}
+}
+
+// BookingDTO is a new appointment and the deposit to pay for it, if any.
+type BookingDTO struct {
+	DetailDTO
+	Payment *PaymentDTO `json:"payment,omitempty" doc:"Deposit the doctor takes; the appointment is pending_payment until the payer completes it at redirect_url"`
+}
+
+func NewBookingDTO(appt medical.Appointment, pay *payment.Payment) BookingDTO {
+	dto := BookingDTO{DetailDTO: NewDetailDTO(appt)}
+	if pay != nil {
+		p := NewPaymentDTO(*pay)
+		dto.Payment = &p
+	}
+	return dto
+}
+
+type PaymentDTO struct {
+	ID            uuid.UUID      `json:"id"`
+	AppointmentID uuid.UUID      `json:"appointment_id"`
+	Amount        int64          `json:"amount" doc:"In whole units of currency"`
+	Currency      money.Currency `json:"currency" doc:"IRR"`
+	Status        payment.Status `json:"status" doc:"pending, paid, failed or expired"`
+	RedirectURL   string         `json:"redirect_url,omitempty" doc:"Gateway page to send the payer to while the payment is pending"`
+	RefID         string         `json:"ref_id,omitempty" doc:"Gateway receipt number of a paid payment"`
+	ExpiresAt     time.Time      `json:"expires_at"`
+	PaidAt        *time.Time     `json:"paid_at,omitempty"`
+}
+
+func NewPaymentDTO(p payment.Payment) PaymentDTO {
+	dto := PaymentDTO{
+		ID:            p.ID,
+		AppointmentID: p.AppointmentID,
+		Amount:        p.Amount.Amount,
+		Currency:      p.Amount.Currency,
+		Status:        p.Status,
+		RefID:         p.RefID,
+		ExpiresAt:     p.ExpiresAt,
+		PaidAt:        p.PaidAt,
+	}
+	if p.Status == payment.StatusPending {
+		dto.RedirectURL = p.RedirectURL
+	}
+	return dto
+}
+
+// CancellationDTO is a cancelled appointment and the refund of its
+// deposit, if any.
+type CancellationDTO struct {
+	DetailDTO
+	Refund *RefundDTO `json:"refund,omitempty" doc:"Refund of the paid deposit, absent when nothing was paid or the cancellation policy keeps all of it"`
+}
+
+func NewCancellationDTO(appt medical.Appointment, refund *payment.Refund) CancellationDTO {
+	dto := CancellationDTO{DetailDTO: NewDetailDTO(appt)}
+	if refund != nil {
+		r := NewRefundDTO(*refund)
+		dto.Refund = &r
+	}
+	return dto
+}
+
+type RefundDTO struct {
+	ID         uuid.UUID            `json:"id"`
+	PaymentID  uuid.UUID            `json:"payment_id"`
+	Amount     int64                `json:"amount" doc:"Refunded, in whole units of currency"`
+	Fee        int64                `json:"fee" doc:"Kept under the cancellation policy, in whole units of currency"`
+	Currency   money.Currency       `json:"currency" doc:"IRR"`
+	Status     payment.RefundStatus `json:"status" doc:"pending, sending, succeeded, failed or unconfirmed"`
+	RefID      string               `json:"ref_id,omitempty"`
+	RefundedAt *time.Time           `json:"refunded_at,omitempty"`
+}
+
+func NewRefundDTO(r payment.Refund) RefundDTO {
+	return RefundDTO{
+		ID:         r.ID,
+		PaymentID:  r.PaymentID,
+		Amount:     r.Amount.Amount,
+		Fee:        r.Fee.Amount,
+		Currency:   r.Amount.Currency,
+		Status:     r.Status,
+		RefID:      r.RefID,
+		RefundedAt: r.RefundedAt,
+	}
+}
+
+type LedgerEntryDTO struct {
+	ID        uuid.UUID         `json:"id"`
+	PaymentID uuid.UUID         `json:"payment_id"`
+	RefundID  *uuid.UUID        `json:"refund_id,omitempty"`
+	Kind      payment.EntryKind `json:"kind" doc:"charge or refund"`
+	Amount    int64             `json:"amount" doc:"In whole units of currency"`
+	Currency  money.Currency    `json:"currency" doc:"IRR"`
+	Reference string            `json:"reference,omitempty" doc:"Gateway reference number"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+type LedgerDTO struct {
+	Entries  []LedgerEntryDTO `json:"entries"`
+	Balance  int64            `json:"balance" doc:"Charged net of refunds, in whole units of currency"`
+	Currency money.Currency   `json:"currency" doc:"IRR"`
+}
+
+// NewLedgerDTO lists entries with their balance, as computed by
+// payment.Balance.
+func NewLedgerDTO(entries []payment.LedgerEntry, balance money.Money) LedgerDTO {
+	dto := LedgerDTO{
+		Entries:  make([]LedgerEntryDTO, len(entries)),
+		Balance:  balance.Amount,
+		Currency: balance.Currency,
+	}
+	for i, e := range entries {
+		dto.Entries[i] = LedgerEntryDTO{
+			ID:        e.ID,
+			PaymentID: e.PaymentID,
+			RefundID:  e.RefundID,
+			Kind:      e.Kind,
+			Amount:    e.Amount.Amount,
+			Currency:  e.Amount.Currency,
+			Reference: e.Reference,
+			CreatedAt: e.CreatedAt,
+		}
+	}
+	return dto
+}
+
+// CallbackParams documents the parameters Zarinpal-style gateways send
+// payers back with; the provider reads them.
+type CallbackParams struct {
+	Authority string `form:"Authority" doc:"Gateway's ID of the payment"`
+	Status    string `form:"Status" doc:"OK when the payer paid, NOK otherwise"`
+}
@@ -0,0 +1,289 @@
+package appointment
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/shayesteh1hs/DrAppointment/internal/api"
+	"github.com/shayesteh1hs/DrAppointment/internal/logging"
+	"github.com/shayesteh1hs/DrAppointment/internal/openapi"
+	"github.com/shayesteh1hs/DrAppointment/internal/payment"
+	"github.com/shayesteh1hs/DrAppointment/internal/repository/medical/appointment"
+	"github.com/shayesteh1hs/DrAppointment/internal/repository/medical/doctor"
+	appointmentService "github.com/shayesteh1hs/DrAppointment/internal/service/medical/appointment"
+)
+
+type Handler struct {
+	service appointmentService.Service
+	// paymentReturnURL is where payers are redirected once their payment
+	// is settled; the callback answers with JSON when it is empty
+	paymentReturnURL string
+}
+
+func NewHandler(service appointmentService.Service, paymentReturnURL string) *Handler {
+	return &Handler{service: service, paymentReturnURL: paymentReturnURL}
+}
+
+func (h *Handler) BookAppointment(c *gin.Context) {
+	var req BookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	appt, pay, err := h.service.Book(c.Request.Context(), req.appointment())
+	if err != nil {
+		switch {
+		case errors.Is(err, appointmentService.ErrStartsInPast):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		case errors.Is(err, doctor.ErrDoctorNotFound):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Doctor not found"})
+		case errors.Is(err, appointment.ErrSlotTaken):
+			c.JSON(http.StatusConflict, gin.H{"error": "The doctor is already booked at this time"})
+		case errors.Is(err, appointmentService.ErrPaymentUnavailable):
+			logging.FromContext(c.Request.Context()).Warn("failed to start deposit payment", "error", err)
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Payment is unavailable, please try again later"})
+		default:
+			logging.FromContext(c.Request.Context()).Error("failed to book appointment", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to book appointment"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, NewBookingDTO(*appt, pay))
+}
+
+func (h *Handler) GetAppointmentByID(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid appointment ID"})
+		return
+	}
+
+	appt, err := h.service.GetByID(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, appointment.ErrAppointmentNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Appointment not found"})
+			return
+		}
+		logging.FromContext(c.Request.Context()).Error("failed to fetch appointment by ID", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch appointment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, NewDetailDTO(*appt))
+}
+
+func (h *Handler) CancelAppointment(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid appointment ID"})
+		return
+	}
+
+	appt, refund, err := h.service.Cancel(c.Request.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, appointment.ErrAppointmentNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Appointment not found"})
+		case errors.Is(err, appointment.ErrNotCancellable):
+			c.JSON(http.StatusConflict, gin.H{"error": "Appointment is already cancelled"})
+		default:
+			logging.FromContext(c.Request.Context()).Error("failed to cancel appointment", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel appointment"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, NewCancellationDTO(*appt, refund))
+}
+
+func (h *Handler) GetLedger(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid appointment ID"})
+		return
+	}
+
+	entries, err := h.service.Ledger(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, appointment.ErrAppointmentNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Appointment not found"})
+			return
+		}
+		logging.FromContext(c.Request.Context()).Error("failed to fetch appointment ledger", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch ledger"})
+		return
+	}
+	balance, err := payment.Balance(entries)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("failed to balance appointment ledger", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch ledger"})
+		return
+	}
+
+	c.JSON(http.StatusOK, NewLedgerDTO(entries, balance))
+}
+
+// PaymentCallback is where the payment provider sends payers back to,
+// with the outcome in the query string or, for some gateways, a form.
+func (h *Handler) PaymentCallback(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payment ID"})
+		return
+	}
+	if err := c.Request.ParseForm(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid callback"})
+		return
+	}
+
+	pay, err := h.service.CompletePayment(c.Request.Context(), id, c.Request.Form)
+	if err != nil {
+		switch {
+		case errors.Is(err, payment.ErrPaymentNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Payment not found"})
+		case errors.Is(err, payment.ErrInvalidCallback):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid callback"})
+		default:
+			logging.FromContext(c.Request.Context()).Error("failed to complete payment", "payment_id", id, "error", err)
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to verify payment, please reload this page"})
+		}
+		return
+	}
+
+	if h.paymentReturnURL != "" {
+		c.Redirect(http.StatusSeeOther, h.returnURL(*pay))
+		return
+	}
+	c.JSON(http.StatusOK, NewPaymentDTO(*pay))
+}
+
+// returnURL adds the payment's outcome to paymentReturnURL.
+func (h *Handler) returnURL(pay payment.Payment) string {
+	u, err := url.Parse(h.paymentReturnURL)
+	if err != nil {
+		// Validated when the configuration was loaded
+		return h.paymentReturnURL
+	}
+	query := u.Query()
+	query.Set("appointment_id", pay.AppointmentID.String())
+	query.Set("payment_id", pay.ID.String())
+	query.Set("status", string(pay.Status))
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
+	appointmentRoutes := router.Group("/appointments")
+	{
+		appointmentRoutes.POST("", h.BookAppointment)
+		appointmentRoutes.GET("/:id", h.GetAppointmentByID)
+		appointmentRoutes.POST("/:id/cancel", h.CancelAppointment)
+		appointmentRoutes.GET("/:id/ledger", h.GetLedger)
+	}
+	// Gateways send payers back with GET or POST
+	router.GET("/payments/:id/callback", h.PaymentCallback)
+	router.POST("/payments/:id/callback", h.PaymentCallback)
+}
+
+// Operations documents the routes added by RegisterRoutes.
+func (h *Handler) Operations() []openapi.Operation {
+	callbackResponses := map[int]any{
+		http.StatusOK: PaymentDTO{},
+		http.StatusSeeOther: openapi.Response{
+			Headers: map[string]string{"Location": "The configured payment return URL with appointment_id, payment_id and status query parameters"},
+		},
+		http.StatusBadRequest: api.ErrorDTO{},
+		http.StatusNotFound:   api.ErrorDTO{},
+		http.StatusBadGateway: api.ErrorDTO{},
+	}
+	return []openapi.Operation{
+		{
+			Method:      http.MethodPost,
+			Path:        "/appointments",
+			ID:          "bookAppointment",
+			Summary:     "Book an appointment",
+			Description: "Books a visit with a doctor. The patient is sent a confirmation right away and reminders 24 and 2 hours before the visit. When the doctor takes a deposit the appointment holds the slot as pending_payment until the payer completes the returned payment at its redirect_url; unpaid bookings are released when the payment expires.",
+			Tags:        []string{"Appointments"},
+			Body:        BookRequest{},
+			Responses: map[int]any{
+				http.StatusCreated:             BookingDTO{},
+				http.StatusBadRequest:          api.ErrorDTO{},
+				http.StatusConflict:            api.ErrorDTO{},
+				http.StatusUnprocessableEntity: api.ErrorDTO{},
+				http.StatusInternalServerError: api.ErrorDTO{},
+				http.StatusBadGateway:          api.ErrorDTO{},
+			},
+		},
+		{
+			Method:     http.MethodGet,
+			Path:       "/appointments/:id",
+			ID:         "getAppointment",
+			Summary:    "Get an appointment",
+			Tags:       []string{"Appointments"},
+			PathParams: map[string]any{"id": uuid.UUID{}},
+			Responses: map[int]any{
+				http.StatusOK:                  DetailDTO{},
+				http.StatusBadRequest:          api.ErrorDTO{},
+				http.StatusNotFound:            api.ErrorDTO{},
+				http.StatusInternalServerError: api.ErrorDTO{},
+			},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/appointments/:id/cancel",
+			ID:          "cancelAppointment",
+			Summary:     "Cancel an appointment",
+			Description: "Cancels a booked appointment; reminders not yet sent are dropped. A paid deposit is refunded under the doctor's cancellation policy: in full until the policy's notice period (24 hours by default), less the policy's late fee (50% by default) until the visit starts, and not at all afterwards. The refund is sent to the payment provider in the background.",
+			Tags:        []string{"Appointments"},
+			PathParams:  map[string]any{"id": uuid.UUID{}},
+			Responses: map[int]any{
+				http.StatusOK:                  CancellationDTO{},
+				http.StatusBadRequest:          api.ErrorDTO{},
+				http.StatusNotFound:            api.ErrorDTO{},
+				http.StatusConflict:            api.ErrorDTO{},
+				http.StatusInternalServerError: api.ErrorDTO{},
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/appointments/:id/ledger",
+			ID:          "getAppointmentLedger",
+			Summary:     "Get the payments of an appointment",
+			Description: "Lists the deposit charged and the refunds made for an appointment, with what was paid net of refunds.",
+			Tags:        []string{"Appointments"},
+			PathParams:  map[string]any{"id": uuid.UUID{}},
+			Responses: map[int]any{
+				http.StatusOK:                  LedgerDTO{},
+				http.StatusBadRequest:          api.ErrorDTO{},
+				http.StatusNotFound:            api.ErrorDTO{},
+				http.StatusInternalServerError: api.ErrorDTO{},
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/payments/:id/callback",
+			ID:          "paymentCallback",
+			Summary:     "Payment gateway callback",
+			Description: "Where the payment gateway sends the payer back to. The payment is verified with the gateway and, once paid, the appointment is booked. Payers are redirected to the configured return URL, or the payment is returned when none is set.",
+			Tags:        []string{"Appointments"},
+			PathParams:  map[string]any{"id": uuid.UUID{}},
+			Query:       []any{CallbackParams{}},
+			Responses:   callbackResponses,
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/payments/:id/callback",
+			ID:          "paymentCallbackPost",
+			Summary:     "Payment gateway callback (form post)",
+			Description: "Same as the GET callback, for gateways posting the outcome as a form.",
+			Tags:        []string{"Appointments"},
+			PathParams:  map[string]any{"id": uuid.UUID{}},
+			Responses:   callbackResponses,
+		},
+	}
+}
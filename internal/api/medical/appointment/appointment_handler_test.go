@@ -0,0 +1,187 @@
+package appointment
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/entity/medical"
+	"github.com/shayesteh1hs/DrAppointment/internal/money"
+	"github.com/shayesteh1hs/DrAppointment/internal/payment"
+	"github.com/shayesteh1hs/DrAppointment/internal/repository/medical/appointment"
+	"github.com/shayesteh1hs/DrAppointment/internal/repository/medical/doctor"
+	appointmentService "github.com/shayesteh1hs/DrAppointment/internal/service/medical/appointment"
+)
+
+type stubService struct {
+	appointmentService.Service
+	booked   medical.Appointment
+	payment  *payment.Payment
+	refund   *payment.Refund
+	ledger   []payment.LedgerEntry
+	callback url.Values
+	err      error
+}
+
+func (s *stubService) Book(ctx context.Context, appt medical.Appointment) (*medical.Appointment, *payment.Payment, error) {
+	if s.err != nil {
+		return nil, nil, s.err
+	}
+	s.booked = appt
+	appt.ID = uuid.New()
+	appt.Status = medical.AppointmentBooked
+	return &appt, s.payment, nil
+}
+
+func (s *stubService) CompletePayment(ctx context.Context, paymentID uuid.UUID, callback url.Values) (*payment.Payment, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	s.callback = callback
+	return s.payment, nil
+}
+
+func (s *stubService) Cancel(ctx context.Context, id uuid.UUID) (*medical.Appointment, *payment.Refund, error) {
+	if s.err != nil {
+		return nil, nil, s.err
+	}
+	return &medical.Appointment{ID: id, Status: medical.AppointmentCancelled}, s.refund, nil
+}
+
+func (s *stubService) Ledger(ctx context.Context, id uuid.UUID) ([]payment.LedgerEntry, error) {
+	return s.ledger, s.err
+}
+
+func serve(t *testing.T, service appointmentService.Service, method, target, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	return serveHandler(t, NewHandler(service, ""), method, target, body)
+}
+
+func serveHandler(t *testing.T, handler *Handler, method, target, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handler.RegisterRoutes(router.Group("/"))
+
+	req, err := http.NewRequest(method, target, strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestHandler_BookAppointment(t *testing.T) {
+	service := &stubService{}
+	body := `{"doctor_id":"` + uuid.NewString() + `","patient_name":"Sara","patient_phone":"+989121234567","starts_at":"2030-01-02T10:00:00Z"}`
+
+	w := serve(t, service, http.MethodPost, "/appointments", body)
+
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Equal(t, "fa", service.booked.Locale)
+	assert.Equal(t, 30*time.Minute, service.booked.EndsAt.Sub(service.booked.StartsAt))
+}
+
+func TestHandler_BookAppointment_Errors(t *testing.T) {
+	body := `{"doctor_id":"` + uuid.NewString() + `","patient_name":"Sara","patient_phone":"+989121234567","starts_at":"2030-01-02T10:00:00Z"}`
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{appointment.ErrSlotTaken, http.StatusConflict},
+		{doctor.ErrDoctorNotFound, http.StatusUnprocessableEntity},
+		{appointmentService.ErrStartsInPast, http.StatusUnprocessableEntity},
+		{appointmentService.ErrPaymentUnavailable, http.StatusBadGateway},
+	}
+	for _, tt := range tests {
+		w := serve(t, &stubService{err: tt.err}, http.MethodPost, "/appointments", body)
+		assert.Equal(t, tt.want, w.Code, tt.err.Error())
+	}
+
+	w := serve(t, &stubService{}, http.MethodPost, "/appointments", `{"patient_name":"Sara"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestHandler_CancelAppointment(t *testing.T) {
+	w := serve(t, &stubService{err: appointment.ErrNotCancellable}, http.MethodPost, "/appointments/"+uuid.NewString()+"/cancel", "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = serve(t, &stubService{err: appointment.ErrAppointmentNotFound}, http.MethodPost, "/appointments/"+uuid.NewString()+"/cancel", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	refund := &payment.Refund{ID: uuid.New(), Amount: money.Rials(250_000), Fee: money.Rials(250_000), Status: payment.RefundPending}
+	w = serve(t, &stubService{refund: refund}, http.MethodPost, "/appointments/"+uuid.NewString()+"/cancel", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"refund":{"id":"`+refund.ID.String()+`"`)
+	assert.Contains(t, w.Body.String(), `"amount":250000,"fee":250000,"currency":"IRR","status":"pending"`)
+
+	w = serve(t, &stubService{}, http.MethodPost, "/appointments/"+uuid.NewString()+"/cancel", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "refund")
+}
+
+func TestHandler_GetLedger(t *testing.T) {
+	paymentID, refundID := uuid.New(), uuid.New()
+	service := &stubService{ledger: []payment.LedgerEntry{
+		{ID: uuid.New(), PaymentID: paymentID, Kind: payment.EntryCharge, Amount: money.Rials(500_000), Reference: "201"},
+		{ID: uuid.New(), PaymentID: paymentID, RefundID: &refundID, Kind: payment.EntryRefund, Amount: money.Rials(250_000)},
+	}}
+
+	w := serve(t, service, http.MethodGet, "/appointments/"+uuid.NewString()+"/ledger", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"balance":250000,"currency":"IRR"`)
+	assert.Contains(t, w.Body.String(), `"refund_id":"`+refundID.String()+`"`)
+
+	w = serve(t, &stubService{err: appointment.ErrAppointmentNotFound}, http.MethodGet, "/appointments/"+uuid.NewString()+"/ledger", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestHandler_BookAppointment_WithDeposit(t *testing.T) {
+	pay := &payment.Payment{
+		ID:          uuid.New(),
+		Amount:      money.Rials(500_000),
+		Status:      payment.StatusPending,
+		RedirectURL: "https://gateway.example.com/StartPay/A1",
+	}
+	body := `{"doctor_id":"` + uuid.NewString() + `","patient_name":"Sara","patient_phone":"+989121234567","starts_at":"2030-01-02T10:00:00Z"}`
+
+	w := serve(t, &stubService{payment: pay}, http.MethodPost, "/appointments", body)
+
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"redirect_url":"https://gateway.example.com/StartPay/A1"`)
+	assert.Contains(t, w.Body.String(), `"amount":500000`)
+}
+
+func TestHandler_PaymentCallback(t *testing.T) {
+	pay := &payment.Payment{ID: uuid.New(), AppointmentID: uuid.New(), Status: payment.StatusPaid, RefID: "201"}
+	target := "/payments/" + pay.ID.String() + "/callback?Authority=A1&Status=OK"
+
+	service := &stubService{payment: pay}
+	w := serve(t, service, http.MethodGet, target, "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "A1", service.callback.Get("Authority"))
+	assert.Contains(t, w.Body.String(), `"ref_id":"201"`)
+
+	w = serveHandler(t, NewHandler(service, "https://app.example.com/booking/done?source=pay"), http.MethodGet, target, "")
+	require.Equal(t, http.StatusSeeOther, w.Code)
+	location, err := url.Parse(w.Header().Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, "app.example.com", location.Host)
+	assert.Equal(t, "pay", location.Query().Get("source"))
+	assert.Equal(t, "paid", location.Query().Get("status"))
+	assert.Equal(t, pay.AppointmentID.String(), location.Query().Get("appointment_id"))
+
+	w = serve(t, &stubService{err: payment.ErrInvalidCallback}, http.MethodPost, target, "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = serve(t, &stubService{err: payment.ErrPaymentNotFound}, http.MethodGet, target, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -3,18 +3,27 @@ package doctor
 import (
 	"github.com/google/uuid"
 	"github.com/shayesteh1hs/DrAppointment/internal/api"
+	specialtyAPI "github.com/shayesteh1hs/DrAppointment/internal/api/medical/specialty"
 	"github.com/shayesteh1hs/DrAppointment/internal/entity/medical"
+	"github.com/shayesteh1hs/DrAppointment/internal/money"
+	"github.com/shayesteh1hs/DrAppointment/internal/utils"
 )
 
 var _ api.PageEntityDTO = (*ListItemDTO)(nil)
 
 type ListItemDTO struct {
-	ID          uuid.UUID `json:"id"`
-	Name        string    `json:"name"`
-	SpecialtyID uuid.UUID `json:"specialty_id"`
-	PhoneNumber string    `json:"phone_number"`
-	AvatarURL   string    `json:"avatar_url,omitempty"`
-	Description string    `json:"description,omitempty"`
+	ID           uuid.UUID                 `json:"id"`
+	Name         string                    `json:"name"`
+	SpecialtyID  uuid.UUID                 `json:"specialty_id"`
+	PhoneNumber  string                    `json:"phone_number"`
+	AvatarURL    string                    `json:"avatar_url,omitempty"`
+	AvatarSrcset map[string]map[int]string `json:"avatar_srcset,omitempty" doc:"Resized copies of the avatar by format (webp, jpeg) and bounding box in pixels (64, 128, 512)"`
+	Description  string                    `json:"description,omitempty"`
+
+	Specialty         *specialtyAPI.ListItemDTO `json:"specialty,omitempty" doc:"Embedded with include=specialty"`
+	Services          []ServiceDTO              `json:"services,omitempty" doc:"Embedded with include=services, cheapest first"`
+	Clinics           []ClinicDTO               `json:"clinics,omitempty" doc:"Embedded with include=clinics"`
+	NextAvailableSlot *SlotDTO                  `json:"next_available_slot,omitempty" doc:"Embedded with include=next_available_slot; absent when nothing is free in the next two weeks"`
 }
 
 func newListItemDTO(doctors []medical.Doctor) []ListItemDTO {
@@ -22,12 +31,13 @@ func newListItemDTO(doctors []medical.Doctor) []ListItemDTO {
 
 	for _, doctor := range doctors {
 		items = append(items, ListItemDTO{
-			ID:          doctor.ID,
-			Name:        doctor.Name,
-			SpecialtyID: doctor.SpecialtyID,
-			PhoneNumber: doctor.PhoneNumber,
-			AvatarURL:   doctor.AvatarURL,
-			Description: doctor.Description,
+			ID:           doctor.ID,
+			Name:         doctor.Name,
+			SpecialtyID:  doctor.SpecialtyID,
+			PhoneNumber:  doctor.PhoneNumber,
+			AvatarURL:    avatarURL(doctor),
+			AvatarSrcset: avatarSrcset(doctor),
+			Description:  doctor.Description,
 		})
 	}
 
@@ -37,26 +47,135 @@ func newListItemDTO(doctors []medical.Doctor) []ListItemDTO {
 func (d ListItemDTO) IsPageEntityDTO() bool { return true }
 func (d ListItemDTO) GetID() string         { return d.ID.String() }
 
+func avatarURL(doctor medical.Doctor) string {
+	if doctor.Avatar == nil {
+		return ""
+	}
+	return utils.GetFullImageURL(doctor.Avatar)
+}
+
+func avatarSrcset(doctor medical.Doctor) map[string]map[int]string {
+	if doctor.Avatar == nil {
+		return nil
+	}
+	return utils.GetImageVariantURLs(doctor.Avatar)
+}
+
 type DetailDTO struct {
-	ID          uuid.UUID `json:"id"`
-	Name        string    `json:"name"`
-	SpecialtyID uuid.UUID `json:"specialty_id"`
-	PhoneNumber string    `json:"phone_number"`
-	AvatarURL   string    `json:"avatar_url,omitempty"`
-	Description string    `json:"description,omitempty"`
-	CreatedAt   string    `json:"created_at"`
-	UpdatedAt   string    `json:"updated_at"`
+	ID           uuid.UUID                 `json:"id"`
+	Name         string                    `json:"name"`
+	SpecialtyID  uuid.UUID                 `json:"specialty_id"`
+	PhoneNumber  string                    `json:"phone_number"`
+	AvatarURL    string                    `json:"avatar_url,omitempty"`
+	AvatarSrcset map[string]map[int]string `json:"avatar_srcset,omitempty" doc:"Resized copies of the avatar by format (webp, jpeg) and bounding box in pixels (64, 128, 512)"`
+	Description  string                    `json:"description,omitempty"`
+	CreatedAt    string                    `json:"created_at"`
+	UpdatedAt    string                    `json:"updated_at"`
+
+	Specialty         *specialtyAPI.ListItemDTO `json:"specialty,omitempty" doc:"Embedded with include=specialty"`
+	Services          []ServiceDTO              `json:"services,omitempty" doc:"Embedded with include=services, cheapest first"`
+	Clinics           []ClinicDTO               `json:"clinics,omitempty" doc:"Embedded with include=clinics"`
+	NextAvailableSlot *SlotDTO                  `json:"next_available_slot,omitempty" doc:"Embedded with include=next_available_slot; absent when nothing is free in the next two weeks"`
 }
 
 func NewDetailDTO(doctor medical.Doctor) DetailDTO {
 	return DetailDTO{
-		ID:          doctor.ID,
-		Name:        doctor.Name,
-		SpecialtyID: doctor.SpecialtyID,
-		PhoneNumber: doctor.PhoneNumber,
-		AvatarURL:   doctor.AvatarURL,
-		Description: doctor.Description,
-		CreatedAt:   doctor.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-		UpdatedAt:   doctor.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		ID:           doctor.ID,
+		Name:         doctor.Name,
+		SpecialtyID:  doctor.SpecialtyID,
+		PhoneNumber:  doctor.PhoneNumber,
+		AvatarURL:    avatarURL(doctor),
+		AvatarSrcset: avatarSrcset(doctor),
+		Description:  doctor.Description,
+		CreatedAt:    doctor.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt:    doctor.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
+
+// PriceDTO is an amount in whole units of its currency.
+type PriceDTO struct {
+	Amount   int64          `json:"amount"`
+	Currency money.Currency `json:"currency" doc:"IRR"`
+	Toman    *int64         `json:"toman,omitempty" doc:"The amount in tomans, when it is a whole number of them"`
+}
+
+func newPriceDTO(price money.Money) PriceDTO {
+	dto := PriceDTO{Amount: price.Amount, Currency: price.Currency}
+	if toman, err := price.In(money.IRT); err == nil {
+		dto.Toman = &toman.Amount
 	}
+	return dto
+}
+
+// ServiceDTO is a service a doctor offers.
+type ServiceDTO struct {
+	ID              uuid.UUID            `json:"id"`
+	Kind            medical.OfferingKind `json:"kind" doc:"in_person, online, follow_up or procedure"`
+	Name            string               `json:"name"`
+	DurationMinutes int                  `json:"duration_minutes"`
+	Price           PriceDTO             `json:"price"`
+}
+
+func newServiceDTOs(offerings []medical.Offering) []ServiceDTO {
+	services := make([]ServiceDTO, 0, len(offerings))
+	for _, o := range offerings {
+		services = append(services, ServiceDTO{
+			ID:              o.ID,
+			Kind:            o.Kind,
+			Name:            o.Name,
+			DurationMinutes: o.DurationMinutes,
+			Price:           newPriceDTO(o.Price),
+		})
+	}
+	return services
+}
+
+// ClinicDTO is a clinic a doctor practices at.
+type ClinicDTO struct {
+	ID          uuid.UUID `json:"id"`
+	Name        string    `json:"name"`
+	Address     string    `json:"address"`
+	City        string    `json:"city"`
+	PhoneNumber string    `json:"phone_number,omitempty"`
+}
+
+func newClinicDTOs(clinics []medical.Clinic) []ClinicDTO {
+	dtos := make([]ClinicDTO, 0, len(clinics))
+	for _, c := range clinics {
+		dtos = append(dtos, ClinicDTO{
+			ID:          c.ID,
+			Name:        c.Name,
+			Address:     c.Address,
+			City:        c.City,
+			PhoneNumber: c.PhoneNumber,
+		})
+	}
+	return dtos
+}
+
+// SlotDTO is a free appointment slot.
+type SlotDTO struct {
+	StartsAt string     `json:"starts_at"`
+	EndsAt   string     `json:"ends_at"`
+	ClinicID *uuid.UUID `json:"clinic_id,omitempty" doc:"The clinic the slot is at, when the working hours name one"`
+}
+
+func newSlotDTO(slot medical.Slot) *SlotDTO {
+	dto := &SlotDTO{
+		StartsAt: slot.StartsAt.Format("2006-01-02T15:04:05Z07:00"),
+		EndsAt:   slot.EndsAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+	if slot.ClinicID != uuid.Nil {
+		dto.ClinicID = &slot.ClinicID
+	}
+	return dto
+}
+
+// BatchQueryParam switches GET /doctors to a lookup by ID.
+type BatchQueryParam struct {
+	IDs string `form:"ids" doc:"Comma-separated doctor IDs to look up, at most 100"`
+}
+
+type BatchGetRequestDTO struct {
+	IDs []uuid.UUID `json:"ids" binding:"required,min=1" doc:"Doctor IDs to look up, at most 100"`
 }
@@ -1,29 +1,70 @@
 package doctor
 
 import (
+	"context"
 	"errors"
-	"log"
+	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/shayesteh1hs/DrAppointment/internal/api"
+	specialtyAPI "github.com/shayesteh1hs/DrAppointment/internal/api/medical/specialty"
+	"github.com/shayesteh1hs/DrAppointment/internal/conditional"
+	"github.com/shayesteh1hs/DrAppointment/internal/database"
+	"github.com/shayesteh1hs/DrAppointment/internal/entity/medical"
 	medicalFilter "github.com/shayesteh1hs/DrAppointment/internal/filter/medical"
+	"github.com/shayesteh1hs/DrAppointment/internal/logging"
+	"github.com/shayesteh1hs/DrAppointment/internal/middleware"
+	"github.com/shayesteh1hs/DrAppointment/internal/openapi"
 	"github.com/shayesteh1hs/DrAppointment/internal/pagination"
 	"github.com/shayesteh1hs/DrAppointment/internal/repository/medical/doctor"
 	medicalService "github.com/shayesteh1hs/DrAppointment/internal/service/medical/doctor"
+	specialtyService "github.com/shayesteh1hs/DrAppointment/internal/service/medical/specialty"
+)
+
+const (
+	// includeSpecialty embeds each doctor's specialty.
+	includeSpecialty = "specialty"
+	// includeServices embeds the services each doctor offers and their
+	// prices.
+	includeServices = "services"
+	// includeClinics embeds the clinics each doctor practices at.
+	includeClinics = "clinics"
+	// includeNextAvailableSlot embeds each doctor's earliest free slot.
+	includeNextAvailableSlot = "next_available_slot"
+
+	// maxBatchIDs caps how many doctors one batch lookup may ask for.
+	maxBatchIDs = 100
 )
 
 type Handler struct {
-	service medicalService.Service
+	service          medicalService.Service
+	specialtyService specialtyService.Service
+	maxImageBytes    int64
 }
 
-func NewHandler(service medicalService.Service) *Handler {
+func NewHandler(service medicalService.Service, specialtyService specialtyService.Service, maxImageBytes int64) *Handler {
 	return &Handler{
-		service: service,
+		service:          service,
+		specialtyService: specialtyService,
+		maxImageBytes:    maxImageBytes,
 	}
 }
 
 func (h *Handler) ListDoctors(c *gin.Context) {
+	if raw, ok := c.GetQuery("ids"); ok {
+		ids, err := parseIDList(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		h.writeBatch(c, ids)
+		return
+	}
+
 	paginator := pagination.NewOffsetPaginator[ListItemDTO]()
 	if err := paginator.BindQueryParam(c); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -40,22 +81,45 @@ func (h *Handler) ListDoctors(c *gin.Context) {
 		return
 	}
 
+	fields, includes, ok := bindExpandParams(c, ListItemDTO{})
+	if !ok {
+		return
+	}
+
 	doctors, totalCount, err := h.service.ListDoctorsOffset(c.Request.Context(), filterParams, paginator.GetParams())
 	if err != nil {
-		log.Printf("failed to fetch doctors: %v", err)
+		logging.FromContext(c.Request.Context()).Error("failed to fetch doctors", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch doctors"})
 		return
 	}
 
-	doctorsDTO := newListItemDTO(doctors)
-	result, err := paginator.CreatePaginationResult(doctorsDTO, totalCount)
+	doctorsDTO, lastModified, err := h.newListItems(c.Request.Context(), doctors, includes)
 	if err != nil {
-		log.Printf("failed to create pagination result: %v", err)
+		logging.FromContext(c.Request.Context()).Error("failed to fetch doctor specialties", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch doctors"})
+		return
+	}
+
+	var result any
+	if fields == nil {
+		result, err = paginator.CreatePaginationResult(doctorsDTO, totalCount)
+	} else {
+		var items []api.SparseItem
+		items, err = api.SelectFields(doctorsDTO, fields)
+		if err == nil {
+			result, err = pagination.NewLimitOffsetPaginator[api.SparseItem](paginator.GetParams()).CreatePaginationResult(items, totalCount)
+		}
+	}
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("failed to create pagination result", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create pagination result"})
 		return
 	}
 
-	c.JSON(http.StatusOK, result)
+	if err := conditional.JSON(c, http.StatusOK, result, lastModified); err != nil {
+		logging.FromContext(c.Request.Context()).Error("failed to write doctors response", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch doctors"})
+	}
 }
 
 func (h *Handler) GetDoctorByID(c *gin.Context) {
@@ -66,18 +130,224 @@ func (h *Handler) GetDoctorByID(c *gin.Context) {
 		return
 	}
 
+	fields, includes, ok := bindExpandParams(c, DetailDTO{})
+	if !ok {
+		return
+	}
+
 	doc, err := h.service.GetByID(c.Request.Context(), id)
 	if err != nil {
 		if errors.Is(err, doctor.ErrDoctorNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Doctor not found"})
 			return
 		}
-		log.Printf("failed to fetch doctor by ID: %v", err)
+		logging.FromContext(c.Request.Context()).Error("failed to fetch doctor by ID", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch doctor"})
 		return
 	}
 
 	response := NewDetailDTO(*doc)
+	lastModified := doc.UpdatedAt
+	if includes.Has(includeSpecialty) {
+		specialties, err := h.loadSpecialties(c.Request.Context(), *doc)
+		if err != nil {
+			logging.FromContext(c.Request.Context()).Error("failed to fetch doctor specialty", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch doctor"})
+			return
+		}
+		response.Specialty = specialties.dto(doc.SpecialtyID)
+		lastModified = later(lastModified, specialties.lastModified)
+	}
+	if includes.Has(includeServices) {
+		services, err := h.loadServices(c.Request.Context(), *doc)
+		if err != nil {
+			logging.FromContext(c.Request.Context()).Error("failed to fetch doctor services", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch doctor"})
+			return
+		}
+		response.Services = services.dtos(doc.ID)
+		lastModified = later(lastModified, services.lastModified)
+	}
+	if includes.Has(includeClinics) {
+		clinics, err := h.loadClinics(c.Request.Context(), *doc)
+		if err != nil {
+			logging.FromContext(c.Request.Context()).Error("failed to fetch doctor clinics", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch doctor"})
+			return
+		}
+		response.Clinics = clinics.dtos(doc.ID)
+		lastModified = later(lastModified, clinics.lastModified)
+	}
+	if includes.Has(includeNextAvailableSlot) {
+		slots, err := h.service.NextAvailableSlots(c.Request.Context(), []uuid.UUID{doc.ID})
+		if err != nil {
+			logging.FromContext(c.Request.Context()).Error("failed to fetch doctor next available slot", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch doctor"})
+			return
+		}
+		if slot, ok := slots[doc.ID]; ok {
+			response.NextAvailableSlot = newSlotDTO(slot)
+		}
+		// Slots change with the clock and with bookings, so only the
+		// ETag can validate the response
+		lastModified = time.Time{}
+	}
+
+	var body any = response
+	if fields != nil {
+		body, err = api.SelectItemFields(response, fields)
+		if err != nil {
+			logging.FromContext(c.Request.Context()).Error("failed to select doctor fields", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch doctor"})
+			return
+		}
+	}
+
+	if err := conditional.JSON(c, http.StatusOK, body, lastModified); err != nil {
+		logging.FromContext(c.Request.Context()).Error("failed to write doctor response", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch doctor"})
+	}
+}
+
+// BatchGetDoctors looks up the doctors listed in the request body, for
+// clients whose ID lists do not fit in a query string.
+func (h *Handler) BatchGetDoctors(c *gin.Context) {
+	// gin cannot route a literal colon, so ":batchGet" arrives as the value
+	// of a parameter that starts right after "/doctors".
+	if c.Param("batchGet") != ":batchGet" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+		return
+	}
+
+	var request BatchGetRequestDTO
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if len(request.IDs) > maxBatchIDs {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("at most %d ids are allowed", maxBatchIDs)})
+		return
+	}
+
+	h.writeBatch(c, request.IDs)
+}
+
+// writeBatch answers a lookup by IDs with the doctors in requested order and
+// the IDs that matched none.
+func (h *Handler) writeBatch(c *gin.Context, ids []uuid.UUID) {
+	fields, includes, ok := bindExpandParams(c, ListItemDTO{})
+	if !ok {
+		return
+	}
+
+	doctors, missing, err := h.service.GetByIDs(c.Request.Context(), ids)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("failed to fetch doctors by IDs", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch doctors"})
+		return
+	}
+	if missing == nil {
+		missing = []uuid.UUID{}
+	}
+
+	doctorsDTO, lastModified, err := h.newListItems(c.Request.Context(), doctors, includes)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("failed to fetch doctor specialties", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch doctors"})
+		return
+	}
+
+	var result any = api.BatchResult[ListItemDTO]{Items: doctorsDTO, Missing: missing}
+	if fields != nil {
+		items, err := api.SelectFields(doctorsDTO, fields)
+		if err != nil {
+			logging.FromContext(c.Request.Context()).Error("failed to select doctor fields", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch doctors"})
+			return
+		}
+		result = api.BatchResult[api.SparseItem]{Items: items, Missing: missing}
+	}
+
+	if err := conditional.JSON(c, http.StatusOK, result, lastModified); err != nil {
+		logging.FromContext(c.Request.Context()).Error("failed to write doctors response", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch doctors"})
+	}
+}
+
+func (h *Handler) UploadDoctorAvatar(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid doctor ID"})
+		return
+	}
+
+	ifUpdatedAt, ok := h.checkPreconditions(c, id)
+	if !ok {
+		return
+	}
+
+	img, ok := api.BindImage(c, h.maxImageBytes)
+	if !ok {
+		return
+	}
+
+	doc, err := h.service.SetAvatar(c.Request.Context(), id, img, ifUpdatedAt)
+	if err != nil {
+		if errors.Is(err, doctor.ErrDoctorModified) {
+			conditional.PreconditionFailed(c)
+			return
+		}
+		if errors.Is(err, doctor.ErrDoctorNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Doctor not found"})
+			return
+		}
+		logging.FromContext(c.Request.Context()).Error("failed to upload doctor avatar", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload doctor avatar"})
+		return
+	}
+
+	writeDetail(c, *doc)
+}
+
+// checkPreconditions compares If-Match and If-Unmodified-Since with the
+// doctor as GET /doctors/:id returns it without fields or includes,
+// aborting the request when they fail. It returns the version it checked
+// against, for the write to be made conditional on; zero when the request
+// has no preconditions.
+func (h *Handler) checkPreconditions(c *gin.Context, id uuid.UUID) (time.Time, bool) {
+	if !conditional.HasPreconditions(c.Request) {
+		return time.Time{}, true
+	}
+
+	// Replicas may not have the client's last write yet
+	doc, err := h.service.GetByID(database.WithPrimary(c.Request.Context()), id)
+	if err != nil {
+		if errors.Is(err, doctor.ErrDoctorNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Doctor not found"})
+			return time.Time{}, false
+		}
+		logging.FromContext(c.Request.Context()).Error("failed to fetch doctor by ID", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch doctor"})
+		return time.Time{}, false
+	}
+
+	etag, err := conditional.ETagOf(NewDetailDTO(*doc))
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("failed to compute doctor ETag", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch doctor"})
+		return time.Time{}, false
+	}
+	return doc.UpdatedAt, conditional.CheckPreconditions(c, etag, doc.UpdatedAt)
+}
+
+// writeDetail answers a write with the doctor's new representation and its
+// validators, so clients can chain conditional requests.
+func writeDetail(c *gin.Context, doc medical.Doctor) {
+	response := NewDetailDTO(doc)
+	if etag, err := conditional.ETagOf(response); err == nil {
+		c.Header("ETag", etag)
+	}
+	c.Header("Last-Modified", doc.UpdatedAt.UTC().Format(http.TimeFormat))
 	c.JSON(http.StatusOK, response)
 }
 
@@ -86,5 +356,287 @@ func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
 	{
 		doctorRoutes.GET("", h.ListDoctors)
 		doctorRoutes.GET("/:id", h.GetDoctorByID)
+		doctorRoutes.PUT("/:id/avatar", h.UploadDoctorAvatar)
+	}
+	router.POST("/doctors:batchGet", h.BatchGetDoctors)
+}
+
+// Operations documents the routes added by RegisterRoutes.
+func (h *Handler) Operations() []openapi.Operation {
+	return []openapi.Operation{
+		{
+			Method:  http.MethodGet,
+			Path:    "/doctors",
+			ID:      "listDoctors",
+			Summary: "List doctors",
+			Description: "Use fields to return a subset of each doctor and include=specialty, " +
+				"include=services, include=clinics or include=next_available_slot to embed the specialty, " +
+				"the services offered and their prices, the clinics or the earliest free slot; " +
+				"omitted fields are left out of the items. The service filters match doctors with at " +
+				"least one service of the given kind within the fee range. " +
+				"With ids the doctors are looked up by ID instead: filters and pagination are " +
+				"ignored and the response lists the items in requested order plus the missing IDs.",
+			Tags:  []string{"Doctors"},
+			Query: []any{pagination.LimitOffsetParams{}, medicalFilter.DoctorQueryParam{}, api.ExpandParams{}},
+			Responses: map[int]any{
+				http.StatusOK:                  pagination.Result[ListItemDTO]{},
+				http.StatusNotModified:         nil,
+				http.StatusBadRequest:          api.ErrorDTO{},
+				http.StatusInternalServerError: api.ErrorDTO{},
+			},
+		},
+		{
+			Method:     http.MethodGet,
+			Path:       "/doctors/:id",
+			ID:         "getDoctor",
+			Summary:    "Get a doctor",
+			Tags:       []string{"Doctors"},
+			PathParams: map[string]any{"id": uuid.UUID{}},
+			Query:      []any{api.ExpandParams{}},
+			Responses: map[int]any{
+				http.StatusOK:                  DetailDTO{},
+				http.StatusNotModified:         nil,
+				http.StatusBadRequest:          api.ErrorDTO{},
+				http.StatusNotFound:            api.ErrorDTO{},
+				http.StatusInternalServerError: api.ErrorDTO{},
+			},
+		},
+		{
+			Method:  http.MethodPut,
+			Path:    "/doctors/:id/avatar",
+			ID:      "uploadDoctorAvatar",
+			Summary: "Upload a doctor's avatar",
+			Description: "Replaces the doctor's avatar; the previous file is deleted. " +
+				"With If-Match or If-Unmodified-Since the avatar is only replaced while the " +
+				"doctor is unchanged since the client fetched it.",
+			Tags:       []string{"Doctors"},
+			PathParams: map[string]any{"id": uuid.UUID{}},
+			Body:       openapi.Request{ContentType: "multipart/form-data", Body: api.ImageUploadForm{}},
+			Responses: map[int]any{
+				http.StatusOK:                    DetailDTO{},
+				http.StatusBadRequest:            api.ErrorDTO{},
+				http.StatusNotFound:              api.ErrorDTO{},
+				http.StatusPreconditionFailed:    middleware.ErrorResponse{},
+				http.StatusRequestEntityTooLarge: api.ErrorDTO{},
+				http.StatusUnsupportedMediaType:  api.ErrorDTO{},
+				http.StatusInternalServerError:   api.ErrorDTO{},
+			},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/doctors:batchGet",
+			ID:          "batchGetDoctors",
+			Summary:     "Get several doctors by ID",
+			Description: "Doctors are returned in requested order; IDs without a doctor are listed in missing.",
+			Tags:        []string{"Doctors"},
+			Query:       []any{api.ExpandParams{}},
+			Body:        BatchGetRequestDTO{},
+			Responses: map[int]any{
+				http.StatusOK:                  api.BatchResult[ListItemDTO]{},
+				http.StatusNotModified:         nil,
+				http.StatusBadRequest:          api.ErrorDTO{},
+				http.StatusInternalServerError: api.ErrorDTO{},
+			},
+		},
+	}
+}
+
+// bindExpandParams parses ?fields= and ?include= against dto, writing a 400
+// response when either is invalid. Included relations are always returned,
+// whatever the fieldset.
+func bindExpandParams(c *gin.Context, dto any) (api.Fieldset, api.Includes, bool) {
+	var params api.ExpandParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid expand parameters"})
+		return nil, nil, false
+	}
+
+	fields, err := api.ParseFieldset(params.Fields, dto)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, nil, false
+	}
+
+	includes, err := api.ParseIncludes(params.Include, includeSpecialty, includeServices, includeClinics, includeNextAvailableSlot)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, nil, false
+	}
+	for name := range includes {
+		fields.Add(name)
+	}
+
+	return fields, includes, true
+}
+
+// newListItems builds the list DTOs of doctors, embedding the requested
+// relations, and returns the Last-Modified of everything included.
+func (h *Handler) newListItems(ctx context.Context, doctors []medical.Doctor, includes api.Includes) ([]ListItemDTO, time.Time, error) {
+	items := newListItemDTO(doctors)
+	lastModified := conditional.LatestUpdate(doctors, func(d medical.Doctor) time.Time { return d.UpdatedAt })
+
+	if includes.Has(includeSpecialty) {
+		specialties, err := h.loadSpecialties(ctx, doctors...)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		for i := range items {
+			items[i].Specialty = specialties.dto(items[i].SpecialtyID)
+		}
+		lastModified = later(lastModified, specialties.lastModified)
+	}
+
+	if includes.Has(includeServices) {
+		services, err := h.loadServices(ctx, doctors...)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		for i := range items {
+			items[i].Services = services.dtos(items[i].ID)
+		}
+		lastModified = later(lastModified, services.lastModified)
+	}
+
+	if includes.Has(includeClinics) {
+		clinics, err := h.loadClinics(ctx, doctors...)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		for i := range items {
+			items[i].Clinics = clinics.dtos(items[i].ID)
+		}
+		lastModified = later(lastModified, clinics.lastModified)
+	}
+
+	if includes.Has(includeNextAvailableSlot) {
+		slots, err := h.service.NextAvailableSlots(ctx, doctorIDs(doctors))
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		for i := range items {
+			if slot, ok := slots[items[i].ID]; ok {
+				items[i].NextAvailableSlot = newSlotDTO(slot)
+			}
+		}
+		// Slots change with the clock and with bookings, so only the
+		// ETag can validate the response
+		lastModified = time.Time{}
+	}
+	return items, lastModified, nil
+}
+
+// parseIDList parses the comma-separated ?ids= value.
+func parseIDList(raw string) ([]uuid.UUID, error) {
+	parts := strings.Split(raw, ",")
+	if len(parts) > maxBatchIDs {
+		return nil, fmt.Errorf("at most %d ids are allowed", maxBatchIDs)
+	}
+
+	ids := make([]uuid.UUID, 0, len(parts))
+	for _, part := range parts {
+		id, err := uuid.Parse(strings.TrimSpace(part))
+		if err != nil {
+			return nil, fmt.Errorf("invalid doctor ID %q", part)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+type specialtySet struct {
+	byID         map[uuid.UUID]specialtyAPI.ListItemDTO
+	lastModified time.Time
+}
+
+func (s specialtySet) dto(id uuid.UUID) *specialtyAPI.ListItemDTO {
+	dto, ok := s.byID[id]
+	if !ok {
+		return nil
+	}
+	return &dto
+}
+
+// loadSpecialties fetches the specialties of doctors in one batched lookup.
+func (h *Handler) loadSpecialties(ctx context.Context, doctors ...medical.Doctor) (specialtySet, error) {
+	ids := make([]uuid.UUID, 0, len(doctors))
+	for _, doc := range doctors {
+		ids = append(ids, doc.SpecialtyID)
+	}
+
+	specialties, _, err := h.specialtyService.GetByIDs(ctx, ids)
+	if err != nil {
+		return specialtySet{}, err
+	}
+
+	set := specialtySet{byID: make(map[uuid.UUID]specialtyAPI.ListItemDTO, len(specialties))}
+	for i, dto := range specialtyAPI.NewListItemDTO(specialties) {
+		set.byID[dto.ID] = dto
+		set.lastModified = later(set.lastModified, specialties[i].UpdatedAt)
+	}
+	return set, nil
+}
+
+type serviceSet struct {
+	byDoctor     map[uuid.UUID][]medical.Offering
+	lastModified time.Time
+}
+
+// dtos returns the services of a doctor, cheapest first.
+func (s serviceSet) dtos(doctorID uuid.UUID) []ServiceDTO {
+	return newServiceDTOs(s.byDoctor[doctorID])
+}
+
+// loadServices fetches the services of doctors in one batched lookup.
+func (h *Handler) loadServices(ctx context.Context, doctors ...medical.Doctor) (serviceSet, error) {
+	offerings, err := h.service.ListOfferings(ctx, doctorIDs(doctors))
+	if err != nil {
+		return serviceSet{}, err
+	}
+
+	set := serviceSet{byDoctor: make(map[uuid.UUID][]medical.Offering, len(doctors))}
+	for _, o := range offerings {
+		set.byDoctor[o.DoctorID] = append(set.byDoctor[o.DoctorID], o)
+		set.lastModified = later(set.lastModified, o.UpdatedAt)
+	}
+	return set, nil
+}
+
+type clinicSet struct {
+	byDoctor     map[uuid.UUID][]medical.Clinic
+	lastModified time.Time
+}
+
+// dtos returns the clinics of a doctor by name.
+func (s clinicSet) dtos(doctorID uuid.UUID) []ClinicDTO {
+	return newClinicDTOs(s.byDoctor[doctorID])
+}
+
+// loadClinics fetches the clinics of doctors in one batched lookup.
+func (h *Handler) loadClinics(ctx context.Context, doctors ...medical.Doctor) (clinicSet, error) {
+	clinics, err := h.service.ListClinics(ctx, doctorIDs(doctors))
+	if err != nil {
+		return clinicSet{}, err
+	}
+
+	set := clinicSet{byDoctor: make(map[uuid.UUID][]medical.Clinic, len(doctors))}
+	for _, c := range clinics {
+		set.byDoctor[c.DoctorID] = append(set.byDoctor[c.DoctorID], c.Clinic)
+		set.lastModified = later(set.lastModified, c.UpdatedAt)
+	}
+	return set, nil
+}
+
+func doctorIDs(doctors []medical.Doctor) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(doctors))
+	for _, doc := range doctors {
+		ids = append(ids, doc.ID)
+	}
+	return ids
+}
+
+func later(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
 	}
+	return a
 }
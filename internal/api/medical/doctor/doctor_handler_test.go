@@ -12,19 +12,21 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	medicalService "github.com/shayesteh1hs/DrAppointment/internal/service/medical/doctor"
+	specialtyService "github.com/shayesteh1hs/DrAppointment/internal/service/medical/specialty"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/shayesteh1hs/DrAppointment/internal/pagination"
 	"github.com/shayesteh1hs/DrAppointment/internal/repository/medical/doctor/memory"
+	specialtyMemory "github.com/shayesteh1hs/DrAppointment/internal/repository/medical/specialty/memory"
 )
 
 type DoctorOffsetPageDTO = pagination.Result[ListItemDTO]
 
 func setupDoctorHandler() *Handler {
 	repo := memory.NewDoctorRepositoryWithTestData()
-	service := medicalService.NewDoctorService(repo)
-	return NewHandler(service)
+	service := medicalService.NewDoctorService(repo, nil, nil, nil, nil)
+	return NewHandler(service, specialtyService.NewSpecialtyService(specialtyMemory.NewSpecialtyRepository(), nil), 5<<20)
 }
 
 func TestDoctorHandler_ListDoctors_Success(t *testing.T) {
@@ -10,9 +10,10 @@ import (
 var _ api.PageEntityDTO = (*ListItemDTO)(nil)
 
 type ListItemDTO struct {
-	ID       uuid.UUID `json:"id"`
-	Name     string    `json:"name"`
-	ImageURL *string   `json:"image_url"`
+	ID          uuid.UUID                 `json:"id"`
+	Name        string                    `json:"name"`
+	ImageURL    *string                   `json:"image_url"`
+	ImageSrcset map[string]map[int]string `json:"image_srcset,omitempty" doc:"Resized copies of the image by format (webp, jpeg) and bounding box in pixels (64, 128, 512)"`
 }
 
 func (p ListItemDTO) IsPageEntityDTO() bool { return true }
@@ -28,6 +29,7 @@ func NewListItemDTO(specialties []medical.Specialty) []ListItemDTO {
 		if specialty.ImagePath != nil {
 			s := utils.GetFullImageURL(specialty.ImagePath)
 			dto.ImageURL = &s
+			dto.ImageSrcset = utils.GetImageVariantURLs(specialty.ImagePath)
 		}
 		items = append(items, dto)
 	}
@@ -35,10 +37,11 @@ func NewListItemDTO(specialties []medical.Specialty) []ListItemDTO {
 }
 
 type DetailDTO struct {
-	ID        uuid.UUID `json:"id"`
-	Name      string    `json:"name"`
-	ImageURL  *string   `json:"image_url"`
-	CreatedAt string    `json:"created_at"`
+	ID          uuid.UUID                 `json:"id"`
+	Name        string                    `json:"name"`
+	ImageURL    *string                   `json:"image_url"`
+	ImageSrcset map[string]map[int]string `json:"image_srcset,omitempty" doc:"Resized copies of the image by format (webp, jpeg) and bounding box in pixels (64, 128, 512)"`
+	CreatedAt   string                    `json:"created_at"`
 }
 
 func NewDetailDTO(specialty medical.Specialty) DetailDTO {
@@ -51,6 +54,7 @@ func NewDetailDTO(specialty medical.Specialty) DetailDTO {
 	if specialty.ImagePath != nil {
 		s := utils.GetFullImageURL(specialty.ImagePath)
 		dto.ImageURL = &s
+		dto.ImageSrcset = utils.GetImageVariantURLs(specialty.ImagePath)
 	}
 
 	return dto
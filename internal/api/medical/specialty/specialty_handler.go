@@ -2,23 +2,32 @@ package specialty
 
 import (
 	"errors"
-	"log"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/shayesteh1hs/DrAppointment/internal/api"
+	"github.com/shayesteh1hs/DrAppointment/internal/conditional"
+	"github.com/shayesteh1hs/DrAppointment/internal/database"
+	"github.com/shayesteh1hs/DrAppointment/internal/entity/medical"
+	"github.com/shayesteh1hs/DrAppointment/internal/logging"
+	"github.com/shayesteh1hs/DrAppointment/internal/middleware"
+	"github.com/shayesteh1hs/DrAppointment/internal/openapi"
 	"github.com/shayesteh1hs/DrAppointment/internal/pagination"
 	"github.com/shayesteh1hs/DrAppointment/internal/repository/medical/specialty"
 	medicalService "github.com/shayesteh1hs/DrAppointment/internal/service/medical/specialty"
 )
 
 type SpecialtyHandler struct {
-	service medicalService.Service
+	service       medicalService.Service
+	maxImageBytes int64
 }
 
-func NewSpecialtyHandler(service medicalService.Service) *SpecialtyHandler {
+func NewSpecialtyHandler(service medicalService.Service, maxImageBytes int64) *SpecialtyHandler {
 	return &SpecialtyHandler{
-		service: service,
+		service:       service,
+		maxImageBytes: maxImageBytes,
 	}
 }
 
@@ -31,7 +40,7 @@ func (h *SpecialtyHandler) ListSpecialties(c *gin.Context) {
 
 	specialties, totalCount, err := h.service.ListSpecialtiesOffset(c.Request.Context(), paginator.GetParams())
 	if err != nil {
-		log.Printf("failed to fetch specialties: %v", err)
+		logging.FromContext(c.Request.Context()).Error("failed to fetch specialties", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch specialties"})
 		return
 	}
@@ -39,12 +48,16 @@ func (h *SpecialtyHandler) ListSpecialties(c *gin.Context) {
 	specialtyDTOs := NewListItemDTO(specialties)
 	result, err := paginator.CreatePaginationResult(specialtyDTOs, totalCount)
 	if err != nil {
-		log.Printf("failed to create pagination result: %v", err)
+		logging.FromContext(c.Request.Context()).Error("failed to create pagination result", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create pagination result"})
 		return
 	}
 
-	c.JSON(http.StatusOK, result)
+	lastModified := conditional.LatestUpdate(specialties, func(s medical.Specialty) time.Time { return s.UpdatedAt })
+	if err := conditional.JSON(c, http.StatusOK, result, lastModified); err != nil {
+		logging.FromContext(c.Request.Context()).Error("failed to write specialties response", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch specialties"})
+	}
 }
 
 func (h *SpecialtyHandler) GetSpecialtyByID(c *gin.Context) {
@@ -61,12 +74,91 @@ func (h *SpecialtyHandler) GetSpecialtyByID(c *gin.Context) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Specialty not found"})
 			return
 		}
-		log.Printf("failed to fetch specialty by ID: %v", err)
+		logging.FromContext(c.Request.Context()).Error("failed to fetch specialty by ID", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch specialty"})
 		return
 	}
 
 	response := NewDetailDTO(*spec)
+	if err := conditional.JSON(c, http.StatusOK, response, spec.UpdatedAt); err != nil {
+		logging.FromContext(c.Request.Context()).Error("failed to write specialty response", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch specialty"})
+	}
+}
+
+func (h *SpecialtyHandler) UploadSpecialtyImage(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid specialty ID"})
+		return
+	}
+
+	ifUpdatedAt, ok := h.checkPreconditions(c, id)
+	if !ok {
+		return
+	}
+
+	img, ok := api.BindImage(c, h.maxImageBytes)
+	if !ok {
+		return
+	}
+
+	spec, err := h.service.SetImage(c.Request.Context(), id, img, ifUpdatedAt)
+	if err != nil {
+		if errors.Is(err, specialty.ErrSpecialtyModified) {
+			conditional.PreconditionFailed(c)
+			return
+		}
+		if errors.Is(err, specialty.ErrSpecialtyNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Specialty not found"})
+			return
+		}
+		logging.FromContext(c.Request.Context()).Error("failed to upload specialty image", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload specialty image"})
+		return
+	}
+
+	writeDetail(c, *spec)
+}
+
+// checkPreconditions compares If-Match and If-Unmodified-Since with the
+// specialty as GET /specialties/:id returns it, aborting the request when
+// they fail. It returns the version it checked against, for the write to be
+// made conditional on; zero when the request has no preconditions.
+func (h *SpecialtyHandler) checkPreconditions(c *gin.Context, id uuid.UUID) (time.Time, bool) {
+	if !conditional.HasPreconditions(c.Request) {
+		return time.Time{}, true
+	}
+
+	// Replicas may not have the client's last write yet
+	spec, err := h.service.GetByID(database.WithPrimary(c.Request.Context()), id)
+	if err != nil {
+		if errors.Is(err, specialty.ErrSpecialtyNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Specialty not found"})
+			return time.Time{}, false
+		}
+		logging.FromContext(c.Request.Context()).Error("failed to fetch specialty by ID", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch specialty"})
+		return time.Time{}, false
+	}
+
+	etag, err := conditional.ETagOf(NewDetailDTO(*spec))
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("failed to compute specialty ETag", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch specialty"})
+		return time.Time{}, false
+	}
+	return spec.UpdatedAt, conditional.CheckPreconditions(c, etag, spec.UpdatedAt)
+}
+
+// writeDetail answers a write with the specialty's new representation and
+// its validators, so clients can chain conditional requests.
+func writeDetail(c *gin.Context, spec medical.Specialty) {
+	response := NewDetailDTO(spec)
+	if etag, err := conditional.ETagOf(response); err == nil {
+		c.Header("ETag", etag)
+	}
+	c.Header("Last-Modified", spec.UpdatedAt.UTC().Format(http.TimeFormat))
 	c.JSON(http.StatusOK, response)
 }
 
@@ -75,5 +167,62 @@ func (h *SpecialtyHandler) RegisterRoutes(router *gin.RouterGroup) {
 	{
 		specialtyRoutes.GET("", h.ListSpecialties)
 		specialtyRoutes.GET("/:id", h.GetSpecialtyByID)
+		specialtyRoutes.PUT("/:id/image", h.UploadSpecialtyImage)
+	}
+}
+
+// Operations documents the routes added by RegisterRoutes.
+func (h *SpecialtyHandler) Operations() []openapi.Operation {
+	return []openapi.Operation{
+		{
+			Method:  http.MethodGet,
+			Path:    "/specialties",
+			ID:      "listSpecialties",
+			Summary: "List specialties",
+			Tags:    []string{"Specialties"},
+			Query:   []any{pagination.LimitOffsetParams{}},
+			Responses: map[int]any{
+				http.StatusOK:                  pagination.Result[ListItemDTO]{},
+				http.StatusNotModified:         nil,
+				http.StatusBadRequest:          api.ErrorDTO{},
+				http.StatusInternalServerError: api.ErrorDTO{},
+			},
+		},
+		{
+			Method:     http.MethodGet,
+			Path:       "/specialties/:id",
+			ID:         "getSpecialty",
+			Summary:    "Get a specialty",
+			Tags:       []string{"Specialties"},
+			PathParams: map[string]any{"id": uuid.UUID{}},
+			Responses: map[int]any{
+				http.StatusOK:                  DetailDTO{},
+				http.StatusNotModified:         nil,
+				http.StatusBadRequest:          api.ErrorDTO{},
+				http.StatusNotFound:            api.ErrorDTO{},
+				http.StatusInternalServerError: api.ErrorDTO{},
+			},
+		},
+		{
+			Method:  http.MethodPut,
+			Path:    "/specialties/:id/image",
+			ID:      "uploadSpecialtyImage",
+			Summary: "Upload a specialty image",
+			Description: "Replaces the specialty's image; the previous file is deleted. " +
+				"With If-Match or If-Unmodified-Since the image is only replaced while the " +
+				"specialty is unchanged since the client fetched it.",
+			Tags:       []string{"Specialties"},
+			PathParams: map[string]any{"id": uuid.UUID{}},
+			Body:       openapi.Request{ContentType: "multipart/form-data", Body: api.ImageUploadForm{}},
+			Responses: map[int]any{
+				http.StatusOK:                    DetailDTO{},
+				http.StatusBadRequest:            api.ErrorDTO{},
+				http.StatusNotFound:              api.ErrorDTO{},
+				http.StatusPreconditionFailed:    middleware.ErrorResponse{},
+				http.StatusRequestEntityTooLarge: api.ErrorDTO{},
+				http.StatusUnsupportedMediaType:  api.ErrorDTO{},
+				http.StatusInternalServerError:   api.ErrorDTO{},
+			},
+		},
 	}
 }
@@ -23,8 +23,8 @@ type SpecialtyOffsetPageDTO = pagination.Result[ListItemDTO]
 
 func setupSpecialtyHandler() *SpecialtyHandler {
 	repo := memory.NewSpecialtyRepository()
-	service := medicalService.NewSpecialtyService(repo)
-	return NewSpecialtyHandler(service)
+	service := medicalService.NewSpecialtyService(repo, nil)
+	return NewSpecialtyHandler(service, 5<<20)
 }
 
 func TestSpecialtyHandler_ListSpecialties_Success(t *testing.T) {
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"errors"
+	"mime/multipart"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/media"
+)
+
+// multipartOverhead is the room left for multipart boundaries and headers on
+// top of the image size limit.
+const multipartOverhead = 64 << 10
+
+// ImageUploadForm documents the multipart body of image upload endpoints.
+type ImageUploadForm struct {
+	Image multipart.FileHeader `json:"image" doc:"JPEG, PNG, GIF or WebP image; the type is detected from the content"`
+}
+
+// BindImage reads and validates the "image" file of a multipart upload,
+// writing a 400, 413 or 415 response when it is missing or unacceptable.
+func BindImage(c *gin.Context, maxBytes int64) (media.Image, bool) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes+multipartOverhead)
+
+	header, err := c.FormFile("image")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": media.ErrTooLarge.Error()})
+			return media.Image{}, false
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "An image file is required in the \"image\" form field"})
+		return media.Image{}, false
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read the uploaded image"})
+		return media.Image{}, false
+	}
+	defer func() { _ = file.Close() }()
+
+	img, err := media.ReadImage(file, maxBytes)
+	switch {
+	case errors.Is(err, media.ErrTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+		return media.Image{}, false
+	case errors.Is(err, media.ErrUnsupportedType):
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+		return media.Image{}, false
+	case err != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return media.Image{}, false
+	}
+
+	return img, true
+}
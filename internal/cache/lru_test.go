@@ -0,0 +1,192 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLRU_GetSet(t *testing.T) {
+	c := NewLRU[string, int](2, time.Minute)
+
+	c.Set("a", 1)
+	value, ok := c.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, value)
+
+	_, ok = c.Get("missing")
+	assert.False(t, ok)
+
+	stats := c.Stats()
+	assert.Equal(t, uint64(1), stats.Hits)
+	assert.Equal(t, uint64(1), stats.Misses)
+	assert.Equal(t, 1, stats.Size)
+}
+
+func TestLRU_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewLRU[string, int](2, time.Minute)
+
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Get("a")
+	c.Set("c", 3)
+
+	_, ok := c.Get("b")
+	assert.False(t, ok)
+	_, ok = c.Get("a")
+	assert.True(t, ok)
+	_, ok = c.Get("c")
+	assert.True(t, ok)
+	assert.Equal(t, uint64(1), c.Stats().Evictions)
+}
+
+func TestLRU_ExpiresAfterTTL(t *testing.T) {
+	c := NewLRU[string, int](2, time.Minute)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	c.Set("a", 1)
+	now = now.Add(time.Minute)
+
+	_, ok := c.Get("a")
+	assert.False(t, ok)
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestLRU_DeleteAndPurge(t *testing.T) {
+	c := NewLRU[string, int](3, time.Minute)
+	c.Set("a", 1)
+	c.Set("b", 2)
+
+	c.Delete("a")
+	_, ok := c.Get("a")
+	assert.False(t, ok)
+
+	c.Purge()
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestLRU_GetOrLoad_CollapsesConcurrentMisses(t *testing.T) {
+	c := NewLRU[string, int](2, time.Minute)
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	load := func(ctx context.Context) (int, error) {
+		calls.Add(1)
+		<-release
+		return 42, nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([]int, 10)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			value, err := c.GetOrLoad(context.Background(), "key", load)
+			assert.NoError(t, err)
+			results[i] = value
+		}(i)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls.Load())
+	for _, value := range results {
+		assert.Equal(t, 42, value)
+	}
+
+	value, ok := c.Get("key")
+	assert.True(t, ok)
+	assert.Equal(t, 42, value)
+}
+
+func TestLRU_GetOrLoad_DoesNotCacheErrors(t *testing.T) {
+	c := NewLRU[string, int](2, time.Minute)
+	loadErr := errors.New("boom")
+
+	_, err := c.GetOrLoad(context.Background(), "key", func(ctx context.Context) (int, error) {
+		return 0, loadErr
+	})
+	require.ErrorIs(t, err, loadErr)
+
+	value, err := c.GetOrLoad(context.Background(), "key", func(ctx context.Context) (int, error) {
+		return 7, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 7, value)
+}
+
+func TestLRU_GetOrLoad_SkipsWriteBackAfterInvalidation(t *testing.T) {
+	c := NewLRU[string, int](2, time.Minute)
+
+	_, err := c.GetOrLoad(context.Background(), "key", func(ctx context.Context) (int, error) {
+		c.Delete("key")
+		return 1, nil
+	})
+	require.NoError(t, err)
+
+	_, ok := c.Get("key")
+	assert.False(t, ok)
+}
+
+func TestLRU_GetOrLoad_SharedLoadOutlivesCancelledCaller(t *testing.T) {
+	c := NewLRU[string, int](2, time.Minute)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	load := func(ctx context.Context) (int, error) {
+		close(started)
+		select {
+		case <-release:
+			return 42, nil
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+
+	first, cancel := context.WithCancel(context.Background())
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := c.GetOrLoad(first, "key", load)
+		firstErr <- err
+	}()
+	<-started
+
+	second := make(chan int, 1)
+	go func() {
+		value, err := c.GetOrLoad(context.Background(), "key", load)
+		assert.NoError(t, err)
+		second <- value
+	}()
+
+	// The caller that started the load gives up without failing it
+	cancel()
+	assert.ErrorIs(t, <-firstErr, context.Canceled)
+
+	close(release)
+	assert.Equal(t, 42, <-second)
+
+	value, ok := c.Get("key")
+	assert.True(t, ok)
+	assert.Equal(t, 42, value)
+}
+
+func TestLRU_GetOrLoad_SharedLoadTimesOut(t *testing.T) {
+	c := NewLRU[string, int](2, time.Minute)
+	c.loadTimeout = 10 * time.Millisecond
+
+	_, err := c.GetOrLoad(context.Background(), "key", func(ctx context.Context) (int, error) {
+		<-ctx.Done()
+		return 0, ctx.Err()
+	})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
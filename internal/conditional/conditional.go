@@ -0,0 +1,150 @@
+package conditional
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/middleware"
+)
+
+// ETag returns a strong entity tag derived from the serialized body, so it
+// changes whenever any field of the representation does.
+func ETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// ETagOf serializes v the way JSON responses do and returns its ETag.
+func ETagOf(v any) (string, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode representation: %w", err)
+	}
+	return ETag(body), nil
+}
+
+// JSON writes v with ETag and, when lastModified is not zero, Last-Modified
+// headers. It answers 304 Not Modified without a body when the client's
+// If-None-Match or If-Modified-Since shows its copy is current.
+func JSON(c *gin.Context, status int, v any, lastModified time.Time) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode response: %w", err)
+	}
+
+	etag := ETag(body)
+	c.Header("ETag", etag)
+	if !lastModified.IsZero() {
+		c.Header("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+	// Let clients and shared caches keep the response but revalidate it
+	c.Header("Cache-Control", "no-cache")
+
+	if notModified(c.Request, etag, lastModified) {
+		c.Status(http.StatusNotModified)
+		return nil
+	}
+
+	c.Data(status, "application/json; charset=utf-8", body)
+	return nil
+}
+
+// CheckPreconditions enforces If-Match and If-Unmodified-Since against the
+// current representation of the resource being modified. It aborts with 412
+// Precondition Failed and returns false when the client's copy is stale.
+// Requests without these headers pass.
+func CheckPreconditions(c *gin.Context, currentETag string, lastModified time.Time) bool {
+	if preconditionsHold(c.Request, currentETag, lastModified) {
+		return true
+	}
+
+	c.Header("ETag", currentETag)
+	PreconditionFailed(c)
+	return false
+}
+
+// PreconditionFailed aborts with 412 Precondition Failed. Handlers use it
+// when a conditional write finds the resource changed after
+// CheckPreconditions let the request through.
+func PreconditionFailed(c *gin.Context) {
+	c.AbortWithStatusJSON(http.StatusPreconditionFailed, middleware.ErrorResponse{
+		Status:  http.StatusPreconditionFailed,
+		Message: "The resource was modified since it was last fetched",
+	})
+}
+
+// HasPreconditions reports whether r carries If-Match or
+// If-Unmodified-Since, so handlers only load the current representation when
+// CheckPreconditions needs it.
+func HasPreconditions(r *http.Request) bool {
+	return r.Header.Get("If-Match") != "" || r.Header.Get("If-Unmodified-Since") != ""
+}
+
+// LatestUpdate is the Last-Modified of a page: its most recently updated
+// item. Removals are only reflected in the ETag.
+func LatestUpdate[T any](items []T, updatedAt func(T) time.Time) time.Time {
+	var latest time.Time
+	for _, item := range items {
+		if t := updatedAt(item); t.After(latest) {
+			latest = t
+		}
+	}
+	return latest
+}
+
+// notModified follows RFC 9110 section 13.2.2: If-None-Match, when present,
+// takes precedence over If-Modified-Since.
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if header := r.Header.Get("If-None-Match"); header != "" {
+		return matches(header, etag, false)
+	}
+
+	if header := r.Header.Get("If-Modified-Since"); header != "" && !lastModified.IsZero() {
+		since, err := http.ParseTime(header)
+		return err == nil && !lastModified.Truncate(time.Second).After(since)
+	}
+
+	return false
+}
+
+func preconditionsHold(r *http.Request, etag string, lastModified time.Time) bool {
+	if header := r.Header.Get("If-Match"); header != "" {
+		return matches(header, etag, true)
+	}
+
+	if header := r.Header.Get("If-Unmodified-Since"); header != "" && !lastModified.IsZero() {
+		since, err := http.ParseTime(header)
+		return err == nil && !lastModified.Truncate(time.Second).After(since)
+	}
+
+	return true
+}
+
+// matches reports whether etag is in the comma separated header list. Weak
+// tags (W/"...") only match with weak comparison, which reads use and
+// If-Match does not.
+func matches(header, etag string, strong bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if strings.HasPrefix(candidate, "W/") {
+			if strong {
+				continue
+			}
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
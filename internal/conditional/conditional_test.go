@@ -0,0 +1,134 @@
+package conditional
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var updatedAt = time.Date(2025, 3, 1, 10, 30, 15, 500, time.UTC)
+
+type doctorDTO struct {
+	Name string `json:"name"`
+}
+
+func setupRouter(name *string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/doctors/1", func(c *gin.Context) {
+		_ = JSON(c, http.StatusOK, doctorDTO{Name: *name}, updatedAt)
+	})
+	router.PUT("/doctors/1", func(c *gin.Context) {
+		etag, err := ETagOf(doctorDTO{Name: *name})
+		if err != nil {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		if !CheckPreconditions(c, etag, updatedAt) {
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+	return router
+}
+
+func get(router *gin.Engine, header, value string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/doctors/1", nil)
+	if header != "" {
+		req.Header.Set(header, value)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func put(router *gin.Engine, header, value string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPut, "/doctors/1", nil)
+	if header != "" {
+		req.Header.Set(header, value)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestJSON_SetsValidators(t *testing.T) {
+	name := "Dr. Jane Doe"
+	w := get(setupRouter(&name), "", "")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"name":"Dr. Jane Doe"}`, w.Body.String())
+	assert.Equal(t, ETag([]byte(`{"name":"Dr. Jane Doe"}`)), w.Header().Get("ETag"))
+	assert.Equal(t, "Sat, 01 Mar 2025 10:30:15 GMT", w.Header().Get("Last-Modified"))
+}
+
+func TestJSON_IfNoneMatch(t *testing.T) {
+	name := "Dr. Jane Doe"
+	router := setupRouter(&name)
+	etag := get(router, "", "").Header().Get("ETag")
+
+	w := get(router, "If-None-Match", etag)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+	assert.Equal(t, etag, w.Header().Get("ETag"))
+
+	assert.Equal(t, http.StatusNotModified, get(router, "If-None-Match", `"other", W/`+etag).Code)
+
+	name = "Dr. John Smith"
+	assert.Equal(t, http.StatusOK, get(router, "If-None-Match", etag).Code)
+}
+
+func TestJSON_IfModifiedSince(t *testing.T) {
+	name := "Dr. Jane Doe"
+	router := setupRouter(&name)
+
+	assert.Equal(t, http.StatusNotModified, get(router, "If-Modified-Since", "Sat, 01 Mar 2025 10:30:15 GMT").Code)
+	assert.Equal(t, http.StatusOK, get(router, "If-Modified-Since", "Sat, 01 Mar 2025 10:30:14 GMT").Code)
+	assert.Equal(t, http.StatusOK, get(router, "If-Modified-Since", "not a date").Code)
+}
+
+func TestJSON_IfNoneMatchTakesPrecedence(t *testing.T) {
+	name := "Dr. Jane Doe"
+	router := setupRouter(&name)
+
+	req := httptest.NewRequest(http.MethodGet, "/doctors/1", nil)
+	req.Header.Set("If-None-Match", `"stale"`)
+	req.Header.Set("If-Modified-Since", "Sat, 01 Mar 2025 10:30:15 GMT")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestCheckPreconditions(t *testing.T) {
+	name := "Dr. Jane Doe"
+	router := setupRouter(&name)
+	etag, err := ETagOf(doctorDTO{Name: name})
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusNoContent, put(router, "", "").Code)
+	assert.Equal(t, http.StatusNoContent, put(router, "If-Match", etag).Code)
+	assert.Equal(t, http.StatusNoContent, put(router, "If-Match", "*").Code)
+	assert.Equal(t, http.StatusPreconditionFailed, put(router, "If-Match", "W/"+etag).Code)
+	assert.Equal(t, http.StatusNoContent, put(router, "If-Unmodified-Since", "Sat, 01 Mar 2025 10:30:15 GMT").Code)
+	assert.Equal(t, http.StatusPreconditionFailed, put(router, "If-Unmodified-Since", "Sat, 01 Mar 2025 10:00:00 GMT").Code)
+
+	name = "Dr. John Smith"
+	w := put(router, "If-Match", etag)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	assert.NotEqual(t, etag, w.Header().Get("ETag"))
+	assert.Contains(t, w.Body.String(), "modified since")
+}
+
+func TestLatestUpdate(t *testing.T) {
+	items := []time.Time{updatedAt.Add(-time.Hour), updatedAt, updatedAt.Add(-time.Minute)}
+	identity := func(t time.Time) time.Time { return t }
+
+	assert.Equal(t, updatedAt, LatestUpdate(items, identity))
+	assert.True(t, LatestUpdate(nil, identity).IsZero())
+}
@@ -0,0 +1,740 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/netip"
+	"net/url"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/database"
+	"github.com/shayesteh1hs/DrAppointment/internal/events"
+	"github.com/shayesteh1hs/DrAppointment/internal/jobs"
+	"github.com/shayesteh1hs/DrAppointment/internal/middleware"
+	"github.com/shayesteh1hs/DrAppointment/internal/notification"
+	"github.com/shayesteh1hs/DrAppointment/internal/payment"
+	"github.com/shayesteh1hs/DrAppointment/internal/ratelimit"
+	"github.com/shayesteh1hs/DrAppointment/internal/storage"
+	"github.com/shayesteh1hs/DrAppointment/internal/webhook"
+)
+
+// Config is the typed application configuration. Every leaf field carries a
+// `key` tag naming it inside the optional config file and an `env` tag naming
+// the environment variable that overrides it. Fields tagged `secret:"true"`
+// are redacted when printed and may also be read from the file named by the
+// <ENV>_FILE variable.
+type Config struct {
+	Server       ServerConfig       `key:"server"`
+	API          APIConfig          `key:"api"`
+	CORS         CORSConfig         `key:"cors"`
+	Security     SecurityConfig     `key:"security"`
+	Database     DatabaseConfig     `key:"database"`
+	Cache        CacheConfig        `key:"cache"`
+	Media        MediaConfig        `key:"media"`
+	Log          LogConfig          `key:"log"`
+	Tracing      TracingConfig      `key:"tracing"`
+	Health       HealthConfig       `key:"health"`
+	RateLimit    RateLimitConfig    `key:"rate_limit"`
+	Idempotency  IdempotencyConfig  `key:"idempotency"`
+	SMS          SMSConfig          `key:"sms"`
+	SMTP         SMTPConfig         `key:"smtp"`
+	Notification NotificationConfig `key:"notification"`
+	Jobs         JobsConfig         `key:"jobs"`
+	Events       EventsConfig       `key:"events"`
+	Webhook      WebhookConfig      `key:"webhook"`
+	Admin        AdminConfig        `key:"admin"`
+	Payment      PaymentConfig      `key:"payment"`
+}
+
+type ServerConfig struct {
+	Port            int           `key:"port" env:"PORT"`
+	ShutdownTimeout time.Duration `key:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT"`
+	// ShutdownDrainDelay is how long /readyz reports failure before the
+	// server stops accepting connections.
+	ShutdownDrainDelay time.Duration `key:"shutdown_drain_delay" env:"SHUTDOWN_DRAIN_DELAY"`
+	// TrustedProxies are the IPs or CIDRs of load balancers whose
+	// X-Forwarded-* headers are honored. Empty trusts none.
+	TrustedProxies []string `key:"trusted_proxies" env:"TRUSTED_PROXIES"`
+	// PublicBaseURL, when set, is used for generated links instead of the
+	// request's scheme and host.
+	PublicBaseURL string `key:"public_base_url" env:"PUBLIC_BASE_URL"`
+}
+
+type APIConfig struct {
+	// LegacyRoutes keeps serving the unversioned /api/medical routes as
+	// deprecated aliases of /api/v1/medical
+	LegacyRoutes bool `key:"legacy_routes" env:"API_LEGACY_ROUTES"`
+	// LegacySunset is the announced removal date of the legacy routes as
+	// YYYY-MM-DD, empty when not yet scheduled
+	LegacySunset string `key:"legacy_sunset" env:"API_LEGACY_SUNSET"`
+}
+
+type CORSConfig struct {
+	AllowedOrigins   []string      `key:"allowed_origins" env:"CORS_ALLOWED_ORIGINS"`
+	AllowedMethods   []string      `key:"allowed_methods" env:"CORS_ALLOWED_METHODS"`
+	AllowedHeaders   []string      `key:"allowed_headers" env:"CORS_ALLOWED_HEADERS"`
+	ExposedHeaders   []string      `key:"exposed_headers" env:"CORS_EXPOSED_HEADERS"`
+	AllowCredentials bool          `key:"allow_credentials" env:"CORS_ALLOW_CREDENTIALS"`
+	MaxAge           time.Duration `key:"max_age" env:"CORS_MAX_AGE"`
+}
+
+type SecurityConfig struct {
+	HSTSMaxAge            time.Duration `key:"hsts_max_age" env:"SECURITY_HSTS_MAX_AGE"`
+	ContentSecurityPolicy string        `key:"content_security_policy" env:"SECURITY_CONTENT_SECURITY_POLICY"`
+}
+
+type DatabaseConfig struct {
+	Host                       string        `key:"host" env:"DB_HOST"`
+	Port                       int           `key:"port" env:"DB_PORT"`
+	User                       string        `key:"user" env:"DB_USER"`
+	Password                   string        `key:"password" env:"DB_PASSWORD" secret:"true"`
+	Name                       string        `key:"name" env:"DB_NAME"`
+	SSLMode                    string        `key:"ssl_mode" env:"DB_SSL_MODE"`
+	ConnectTimeout             time.Duration `key:"connect_timeout" env:"DB_CONNECT_TIMEOUT"`
+	MaxOpenConns               int           `key:"max_open_conns" env:"DB_MAX_OPEN_CONNS"`
+	MaxIdleConns               int           `key:"max_idle_conns" env:"DB_MAX_IDLE_CONNS"`
+	ConnMaxLifetime            time.Duration `key:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME"`
+	ReplicaHosts               []string      `key:"replica_hosts" env:"DB_REPLICA_HOSTS"`
+	ReplicaStickiness          time.Duration `key:"replica_stickiness" env:"DB_REPLICA_STICKINESS"`
+	ReplicaHealthCheckInterval time.Duration `key:"replica_health_check_interval" env:"DB_REPLICA_HEALTH_CHECK_INTERVAL"`
+}
+
+type CacheConfig struct {
+	Capacity int           `key:"capacity" env:"CACHE_CAPACITY"`
+	TTL      time.Duration `key:"ttl" env:"CACHE_TTL"`
+}
+
+type MediaConfig struct {
+	// ImageBaseURL defaults to the /media/ route of the public base URL, or
+	// of http://localhost:<server port>, when empty.
+	ImageBaseURL string `key:"image_base_url" env:"IMAGE_BASE_URL"`
+	// Storage is "local" (files below LocalDir) or "s3"
+	Storage       string        `key:"storage" env:"MEDIA_STORAGE"`
+	LocalDir      string        `key:"local_dir" env:"MEDIA_LOCAL_DIR"`
+	MaxImageBytes int           `key:"max_image_bytes" env:"MEDIA_MAX_IMAGE_BYTES"`
+	S3            MediaS3Config `key:"s3" env:"MEDIA_S3"`
+	// SigningKey signs links to private objects. It is required and must
+	// be the same on every instance, or links stop working across
+	// instances and restarts.
+	SigningKey string `key:"signing_key" env:"MEDIA_SIGNING_KEY" secret:"true"`
+	// SignedURLTTL is how long a link to a private object stays valid
+	SignedURLTTL time.Duration `key:"signed_url_ttl" env:"MEDIA_SIGNED_URL_TTL"`
+}
+
+type MediaS3Config struct {
+	Endpoint        string `key:"endpoint" env:"ENDPOINT"`
+	Region          string `key:"region" env:"REGION"`
+	Bucket          string `key:"bucket" env:"BUCKET"`
+	AccessKeyID     string `key:"access_key_id" env:"ACCESS_KEY_ID"`
+	SecretAccessKey string `key:"secret_access_key" env:"SECRET_ACCESS_KEY" secret:"true"`
+	// PathStyle addresses the bucket as <endpoint>/<bucket>, as most
+	// self-hosted S3-compatible services require
+	PathStyle bool `key:"path_style" env:"PATH_STYLE"`
+}
+
+type LogConfig struct {
+	Level  string `key:"level" env:"LOG_LEVEL"`
+	Format string `key:"format" env:"LOG_FORMAT"`
+}
+
+type TracingConfig struct {
+	// Exporter is "none", "stdout" or "file"
+	Exporter    string  `key:"exporter" env:"TRACING_EXPORTER"`
+	FilePath    string  `key:"file_path" env:"TRACING_FILE_PATH"`
+	SampleRatio float64 `key:"sample_ratio" env:"TRACING_SAMPLE_RATIO"`
+}
+
+type RateLimitConfig struct {
+	Enabled bool `key:"enabled" env:"RATE_LIMIT_ENABLED"`
+	// Store is "memory" (per instance) or "postgres" (shared by instances)
+	Store string `key:"store" env:"RATE_LIMIT_STORE"`
+	// Public applies per client IP to the public catalog and search routes
+	Public RatePolicyConfig `key:"public" env:"RATE_LIMIT_PUBLIC"`
+}
+
+type IdempotencyConfig struct {
+	// Store is "memory" (per instance) or "postgres" (shared by instances)
+	Store string `key:"store" env:"IDEMPOTENCY_STORE"`
+	// TTL is how long a key is remembered and its response replayed
+	TTL time.Duration `key:"ttl" env:"IDEMPOTENCY_TTL"`
+	// LockTimeout is after how long an unfinished request no longer blocks
+	// retries with its key
+	LockTimeout time.Duration `key:"lock_timeout" env:"IDEMPOTENCY_LOCK_TIMEOUT"`
+}
+
+type RatePolicyConfig struct {
+	Requests int           `key:"requests" env:"REQUESTS"`
+	Per      time.Duration `key:"per" env:"PER"`
+	Burst    int           `key:"burst" env:"BURST"`
+}
+
+type HealthConfig struct {
+	CheckTimeout time.Duration `key:"check_timeout" env:"HEALTH_CHECK_TIMEOUT"`
+	// MigrationCheck makes readiness compare the schema_migrations table
+	// against the newest migration. Enable it only when migrations are
+	// applied with a tool that keeps that table, such as golang-migrate.
+	MigrationCheck bool `key:"migration_check" env:"HEALTH_MIGRATION_CHECK"`
+}
+
+type SMSConfig struct {
+	// BaseURL of the SMS provider API; readiness probes it when set, and
+	// SMS notifications are only sent when it is set.
+	BaseURL string `key:"base_url" env:"SMS_BASE_URL"`
+	APIKey  string `key:"api_key" env:"SMS_API_KEY" secret:"true"`
+	// Sender is the line number or sender ID messages are sent from
+	Sender string `key:"sender" env:"SMS_SENDER"`
+}
+
+type SMTPConfig struct {
+	// Host of the mail submission server; email notifications are only sent
+	// when it is set.
+	Host     string `key:"host" env:"SMTP_HOST"`
+	Port     int    `key:"port" env:"SMTP_PORT"`
+	Username string `key:"username" env:"SMTP_USERNAME"`
+	Password string `key:"password" env:"SMTP_PASSWORD" secret:"true"`
+	// From is the sender address, e.g. "DrAppointment <noreply@example.com>"
+	From string `key:"from" env:"SMTP_FROM"`
+}
+
+type NotificationConfig struct {
+	// Store is "memory" (per instance) or "postgres" (shared by instances)
+	// for preferences, deliveries and the in-app inbox
+	Store string `key:"store" env:"NOTIFICATION_STORE"`
+	// TimeZone dates and times in messages are shown in
+	TimeZone string `key:"time_zone" env:"NOTIFICATION_TIME_ZONE"`
+	// MaxAttempts is how often a delivery is tried before it is failed
+	MaxAttempts int `key:"max_attempts" env:"NOTIFICATION_MAX_ATTEMPTS"`
+	// RetryBackoff is the wait after the first failed attempt, doubled
+	// after each further one up to MaxBackoff
+	RetryBackoff time.Duration `key:"retry_backoff" env:"NOTIFICATION_RETRY_BACKOFF"`
+	MaxBackoff   time.Duration `key:"max_backoff" env:"NOTIFICATION_MAX_BACKOFF"`
+	// PollInterval is how often deliveries due for a retry are looked for
+	PollInterval time.Duration `key:"poll_interval" env:"NOTIFICATION_POLL_INTERVAL"`
+	SendTimeout  time.Duration `key:"send_timeout" env:"NOTIFICATION_SEND_TIMEOUT"`
+}
+
+type JobsConfig struct {
+	// Store is "memory" (per instance, lost on restart) or "postgres"
+	// (shared by instances) for the background job queue
+	Store string `key:"store" env:"JOBS_STORE"`
+	// Concurrency is how many jobs run at once on each instance
+	Concurrency  int           `key:"concurrency" env:"JOBS_CONCURRENCY"`
+	PollInterval time.Duration `key:"poll_interval" env:"JOBS_POLL_INTERVAL"`
+	// Lease is the longest a job may run before it is run again elsewhere
+	Lease time.Duration `key:"lease" env:"JOBS_LEASE"`
+	// RetryBackoff is the wait after the first failed run, doubled after
+	// each further one up to MaxBackoff
+	RetryBackoff time.Duration `key:"retry_backoff" env:"JOBS_RETRY_BACKOFF"`
+	MaxBackoff   time.Duration `key:"max_backoff" env:"JOBS_MAX_BACKOFF"`
+	// Retention is how long succeeded jobs are kept
+	Retention time.Duration `key:"retention" env:"JOBS_RETENTION"`
+}
+
+type EventsConfig struct {
+	// Store is "memory" (per instance, lost on restart) or "postgres" for
+	// the outbox; only the postgres outbox is written in the transaction of
+	// the change an event describes
+	Store string `key:"store" env:"EVENTS_STORE"`
+	// PollInterval is how often the outbox is checked for new events
+	PollInterval time.Duration `key:"poll_interval" env:"EVENTS_POLL_INTERVAL"`
+	// Retention is how long published events are kept
+	Retention time.Duration `key:"retention" env:"EVENTS_RETENTION"`
+}
+
+type WebhookConfig struct {
+	// Store is "memory" (per instance, lost on restart) or "postgres" for
+	// subscriptions and the delivery log
+	Store string `key:"store" env:"WEBHOOK_STORE"`
+	// Timeout bounds a single delivery attempt
+	Timeout time.Duration `key:"timeout" env:"WEBHOOK_TIMEOUT"`
+	// MaxAttempts is how often a delivery is tried before it is failed;
+	// the wait between attempts is the jobs retry backoff
+	MaxAttempts int `key:"max_attempts" env:"WEBHOOK_MAX_ATTEMPTS"`
+	// DisableAfter is how many attempts in a row may fail before a
+	// subscription is disabled
+	DisableAfter int `key:"disable_after" env:"WEBHOOK_DISABLE_AFTER"`
+	// Retention is how long deliveries stay in the log
+	Retention time.Duration `key:"retention" env:"WEBHOOK_RETENTION"`
+}
+
+type PaymentConfig struct {
+	// Provider takes the deposits of doctors who require one: "zarinpal",
+	// or "fake", which approves every payment without charging, for local
+	// runs
+	Provider string `key:"provider" env:"PAYMENT_PROVIDER"`
+	// Expiry is how long a booking holds its slot waiting for the deposit
+	Expiry time.Duration `key:"expiry" env:"PAYMENT_EXPIRY"`
+	// Timeout bounds each call to the provider
+	Timeout time.Duration `key:"timeout" env:"PAYMENT_TIMEOUT"`
+	// ReturnURL is where payers are sent once their payment is settled,
+	// with appointment_id, payment_id and status query parameters; the
+	// callback answers with JSON when it is empty
+	ReturnURL string                `key:"return_url" env:"PAYMENT_RETURN_URL"`
+	Zarinpal  PaymentZarinpalConfig `key:"zarinpal" env:"PAYMENT_ZARINPAL"`
+}
+
+type PaymentZarinpalConfig struct {
+	MerchantID string `key:"merchant_id" env:"MERCHANT_ID" secret:"true"`
+	// BaseURL defaults to the production gateway; use
+	// https://sandbox.zarinpal.com to test
+	BaseURL string `key:"base_url" env:"BASE_URL"`
+	// AccessToken authenticates refunds with the merchant API; refunds
+	// are marked failed, to be made by hand, when it is empty
+	AccessToken string `key:"access_token" env:"ACCESS_TOKEN" secret:"true"`
+	RefundURL   string `key:"refund_url" env:"REFUND_URL"`
+}
+
+type AdminConfig struct {
+	// APIToken is the bearer token of the /api/v1/admin routes, which are
+	// not served when it is empty
+	APIToken string `key:"api_token" env:"ADMIN_API_TOKEN" secret:"true"`
+}
+
+var (
+	sslModes         = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+	logLevels        = []string{"debug", "info", "warn", "error"}
+	logFormats       = []string{"json", "text"}
+	exporters        = []string{"none", "stdout", "file"}
+	storeKinds       = []string{"memory", "postgres"}
+	mediaKinds       = []string{"local", "s3"}
+	paymentProviders = []string{"fake", "zarinpal"}
+)
+
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Port:               8000,
+			ShutdownTimeout:    5 * time.Second,
+			ShutdownDrainDelay: 5 * time.Second,
+		},
+		API: APIConfig{
+			LegacyRoutes: true,
+		},
+		CORS: CORSConfig{
+			AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+			AllowedHeaders: []string{"Authorization", "Content-Type", "X-Request-ID", "Idempotency-Key", "If-Match", "If-None-Match", "If-Unmodified-Since", "If-Modified-Since"},
+			ExposedHeaders: []string{"X-Request-ID", "Retry-After", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Idempotent-Replayed", "ETag", "Deprecation", "Sunset", "Link"},
+			MaxAge:         12 * time.Hour,
+		},
+		Security: SecurityConfig{
+			HSTSMaxAge:            365 * 24 * time.Hour,
+			ContentSecurityPolicy: "default-src 'none'; frame-ancestors 'none'",
+		},
+		Database: DatabaseConfig{
+			Host:                       "localhost",
+			Port:                       5432,
+			User:                       "postgres",
+			Password:                   "postgres",
+			Name:                       "drgo",
+			SSLMode:                    "disable",
+			ConnectTimeout:             5 * time.Second,
+			MaxOpenConns:               25,
+			MaxIdleConns:               25,
+			ConnMaxLifetime:            5 * time.Minute,
+			ReplicaStickiness:          2 * time.Second,
+			ReplicaHealthCheckInterval: 10 * time.Second,
+		},
+		Cache: CacheConfig{
+			Capacity: 1000,
+			TTL:      5 * time.Minute,
+		},
+		Media: MediaConfig{
+			Storage:       "local",
+			LocalDir:      "uploads",
+			MaxImageBytes: 5 << 20,
+			S3:            MediaS3Config{Region: "us-east-1"},
+			SignedURLTTL:  15 * time.Minute,
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "json",
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			FilePath:    "traces.jsonl",
+			SampleRatio: 1,
+		},
+		Health: HealthConfig{
+			CheckTimeout: 2 * time.Second,
+		},
+		RateLimit: RateLimitConfig{
+			Enabled: true,
+			Store:   "memory",
+			Public:  RatePolicyConfig{Requests: 60, Per: time.Minute, Burst: 120},
+		},
+		Idempotency: IdempotencyConfig{
+			Store:       "postgres",
+			TTL:         24 * time.Hour,
+			LockTimeout: time.Minute,
+		},
+		SMTP: SMTPConfig{
+			Port: 587,
+		},
+		Notification: NotificationConfig{
+			Store:        "postgres",
+			TimeZone:     "Asia/Tehran",
+			MaxAttempts:  5,
+			RetryBackoff: 30 * time.Second,
+			MaxBackoff:   time.Hour,
+			PollInterval: 15 * time.Second,
+			SendTimeout:  30 * time.Second,
+		},
+		Jobs: JobsConfig{
+			Store:        "postgres",
+			Concurrency:  4,
+			PollInterval: time.Second,
+			Lease:        5 * time.Minute,
+			RetryBackoff: 30 * time.Second,
+			MaxBackoff:   time.Hour,
+			Retention:    7 * 24 * time.Hour,
+		},
+		Events: EventsConfig{
+			Store:        "postgres",
+			PollInterval: time.Second,
+			Retention:    7 * 24 * time.Hour,
+		},
+		Webhook: WebhookConfig{
+			Store:        "postgres",
+			Timeout:      10 * time.Second,
+			MaxAttempts:  8,
+			DisableAfter: 20,
+			Retention:    30 * 24 * time.Hour,
+		},
+		Payment: PaymentConfig{
+			Provider: "fake",
+			Expiry:   15 * time.Minute,
+			Timeout:  15 * time.Second,
+			Zarinpal: PaymentZarinpalConfig{
+				BaseURL:   payment.DefaultZarinpalBaseURL,
+				RefundURL: payment.DefaultZarinpalRefundURL,
+			},
+		},
+	}
+}
+
+// Validate reports every invalid value at once.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(validPort(c.Server.Port), "server.port must be between 1 and 65535, got %d", c.Server.Port)
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout must be positive")
+	check(c.Server.ShutdownDrainDelay >= 0, "server.shutdown_drain_delay must not be negative")
+	for _, proxy := range c.Server.TrustedProxies {
+		check(validIPOrCIDR(proxy), "server.trusted_proxies must contain IPs or CIDRs, got %q", proxy)
+	}
+	check(validURL(c.Server.PublicBaseURL), "server.public_base_url must be an absolute URL, got %q", c.Server.PublicBaseURL)
+
+	_, err := c.API.LegacySunsetDate()
+	check(err == nil, "api.legacy_sunset must be a date such as 2027-01-31, got %q", c.API.LegacySunset)
+
+	for _, origin := range c.CORS.AllowedOrigins {
+		check(origin == "*" || validURL(origin), "cors.allowed_origins must contain origins such as https://app.example.com or *, got %q", origin)
+	}
+	check(!c.CORS.AllowCredentials || !slices.Contains(c.CORS.AllowedOrigins, "*"), "cors.allow_credentials cannot be combined with the * origin")
+	check(c.CORS.MaxAge >= 0, "cors.max_age must not be negative")
+	check(c.Security.HSTSMaxAge >= 0, "security.hsts_max_age must not be negative")
+
+	db := c.Database
+	check(strings.TrimSpace(db.Host) != "", "database.host is required")
+	check(validPort(db.Port), "database.port must be between 1 and 65535, got %d", db.Port)
+	check(strings.TrimSpace(db.User) != "", "database.user is required")
+	check(strings.TrimSpace(db.Name) != "", "database.name is required")
+	check(slices.Contains(sslModes, db.SSLMode), "database.ssl_mode must be one of %s, got %q", strings.Join(sslModes, ", "), db.SSLMode)
+	check(db.ConnectTimeout > 0, "database.connect_timeout must be positive")
+	check(db.MaxOpenConns >= 0, "database.max_open_conns must not be negative")
+	check(db.MaxIdleConns >= 0, "database.max_idle_conns must not be negative")
+	check(db.MaxOpenConns == 0 || db.MaxIdleConns <= db.MaxOpenConns, "database.max_idle_conns (%d) must not exceed database.max_open_conns (%d)", db.MaxIdleConns, db.MaxOpenConns)
+	check(db.ConnMaxLifetime >= 0, "database.conn_max_lifetime must not be negative")
+	check(db.ReplicaStickiness >= 0, "database.replica_stickiness must not be negative")
+	check(db.ReplicaHealthCheckInterval >= 0, "database.replica_health_check_interval must not be negative")
+	for _, host := range db.ReplicaHosts {
+		check(strings.TrimSpace(host) != "", "database.replica_hosts must not contain empty entries")
+	}
+
+	check(c.Cache.Capacity > 0, "cache.capacity must be positive, got %d", c.Cache.Capacity)
+	check(c.Cache.TTL >= 0, "cache.ttl must not be negative")
+
+	check(validURL(c.Media.ImageBaseURL), "media.image_base_url must be an absolute URL, got %q", c.Media.ImageBaseURL)
+	check(slices.Contains(mediaKinds, c.Media.Storage), "media.storage must be one of %s, got %q", strings.Join(mediaKinds, ", "), c.Media.Storage)
+	check(c.Media.Storage != "local" || strings.TrimSpace(c.Media.LocalDir) != "", "media.local_dir is required for local storage")
+	check(c.Media.MaxImageBytes > 0, "media.max_image_bytes must be positive, got %d", c.Media.MaxImageBytes)
+	check(c.Media.SigningKey != "", "media.signing_key is required")
+	check(c.Media.SigningKey == "" || len(c.Media.SigningKey) >= 32, "media.signing_key must be at least 32 characters")
+	check(c.Media.SignedURLTTL > 0, "media.signed_url_ttl must be positive")
+	if c.Media.Storage == "s3" {
+		check(c.Media.S3.Endpoint != "" && validURL(c.Media.S3.Endpoint), "media.s3.endpoint must be an absolute URL for s3 storage, got %q", c.Media.S3.Endpoint)
+		check(strings.TrimSpace(c.Media.S3.Bucket) != "", "media.s3.bucket is required for s3 storage")
+		check(strings.TrimSpace(c.Media.S3.Region) != "", "media.s3.region is required for s3 storage")
+	}
+	check(slices.Contains(logLevels, c.Log.Level), "log.level must be one of %s, got %q", strings.Join(logLevels, ", "), c.Log.Level)
+	check(slices.Contains(logFormats, c.Log.Format), "log.format must be one of %s, got %q", strings.Join(logFormats, ", "), c.Log.Format)
+	check(slices.Contains(exporters, c.Tracing.Exporter), "tracing.exporter must be one of %s, got %q", strings.Join(exporters, ", "), c.Tracing.Exporter)
+	check(c.Tracing.Exporter != "file" || c.Tracing.FilePath != "", "tracing.file_path is required for the file exporter")
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sample_ratio must be between 0 and 1, got %v", c.Tracing.SampleRatio)
+	check(c.Health.CheckTimeout > 0, "health.check_timeout must be positive")
+	check(slices.Contains(storeKinds, c.RateLimit.Store), "rate_limit.store must be one of %s, got %q", strings.Join(storeKinds, ", "), c.RateLimit.Store)
+	for name, policy := range map[string]RatePolicyConfig{"public": c.RateLimit.Public} {
+		check(policy.Requests > 0, "rate_limit.%s.requests must be positive", name)
+		check(policy.Per > 0, "rate_limit.%s.per must be positive", name)
+		check(policy.Burst >= 0, "rate_limit.%s.burst must not be negative", name)
+	}
+	check(slices.Contains(storeKinds, c.Idempotency.Store), "idempotency.store must be one of %s, got %q", strings.Join(storeKinds, ", "), c.Idempotency.Store)
+	check(c.Idempotency.TTL > 0, "idempotency.ttl must be positive")
+	check(c.Idempotency.LockTimeout > 0, "idempotency.lock_timeout must be positive")
+	check(validURL(c.SMS.BaseURL), "sms.base_url must be an absolute URL, got %q", c.SMS.BaseURL)
+	if c.SMTP.Host != "" {
+		check(validPort(c.SMTP.Port), "smtp.port must be between 1 and 65535, got %d", c.SMTP.Port)
+		_, err := mail.ParseAddress(c.SMTP.From)
+		check(err == nil, "smtp.from must be an email address, got %q", c.SMTP.From)
+	}
+
+	n := c.Notification
+	check(slices.Contains(storeKinds, n.Store), "notification.store must be one of %s, got %q", strings.Join(storeKinds, ", "), n.Store)
+	_, err = n.Location()
+	check(err == nil, "notification.time_zone must be an IANA zone such as Asia/Tehran, got %q", n.TimeZone)
+	check(n.MaxAttempts > 0, "notification.max_attempts must be positive, got %d", n.MaxAttempts)
+	check(n.RetryBackoff > 0, "notification.retry_backoff must be positive")
+	check(n.MaxBackoff >= n.RetryBackoff, "notification.max_backoff must not be shorter than notification.retry_backoff")
+	check(n.PollInterval > 0, "notification.poll_interval must be positive")
+	check(n.SendTimeout > 0, "notification.send_timeout must be positive")
+
+	j := c.Jobs
+	check(slices.Contains(storeKinds, j.Store), "jobs.store must be one of %s, got %q", strings.Join(storeKinds, ", "), j.Store)
+	check(j.Concurrency > 0, "jobs.concurrency must be positive, got %d", j.Concurrency)
+	check(j.PollInterval > 0, "jobs.poll_interval must be positive")
+	check(j.Lease > 0, "jobs.lease must be positive")
+	check(j.RetryBackoff > 0, "jobs.retry_backoff must be positive")
+	check(j.MaxBackoff >= j.RetryBackoff, "jobs.max_backoff must not be shorter than jobs.retry_backoff")
+	check(j.Retention > 0, "jobs.retention must be positive")
+
+	e := c.Events
+	check(slices.Contains(storeKinds, e.Store), "events.store must be one of %s, got %q", strings.Join(storeKinds, ", "), e.Store)
+	check(e.PollInterval > 0, "events.poll_interval must be positive")
+	check(e.Retention > 0, "events.retention must be positive")
+
+	w := c.Webhook
+	check(slices.Contains(storeKinds, w.Store), "webhook.store must be one of %s, got %q", strings.Join(storeKinds, ", "), w.Store)
+	check(w.Timeout > 0, "webhook.timeout must be positive")
+	check(w.MaxAttempts > 0, "webhook.max_attempts must be positive, got %d", w.MaxAttempts)
+	check(w.DisableAfter > 0, "webhook.disable_after must be positive, got %d", w.DisableAfter)
+	check(w.Retention > 0, "webhook.retention must be positive")
+	check(c.Admin.APIToken == "" || len(c.Admin.APIToken) >= 32, "admin.api_token must be at least 32 characters")
+
+	p := c.Payment
+	check(slices.Contains(paymentProviders, p.Provider), "payment.provider must be one of %s, got %q", strings.Join(paymentProviders, ", "), p.Provider)
+	check(p.Expiry > 0, "payment.expiry must be positive")
+	check(p.Timeout > 0, "payment.timeout must be positive")
+	check(validURL(p.ReturnURL), "payment.return_url must be an absolute URL, got %q", p.ReturnURL)
+	if p.Provider == "zarinpal" {
+		check(p.Zarinpal.MerchantID != "", "payment.zarinpal.merchant_id is required for the zarinpal provider")
+		check(p.Zarinpal.BaseURL != "" && validURL(p.Zarinpal.BaseURL), "payment.zarinpal.base_url must be an absolute URL, got %q", p.Zarinpal.BaseURL)
+		check(validURL(p.Zarinpal.RefundURL), "payment.zarinpal.refund_url must be an absolute URL, got %q", p.Zarinpal.RefundURL)
+		// The gateway sends payers back to this server
+		check(c.Server.PublicBaseURL != "", "server.public_base_url is required for the zarinpal provider")
+	}
+
+	return errors.Join(errs...)
+}
+
+// ImageBaseURL returns the configured media base URL or, when none is set,
+// the /media/ route of this server.
+func (c *Config) ImageBaseURL() string {
+	if c.Media.ImageBaseURL != "" {
+		return c.Media.ImageBaseURL
+	}
+	if c.Server.PublicBaseURL != "" {
+		return strings.TrimSuffix(c.Server.PublicBaseURL, "/") + "/media/"
+	}
+	return fmt.Sprintf("http://localhost:%d/media/", c.Server.Port)
+}
+
+// PaymentCallbackURL returns the absolute URL of the payment routes
+// gateways send payers back to.
+func (c *Config) PaymentCallbackURL() string {
+	base := fmt.Sprintf("http://localhost:%d", c.Server.Port)
+	if c.Server.PublicBaseURL != "" {
+		base = strings.TrimSuffix(c.Server.PublicBaseURL, "/")
+	}
+	return base + "/api/v1/medical/payments"
+}
+
+func (c DatabaseConfig) Connection() database.Config {
+	return database.Config{
+		Host:                       c.Host,
+		Port:                       c.Port,
+		User:                       c.User,
+		Password:                   c.Password,
+		DBName:                     c.Name,
+		SSLMode:                    c.SSLMode,
+		MaxOpenConns:               c.MaxOpenConns,
+		MaxIdleConns:               c.MaxIdleConns,
+		ConnMaxLifetime:            c.ConnMaxLifetime,
+		ReplicaHosts:               c.ReplicaHosts,
+		ReplicaStickiness:          c.ReplicaStickiness,
+		ReplicaHealthCheckInterval: c.ReplicaHealthCheckInterval,
+	}
+}
+
+func (c MediaS3Config) Options() storage.S3Config {
+	return storage.S3Config{
+		Endpoint:        c.Endpoint,
+		Region:          c.Region,
+		Bucket:          c.Bucket,
+		AccessKeyID:     c.AccessKeyID,
+		SecretAccessKey: c.SecretAccessKey,
+		PathStyle:       c.PathStyle,
+	}
+}
+
+func (c SMSConfig) Options() notification.SMSConfig {
+	return notification.SMSConfig{BaseURL: c.BaseURL, APIKey: c.APIKey, Sender: c.Sender}
+}
+
+func (c SMTPConfig) Options() notification.SMTPConfig {
+	return notification.SMTPConfig{
+		Host:     c.Host,
+		Port:     c.Port,
+		Username: c.Username,
+		Password: c.Password,
+		From:     c.From,
+	}
+}
+
+func (c NotificationConfig) Options() notification.Options {
+	opts := notification.DefaultOptions()
+	opts.MaxAttempts = c.MaxAttempts
+	opts.RetryBackoff = c.RetryBackoff
+	opts.MaxBackoff = c.MaxBackoff
+	opts.PollInterval = c.PollInterval
+	opts.SendTimeout = c.SendTimeout
+	return opts
+}
+
+func (c JobsConfig) Options() jobs.Options {
+	return jobs.Options{
+		Concurrency:  c.Concurrency,
+		PollInterval: c.PollInterval,
+		Lease:        c.Lease,
+		RetryBackoff: c.RetryBackoff,
+		MaxBackoff:   c.MaxBackoff,
+		Retention:    c.Retention,
+	}
+}
+
+func (c EventsConfig) Options() events.Options {
+	opts := events.DefaultOptions()
+	opts.PollInterval = c.PollInterval
+	opts.Retention = c.Retention
+	return opts
+}
+
+func (c WebhookConfig) Options() webhook.Options {
+	return webhook.Options{
+		Timeout:      c.Timeout,
+		MaxAttempts:  c.MaxAttempts,
+		DisableAfter: c.DisableAfter,
+		Retention:    c.Retention,
+	}
+}
+
+func (c PaymentZarinpalConfig) Options() payment.ZarinpalConfig {
+	return payment.ZarinpalConfig{
+		MerchantID:  c.MerchantID,
+		BaseURL:     c.BaseURL,
+		AccessToken: c.AccessToken,
+		RefundURL:   c.RefundURL,
+	}
+}
+
+// Location loads TimeZone. An empty zone is rejected rather than read as
+// UTC.
+func (c NotificationConfig) Location() (*time.Location, error) {
+	if c.TimeZone == "" {
+		return nil, errors.New("time zone is required")
+	}
+	return time.LoadLocation(c.TimeZone)
+}
+
+// LegacySunsetDate parses LegacySunset, returning the zero time when unset.
+func (c APIConfig) LegacySunsetDate() (time.Time, error) {
+	if c.LegacySunset == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.DateOnly, c.LegacySunset)
+}
+
+func (c CORSConfig) Options() middleware.CORSOptions {
+	return middleware.CORSOptions{
+		AllowedOrigins:   c.AllowedOrigins,
+		AllowedMethods:   c.AllowedMethods,
+		AllowedHeaders:   c.AllowedHeaders,
+		ExposedHeaders:   c.ExposedHeaders,
+		AllowCredentials: c.AllowCredentials,
+		MaxAge:           c.MaxAge,
+	}
+}
+
+func (c SecurityConfig) Options() middleware.SecurityHeadersOptions {
+	return middleware.SecurityHeadersOptions{
+		HSTSMaxAge:            c.HSTSMaxAge,
+		ContentSecurityPolicy: c.ContentSecurityPolicy,
+	}
+}
+
+func (c RatePolicyConfig) Limit() ratelimit.Limit {
+	return ratelimit.Limit{Requests: c.Requests, Per: c.Per, Burst: c.Burst}
+}
+
+// IdleTTL is how long buckets are kept without traffic: the longest policy
+// period, after which any bucket would be full again.
+func (c RateLimitConfig) IdleTTL() time.Duration {
+	return c.Public.Per
+}
+
+// String renders the effective configuration one key per line with secrets
+// masked, suitable for startup logs.
+func (c Config) String() string {
+	var lines []string
+	_ = walk(reflect.ValueOf(&c).Elem(), "", func(f field) error {
+		value := fmt.Sprint(f.value.Interface())
+		if f.secret && value != "" {
+			value = "********"
+		}
+		lines = append(lines, f.key+"="+value)
+		return nil
+	})
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
+
+func validPort(port int) bool {
+	return port > 0 && port <= 65535
+}
+
+// validIPOrCIDR accepts a single IP address or a CIDR range.
+func validIPOrCIDR(raw string) bool {
+	if _, err := netip.ParsePrefix(raw); err == nil {
+		return true
+	}
+	_, err := netip.ParseAddr(raw)
+	return err == nil
+}
+
+// validURL accepts an empty (unset) value or an absolute URL with a host.
+func validURL(raw string) bool {
+	if raw == "" {
+		return true
+	}
+	u, err := url.Parse(raw)
+	return err == nil && u.IsAbs() && u.Host != ""
+}
@@ -0,0 +1,329 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// requiredEnv holds the settings that have no default, which env falls
+// back to.
+var requiredEnv = map[string]string{
+	"MEDIA_SIGNING_KEY": "0123456789abcdef0123456789abcdef",
+}
+
+func envLookup(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		if value, ok := env[key]; ok {
+			return value, ok
+		}
+		value, ok := requiredEnv[key]
+		return value, ok
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	cfg, err := load("", envLookup(nil))
+	require.NoError(t, err)
+
+	assert.Equal(t, 8000, cfg.Server.Port)
+	assert.Equal(t, "localhost", cfg.Database.Host)
+	assert.Equal(t, "http://localhost:8000/media/", cfg.ImageBaseURL())
+	assert.Equal(t, "local", cfg.Media.Storage)
+}
+
+func TestLoad_EnvOverrides(t *testing.T) {
+	cfg, err := load("", envLookup(map[string]string{
+		"PORT":                 "9090",
+		"DB_HOST":              "db.internal",
+		"DB_MAX_OPEN_CONNS":    "50",
+		"DB_CONN_MAX_LIFETIME": "30m",
+		"DB_REPLICA_HOSTS":     "replica-1, replica-2:5433",
+	}))
+	require.NoError(t, err)
+
+	assert.Equal(t, 9090, cfg.Server.Port)
+	assert.Equal(t, "db.internal", cfg.Database.Host)
+	assert.Equal(t, 50, cfg.Database.MaxOpenConns)
+	assert.Equal(t, 30*time.Minute, cfg.Database.ConnMaxLifetime)
+	assert.Equal(t, []string{"replica-1", "replica-2:5433"}, cfg.Database.ReplicaHosts)
+
+	conn := cfg.Database.Connection()
+	assert.Equal(t, 50, conn.MaxOpenConns)
+	assert.Equal(t, 30*time.Minute, conn.ConnMaxLifetime)
+}
+
+func TestLoad_NestedEnvPrefix(t *testing.T) {
+	cfg, err := load("", envLookup(map[string]string{
+		"RATE_LIMIT_PUBLIC_REQUESTS": "100",
+		"RATE_LIMIT_PUBLIC_PER":      "1h",
+	}))
+	require.NoError(t, err)
+
+	assert.Equal(t, 100, cfg.RateLimit.Public.Requests)
+	assert.Equal(t, time.Hour, cfg.RateLimit.Public.Per)
+	assert.Equal(t, 120, cfg.RateLimit.Public.Burst)
+	assert.Equal(t, time.Hour, cfg.RateLimit.IdleTTL())
+}
+
+func TestLoad_S3MediaStorage(t *testing.T) {
+	_, err := load("", envLookup(map[string]string{"MEDIA_STORAGE": "s3"}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "media.s3.endpoint")
+	assert.Contains(t, err.Error(), "media.s3.bucket")
+
+	cfg, err := load("", envLookup(map[string]string{
+		"MEDIA_STORAGE":              "s3",
+		"MEDIA_S3_ENDPOINT":          "http://minio:9000",
+		"MEDIA_S3_BUCKET":            "drgo",
+		"MEDIA_S3_SECRET_ACCESS_KEY": "s3cret",
+		"MEDIA_S3_PATH_STYLE":        "true",
+		"PUBLIC_BASE_URL":            "https://api.example.com",
+	}))
+	require.NoError(t, err)
+
+	opts := cfg.Media.S3.Options()
+	assert.Equal(t, "drgo", opts.Bucket)
+	assert.True(t, opts.PathStyle)
+	assert.Equal(t, "https://api.example.com/media/", cfg.ImageBaseURL())
+	assert.NotContains(t, cfg.String(), "s3cret")
+}
+
+func TestLoad_Notification(t *testing.T) {
+	_, err := load("", envLookup(map[string]string{
+		"SMTP_HOST":              "mail.internal",
+		"SMTP_FROM":              "not an address",
+		"NOTIFICATION_TIME_ZONE": "Mars/Olympus",
+	}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "smtp.from")
+	assert.Contains(t, err.Error(), "notification.time_zone")
+
+	cfg, err := load("", envLookup(map[string]string{
+		"SMTP_HOST":                  "mail.internal",
+		"SMTP_FROM":                  "DrAppointment <noreply@example.com>",
+		"NOTIFICATION_MAX_ATTEMPTS":  "3",
+		"NOTIFICATION_RETRY_BACKOFF": "1m",
+	}))
+	require.NoError(t, err)
+
+	zone, err := cfg.Notification.Location()
+	require.NoError(t, err)
+	assert.Equal(t, "Asia/Tehran", zone.String())
+	assert.Equal(t, 587, cfg.SMTP.Options().Port)
+	opts := cfg.Notification.Options()
+	assert.Equal(t, 3, opts.MaxAttempts)
+	assert.Equal(t, time.Minute, opts.RetryBackoff)
+	assert.Equal(t, 15*time.Second, opts.PollInterval)
+}
+
+func TestLoad_JobsAndEvents(t *testing.T) {
+	_, err := load("", envLookup(map[string]string{
+		"JOBS_STORE":       "redis",
+		"JOBS_CONCURRENCY": "0",
+	}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "jobs.store")
+	assert.Contains(t, err.Error(), "jobs.concurrency")
+
+	cfg, err := load("", envLookup(map[string]string{
+		"JOBS_CONCURRENCY": "8",
+		"JOBS_LEASE":       "10m",
+	}))
+	require.NoError(t, err)
+
+	opts := cfg.Jobs.Options()
+	assert.Equal(t, 8, opts.Concurrency)
+	assert.Equal(t, 10*time.Minute, opts.Lease)
+	assert.Equal(t, 7*24*time.Hour, opts.Retention)
+
+	_, err = load("", envLookup(map[string]string{"EVENTS_STORE": "kafka"}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "events.store")
+}
+
+func TestLoad_WebhookAndAdmin(t *testing.T) {
+	_, err := load("", envLookup(map[string]string{
+		"WEBHOOK_MAX_ATTEMPTS": "0",
+		"ADMIN_API_TOKEN":      "short",
+	}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "webhook.max_attempts")
+	assert.Contains(t, err.Error(), "admin.api_token")
+
+	token := strings.Repeat("t", 32)
+	cfg, err := load("", envLookup(map[string]string{
+		"WEBHOOK_DISABLE_AFTER": "5",
+		"ADMIN_API_TOKEN":       token,
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, token, cfg.Admin.APIToken)
+	assert.NotContains(t, cfg.String(), token)
+
+	opts := cfg.Webhook.Options()
+	assert.Equal(t, 5, opts.DisableAfter)
+	assert.Equal(t, 8, opts.MaxAttempts)
+	assert.Equal(t, 10*time.Second, opts.Timeout)
+}
+
+func TestLoad_Payment(t *testing.T) {
+	_, err := load("", envLookup(map[string]string{
+		"PAYMENT_PROVIDER":   "zarinpal",
+		"PAYMENT_RETURN_URL": "/done",
+	}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "payment.zarinpal.merchant_id")
+	assert.Contains(t, err.Error(), "server.public_base_url")
+	assert.Contains(t, err.Error(), "payment.return_url")
+
+	cfg, err := load("", envLookup(map[string]string{
+		"PAYMENT_PROVIDER":              "zarinpal",
+		"PAYMENT_ZARINPAL_MERCHANT_ID":  "3c5a8d1e-0000-4000-8000-000000000000",
+		"PAYMENT_ZARINPAL_ACCESS_TOKEN": "zarinpal-access-token",
+		"PUBLIC_BASE_URL":               "https://api.example.com/",
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, "https://api.example.com/api/v1/medical/payments", cfg.PaymentCallbackURL())
+	opts := cfg.Payment.Zarinpal.Options()
+	assert.Equal(t, "3c5a8d1e-0000-4000-8000-000000000000", opts.MerchantID)
+	assert.Equal(t, "https://payment.zarinpal.com", opts.BaseURL)
+	assert.Equal(t, "https://next.zarinpal.com/api/v4/graphql", opts.RefundURL)
+	assert.NotContains(t, cfg.String(), opts.MerchantID)
+	assert.NotContains(t, cfg.String(), opts.AccessToken)
+
+	cfg, err = load("", envLookup(nil))
+	require.NoError(t, err)
+	assert.Equal(t, "fake", cfg.Payment.Provider)
+	assert.Equal(t, "http://localhost:8000/api/v1/medical/payments", cfg.PaymentCallbackURL())
+}
+
+func TestLoad_YAMLFileThenEnv(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+server:
+  port: 7000
+database:
+  host: yaml-host
+  ssl_mode: require
+  replica_hosts: [r1, r2]
+cache:
+  ttl: 1m
+`)
+
+	cfg, err := load(path, envLookup(map[string]string{"DB_HOST": "env-host"}))
+	require.NoError(t, err)
+
+	assert.Equal(t, 7000, cfg.Server.Port)
+	assert.Equal(t, "env-host", cfg.Database.Host)
+	assert.Equal(t, "require", cfg.Database.SSLMode)
+	assert.Equal(t, []string{"r1", "r2"}, cfg.Database.ReplicaHosts)
+	assert.Equal(t, time.Minute, cfg.Cache.TTL)
+}
+
+func TestLoad_TOMLFile(t *testing.T) {
+	path := writeFile(t, "config.toml", `
+[database]
+name = "toml_db"
+max_idle_conns = 5
+
+[media]
+image_base_url = "https://cdn.example.com/media"
+`)
+
+	cfg, err := load(path, envLookup(nil))
+	require.NoError(t, err)
+
+	assert.Equal(t, "toml_db", cfg.Database.Name)
+	assert.Equal(t, 5, cfg.Database.MaxIdleConns)
+	assert.Equal(t, "https://cdn.example.com/media", cfg.ImageBaseURL())
+}
+
+func TestLoad_UnknownFileKey(t *testing.T) {
+	path := writeFile(t, "config.yaml", "database:\n  hostname: typo\n")
+
+	_, err := load(path, envLookup(nil))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown config key "database.hostname"`)
+}
+
+func TestLoad_AggregatesErrors(t *testing.T) {
+	_, err := load("", envLookup(map[string]string{
+		"PORT":              "not-a-number",
+		"DB_PORT":           "99999",
+		"DB_SSL_MODE":       "sometimes",
+		"CACHE_TTL":         "forever",
+		"MEDIA_SIGNING_KEY": "too-short",
+	}))
+	require.Error(t, err)
+
+	msg := err.Error()
+	assert.Contains(t, msg, "PORT: invalid integer")
+	assert.Contains(t, msg, "CACHE_TTL: invalid duration")
+	assert.Contains(t, msg, "database.port must be between 1 and 65535")
+	assert.Contains(t, msg, "database.ssl_mode must be one of")
+	assert.Contains(t, msg, "media.signing_key must be at least 32 characters")
+}
+
+func TestLoad_RequiresSigningKey(t *testing.T) {
+	_, err := load("", envLookup(map[string]string{"MEDIA_SIGNING_KEY": ""}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "media.signing_key is required")
+	assert.NotContains(t, err.Error(), "at least 32 characters")
+}
+
+func TestValidate_AggregatesErrors(t *testing.T) {
+	cfg := Default()
+	cfg.Database.Port = 99999
+	cfg.Database.SSLMode = "sometimes"
+	cfg.Database.MaxOpenConns = 5
+	cfg.Database.MaxIdleConns = 10
+
+	err := cfg.Validate()
+	require.Error(t, err)
+
+	msg := err.Error()
+	assert.Contains(t, msg, "database.port")
+	assert.Contains(t, msg, "database.ssl_mode")
+	assert.Contains(t, msg, "database.max_idle_conns")
+}
+
+func TestLoad_SecretFromFile(t *testing.T) {
+	path := writeFile(t, "db_password", "s3cret\n")
+
+	cfg, err := load("", envLookup(map[string]string{"DB_PASSWORD_FILE": path}))
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret", cfg.Database.Password)
+}
+
+func TestLoad_SecretAndSecretFileConflict(t *testing.T) {
+	path := writeFile(t, "db_password", "s3cret")
+
+	_, err := load("", envLookup(map[string]string{
+		"DB_PASSWORD":      "other",
+		"DB_PASSWORD_FILE": path,
+	}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "mutually exclusive")
+}
+
+func TestConfig_StringRedactsSecrets(t *testing.T) {
+	cfg := Default()
+	cfg.Database.Password = "s3cret"
+
+	out := cfg.String()
+	assert.NotContains(t, out, "s3cret")
+	assert.Contains(t, out, "database.password=********")
+	assert.Contains(t, out, "database.host=localhost")
+	assert.True(t, strings.Contains(out, "server.port=8000"))
+}
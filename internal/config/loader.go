@@ -0,0 +1,255 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// FileEnv names the environment variable pointing at an optional YAML or TOML
+// config file.
+const FileEnv = "CONFIG_FILE"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Load builds the configuration from defaults, the file named by CONFIG_FILE
+// (if any) and finally environment variables, then validates it. All parse
+// and validation problems are reported together.
+func Load() (*Config, error) {
+	return load(os.Getenv(FileEnv), os.LookupEnv)
+}
+
+func load(path string, lookupEnv func(string) (string, bool)) (*Config, error) {
+	cfg := Default()
+
+	var errs []error
+	if path != "" {
+		values, err := readFile(path)
+		if err != nil {
+			return nil, err
+		}
+		errs = append(errs, applyFile(cfg, values))
+	}
+
+	// Fields that failed to parse keep their previous value, so validation
+	// still runs and every problem is reported in one go.
+	errs = append(errs, applyEnv(cfg, lookupEnv), cfg.Validate())
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+
+	return cfg, nil
+}
+
+func readFile(path string) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	values := map[string]any{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	case ".toml":
+		err = toml.Unmarshal(data, &values)
+	default:
+		return nil, fmt.Errorf("unsupported config file extension %q, use .yaml, .yml or .toml", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	return values, nil
+}
+
+type field struct {
+	key    string
+	env    string
+	secret bool
+	value  reflect.Value
+}
+
+// walk visits every leaf field of a config struct, joining nested `key` tags
+// with dots. An `env` tag on a nested struct prefixes the env names of its
+// fields, so one struct type can be reused for several sections.
+func walk(v reflect.Value, prefix string, visit func(f field) error) error {
+	return walkEnv(v, prefix, "", visit)
+}
+
+func walkEnv(v reflect.Value, prefix, envPrefix string, visit func(f field) error) error {
+	var errs []error
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		key := sf.Tag.Get("key")
+		if key == "" {
+			continue
+		}
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+
+		env := sf.Tag.Get("env")
+		if env != "" && envPrefix != "" {
+			env = envPrefix + "_" + env
+		}
+
+		fv := v.Field(i)
+		if fv.Kind() == reflect.Struct {
+			errs = append(errs, walkEnv(fv, key, env, visit))
+			continue
+		}
+
+		errs = append(errs, visit(field{
+			key:    key,
+			env:    env,
+			secret: sf.Tag.Get("secret") == "true",
+			value:  fv,
+		}))
+	}
+	return errors.Join(errs...)
+}
+
+func applyFile(cfg *Config, values map[string]any) error {
+	flat := map[string]any{}
+	flatten("", values, flat)
+
+	var errs []error
+	err := walk(reflect.ValueOf(cfg).Elem(), "", func(f field) error {
+		raw, ok := flat[f.key]
+		if !ok {
+			return nil
+		}
+		delete(flat, f.key)
+		if err := setValue(f.value, raw); err != nil {
+			return fmt.Errorf("%s: %w", f.key, err)
+		}
+		return nil
+	})
+	errs = append(errs, err)
+
+	unknown := make([]string, 0, len(flat))
+	for key := range flat {
+		unknown = append(unknown, key)
+	}
+	sort.Strings(unknown)
+	for _, key := range unknown {
+		errs = append(errs, fmt.Errorf("unknown config key %q", key))
+	}
+
+	return errors.Join(errs...)
+}
+
+func flatten(prefix string, values map[string]any, out map[string]any) {
+	for key, value := range values {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if nested, ok := value.(map[string]any); ok {
+			flatten(key, nested, out)
+			continue
+		}
+		out[key] = value
+	}
+}
+
+func applyEnv(cfg *Config, lookupEnv func(string) (string, bool)) error {
+	return walk(reflect.ValueOf(cfg).Elem(), "", func(f field) error {
+		if f.env == "" {
+			return nil
+		}
+
+		raw, ok := lookupEnv(f.env)
+		if f.secret {
+			if path, fileOK := lookupEnv(f.env + "_FILE"); fileOK && path != "" {
+				if ok {
+					return fmt.Errorf("%s and %s_FILE are mutually exclusive", f.env, f.env)
+				}
+				data, err := os.ReadFile(path)
+				if err != nil {
+					return fmt.Errorf("%s_FILE: %w", f.env, err)
+				}
+				raw, ok = strings.TrimRight(string(data), "\r\n"), true
+			}
+		}
+		if !ok || raw == "" {
+			return nil
+		}
+
+		if err := setValue(f.value, raw); err != nil {
+			return fmt.Errorf("%s: %w", f.env, err)
+		}
+		return nil
+	})
+}
+
+func setValue(v reflect.Value, raw any) error {
+	if v.Type() == durationType {
+		s, ok := raw.(string)
+		if !ok {
+			return fmt.Errorf("duration must be a string such as \"30s\", got %v", raw)
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", s)
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(fmt.Sprint(raw))
+	case reflect.Int:
+		n, err := strconv.Atoi(fmt.Sprint(raw))
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", fmt.Sprint(raw))
+		}
+		v.SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(fmt.Sprint(raw), 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", fmt.Sprint(raw))
+		}
+		v.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(fmt.Sprint(raw))
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", fmt.Sprint(raw))
+		}
+		v.SetBool(b)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported slice type %s", v.Type())
+		}
+		var items []string
+		switch raw := raw.(type) {
+		case string:
+			for _, item := range strings.Split(raw, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, item)
+				}
+			}
+		case []any:
+			for _, item := range raw {
+				items = append(items, fmt.Sprint(item))
+			}
+		default:
+			return fmt.Errorf("expected a list, got %v", raw)
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
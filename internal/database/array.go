@@ -0,0 +1,18 @@
+package database
+
+import (
+	"database/sql/driver"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// UUIDArray encodes ids as a postgres array literal, e.g. for
+// "WHERE id = ANY($1)".
+func UUIDArray(ids []uuid.UUID) driver.Valuer {
+	values := make([]string, len(ids))
+	for i, id := range ids {
+		values[i] = id.String()
+	}
+	return pq.StringArray(values)
+}
@@ -4,13 +4,21 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"log"
+	"log/slog"
+	"net"
 	"net/url"
+	"strconv"
 	"time"
 
 	_ "github.com/lib/pq"
 )
 
+const (
+	defaultReplicaStickiness          = 2 * time.Second
+	defaultReplicaHealthCheckInterval = 10 * time.Second
+	replicaPingTimeout                = 2 * time.Second
+)
+
 type Config struct {
 	Host            string
 	Port            int
@@ -21,13 +29,83 @@ type Config struct {
 	MaxOpenConns    int
 	MaxIdleConns    int
 	ConnMaxLifetime time.Duration
+
+	// ReplicaHosts lists read replicas as "host" or "host:port"; they share
+	// credentials, database name and pool settings with the primary.
+	ReplicaHosts               []string
+	ReplicaStickiness          time.Duration
+	ReplicaHealthCheckInterval time.Duration
+}
+
+func Connect(ctx context.Context, config *Config) (*DB, error) {
+	primary, err := open(ctx, config, config.Host, config.Port)
+	if err != nil {
+		return nil, err
+	}
+
+	stickiness := config.ReplicaStickiness
+	if stickiness <= 0 {
+		stickiness = defaultReplicaStickiness
+	}
+	db := newDB(primary, stickiness)
+
+	for _, replicaHost := range config.ReplicaHosts {
+		host, port, err := splitHostPort(replicaHost, config.Port)
+		if err != nil {
+			if closeErr := db.Close(); closeErr != nil {
+				slog.Error("failed to close database after replica config failure", "error", closeErr)
+			}
+			return nil, fmt.Errorf("invalid replica host %q: %w", replicaHost, err)
+		}
+
+		replicaDB, err := openPool(config, host, port)
+		if err != nil {
+			if closeErr := db.Close(); closeErr != nil {
+				slog.Error("failed to close database after replica open failure", "error", closeErr)
+			}
+			return nil, err
+		}
+
+		// An unreachable replica must not block startup, it just stays out
+		// of rotation until a health check succeeds.
+		healthy := true
+		if err := replicaDB.PingContext(ctx); err != nil {
+			slog.Warn("replica is unreachable, routing reads to primary", "replica", replicaHost, "error", err)
+			healthy = false
+		}
+		db.addReplica(replicaHost, replicaDB, healthy)
+	}
+
+	interval := config.ReplicaHealthCheckInterval
+	if interval <= 0 {
+		interval = defaultReplicaHealthCheckInterval
+	}
+	db.startHealthChecks(interval, replicaPingTimeout)
+
+	return db, nil
 }
 
-func Connect(ctx context.Context, config *Config) (*sql.DB, error) {
+func open(ctx context.Context, config *Config, host string, port int) (*sql.DB, error) {
+	db, err := openPool(config, host, port)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.PingContext(ctx); err != nil {
+		if closeErr := db.Close(); closeErr != nil {
+			slog.Error("failed to close database after ping failure", "error", closeErr)
+		}
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	return db, nil
+}
+
+func openPool(config *Config, host string, port int) (*sql.DB, error) {
 	u := &url.URL{
 		Scheme: "postgres",
 		User:   url.UserPassword(config.User, config.Password),
-		Host:   fmt.Sprintf("%s:%d", config.Host, config.Port),
+		Host:   fmt.Sprintf("%s:%d", host, port),
 		Path:   "/" + config.DBName,
 	}
 	q := u.Query()
@@ -50,12 +128,19 @@ func Connect(ctx context.Context, config *Config) (*sql.DB, error) {
 		db.SetConnMaxLifetime(config.ConnMaxLifetime)
 	}
 
-	if err := db.PingContext(ctx); err != nil {
-		if closeErr := db.Close(); closeErr != nil {
-			log.Printf("Failed to close database after ping failure: %v", closeErr)
-		}
-		return nil, fmt.Errorf("failed to ping database: %w", err)
+	return db, nil
+}
+
+func splitHostPort(hostPort string, defaultPort int) (string, int, error) {
+	host, portStr, err := net.SplitHostPort(hostPort)
+	if err != nil {
+		// No port given, use the primary's
+		return hostPort, defaultPort, nil
 	}
 
-	return db, nil
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid port: %w", err)
+	}
+	return host, port, nil
 }
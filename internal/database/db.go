@@ -0,0 +1,436 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Querier is the subset of *sql.DB and *sql.Tx used by repositories.
+type Querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+var _ Querier = (*DB)(nil)
+
+const primaryPool = "primary"
+
+type ctxKey int
+
+const (
+	txCtxKey ctxKey = iota
+	primaryCtxKey
+	commitHooksCtxKey
+	sessionCtxKey
+)
+
+// WithTx binds tx to ctx so every statement issued through DB with the
+// returned context runs inside that transaction.
+func WithTx(ctx context.Context, tx *sql.Tx) context.Context {
+	return context.WithValue(ctx, txCtxKey, tx)
+}
+
+// WithoutTx detaches ctx from the transaction bound to it, so statements
+// issued with the returned context, and transactions begun with it, commit
+// on their own. It is for writes that must outlive a transaction the
+// caller might still roll back.
+func WithoutTx(ctx context.Context) context.Context {
+	return context.WithValue(ctx, txCtxKey, (*sql.Tx)(nil))
+}
+
+func TxFromContext(ctx context.Context) (*sql.Tx, bool) {
+	tx, ok := ctx.Value(txCtxKey).(*sql.Tx)
+	return tx, ok && tx != nil
+}
+
+// WithPrimary forces every read issued with the returned context to the
+// primary, e.g. when a handler must read its own writes.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryCtxKey, true)
+}
+
+func isPrimaryForced(ctx context.Context) bool {
+	forced, _ := ctx.Value(primaryCtxKey).(bool)
+	return forced
+}
+
+// session remembers when a request or job last wrote, for the reads it
+// issues afterwards.
+type session struct {
+	lastWrite atomic.Int64
+}
+
+// WithSession scopes read-your-writes to ctx, typically one request or job.
+// Once a write is issued with ctx, or a context derived from it, reads
+// issued with them stay on the primary for the stickiness window. Writes
+// never hold back reads outside their session, and reads without a session
+// are only kept off replicas by WithPrimary.
+func WithSession(ctx context.Context) context.Context {
+	return context.WithValue(ctx, sessionCtxKey, &session{})
+}
+
+// WithoutSession detaches ctx from its session, for bookkeeping writes such
+// as rate limit counters that the caller's later reads do not depend on and
+// that must not keep those reads off the replicas.
+func WithoutSession(ctx context.Context) context.Context {
+	return context.WithValue(ctx, sessionCtxKey, (*session)(nil))
+}
+
+func sessionFrom(ctx context.Context) *session {
+	s, _ := ctx.Value(sessionCtxKey).(*session)
+	return s
+}
+
+// ReadsPrimary reports whether reads issued with ctx must see the primary's
+// latest state, because they run inside a transaction or were forced with
+// WithPrimary. Caches in front of the database step aside for them.
+func ReadsPrimary(ctx context.Context) bool {
+	_, inTx := TxFromContext(ctx)
+	return inTx || isPrimaryForced(ctx)
+}
+
+// AfterCommit runs fn once the transaction InTx bound to ctx commits, and
+// drops it if the transaction rolls back. Without a transaction the write
+// has already committed, so fn runs right away. Caches use it so readers
+// cannot load the old row back between invalidation and commit.
+func AfterCommit(ctx context.Context, fn func()) {
+	if _, ok := TxFromContext(ctx); ok {
+		if hooks, ok := ctx.Value(commitHooksCtxKey).(*[]func()); ok {
+			*hooks = append(*hooks, fn)
+			return
+		}
+	}
+	fn()
+}
+
+type replica struct {
+	host    string
+	db      *sql.DB
+	healthy atomic.Bool
+}
+
+// DB routes writes and transactions to the primary and spreads read-only
+// statements over healthy replicas in round-robin order. Only plain SELECTs
+// count as read-only: statements that write, lock rows or return what they
+// wrote go to the primary whichever method issues them. Reads fall back to
+// the primary when no replica is healthy, inside a transaction, when forced
+// with WithPrimary, or within the stickiness window after a write in the
+// same session (see WithSession) so callers do not observe replication lag.
+type DB struct {
+	primary    *sql.DB
+	replicas   []*replica
+	next       atomic.Uint64
+	stickiness time.Duration
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+	now  func() time.Time
+}
+
+func newDB(primary *sql.DB, stickiness time.Duration) *DB {
+	return &DB{
+		primary:    primary,
+		stickiness: stickiness,
+		stop:       make(chan struct{}),
+		now:        time.Now,
+	}
+}
+
+// New wraps an already opened pool without replicas, e.g. a sqlmock
+// connection in tests.
+func New(primary *sql.DB) *DB {
+	return newDB(primary, defaultReplicaStickiness)
+}
+
+// NewWithReplicas wraps already opened pools like New, adding healthy
+// replicas for reads, e.g. to test statement routing.
+func NewWithReplicas(primary *sql.DB, replicas ...*sql.DB) *DB {
+	d := New(primary)
+	for i, r := range replicas {
+		d.addReplica(fmt.Sprintf("replica-%d", i), r, true)
+	}
+	return d
+}
+
+func (d *DB) addReplica(host string, db *sql.DB, healthy bool) {
+	r := &replica{host: host, db: db}
+	r.healthy.Store(healthy)
+	d.replicas = append(d.replicas, r)
+}
+
+// Primary exposes the primary pool for callers that need *sql.DB directly.
+func (d *DB) Primary() *sql.DB {
+	return d.primary
+}
+
+// Pools returns every underlying pool keyed by "primary" or the replica host,
+// e.g. for exporting pool statistics.
+func (d *DB) Pools() map[string]*sql.DB {
+	pools := make(map[string]*sql.DB, len(d.replicas)+1)
+	pools[primaryPool] = d.primary
+	for _, r := range d.replicas {
+		pools[r.host] = r.db
+	}
+	return pools
+}
+
+func (d *DB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	ctx, span := startStatementSpan(ctx, query)
+	rows, pool, err := d.query(ctx, query, args...)
+	endStatementSpan(span, pool, err)
+	return rows, err
+}
+
+func (d *DB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	ctx, span := startStatementSpan(ctx, query)
+	row, pool := d.queryRow(ctx, query, args...)
+	endStatementSpan(span, pool, row.Err())
+	return row
+}
+
+func (d *DB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ctx, span := startStatementSpan(ctx, query)
+	result, pool, err := d.exec(ctx, query, args...)
+	endStatementSpan(span, pool, err)
+	return result, err
+}
+
+func (d *DB) query(ctx context.Context, query string, args ...any) (*sql.Rows, string, error) {
+	if tx, ok := TxFromContext(ctx); ok {
+		rows, err := tx.QueryContext(ctx, query, args...)
+		return rows, primaryPool, err
+	}
+
+	if r := d.reader(ctx, query); r != nil {
+		rows, err := r.db.QueryContext(ctx, query, args...)
+		if err == nil || !d.failover(ctx, r, err) {
+			return rows, r.host, err
+		}
+	}
+
+	if !readOnly(query) {
+		d.markWrite(ctx)
+	}
+	rows, err := d.primary.QueryContext(ctx, query, args...)
+	return rows, primaryPool, err
+}
+
+func (d *DB) queryRow(ctx context.Context, query string, args ...any) (*sql.Row, string) {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx.QueryRowContext(ctx, query, args...), primaryPool
+	}
+
+	if r := d.reader(ctx, query); r != nil {
+		row := r.db.QueryRowContext(ctx, query, args...)
+		if err := row.Err(); err == nil || !d.failover(ctx, r, err) {
+			return row, r.host
+		}
+	}
+
+	if !readOnly(query) {
+		d.markWrite(ctx)
+	}
+	return d.primary.QueryRowContext(ctx, query, args...), primaryPool
+}
+
+func (d *DB) exec(ctx context.Context, query string, args ...any) (sql.Result, string, error) {
+	if tx, ok := TxFromContext(ctx); ok {
+		result, err := tx.ExecContext(ctx, query, args...)
+		return result, primaryPool, err
+	}
+
+	d.markWrite(ctx)
+	result, err := d.primary.ExecContext(ctx, query, args...)
+	return result, primaryPool, err
+}
+
+// BeginTx always starts the transaction on the primary. Use WithTx to route
+// repository calls through it.
+func (d *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	if opts == nil || !opts.ReadOnly {
+		d.markWrite(ctx)
+	}
+	return d.primary.BeginTx(ctx, opts)
+}
+
+// Transactor runs functions inside a database transaction.
+type Transactor interface {
+	InTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+var _ Transactor = (*DB)(nil)
+
+// InTx runs fn in a transaction bound to the context it is given, committing
+// when fn succeeds and rolling back otherwise. Inside an existing
+// transaction fn simply joins it.
+func (d *DB) InTx(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	if _, ok := TxFromContext(ctx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := d.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				slog.Warn("failed to roll back transaction", "error", rbErr)
+			}
+		}
+	}()
+
+	var hooks []func()
+	ctx = context.WithValue(WithTx(ctx, tx), commitHooksCtxKey, &hooks)
+	if err = fn(ctx); err != nil {
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	for _, hook := range hooks {
+		hook()
+	}
+	return nil
+}
+
+func (d *DB) PingContext(ctx context.Context) error {
+	return d.primary.PingContext(ctx)
+}
+
+func (d *DB) Stats() sql.DBStats {
+	return d.primary.Stats()
+}
+
+func (d *DB) Close() error {
+	select {
+	case <-d.stop:
+	default:
+		close(d.stop)
+	}
+	d.wg.Wait()
+
+	errs := make([]error, 0, len(d.replicas)+1)
+	for _, r := range d.replicas {
+		errs = append(errs, r.db.Close())
+	}
+	errs = append(errs, d.primary.Close())
+	return errors.Join(errs...)
+}
+
+func (d *DB) markWrite(ctx context.Context) {
+	if s := sessionFrom(ctx); s != nil {
+		s.lastWrite.Store(d.now().UnixNano())
+	}
+}
+
+// reader picks the replica for a statement, or nil when the primary must
+// serve it.
+func (d *DB) reader(ctx context.Context, query string) *replica {
+	if len(d.replicas) == 0 || isPrimaryForced(ctx) || !readOnly(query) {
+		return nil
+	}
+
+	if s := sessionFrom(ctx); s != nil {
+		if lastWrite := s.lastWrite.Load(); lastWrite != 0 && d.now().Sub(time.Unix(0, lastWrite)) < d.stickiness {
+			return nil
+		}
+	}
+
+	start := d.next.Add(1)
+	for i := range d.replicas {
+		r := d.replicas[(start+uint64(i))%uint64(len(d.replicas))]
+		if r.healthy.Load() {
+			return r
+		}
+	}
+
+	return nil
+}
+
+// primaryOnly matches what makes a SELECT unfit for a replica: a
+// data-modifying CTE, a RETURNING clause, a row lock or a sequence call.
+// Identifiers that merely contain these words, like updated_at, do not match.
+var primaryOnly = regexp.MustCompile(`(?i)\b(insert|update|delete|merge|returning|share|nextval|setval)\b`)
+
+// readOnly reports whether query is a plain SELECT that a replica can
+// answer. Anything it is unsure about goes to the primary.
+func readOnly(query string) bool {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return false
+	}
+	switch strings.ToUpper(fields[0]) {
+	case "SELECT", "WITH":
+		return !primaryOnly.MatchString(query)
+	default:
+		return false
+	}
+}
+
+// failover reports whether a failed replica read should be retried on the
+// primary. Server-side errors (bad SQL, constraint violations) and cancelled
+// contexts are returned as-is; anything else is treated as a connection
+// failure and takes the replica out of rotation until the next health check.
+func (d *DB) failover(ctx context.Context, r *replica, err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) || errors.Is(err, sql.ErrNoRows) || ctx.Err() != nil {
+		return false
+	}
+
+	if r.healthy.CompareAndSwap(true, false) {
+		slog.Warn("replica marked unhealthy", "replica", r.host, "error", err)
+	}
+	return true
+}
+
+func (d *DB) startHealthChecks(interval, timeout time.Duration) {
+	if len(d.replicas) == 0 || interval <= 0 {
+		return
+	}
+
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-d.stop:
+				return
+			case <-ticker.C:
+				d.checkReplicas(timeout)
+			}
+		}
+	}()
+}
+
+func (d *DB) checkReplicas(timeout time.Duration) {
+	for _, r := range d.replicas {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		err := r.db.PingContext(ctx)
+		cancel()
+
+		healthy := err == nil
+		if r.healthy.Swap(healthy) != healthy {
+			if healthy {
+				slog.Info("replica is healthy again", "replica", r.host)
+			} else {
+				slog.Warn("replica marked unhealthy", "replica", r.host, "error", err)
+			}
+		}
+	}
+}
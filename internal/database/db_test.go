@@ -0,0 +1,340 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockDB struct {
+	db   *sql.DB
+	mock sqlmock.Sqlmock
+}
+
+func newMockDB(t *testing.T) mockDB {
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	return mockDB{db: db, mock: mock}
+}
+
+func setupReplicatedDB(t *testing.T, replicas int) (*DB, mockDB, []mockDB) {
+	primary := newMockDB(t)
+	db := newDB(primary.db, time.Second)
+
+	replicaMocks := make([]mockDB, replicas)
+	for i := range replicaMocks {
+		replicaMocks[i] = newMockDB(t)
+		db.addReplica("replica", replicaMocks[i].db, true)
+	}
+	return db, primary, replicaMocks
+}
+
+func countRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"count"}).AddRow(1)
+}
+
+func TestDB_ReadsRoundRobinOverReplicas(t *testing.T) {
+	db, primary, replicas := setupReplicatedDB(t, 2)
+	ctx := context.Background()
+
+	replicas[0].mock.ExpectQuery("SELECT 1").WillReturnRows(countRows())
+	replicas[1].mock.ExpectQuery("SELECT 1").WillReturnRows(countRows())
+	replicas[0].mock.ExpectQuery("SELECT 1").WillReturnRows(countRows())
+	replicas[1].mock.ExpectQuery("SELECT 1").WillReturnRows(countRows())
+
+	for i := 0; i < 4; i++ {
+		var count int
+		require.NoError(t, db.QueryRowContext(ctx, "SELECT 1").Scan(&count))
+	}
+
+	require.NoError(t, primary.mock.ExpectationsWereMet())
+	for _, r := range replicas {
+		require.NoError(t, r.mock.ExpectationsWereMet())
+	}
+}
+
+func TestDB_ReadsFallBackToPrimaryWithoutReplicas(t *testing.T) {
+	db, primary, _ := setupReplicatedDB(t, 0)
+
+	primary.mock.ExpectQuery("SELECT 1").WillReturnRows(countRows())
+
+	rows, err := db.QueryContext(context.Background(), "SELECT 1")
+	require.NoError(t, err)
+	require.NoError(t, rows.Close())
+	require.NoError(t, primary.mock.ExpectationsWereMet())
+}
+
+func TestDB_SkipsUnhealthyReplicas(t *testing.T) {
+	db, primary, replicas := setupReplicatedDB(t, 2)
+	db.replicas[0].healthy.Store(false)
+
+	replicas[1].mock.ExpectQuery("SELECT 1").WillReturnRows(countRows())
+	replicas[1].mock.ExpectQuery("SELECT 1").WillReturnRows(countRows())
+
+	for i := 0; i < 2; i++ {
+		rows, err := db.QueryContext(context.Background(), "SELECT 1")
+		require.NoError(t, err)
+		require.NoError(t, rows.Close())
+	}
+
+	require.NoError(t, primary.mock.ExpectationsWereMet())
+	require.NoError(t, replicas[1].mock.ExpectationsWereMet())
+}
+
+func TestDB_FailsOverToPrimaryOnConnectionError(t *testing.T) {
+	db, primary, replicas := setupReplicatedDB(t, 1)
+
+	replicas[0].mock.ExpectQuery("SELECT 1").WillReturnError(driver.ErrBadConn)
+	primary.mock.ExpectQuery("SELECT 1").WillReturnRows(countRows())
+
+	var count int
+	require.NoError(t, db.QueryRowContext(context.Background(), "SELECT 1").Scan(&count))
+	assert.Equal(t, 1, count)
+	assert.False(t, db.replicas[0].healthy.Load())
+
+	require.NoError(t, primary.mock.ExpectationsWereMet())
+}
+
+func TestDB_ReadsAfterWriteGoToPrimary(t *testing.T) {
+	db, primary, replicas := setupReplicatedDB(t, 1)
+	now := time.Now()
+	db.now = func() time.Time { return now }
+	ctx := WithSession(context.Background())
+
+	primary.mock.ExpectExec("UPDATE doctors").WillReturnResult(sqlmock.NewResult(0, 1))
+	primary.mock.ExpectQuery("SELECT 1").WillReturnRows(countRows())
+	replicas[0].mock.ExpectQuery("SELECT 1").WillReturnRows(countRows())
+
+	_, err := db.ExecContext(ctx, "UPDATE doctors")
+	require.NoError(t, err)
+
+	var count int
+	require.NoError(t, db.QueryRowContext(ctx, "SELECT 1").Scan(&count))
+
+	now = now.Add(2 * time.Second)
+	require.NoError(t, db.QueryRowContext(ctx, "SELECT 1").Scan(&count))
+
+	require.NoError(t, primary.mock.ExpectationsWereMet())
+	require.NoError(t, replicas[0].mock.ExpectationsWereMet())
+	assert.True(t, db.replicas[0].healthy.Load())
+}
+
+func TestDB_WritesOnlyHoldBackTheirSession(t *testing.T) {
+	db, primary, replicas := setupReplicatedDB(t, 1)
+
+	primary.mock.ExpectExec("UPDATE doctors").WillReturnResult(sqlmock.NewResult(0, 1))
+	replicas[0].mock.ExpectQuery("SELECT 1").WillReturnRows(countRows())
+	replicas[0].mock.ExpectQuery("SELECT 1").WillReturnRows(countRows())
+
+	_, err := db.ExecContext(WithSession(context.Background()), "UPDATE doctors")
+	require.NoError(t, err)
+
+	var count int
+	require.NoError(t, db.QueryRowContext(WithSession(context.Background()), "SELECT 1").Scan(&count))
+	require.NoError(t, db.QueryRowContext(context.Background(), "SELECT 1").Scan(&count))
+
+	require.NoError(t, primary.mock.ExpectationsWereMet())
+	require.NoError(t, replicas[0].mock.ExpectationsWereMet())
+	assert.True(t, db.replicas[0].healthy.Load())
+}
+
+func TestDB_WritesWithoutSessionDoNotHoldBackReads(t *testing.T) {
+	db, primary, replicas := setupReplicatedDB(t, 1)
+	ctx := WithSession(context.Background())
+
+	primary.mock.ExpectExec("UPDATE rate_limit_buckets").WillReturnResult(sqlmock.NewResult(0, 1))
+	replicas[0].mock.ExpectQuery("SELECT 1").WillReturnRows(countRows())
+
+	_, err := db.ExecContext(WithoutSession(ctx), "UPDATE rate_limit_buckets")
+	require.NoError(t, err)
+
+	var count int
+	require.NoError(t, db.QueryRowContext(ctx, "SELECT 1").Scan(&count))
+
+	require.NoError(t, primary.mock.ExpectationsWereMet())
+	require.NoError(t, replicas[0].mock.ExpectationsWereMet())
+	assert.True(t, db.replicas[0].healthy.Load())
+}
+
+func TestDB_RoutesWritingQueriesToPrimary(t *testing.T) {
+	db, primary, replicas := setupReplicatedDB(t, 1)
+	ctx := WithSession(context.Background())
+
+	primary.mock.ExpectQuery("INSERT INTO jobs").WillReturnRows(countRows())
+	// The session wrote, so its next read stays on the primary too
+	primary.mock.ExpectQuery("SELECT id FROM jobs").WillReturnRows(countRows())
+
+	var count int
+	require.NoError(t, db.QueryRowContext(ctx, "INSERT INTO jobs (id) VALUES ($1) RETURNING id", 1).Scan(&count))
+	require.NoError(t, db.QueryRowContext(ctx, "SELECT id FROM jobs WHERE id = $1", 1).Scan(&count))
+
+	require.NoError(t, primary.mock.ExpectationsWereMet())
+	require.NoError(t, replicas[0].mock.ExpectationsWereMet())
+}
+
+func TestReadOnly(t *testing.T) {
+	tests := []struct {
+		query    string
+		readOnly bool
+	}{
+		{"SELECT id, updated_at FROM doctors WHERE id = $1", true},
+		{"\n  select count(*) from specialties", true},
+		{"WITH recent AS (SELECT id FROM jobs) SELECT id FROM recent", true},
+		{"SELECT id FROM refunds WHERE id = $1 FOR UPDATE", false},
+		{"SELECT id FROM refunds WHERE id = $1 FOR NO KEY UPDATE", false},
+		{"SELECT id FROM refunds WHERE id = $1 FOR SHARE", false},
+		{"WITH old AS (SELECT id FROM doctors) UPDATE doctors SET name = $1 FROM old", false},
+		{"INSERT INTO jobs (id) VALUES ($1) RETURNING id", false},
+		{"UPDATE jobs SET status = 'running' RETURNING id", false},
+		{"DELETE FROM outbox_events WHERE published_at < $1", false},
+		{"SELECT nextval('orders_seq')", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.readOnly, readOnly(tt.query), tt.query)
+	}
+}
+
+func TestDB_WithPrimaryForcesPrimary(t *testing.T) {
+	db, primary, replicas := setupReplicatedDB(t, 1)
+
+	primary.mock.ExpectQuery("SELECT 1").WillReturnRows(countRows())
+
+	var count int
+	require.NoError(t, db.QueryRowContext(WithPrimary(context.Background()), "SELECT 1").Scan(&count))
+
+	require.NoError(t, primary.mock.ExpectationsWereMet())
+	require.NoError(t, replicas[0].mock.ExpectationsWereMet())
+}
+
+func TestDB_ReadsInsideTransactionUseTx(t *testing.T) {
+	db, primary, replicas := setupReplicatedDB(t, 1)
+
+	primary.mock.ExpectBegin()
+	primary.mock.ExpectQuery("SELECT 1").WillReturnRows(countRows())
+	primary.mock.ExpectCommit()
+
+	tx, err := db.BeginTx(context.Background(), nil)
+	require.NoError(t, err)
+
+	var count int
+	ctx := WithTx(context.Background(), tx)
+	require.NoError(t, db.QueryRowContext(ctx, "SELECT 1").Scan(&count))
+	require.NoError(t, tx.Commit())
+
+	require.NoError(t, primary.mock.ExpectationsWereMet())
+	require.NoError(t, replicas[0].mock.ExpectationsWereMet())
+}
+
+func TestDB_InTx(t *testing.T) {
+	db, primary, _ := setupReplicatedDB(t, 1)
+
+	primary.mock.ExpectBegin()
+	primary.mock.ExpectExec("UPDATE a").WillReturnResult(sqlmock.NewResult(0, 1))
+	primary.mock.ExpectExec("UPDATE b").WillReturnResult(sqlmock.NewResult(0, 1))
+	primary.mock.ExpectCommit()
+
+	err := db.InTx(context.Background(), func(ctx context.Context) error {
+		if _, err := db.ExecContext(ctx, "UPDATE a"); err != nil {
+			return err
+		}
+		// Nested calls join the outer transaction
+		return db.InTx(ctx, func(ctx context.Context) error {
+			_, err := db.ExecContext(ctx, "UPDATE b")
+			return err
+		})
+	})
+	require.NoError(t, err)
+	require.NoError(t, primary.mock.ExpectationsWereMet())
+}
+
+func TestDB_InTxWithoutTxCommitsOnItsOwn(t *testing.T) {
+	db, primary, _ := setupReplicatedDB(t, 0)
+
+	primary.mock.ExpectBegin()
+	primary.mock.ExpectBegin()
+	primary.mock.ExpectExec("UPDATE b").WillReturnResult(sqlmock.NewResult(0, 1))
+	primary.mock.ExpectCommit()
+	primary.mock.ExpectRollback()
+
+	failure := errors.New("failure")
+	err := db.InTx(context.Background(), func(ctx context.Context) error {
+		err := db.InTx(WithoutTx(ctx), func(ctx context.Context) error {
+			_, err := db.ExecContext(ctx, "UPDATE b")
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		return failure
+	})
+	assert.ErrorIs(t, err, failure)
+	require.NoError(t, primary.mock.ExpectationsWereMet())
+}
+
+func TestDB_InTxRollsBackOnError(t *testing.T) {
+	db, primary, _ := setupReplicatedDB(t, 0)
+
+	primary.mock.ExpectBegin()
+	primary.mock.ExpectRollback()
+
+	failure := errors.New("failure")
+	err := db.InTx(context.Background(), func(ctx context.Context) error { return failure })
+	assert.ErrorIs(t, err, failure)
+	require.NoError(t, primary.mock.ExpectationsWereMet())
+}
+
+func TestDB_AfterCommitRunsOnceCommitted(t *testing.T) {
+	db, primary, _ := setupReplicatedDB(t, 0)
+
+	primary.mock.ExpectBegin()
+	primary.mock.ExpectCommit()
+	primary.mock.ExpectBegin()
+	primary.mock.ExpectRollback()
+
+	var ran []string
+	err := db.InTx(context.Background(), func(ctx context.Context) error {
+		AfterCommit(ctx, func() { ran = append(ran, "committed") })
+		// Nested calls defer to the outer commit
+		err := db.InTx(ctx, func(ctx context.Context) error {
+			AfterCommit(ctx, func() { ran = append(ran, "nested") })
+			return nil
+		})
+		assert.Empty(t, ran)
+		return err
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"committed", "nested"}, ran)
+
+	err = db.InTx(context.Background(), func(ctx context.Context) error {
+		AfterCommit(ctx, func() { ran = append(ran, "rolled back") })
+		return errors.New("failure")
+	})
+	require.Error(t, err)
+	assert.Equal(t, []string{"committed", "nested"}, ran)
+
+	// Outside a transaction there is nothing to wait for
+	AfterCommit(context.Background(), func() { ran = append(ran, "immediate") })
+	assert.Equal(t, []string{"committed", "nested", "immediate"}, ran)
+
+	require.NoError(t, primary.mock.ExpectationsWereMet())
+}
+
+func TestDB_HealthCheckRestoresReplica(t *testing.T) {
+	db, _, replicas := setupReplicatedDB(t, 1)
+	db.replicas[0].healthy.Store(false)
+
+	replicas[0].mock.ExpectPing()
+	db.checkReplicas(time.Second)
+
+	assert.True(t, db.replicas[0].healthy.Load())
+}
@@ -0,0 +1,42 @@
+package database
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// LatestMigrationVersion returns the number prefix of the newest migration
+// shipped with the binary, e.g. 2 for 002_add_image_path_to_specialties.sql.
+func LatestMigrationVersion() (int, error) {
+	entries, err := fs.Glob(migrationFiles, "migrations/*.sql")
+	if err != nil {
+		return 0, err
+	}
+
+	latest := -1
+	for _, entry := range entries {
+		name := path.Base(entry)
+		prefix, _, ok := strings.Cut(name, "_")
+		if !ok {
+			return 0, fmt.Errorf("migration %s has no version prefix", name)
+		}
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return 0, fmt.Errorf("migration %s has an invalid version prefix: %w", name, err)
+		}
+		latest = max(latest, version)
+	}
+
+	if latest < 0 {
+		return 0, errors.New("no migrations found")
+	}
+	return latest, nil
+}
@@ -0,0 +1,4 @@
+-- Images are uploaded through internal/storage; rows keep the storage key
+-- and URLs are derived from it. Existing avatar values are full URLs, which
+-- the application still renders as is.
+ALTER TABLE doctors RENAME COLUMN avatar_url TO avatar_key;
@@ -0,0 +1,32 @@
+-- Booked visits. patient_id is the booking user, when signed in; there is
+-- no users table yet, so it is not a foreign key.
+CREATE TABLE IF NOT EXISTS appointments (
+    id UUID DEFAULT uuidv7() PRIMARY KEY,
+    doctor_id UUID NOT NULL,
+    patient_id UUID,
+    patient_name VARCHAR(100) NOT NULL,
+    patient_phone VARCHAR(20) NOT NULL,
+    patient_email VARCHAR(255) NOT NULL DEFAULT '',
+    locale VARCHAR(8) NOT NULL DEFAULT 'fa',
+    starts_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    ends_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'booked',
+    cancelled_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_appointments_doctor_id FOREIGN KEY (doctor_id) REFERENCES doctors(id) ON DELETE RESTRICT ON UPDATE CASCADE,
+    CONSTRAINT chk_appointments_ends_after_start CHECK (ends_at > starts_at)
+);
+
+--
+-- A doctor's slot can be booked again once cancelled
+CREATE UNIQUE INDEX IF NOT EXISTS idx_appointments_doctor_slot ON appointments(doctor_id, starts_at) WHERE status <> 'cancelled';
+
+--
+CREATE INDEX IF NOT EXISTS idx_appointments_patient_id ON appointments(patient_id, starts_at);
+
+--
+CREATE TRIGGER update_appointments_updated_at
+    BEFORE UPDATE ON appointments
+    FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_column();
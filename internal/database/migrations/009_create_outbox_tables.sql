@@ -0,0 +1,28 @@
+-- Transactional outbox: events are inserted in the transaction of the
+-- change they describe and published by the relay afterwards.
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id UUID PRIMARY KEY,
+    type VARCHAR(100) NOT NULL,
+    aggregate_id UUID NOT NULL,
+    data JSONB NOT NULL DEFAULT '{}',
+    occurred_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    published_at TIMESTAMP WITH TIME ZONE
+);
+
+--
+CREATE INDEX IF NOT EXISTS idx_outbox_events_unpublished ON outbox_events(occurred_at) WHERE published_at IS NULL;
+
+--
+CREATE INDEX IF NOT EXISTS idx_outbox_events_published_at ON outbox_events(published_at) WHERE published_at IS NOT NULL;
+
+--
+-- Events each subscriber has handled, so redelivered events are skipped
+CREATE TABLE IF NOT EXISTS event_consumptions (
+    subscriber VARCHAR(100) NOT NULL,
+    event_id UUID NOT NULL REFERENCES outbox_events(id) ON DELETE CASCADE,
+    consumed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (subscriber, event_id)
+);
+
+--
+CREATE INDEX IF NOT EXISTS idx_event_consumptions_event_id ON event_consumptions(event_id);
@@ -0,0 +1,51 @@
+-- Deposits, in rials, doctors take before a booking is confirmed; doctors
+-- without a row take none. Bookings of doctors with a deposit wait in
+-- appointments.status 'pending_payment' until it is paid.
+CREATE TABLE IF NOT EXISTS doctor_deposits (
+    doctor_id UUID PRIMARY KEY,
+    amount BIGINT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_doctor_deposits_doctor_id FOREIGN KEY (doctor_id) REFERENCES doctors(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT chk_doctor_deposits_amount_positive CHECK (amount > 0)
+);
+
+--
+CREATE TRIGGER update_doctor_deposits_updated_at
+    BEFORE UPDATE ON doctor_deposits
+    FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_column();
+
+--
+-- Payments made through a gateway, one per checkout attempt
+CREATE TABLE IF NOT EXISTS payments (
+    id UUID PRIMARY KEY DEFAULT uuidv7(),
+    appointment_id UUID NOT NULL,
+    provider VARCHAR(32) NOT NULL,
+    amount BIGINT NOT NULL,
+    currency CHAR(3) NOT NULL DEFAULT 'IRR',
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    authority VARCHAR(255) NOT NULL DEFAULT '',
+    redirect_url TEXT NOT NULL DEFAULT '',
+    ref_id VARCHAR(64) NOT NULL DEFAULT '',
+    card_pan VARCHAR(32) NOT NULL DEFAULT '',
+    last_error TEXT NOT NULL DEFAULT '',
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    paid_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_payments_appointment_id FOREIGN KEY (appointment_id) REFERENCES appointments(id) ON DELETE RESTRICT ON UPDATE CASCADE,
+    CONSTRAINT chk_payments_amount_positive CHECK (amount > 0)
+);
+
+--
+CREATE INDEX IF NOT EXISTS idx_payments_appointment_id ON payments(appointment_id);
+
+--
+CREATE INDEX IF NOT EXISTS idx_payments_pending_expires_at ON payments(expires_at) WHERE status = 'pending';
+
+--
+CREATE TRIGGER update_payments_updated_at
+    BEFORE UPDATE ON payments
+    FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_column();
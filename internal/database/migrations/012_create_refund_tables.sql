@@ -0,0 +1,71 @@
+-- Cancellation policies of doctors; doctors without a row get a full
+-- refund 24 hours before the visit and half of it until the visit starts.
+CREATE TABLE IF NOT EXISTS cancellation_policies (
+    doctor_id UUID PRIMARY KEY,
+    full_refund_hours INTEGER NOT NULL,
+    late_fee_percent INTEGER NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_cancellation_policies_doctor_id FOREIGN KEY (doctor_id) REFERENCES doctors(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT chk_cancellation_policies_full_refund_hours CHECK (full_refund_hours >= 0),
+    CONSTRAINT chk_cancellation_policies_late_fee_percent CHECK (late_fee_percent BETWEEN 0 AND 100)
+);
+
+--
+CREATE TRIGGER update_cancellation_policies_updated_at
+    BEFORE UPDATE ON cancellation_policies
+    FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_column();
+
+--
+-- Refunds of deposits for cancelled appointments, sent to the provider in
+-- the background
+CREATE TABLE IF NOT EXISTS refunds (
+    id UUID PRIMARY KEY DEFAULT uuidv7(),
+    payment_id UUID NOT NULL,
+    appointment_id UUID NOT NULL,
+    amount BIGINT NOT NULL,
+    fee BIGINT NOT NULL DEFAULT 0,
+    currency CHAR(3) NOT NULL DEFAULT 'IRR',
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    ref_id VARCHAR(64) NOT NULL DEFAULT '',
+    last_error TEXT NOT NULL DEFAULT '',
+    refunded_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_refunds_payment_id FOREIGN KEY (payment_id) REFERENCES payments(id) ON DELETE RESTRICT ON UPDATE CASCADE,
+    CONSTRAINT fk_refunds_appointment_id FOREIGN KEY (appointment_id) REFERENCES appointments(id) ON DELETE RESTRICT ON UPDATE CASCADE,
+    CONSTRAINT chk_refunds_amount_positive CHECK (amount > 0),
+    CONSTRAINT chk_refunds_fee_not_negative CHECK (fee >= 0)
+);
+
+--
+CREATE INDEX IF NOT EXISTS idx_refunds_appointment_id ON refunds(appointment_id);
+
+--
+CREATE TRIGGER update_refunds_updated_at
+    BEFORE UPDATE ON refunds
+    FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_column();
+
+--
+-- Money paid and refunded per appointment; rows are never updated
+CREATE TABLE IF NOT EXISTS ledger_entries (
+    id UUID PRIMARY KEY DEFAULT uuidv7(),
+    appointment_id UUID NOT NULL,
+    payment_id UUID NOT NULL,
+    refund_id UUID,
+    kind VARCHAR(16) NOT NULL,
+    amount BIGINT NOT NULL,
+    currency CHAR(3) NOT NULL DEFAULT 'IRR',
+    reference VARCHAR(64) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_ledger_entries_appointment_id FOREIGN KEY (appointment_id) REFERENCES appointments(id) ON DELETE RESTRICT ON UPDATE CASCADE,
+    CONSTRAINT fk_ledger_entries_payment_id FOREIGN KEY (payment_id) REFERENCES payments(id) ON DELETE RESTRICT ON UPDATE CASCADE,
+    CONSTRAINT fk_ledger_entries_refund_id FOREIGN KEY (refund_id) REFERENCES refunds(id) ON DELETE RESTRICT ON UPDATE CASCADE,
+    CONSTRAINT chk_ledger_entries_kind CHECK (kind IN ('charge', 'refund')),
+    CONSTRAINT chk_ledger_entries_amount_positive CHECK (amount > 0)
+);
+
+--
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_appointment_id ON ledger_entries(appointment_id);
@@ -0,0 +1,29 @@
+-- Services doctors offer and their prices, in rials; prices are whole
+-- tomans so they can be shown in either currency
+CREATE TABLE IF NOT EXISTS doctor_services (
+    id UUID PRIMARY KEY DEFAULT uuidv7(),
+    doctor_id UUID NOT NULL,
+    kind VARCHAR(16) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    duration_minutes INTEGER NOT NULL,
+    price BIGINT NOT NULL,
+    currency CHAR(3) NOT NULL DEFAULT 'IRR',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_doctor_services_doctor_id FOREIGN KEY (doctor_id) REFERENCES doctors(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT uq_doctor_services_doctor_kind_name UNIQUE (doctor_id, kind, name),
+    CONSTRAINT chk_doctor_services_kind CHECK (kind IN ('in_person', 'online', 'follow_up', 'procedure')),
+    CONSTRAINT chk_doctor_services_duration CHECK (duration_minutes BETWEEN 5 AND 480),
+    CONSTRAINT chk_doctor_services_price CHECK (price >= 0 AND price % 10 = 0),
+    CONSTRAINT chk_doctor_services_currency CHECK (currency = 'IRR')
+);
+
+--
+-- Serves both the services of a doctor and the fee range filter
+CREATE INDEX IF NOT EXISTS idx_doctor_services_doctor_id_price ON doctor_services(doctor_id, price);
+
+--
+CREATE TRIGGER update_doctor_services_updated_at
+    BEFORE UPDATE ON doctor_services
+    FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_column();
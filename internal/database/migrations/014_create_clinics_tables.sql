@@ -0,0 +1,58 @@
+-- Clinics doctors practice at
+CREATE TABLE IF NOT EXISTS clinics (
+    id UUID PRIMARY KEY DEFAULT uuidv7(),
+    name VARCHAR(150) NOT NULL,
+    address TEXT NOT NULL DEFAULT '',
+    city VARCHAR(100) NOT NULL DEFAULT '',
+    phone_number VARCHAR(20) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+--
+CREATE TABLE IF NOT EXISTS doctor_clinics (
+    doctor_id UUID NOT NULL,
+    clinic_id UUID NOT NULL,
+    PRIMARY KEY (doctor_id, clinic_id),
+    CONSTRAINT fk_doctor_clinics_doctor_id FOREIGN KEY (doctor_id) REFERENCES doctors(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT fk_doctor_clinics_clinic_id FOREIGN KEY (clinic_id) REFERENCES clinics(id) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+--
+CREATE INDEX IF NOT EXISTS idx_doctor_clinics_clinic_id ON doctor_clinics(clinic_id);
+
+--
+-- Weekly working hours, as wall-clock times in time_zone; weekday 0 is
+-- Sunday. Visits are booked in slots of slot_minutes from opens_at.
+CREATE TABLE IF NOT EXISTS doctor_working_hours (
+    id UUID PRIMARY KEY DEFAULT uuidv7(),
+    doctor_id UUID NOT NULL,
+    clinic_id UUID,
+    weekday SMALLINT NOT NULL,
+    opens_at TIME NOT NULL,
+    closes_at TIME NOT NULL,
+    slot_minutes INTEGER NOT NULL DEFAULT 30,
+    time_zone VARCHAR(64) NOT NULL DEFAULT 'Asia/Tehran',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_doctor_working_hours_doctor_id FOREIGN KEY (doctor_id) REFERENCES doctors(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT fk_doctor_working_hours_clinic_id FOREIGN KEY (clinic_id) REFERENCES clinics(id) ON DELETE SET NULL ON UPDATE CASCADE,
+    CONSTRAINT chk_doctor_working_hours_weekday CHECK (weekday BETWEEN 0 AND 6),
+    CONSTRAINT chk_doctor_working_hours_closes_after_opens CHECK (closes_at > opens_at),
+    CONSTRAINT chk_doctor_working_hours_slot CHECK (slot_minutes BETWEEN 5 AND 480)
+);
+
+--
+CREATE INDEX IF NOT EXISTS idx_doctor_working_hours_doctor_id ON doctor_working_hours(doctor_id);
+
+--
+CREATE TRIGGER update_clinics_updated_at
+    BEFORE UPDATE ON clinics
+    FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_column();
+
+--
+CREATE TRIGGER update_doctor_working_hours_updated_at
+    BEFORE UPDATE ON doctor_working_hours
+    FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_column();
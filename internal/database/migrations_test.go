@@ -0,0 +1,14 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLatestMigrationVersion(t *testing.T) {
+	version, err := LatestMigrationVersion()
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, version, 2)
+}
@@ -0,0 +1,46 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestDB_StatementSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	primary := newMockDB(t)
+	db := newDB(primary.db, time.Second)
+
+	query := "SELECT count(*) FROM doctors WHERE name LIKE $1"
+	primary.mock.ExpectQuery(`SELECT count\(\*\) FROM doctors`).
+		WithArgs("%secret-name%").
+		WillReturnRows(countRows())
+
+	var count int
+	require.NoError(t, db.QueryRowContext(context.Background(), query, "%secret-name%").Scan(&count))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "SELECT", spans[0].Name())
+
+	attrs := map[string]string{}
+	for _, attr := range spans[0].Attributes() {
+		attrs[string(attr.Key)] = attr.Value.Emit()
+	}
+	assert.Equal(t, query, attrs["db.query.text"])
+	assert.Equal(t, "postgresql", attrs["db.system.name"])
+	assert.Equal(t, "primary", attrs["db.pool"])
+	for _, value := range attrs {
+		assert.NotContains(t, value, "secret-name")
+	}
+}
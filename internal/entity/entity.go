@@ -1,11 +1,42 @@
 package entity
 
-import "net/url"
+import (
+	"net/url"
+	"strings"
+)
 
 type ModelEntity interface {
 	GetPK() string
 }
 
+// PrivateDir is the storage directory of objects that are only served
+// through signed, expiring URLs, such as patient documents.
+const PrivateDir = "private"
+
 type Image interface {
 	GetPath() *url.URL
+	// IsPublic reports whether the image may be linked with a permanent URL;
+	// private images only get signed, expiring links.
+	IsPublic() bool
+}
+
+// IsPrivateKey reports whether the object stored at key is private.
+func IsPrivateKey(key string) bool {
+	return strings.HasPrefix(key, PrivateDir+"/")
+}
+
+// IsPublicPath is the IsPublic of images whose path comes from StoragePath.
+// External URLs are always public.
+func IsPublicPath(path *url.URL) bool {
+	return path.IsAbs() || !IsPrivateKey(path.Path)
+}
+
+// StoragePath turns a storage key into the path an image is served under.
+// The path is relative so it resolves below the image base URL, whatever
+// prefix that has. Absolute URLs stored before uploads existed are kept.
+func StoragePath(key string) url.URL {
+	if u, err := url.Parse(key); err == nil && u.IsAbs() {
+		return *u
+	}
+	return url.URL{Path: strings.TrimPrefix(key, "/")}
 }
@@ -0,0 +1,41 @@
+package medical
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shayesteh1hs/DrAppointment/internal/entity"
+)
+
+var _ entity.ModelEntity = (*Appointment)(nil)
+
+type AppointmentStatus string
+
+const (
+	// AppointmentPendingPayment appointments hold their slot until the
+	// doctor's deposit is paid
+	AppointmentPendingPayment AppointmentStatus = "pending_payment"
+	AppointmentBooked         AppointmentStatus = "booked"
+	AppointmentCancelled      AppointmentStatus = "cancelled"
+)
+
+type Appointment struct {
+	ID       uuid.UUID `json:"id" db:"id"`
+	DoctorID uuid.UUID `json:"doctor_id" db:"doctor_id"`
+	// PatientID is the booking user, uuid.Nil for guest bookings
+	PatientID    uuid.UUID         `json:"patient_id" db:"patient_id"`
+	PatientName  string            `json:"patient_name" db:"patient_name"`
+	PatientPhone string            `json:"patient_phone" db:"patient_phone"`
+	PatientEmail string            `json:"patient_email" db:"patient_email"`
+	Locale       string            `json:"locale" db:"locale"`
+	StartsAt     time.Time         `json:"starts_at" db:"starts_at"`
+	EndsAt       time.Time         `json:"ends_at" db:"ends_at"`
+	Status       AppointmentStatus `json:"status" db:"status"`
+	CancelledAt  *time.Time        `json:"cancelled_at" db:"cancelled_at"`
+	CreatedAt    time.Time         `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time         `json:"updated_at" db:"updated_at"`
+}
+
+func (a Appointment) GetPK() string {
+	return a.ID.String()
+}
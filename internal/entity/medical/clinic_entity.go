@@ -0,0 +1,31 @@
+package medical
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shayesteh1hs/DrAppointment/internal/entity"
+)
+
+var _ entity.ModelEntity = (*Clinic)(nil)
+
+// Clinic is a place doctors see patients at.
+type Clinic struct {
+	ID          uuid.UUID `json:"id" db:"id"`
+	Name        string    `json:"name" db:"name"`
+	Address     string    `json:"address" db:"address"`
+	City        string    `json:"city" db:"city"`
+	PhoneNumber string    `json:"phone_number" db:"phone_number"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+func (c Clinic) GetPK() string {
+	return c.ID.String()
+}
+
+// DoctorClinic is a clinic a doctor practices at.
+type DoctorClinic struct {
+	DoctorID uuid.UUID `json:"doctor_id" db:"doctor_id"`
+	Clinic
+}
@@ -1,6 +1,7 @@
 package medical
 
 import (
+	"net/url"
 	"time"
 
 	"github.com/google/uuid"
@@ -8,18 +9,40 @@ import (
 )
 
 var _ entity.ModelEntity = (*Doctor)(nil)
+var _ entity.Image = (*DoctorAvatar)(nil)
 
 type Doctor struct {
-	ID          uuid.UUID `json:"id" db:"id"`
-	Name        string    `json:"name" db:"name"`
-	SpecialtyID uuid.UUID `json:"specialty_id" db:"specialty_id"`
-	PhoneNumber string    `json:"phone_number" db:"phone_number"`
-	AvatarURL   string    `json:"avatar_url" db:"avatar_url"`
-	Description string    `json:"description" db:"description"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	ID          uuid.UUID     `json:"id" db:"id"`
+	Name        string        `json:"name" db:"name"`
+	SpecialtyID uuid.UUID     `json:"specialty_id" db:"specialty_id"`
+	PhoneNumber string        `json:"phone_number" db:"phone_number"`
+	Avatar      *DoctorAvatar `json:"avatar" db:"-"`
+	Description string        `json:"description" db:"description"`
+	CreatedAt   time.Time     `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at" db:"updated_at"`
 }
 
 func (d Doctor) GetPK() string {
 	return d.ID.String()
 }
+
+type DoctorAvatar struct {
+	Path url.URL `json:"path" db:"avatar_key"`
+}
+
+func (a *DoctorAvatar) GetPath() *url.URL {
+	return &a.Path
+}
+
+func (a *DoctorAvatar) IsPublic() bool {
+	return entity.IsPublicPath(&a.Path)
+}
+
+// NewDoctorAvatar builds the avatar from the storage key kept in
+// doctors.avatar_key; older rows may still hold a full URL.
+func NewDoctorAvatar(key string) *DoctorAvatar {
+	if key == "" {
+		return nil
+	}
+	return &DoctorAvatar{Path: entity.StoragePath(key)}
+}
@@ -0,0 +1,42 @@
+package medical
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shayesteh1hs/DrAppointment/internal/entity"
+	"github.com/shayesteh1hs/DrAppointment/internal/money"
+)
+
+var _ entity.ModelEntity = (*Offering)(nil)
+
+type OfferingKind string
+
+const (
+	OfferingInPerson  OfferingKind = "in_person"
+	OfferingOnline    OfferingKind = "online"
+	OfferingFollowUp  OfferingKind = "follow_up"
+	OfferingProcedure OfferingKind = "procedure"
+)
+
+// OfferingKinds lists every OfferingKind.
+var OfferingKinds = []OfferingKind{OfferingInPerson, OfferingOnline, OfferingFollowUp, OfferingProcedure}
+
+// Offering is a service a doctor provides, such as an in-person visit or
+// a procedure, and what it costs.
+type Offering struct {
+	ID       uuid.UUID    `json:"id" db:"id"`
+	DoctorID uuid.UUID    `json:"doctor_id" db:"doctor_id"`
+	Kind     OfferingKind `json:"kind" db:"kind"`
+	Name     string       `json:"name" db:"name"`
+	// DurationMinutes is how long the visit is booked for
+	DurationMinutes int `json:"duration_minutes" db:"duration_minutes"`
+	// Price is in rials
+	Price     money.Money `json:"price" db:"-"`
+	CreatedAt time.Time   `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at" db:"updated_at"`
+}
+
+func (o Offering) GetPK() string {
+	return o.ID.String()
+}
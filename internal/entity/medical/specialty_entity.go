@@ -2,7 +2,6 @@ package medical
 
 import (
 	"net/url"
-	"path/filepath"
 	"strings"
 	"time"
 
@@ -33,17 +32,21 @@ func (si *SpecialtyImage) GetPath() *url.URL {
 	return &si.Path
 }
 
-func NewSpecialtyImage(fileName string) *SpecialtyImage {
-	if fileName == "" {
+func (si *SpecialtyImage) IsPublic() bool {
+	return entity.IsPublicPath(&si.Path)
+}
+
+// NewSpecialtyImage builds the image from the storage key kept in
+// specialties.image_path. Bare file names predate uploads and live under
+// "specialties/".
+func NewSpecialtyImage(key string) *SpecialtyImage {
+	if key == "" {
 		return nil
 	}
 
-	path := "specialties/" + filepath.Base(fileName)
-	if !strings.HasPrefix(path, "/") {
-		path = "/" + path
+	if !strings.Contains(key, "/") {
+		key = "specialties/" + key
 	}
 
-	return &SpecialtyImage{Path: url.URL{
-		Path: path,
-	}}
+	return &SpecialtyImage{Path: entity.StoragePath(key)}
 }
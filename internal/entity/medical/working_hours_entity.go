@@ -0,0 +1,42 @@
+package medical
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shayesteh1hs/DrAppointment/internal/entity"
+)
+
+var _ entity.ModelEntity = (*WorkingHours)(nil)
+
+// WorkingHours is a weekly period a doctor takes visits in, booked in slots
+// of SlotMinutes from Opens.
+type WorkingHours struct {
+	ID       uuid.UUID `json:"id" db:"id"`
+	DoctorID uuid.UUID `json:"doctor_id" db:"doctor_id"`
+	// ClinicID is where the visits take place, uuid.Nil when not tied to a
+	// clinic (e.g. online visits)
+	ClinicID uuid.UUID    `json:"clinic_id" db:"clinic_id"`
+	Weekday  time.Weekday `json:"weekday" db:"weekday"`
+	// Opens and Closes are wall-clock times in TimeZone, as offsets from
+	// midnight
+	Opens       time.Duration `json:"opens" db:"opens_at"`
+	Closes      time.Duration `json:"closes" db:"closes_at"`
+	SlotMinutes int           `json:"slot_minutes" db:"slot_minutes"`
+	TimeZone    string        `json:"time_zone" db:"time_zone"`
+	CreatedAt   time.Time     `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at" db:"updated_at"`
+}
+
+func (w WorkingHours) GetPK() string {
+	return w.ID.String()
+}
+
+// Slot is a bookable visit time of a doctor.
+type Slot struct {
+	DoctorID uuid.UUID
+	// ClinicID is uuid.Nil when the working hours name no clinic
+	ClinicID uuid.UUID
+	StartsAt time.Time
+	EndsAt   time.Time
+}
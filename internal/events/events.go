@@ -0,0 +1,80 @@
+// Package events records domain events in a transactional outbox and relays
+// them to in-process subscribers.
+//
+// Services add events to the Outbox in the same transaction as the change
+// they describe, so an event exists exactly when its change was committed.
+// The Relay later hands each event to every subscriber through the job
+// queue, at least once and in no particular order; Consume lets subscribers
+// skip events they have already handled.
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event types
+const (
+	AppointmentBooked    = "appointment.booked"
+	AppointmentCancelled = "appointment.cancelled"
+	DoctorUpdated        = "doctor.updated"
+)
+
+// Types lists every event type.
+var Types = []string{AppointmentBooked, AppointmentCancelled, DoctorUpdated}
+
+// Event is a change to one aggregate, such as an appointment or a doctor.
+type Event struct {
+	ID          uuid.UUID       `json:"id"`
+	Type        string          `json:"type"`
+	AggregateID uuid.UUID       `json:"aggregate_id"`
+	Data        json.RawMessage `json:"data"`
+	OccurredAt  time.Time       `json:"occurred_at"`
+}
+
+// New builds an event of eventType about aggregateID carrying data, one of
+// the payload types below.
+func New(eventType string, aggregateID uuid.UUID, data any) (Event, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return Event{}, fmt.Errorf("failed to encode %s event: %w", eventType, err)
+	}
+	id, err := uuid.NewV7()
+	if err != nil {
+		return Event{}, err
+	}
+	return Event{
+		ID:          id,
+		Type:        eventType,
+		AggregateID: aggregateID,
+		Data:        encoded,
+		OccurredAt:  time.Now().UTC(),
+	}, nil
+}
+
+// Decode unmarshals the event's data into v.
+func (e Event) Decode(v any) error {
+	if err := json.Unmarshal(e.Data, v); err != nil {
+		return fmt.Errorf("invalid %s event data: %w", e.Type, err)
+	}
+	return nil
+}
+
+// Appointment is the data of AppointmentBooked and AppointmentCancelled.
+type Appointment struct {
+	AppointmentID uuid.UUID `json:"appointment_id"`
+	DoctorID      uuid.UUID `json:"doctor_id"`
+	StartsAt      time.Time `json:"starts_at"`
+	EndsAt        time.Time `json:"ends_at"`
+	Status        string    `json:"status"`
+}
+
+// Doctor is the data of DoctorUpdated.
+type Doctor struct {
+	DoctorID uuid.UUID `json:"doctor_id"`
+	// Fields names the changed profile fields, e.g. "avatar"
+	Fields []string `json:"fields"`
+}
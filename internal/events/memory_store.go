@@ -0,0 +1,148 @@
+package events
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type memoryEvent struct {
+	Event
+	publishedAt time.Time
+}
+
+type consumption struct {
+	subscriber string
+	eventID    uuid.UUID
+}
+
+// MemoryStore keeps the outbox in process memory, for tests and single
+// instance deployments. Events are lost on restart, and adding them does
+// not take part in database transactions.
+type MemoryStore struct {
+	mu        sync.Mutex
+	publishMu sync.Mutex
+	events    []*memoryEvent
+	consumed  map[consumption]struct{}
+	consuming map[consumption]*sync.Mutex
+	now       func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		consumed:  make(map[consumption]struct{}),
+		consuming: make(map[consumption]*sync.Mutex),
+		now:       time.Now,
+	}
+}
+
+func (s *MemoryStore) Add(ctx context.Context, events ...Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, event := range events {
+		s.events = append(s.events, &memoryEvent{Event: event})
+	}
+	return nil
+}
+
+func (s *MemoryStore) Publish(ctx context.Context, limit int, fn func(ctx context.Context, events []Event) error) (int, error) {
+	s.publishMu.Lock()
+	defer s.publishMu.Unlock()
+
+	s.mu.Lock()
+	var pending []*memoryEvent
+	for _, event := range s.events {
+		if event.publishedAt.IsZero() && len(pending) < limit {
+			pending = append(pending, event)
+		}
+	}
+	s.mu.Unlock()
+
+	if len(pending) == 0 {
+		return 0, nil
+	}
+	batch := make([]Event, len(pending))
+	for i, event := range pending {
+		batch[i] = event.Event
+	}
+	if err := fn(ctx, batch); err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	for _, event := range pending {
+		event.publishedAt = now
+	}
+	return len(pending), nil
+}
+
+func (s *MemoryStore) Consume(ctx context.Context, subscriber string, eventID uuid.UUID, fn func(ctx context.Context) error) error {
+	key := consumption{subscriber: subscriber, eventID: eventID}
+
+	// Serialize consumers of the same event, as the row lock does in
+	// PostgresStore
+	s.mu.Lock()
+	lock, ok := s.consuming[key]
+	if !ok {
+		lock = &sync.Mutex{}
+		s.consuming[key] = lock
+	}
+	s.mu.Unlock()
+	lock.Lock()
+	defer lock.Unlock()
+
+	s.mu.Lock()
+	_, done := s.consumed[key]
+	s.mu.Unlock()
+	if done {
+		return nil
+	}
+
+	if err := fn(ctx); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.consumed[key] = struct{}{}
+	delete(s.consuming, key)
+	return nil
+}
+
+func (s *MemoryStore) DeletePublished(ctx context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deleted int64
+	s.events = slices.DeleteFunc(s.events, func(event *memoryEvent) bool {
+		if event.publishedAt.IsZero() || !event.publishedAt.Before(before) {
+			return false
+		}
+		for key := range s.consumed {
+			if key.eventID == event.ID {
+				delete(s.consumed, key)
+			}
+		}
+		deleted++
+		return true
+	})
+	return deleted, nil
+}
+
+// Events returns every event still in the store, for inspection in tests.
+func (s *MemoryStore) Events() []Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	events := make([]Event, len(s.events))
+	for i, event := range s.events {
+		events[i] = event.Event
+	}
+	return events
+}
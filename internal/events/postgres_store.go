@@ -0,0 +1,126 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/database"
+)
+
+const (
+	insertEventQuery = "INSERT INTO outbox_events (id, type, aggregate_id, data, occurred_at) VALUES ($1, $2, $3, $4, $5)"
+
+	// selectUnpublishedQuery locks the batch until the publishing
+	// transaction ends; rows locked by another relay are skipped.
+	selectUnpublishedQuery = `SELECT id, type, aggregate_id, data, occurred_at FROM outbox_events
+WHERE published_at IS NULL
+ORDER BY occurred_at
+LIMIT $1
+FOR UPDATE SKIP LOCKED`
+
+	markPublishedQuery = "UPDATE outbox_events SET published_at = now() WHERE id = ANY($1)"
+
+	// insertConsumptionQuery waits for a concurrent consumer of the same
+	// event to finish and inserts nothing if it committed.
+	insertConsumptionQuery = `INSERT INTO event_consumptions (subscriber, event_id) VALUES ($1, $2)
+ON CONFLICT (subscriber, event_id) DO NOTHING`
+
+	// Consumptions are deleted with their event
+	deletePublishedQuery = "DELETE FROM outbox_events WHERE published_at < $1"
+)
+
+// PostgresStore keeps the outbox in the outbox_events table, next to the
+// data the events describe.
+type PostgresStore struct {
+	db *database.DB
+}
+
+func NewPostgresStore(db *database.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+func (s *PostgresStore) Add(ctx context.Context, events ...Event) error {
+	for _, event := range events {
+		_, err := s.db.ExecContext(ctx, insertEventQuery, event.ID, event.Type, event.AggregateID, []byte(event.Data), event.OccurredAt)
+		if err != nil {
+			return fmt.Errorf("failed to add %s event to the outbox: %w", event.Type, err)
+		}
+	}
+	return nil
+}
+
+func (s *PostgresStore) Publish(ctx context.Context, limit int, fn func(ctx context.Context, events []Event) error) (published int, err error) {
+	err = s.db.InTx(ctx, func(ctx context.Context) error {
+		events, err := s.unpublished(ctx, limit)
+		if err != nil || len(events) == 0 {
+			return err
+		}
+		if err := fn(ctx, events); err != nil {
+			return err
+		}
+
+		ids := make([]uuid.UUID, len(events))
+		for i, event := range events {
+			ids[i] = event.ID
+		}
+		if _, err := s.db.ExecContext(ctx, markPublishedQuery, pq.Array(ids)); err != nil {
+			return fmt.Errorf("failed to mark events published: %w", err)
+		}
+		published = len(events)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return published, nil
+}
+
+func (s *PostgresStore) unpublished(ctx context.Context, limit int) ([]Event, error) {
+	rows, err := s.db.QueryContext(ctx, selectUnpublishedQuery, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the outbox: %w", err)
+	}
+	defer rows.Close()
+
+	var events []Event
+	for rows.Next() {
+		var (
+			event Event
+			data  []byte
+		)
+		if err := rows.Scan(&event.ID, &event.Type, &event.AggregateID, &data, &event.OccurredAt); err != nil {
+			return nil, fmt.Errorf("failed to scan event: %w", err)
+		}
+		event.Data = data
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read the outbox: %w", err)
+	}
+	return events, nil
+}
+
+func (s *PostgresStore) Consume(ctx context.Context, subscriber string, eventID uuid.UUID, fn func(ctx context.Context) error) error {
+	return s.db.InTx(ctx, func(ctx context.Context) error {
+		result, err := s.db.ExecContext(ctx, insertConsumptionQuery, subscriber, eventID)
+		if err != nil {
+			return fmt.Errorf("failed to record consumption of event %s: %w", eventID, err)
+		}
+		if n, err := result.RowsAffected(); err != nil || n == 0 {
+			return err
+		}
+		return fn(ctx)
+	})
+}
+
+func (s *PostgresStore) DeletePublished(ctx context.Context, before time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx, deletePublishedQuery, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete published events: %w", err)
+	}
+	return result.RowsAffected()
+}
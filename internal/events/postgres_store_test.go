@@ -0,0 +1,74 @@
+package events
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/database"
+)
+
+func TestPostgresStore_Publish(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db := database.New(sqlDB)
+	defer db.Close()
+
+	id, aggregateID := uuid.New(), uuid.New()
+	occurredAt := time.Date(2026, time.October, 18, 9, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(selectUnpublishedQuery)).
+		WithArgs(10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "type", "aggregate_id", "data", "occurred_at"}).
+			AddRow(id, AppointmentBooked, aggregateID, []byte(`{"status":"booked"}`), occurredAt))
+	mock.ExpectExec(regexp.QuoteMeta(markPublishedQuery)).
+		WithArgs(pq.Array([]uuid.UUID{id})).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	var seen []Event
+	published, err := NewPostgresStore(db).Publish(context.Background(), 10, func(ctx context.Context, events []Event) error {
+		_, inTx := database.TxFromContext(ctx)
+		assert.True(t, inTx, "events are handed over inside the publishing transaction")
+		seen = events
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, published)
+	require.Len(t, seen, 1)
+	assert.Equal(t, Event{ID: id, Type: AppointmentBooked, AggregateID: aggregateID, Data: []byte(`{"status":"booked"}`), OccurredAt: occurredAt}, seen[0])
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStore_ConsumeSkipsConsumedEvents(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db := database.New(sqlDB)
+	defer db.Close()
+
+	eventID := uuid.New()
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(insertConsumptionQuery)).
+		WithArgs("analytics", eventID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	called := false
+	err = NewPostgresStore(db).Consume(context.Background(), "analytics", eventID, func(ctx context.Context) error {
+		called = true
+		return nil
+	})
+	require.NoError(t, err)
+	assert.False(t, called)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
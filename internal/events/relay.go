@@ -0,0 +1,167 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/jobs"
+	"github.com/shayesteh1hs/DrAppointment/internal/logging"
+	"github.com/shayesteh1hs/DrAppointment/internal/tracing"
+)
+
+const (
+	// DeliverJob is the job kind handing one event to one subscriber.
+	DeliverJob = "events.deliver"
+	cleanupJob = "events.cleanup"
+)
+
+// Handler consumes an event. Returning an error retries the delivery with
+// backoff unless it is marked with jobs.Permanent.
+type Handler func(ctx context.Context, event Event) error
+
+// Options tune how the Relay reads the outbox.
+type Options struct {
+	// PollInterval is how often the outbox is checked for new events
+	PollInterval time.Duration
+	// BatchSize bounds the events published per transaction
+	BatchSize int
+	// Retention is how long published events are kept
+	Retention time.Duration
+}
+
+func DefaultOptions() Options {
+	return Options{
+		PollInterval: time.Second,
+		BatchSize:    100,
+		Retention:    7 * 24 * time.Hour,
+	}
+}
+
+type subscriber struct {
+	types   []string
+	handler Handler
+}
+
+// Relay publishes outbox events to subscribers. Publishing queues one
+// delivery job per event and subscriber in the transaction that marks the
+// event published, so no event is lost or queued twice; the job runner then
+// retries failed deliveries.
+type Relay struct {
+	store       Store
+	queue       jobs.Enqueuer
+	opts        Options
+	subscribers map[string]subscriber
+	now         func() time.Time
+}
+
+func NewRelay(store Store, queue jobs.Enqueuer, opts Options) *Relay {
+	return &Relay{store: store, queue: queue, opts: opts, subscribers: make(map[string]subscriber), now: time.Now}
+}
+
+// Subscribe has h consume events of the given types, or of every type when
+// none are given. name identifies the subscriber in queued deliveries and
+// recorded consumptions, so it must not change between releases.
+// Subscribers must be registered before Run.
+func (r *Relay) Subscribe(name string, h Handler, types ...string) {
+	r.subscribers[name] = subscriber{types: types, handler: h}
+}
+
+// Register adds the jobs delivering events and deleting old ones to runner.
+func (r *Relay) Register(runner *jobs.Runner) {
+	runner.Register(DeliverJob, r.deliver)
+	runner.Every(cleanupJob, time.Hour, r.cleanup)
+}
+
+// Run publishes new events every poll interval until ctx is canceled.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := r.PublishPending(ctx); err != nil && ctx.Err() == nil {
+			logging.FromContext(ctx).Error("failed to publish events", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PublishPending publishes every unpublished event and returns how many it
+// published.
+func (r *Relay) PublishPending(ctx context.Context) (published int, err error) {
+	ctx, span := tracing.Start(ctx, "Relay.PublishPending")
+	defer func() { tracing.End(span, err) }()
+
+	for {
+		n, err := r.store.Publish(ctx, r.opts.BatchSize, r.queueDeliveries)
+		published += n
+		if err != nil {
+			return published, err
+		}
+		if n < r.opts.BatchSize {
+			span.SetAttributes(attribute.Int("events.published", published))
+			return published, nil
+		}
+	}
+}
+
+type delivery struct {
+	Subscriber string `json:"subscriber"`
+	Event      Event  `json:"event"`
+}
+
+func (r *Relay) queueDeliveries(ctx context.Context, events []Event) error {
+	names := slices.Sorted(maps.Keys(r.subscribers))
+	for _, event := range events {
+		for _, name := range names {
+			sub := r.subscribers[name]
+			if len(sub.types) > 0 && !slices.Contains(sub.types, event.Type) {
+				continue
+			}
+			job, err := jobs.New(DeliverJob, delivery{Subscriber: name, Event: event})
+			if err != nil {
+				return err
+			}
+			job.UniqueKey = DeliverJob + ":" + event.ID.String() + ":" + name
+			if _, err := r.queue.Enqueue(ctx, job); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *Relay) deliver(ctx context.Context, job jobs.Job) error {
+	var d delivery
+	if err := job.Decode(&d); err != nil {
+		return err
+	}
+	sub, ok := r.subscribers[d.Subscriber]
+	if !ok {
+		return jobs.Permanent(fmt.Errorf("unknown event subscriber %q", d.Subscriber))
+	}
+
+	return r.store.Consume(ctx, d.Subscriber, d.Event.ID, func(ctx context.Context) error {
+		return sub.handler(ctx, d.Event)
+	})
+}
+
+func (r *Relay) cleanup(ctx context.Context, _ jobs.Job) error {
+	deleted, err := r.store.DeletePublished(ctx, r.now().Add(-r.opts.Retention))
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		logging.FromContext(ctx).Info("deleted published events", "count", deleted)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/jobs"
+)
+
+type recorder struct {
+	received []Event
+	err      error
+}
+
+func (r *recorder) handle(ctx context.Context, event Event) error {
+	if r.err != nil {
+		return r.err
+	}
+	r.received = append(r.received, event)
+	return nil
+}
+
+func newTestRelay() (*Relay, *MemoryStore, *jobs.MemoryStore) {
+	outbox, queue := NewMemoryStore(), jobs.NewMemoryStore()
+	return NewRelay(outbox, queue, DefaultOptions()), outbox, queue
+}
+
+func addEvent(t *testing.T, outbox Outbox, eventType string) Event {
+	t.Helper()
+	id := uuid.New()
+	event, err := New(eventType, id, Appointment{AppointmentID: id})
+	require.NoError(t, err)
+	require.NoError(t, outbox.Add(context.Background(), event))
+	return event
+}
+
+func TestRelay_PublishPendingQueuesDeliveriesPerSubscriber(t *testing.T) {
+	relay, outbox, queue := newTestRelay()
+	relay.Subscribe("analytics", (&recorder{}).handle)
+	relay.Subscribe("notifications", (&recorder{}).handle, AppointmentBooked)
+
+	booked := addEvent(t, outbox, AppointmentBooked)
+	addEvent(t, outbox, AppointmentCancelled)
+
+	published, err := relay.PublishPending(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, published)
+
+	var keys []string
+	for _, job := range queue.Jobs() {
+		keys = append(keys, job.UniqueKey)
+	}
+	assert.Len(t, keys, 3)
+	assert.Contains(t, keys, DeliverJob+":"+booked.ID.String()+":notifications")
+
+	// Published events are not published again
+	published, err = relay.PublishPending(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, published)
+	assert.Len(t, queue.Jobs(), 3)
+}
+
+func TestRelay_DeliverSkipsConsumedEvents(t *testing.T) {
+	relay, outbox, queue := newTestRelay()
+	consumer := &recorder{err: errors.New("analytics is down")}
+	relay.Subscribe("analytics", consumer.handle)
+
+	event := addEvent(t, outbox, DoctorUpdated)
+	_, err := relay.PublishPending(context.Background())
+	require.NoError(t, err)
+	claimed, err := queue.Claim(context.Background(), []string{DeliverJob}, 1, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+
+	// A failed delivery is not recorded as consumed
+	assert.Error(t, relay.deliver(context.Background(), claimed[0]))
+
+	consumer.err = nil
+	require.NoError(t, relay.deliver(context.Background(), claimed[0]))
+	require.NoError(t, relay.deliver(context.Background(), claimed[0]))
+	require.Len(t, consumer.received, 1)
+	assert.Equal(t, event.ID, consumer.received[0].ID)
+	assert.Equal(t, DoctorUpdated, consumer.received[0].Type)
+}
+
+func TestRelay_DeliverToUnknownSubscriberIsPermanent(t *testing.T) {
+	relay, _, _ := newTestRelay()
+
+	job, err := jobs.New(DeliverJob, delivery{Subscriber: "gone", Event: Event{ID: uuid.New()}})
+	require.NoError(t, err)
+
+	assert.True(t, jobs.IsPermanent(relay.deliver(context.Background(), job)))
+}
+
+func TestRelay_CleanupDeletesPublishedEvents(t *testing.T) {
+	relay, outbox, _ := newTestRelay()
+	addEvent(t, outbox, AppointmentBooked)
+	_, err := relay.PublishPending(context.Background())
+	require.NoError(t, err)
+	pending := addEvent(t, outbox, AppointmentBooked)
+
+	relay.now = func() time.Time { return time.Now().Add(8 * 24 * time.Hour) }
+	require.NoError(t, relay.cleanup(context.Background(), jobs.Job{}))
+
+	remaining := outbox.Events()
+	require.Len(t, remaining, 1)
+	assert.Equal(t, pending.ID, remaining[0].ID)
+}
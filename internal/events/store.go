@@ -0,0 +1,30 @@
+package events
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Outbox records events. Adding inside a transaction (see
+// database.Transactor) commits or rolls back the events with it.
+type Outbox interface {
+	Add(ctx context.Context, events ...Event) error
+}
+
+// Store is the outbox as seen by the Relay.
+type Store interface {
+	Outbox
+	// Publish passes up to limit unpublished events, oldest first, to fn and
+	// marks them published when it returns nil. Concurrent calls are given
+	// different events.
+	Publish(ctx context.Context, limit int, fn func(ctx context.Context, events []Event) error) (int, error)
+	// Consume runs fn unless subscriber already consumed the event. The
+	// consumption is recorded together with fn's database writes, and not
+	// at all when fn fails.
+	Consume(ctx context.Context, subscriber string, eventID uuid.UUID, fn func(ctx context.Context) error) error
+	// DeletePublished removes events published before the given time and
+	// the consumptions recorded for them.
+	DeletePublished(ctx context.Context, before time.Time) (int64, error)
+}
@@ -1,20 +1,84 @@
 package medical
 
 import (
+	"errors"
+	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/google/uuid"
 	"github.com/huandu/go-sqlbuilder"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/entity/medical"
+	"github.com/shayesteh1hs/DrAppointment/internal/money"
 )
 
 type DoctorQueryParam struct {
-	Name        string    `form:"name"`
-	SpecialtyID uuid.UUID `form:"specialty_id"`
+	Name        string    `form:"name" doc:"Part of the doctor's name"`
+	SpecialtyID uuid.UUID `form:"specialty_id" doc:"Only doctors of this specialty"`
+	// The service filters match doctors offering at least one service
+	// meeting all of them
+	Service     medical.OfferingKind `form:"service" doc:"Only doctors offering this kind of service: in_person, online, follow_up or procedure"`
+	MinFee      *int64               `form:"min_fee" doc:"Only doctors with a service costing at least this much, in fee_currency"`
+	MaxFee      *int64               `form:"max_fee" doc:"Only doctors with a service costing at most this much, in fee_currency"`
+	FeeCurrency string               `form:"fee_currency" doc:"Currency of min_fee and max_fee: IRR (default) or IRT (toman)"`
 }
 
 func (f DoctorQueryParam) Validate() error {
-	return nil
+	var errs error
+	if f.Service != "" && !slices.Contains(medical.OfferingKinds, f.Service) {
+		errs = errors.Join(errs, fmt.Errorf("unknown service %q", f.Service))
+	}
+	if f.MinFee != nil && *f.MinFee < 0 {
+		errs = errors.Join(errs, errors.New("min_fee must not be negative"))
+	}
+	if f.MaxFee != nil && *f.MaxFee < 0 {
+		errs = errors.Join(errs, errors.New("max_fee must not be negative"))
+	}
+	if f.MinFee != nil && f.MaxFee != nil && *f.MinFee > *f.MaxFee {
+		errs = errors.Join(errs, errors.New("min_fee must not be greater than max_fee"))
+	}
+	if f.FeeCurrency != "" {
+		if _, err := money.ParseCurrency(f.FeeCurrency); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("invalid fee_currency: %w", err))
+		}
+	}
+	if errs != nil {
+		return errs
+	}
+	// Fees must fit in rials
+	_, _, err := f.FeeRange()
+	return err
+}
+
+// FeeRange returns the bounds of min_fee and max_fee in rials, nil where
+// unset.
+func (f DoctorQueryParam) FeeRange() (min, max *int64, err error) {
+	currency := money.IRR
+	if f.FeeCurrency != "" {
+		if currency, err = money.ParseCurrency(f.FeeCurrency); err != nil {
+			return nil, nil, err
+		}
+	}
+	toRials := func(amount *int64) (*int64, error) {
+		if amount == nil {
+			return nil, nil
+		}
+		m, err := money.New(*amount, currency).In(money.IRR)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fee: %w", err)
+		}
+		return &m.Amount, nil
+	}
+	if min, err = toRials(f.MinFee); err != nil {
+		return nil, nil, err
+	}
+	if max, err = toRials(f.MaxFee); err != nil {
+		return nil, nil, err
+	}
+	return min, max, nil
 }
+
 func (f DoctorQueryParam) Apply(sb *sqlbuilder.SelectBuilder) *sqlbuilder.SelectBuilder {
 	trimmedName := strings.TrimSpace(f.Name)
 	if trimmedName != "" {
@@ -23,5 +87,36 @@ func (f DoctorQueryParam) Apply(sb *sqlbuilder.SelectBuilder) *sqlbuilder.Select
 	if f.SpecialtyID != uuid.Nil {
 		sb.Where(sb.Equal("specialty_id", f.SpecialtyID.String()))
 	}
+
+	// Invalid fees were rejected by Validate
+	minFee, maxFee, _ := f.FeeRange()
+	if f.Service == "" && minFee == nil && maxFee == nil {
+		return sb
+	}
+	conditions := []string{"doctor_services.doctor_id = doctors.id"}
+	if f.Service != "" {
+		conditions = append(conditions, "doctor_services.kind = "+sb.Var(string(f.Service)))
+	}
+	if minFee != nil {
+		conditions = append(conditions, "doctor_services.price >= "+sb.Var(*minFee))
+	}
+	if maxFee != nil {
+		conditions = append(conditions, "doctor_services.price <= "+sb.Var(*maxFee))
+	}
+	sb.Where("EXISTS (SELECT 1 FROM doctor_services WHERE " + strings.Join(conditions, " AND ") + ")")
 	return sb
 }
+
+// MatchesOfferings reports whether a doctor offering offerings passes the
+// service filters, for repositories that can't run SQL.
+func (f DoctorQueryParam) MatchesOfferings(offerings []medical.Offering) bool {
+	minFee, maxFee, _ := f.FeeRange()
+	if f.Service == "" && minFee == nil && maxFee == nil {
+		return true
+	}
+	return slices.ContainsFunc(offerings, func(o medical.Offering) bool {
+		return (f.Service == "" || o.Kind == f.Service) &&
+			(minFee == nil || o.Price.Amount >= *minFee) &&
+			(maxFee == nil || o.Price.Amount <= *maxFee)
+	})
+}
@@ -1,6 +1,7 @@
 package medical
 
 import (
+	"math"
 	"strings"
 	"testing"
 
@@ -8,6 +9,9 @@ import (
 	"github.com/huandu/go-sqlbuilder"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/entity/medical"
+	"github.com/shayesteh1hs/DrAppointment/internal/money"
 )
 
 // Helper functions
@@ -21,7 +25,7 @@ func newTestFilter(name string, specialtyID uuid.UUID) DoctorQueryParam {
 
 func newTestSelectBuilder() *sqlbuilder.SelectBuilder {
 	sb := sqlbuilder.PostgreSQL.NewSelectBuilder()
-	sb.Select("id", "name", "specialty_id", "phone_number", "avatar_url", "description", "created_at", "updated_at").
+	sb.Select("id", "name", "specialty_id", "phone_number", "avatar_key", "description", "created_at", "updated_at").
 		From("doctors")
 	return sb
 }
@@ -172,3 +176,58 @@ func TestDoctorQueryParam_Apply(t *testing.T) {
 		})
 	}
 }
+
+func TestDoctorQueryParam_Apply_Services(t *testing.T) {
+	minFee, maxFee := int64(150000), int64(300000)
+	f := DoctorQueryParam{Service: medical.OfferingOnline, MinFee: &minFee, MaxFee: &maxFee, FeeCurrency: "IRT"}
+	require.NoError(t, f.Validate())
+
+	sql, args := f.Apply(newTestSelectBuilder()).Build()
+
+	assertSQLContains(t, sql, "EXISTS (SELECT 1 FROM doctor_services WHERE doctor_services.doctor_id = doctors.id")
+	assertSQLContains(t, sql, "doctor_services.kind = $1 AND doctor_services.price >= $2 AND doctor_services.price <= $3")
+	// Toman bounds are compared in rials
+	assert.Equal(t, []interface{}{"online", int64(1500000), int64(3000000)}, args)
+}
+
+func TestDoctorQueryParam_Validate_Services(t *testing.T) {
+	fee := func(v int64) *int64 { return &v }
+	tests := []struct {
+		name    string
+		filter  DoctorQueryParam
+		wantErr string
+	}{
+		{name: "no filters", filter: DoctorQueryParam{}},
+		{name: "rial range", filter: DoctorQueryParam{Service: medical.OfferingProcedure, MinFee: fee(0), MaxFee: fee(10)}},
+		{name: "unknown service", filter: DoctorQueryParam{Service: "massage"}, wantErr: `unknown service "massage"`},
+		{name: "negative fee", filter: DoctorQueryParam{MinFee: fee(-1)}, wantErr: "min_fee must not be negative"},
+		{name: "inverted range", filter: DoctorQueryParam{MinFee: fee(20), MaxFee: fee(10)}, wantErr: "min_fee must not be greater than max_fee"},
+		{name: "unknown currency", filter: DoctorQueryParam{MaxFee: fee(10), FeeCurrency: "USD"}, wantErr: "invalid fee_currency"},
+		{name: "overflow", filter: DoctorQueryParam{MaxFee: fee(math.MaxInt64), FeeCurrency: "IRT"}, wantErr: "invalid fee"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.filter.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestDoctorQueryParam_MatchesOfferings(t *testing.T) {
+	fee := func(v int64) *int64 { return &v }
+	offerings := []medical.Offering{
+		{Kind: medical.OfferingInPerson, Price: money.Rials(2000000)},
+		{Kind: medical.OfferingOnline, Price: money.Rials(1000000)},
+	}
+
+	assert.True(t, DoctorQueryParam{}.MatchesOfferings(nil))
+	assert.True(t, DoctorQueryParam{Service: medical.OfferingOnline, MaxFee: fee(100000), FeeCurrency: "IRT"}.MatchesOfferings(offerings))
+	// Both conditions must hold for the same service
+	assert.False(t, DoctorQueryParam{Service: medical.OfferingOnline, MinFee: fee(1500000)}.MatchesOfferings(offerings))
+	assert.False(t, DoctorQueryParam{Service: medical.OfferingProcedure}.MatchesOfferings(offerings))
+}
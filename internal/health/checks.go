@@ -0,0 +1,69 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/database"
+)
+
+type pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+func DatabaseCheck(db pinger) CheckFunc {
+	return func(ctx context.Context) error {
+		return db.PingContext(ctx)
+	}
+}
+
+// MigrationCheck verifies that the schema_migrations table (as maintained by
+// golang-migrate) is clean and at the expected version. The migrations in
+// this repository are plain SQL files and do not create that table, so the
+// check is only registered when the deployment's migration tool keeps it.
+func MigrationCheck(db database.Querier, expectedVersion int) CheckFunc {
+	return func(ctx context.Context) error {
+		var version int
+		var dirty bool
+		err := db.QueryRowContext(database.WithPrimary(ctx), "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return errors.New("no migrations applied")
+			}
+			return fmt.Errorf("failed to read migration version: %w", err)
+		}
+
+		if dirty {
+			return fmt.Errorf("migration %d is dirty", version)
+		}
+		if version != expectedVersion {
+			return fmt.Errorf("schema is at version %d, expected %d", version, expectedVersion)
+		}
+		return nil
+	}
+}
+
+// HTTPCheck reports a dependency as reachable when a GET to url answers with
+// a non-5xx status.
+func HTTPCheck(client *http.Client, url string) CheckFunc {
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return fmt.Errorf("unexpected status %d", resp.StatusCode)
+		}
+		return nil
+	}
+}
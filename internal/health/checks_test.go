@@ -0,0 +1,61 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const migrationQuery = "SELECT version, dirty FROM schema_migrations LIMIT 1"
+
+func TestMigrationCheck(t *testing.T) {
+	tests := []struct {
+		name      string
+		version   int
+		dirty     bool
+		expectErr string
+	}{
+		{name: "up to date", version: 2},
+		{name: "behind", version: 1, expectErr: "schema is at version 1, expected 2"},
+		{name: "dirty", version: 2, dirty: true, expectErr: "migration 2 is dirty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			mock.ExpectQuery(regexp.QuoteMeta(migrationQuery)).
+				WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}).AddRow(tt.version, tt.dirty))
+
+			err = MigrationCheck(db, 2)(context.Background())
+			if tt.expectErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.expectErr)
+			}
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestHTTPCheck(t *testing.T) {
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	check := HTTPCheck(server.Client(), server.URL)
+	assert.NoError(t, check(context.Background()))
+
+	status = http.StatusBadGateway
+	assert.EqualError(t, check(context.Background()), "unexpected status 502")
+}
@@ -0,0 +1,60 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry_Readiness_AllChecksPass(t *testing.T) {
+	registry := NewRegistry(time.Second)
+	registry.AddReadinessCheck("database", func(ctx context.Context) error { return nil })
+	registry.AddReadinessCheck("cache", func(ctx context.Context) error { return nil })
+
+	report := registry.Readiness(context.Background())
+
+	assert.True(t, report.Healthy())
+	assert.Len(t, report.Checks, 2)
+	assert.Equal(t, StatusOK, report.Checks["database"].Status)
+}
+
+func TestRegistry_Readiness_FailingCheck(t *testing.T) {
+	registry := NewRegistry(time.Second)
+	registry.AddReadinessCheck("database", func(ctx context.Context) error { return errors.New("connection refused") })
+	registry.AddReadinessCheck("cache", func(ctx context.Context) error { return nil })
+
+	report := registry.Readiness(context.Background())
+
+	assert.False(t, report.Healthy())
+	assert.Equal(t, StatusFail, report.Checks["database"].Status)
+	assert.Equal(t, "connection refused", report.Checks["database"].Error)
+	assert.Equal(t, StatusOK, report.Checks["cache"].Status)
+}
+
+func TestRegistry_Readiness_TimesOutSlowChecks(t *testing.T) {
+	registry := NewRegistry(10 * time.Millisecond)
+	registry.AddReadinessCheck("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	report := registry.Readiness(context.Background())
+
+	assert.False(t, report.Healthy())
+	assert.Contains(t, report.Checks["slow"].Error, "deadline exceeded")
+}
+
+func TestRegistry_Readiness_FailsDuringShutdown(t *testing.T) {
+	registry := NewRegistry(time.Second)
+	registry.AddReadinessCheck("database", func(ctx context.Context) error { return nil })
+
+	registry.SetShuttingDown()
+	report := registry.Readiness(context.Background())
+
+	assert.False(t, report.Healthy())
+	assert.Equal(t, StatusFail, report.Checks["shutdown"].Status)
+	assert.True(t, registry.Liveness(context.Background()).Healthy())
+}
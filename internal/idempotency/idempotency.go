@@ -0,0 +1,34 @@
+package idempotency
+
+import (
+	"context"
+	"net/http"
+)
+
+// Response is the stored outcome of the first request made with a key.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// Record is what a store holds for a claimed key. Response is nil while the
+// first request is still being processed.
+type Record struct {
+	Fingerprint string
+	Response    *Response
+}
+
+// Store persists idempotency records. Keys expire after a window fixed by
+// the store, after which the same key can be used for a new request.
+type Store interface {
+	// Acquire claims key for a request with the given fingerprint. When the
+	// key is already claimed it returns the existing record and false. A
+	// claim whose request never completed is taken over after the store's
+	// lock timeout, e.g. when an instance crashed mid-request.
+	Acquire(ctx context.Context, key, fingerprint string) (Record, bool, error)
+	// Complete stores the response for a claimed key.
+	Complete(ctx context.Context, key string, response Response) error
+	// Release drops an in-flight claim so the request can be retried.
+	Release(ctx context.Context, key string) error
+}
@@ -0,0 +1,92 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryRecord struct {
+	Record
+	lockedAt  time.Time
+	expiresAt time.Time
+}
+
+// MemoryStore keeps records in process memory, for tests and single
+// instance deployments.
+type MemoryStore struct {
+	mu          sync.Mutex
+	records     map[string]*memoryRecord
+	ttl         time.Duration
+	lockTimeout time.Duration
+	lastCleanup time.Time
+	now         func() time.Time
+}
+
+func NewMemoryStore(ttl, lockTimeout time.Duration) *MemoryStore {
+	return &MemoryStore{
+		records:     make(map[string]*memoryRecord),
+		ttl:         ttl,
+		lockTimeout: lockTimeout,
+		lastCleanup: time.Now(),
+		now:         time.Now,
+	}
+}
+
+func (s *MemoryStore) Acquire(ctx context.Context, key, fingerprint string) (Record, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.lastCleanup) >= s.ttl {
+		s.cleanup(now)
+	}
+
+	if r, ok := s.records[key]; ok {
+		expired := !now.Before(r.expiresAt)
+		stale := r.Response == nil && !now.Before(r.lockedAt.Add(s.lockTimeout))
+		if !expired && !stale {
+			return r.Record, false, nil
+		}
+	}
+
+	s.records[key] = &memoryRecord{
+		Record:    Record{Fingerprint: fingerprint},
+		lockedAt:  now,
+		expiresAt: now.Add(s.ttl),
+	}
+	return Record{Fingerprint: fingerprint}, true, nil
+}
+
+func (s *MemoryStore) Complete(ctx context.Context, key string, response Response) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r, ok := s.records[key]; ok {
+		r.Response = &Response{
+			StatusCode: response.StatusCode,
+			Header:     response.Header.Clone(),
+			Body:       append([]byte(nil), response.Body...),
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r, ok := s.records[key]; ok && r.Response == nil {
+		delete(s.records, key)
+	}
+	return nil
+}
+
+func (s *MemoryStore) cleanup(now time.Time) {
+	for key, r := range s.records {
+		if !now.Before(r.expiresAt) {
+			delete(s.records, key)
+		}
+	}
+	s.lastCleanup = now
+}
@@ -0,0 +1,96 @@
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestMemoryStore(now *time.Time) *MemoryStore {
+	s := NewMemoryStore(time.Hour, time.Minute)
+	s.now = func() time.Time { return *now }
+	s.lastCleanup = *now
+	return s
+}
+
+func TestMemoryStore_AcquireCompleteReplay(t *testing.T) {
+	now := time.Now()
+	s := newTestMemoryStore(&now)
+	ctx := context.Background()
+
+	_, acquired, err := s.Acquire(ctx, "k", "fp")
+	require.NoError(t, err)
+	require.True(t, acquired)
+
+	record, acquired, err := s.Acquire(ctx, "k", "fp")
+	require.NoError(t, err)
+	assert.False(t, acquired)
+	assert.Nil(t, record.Response)
+
+	require.NoError(t, s.Complete(ctx, "k", Response{StatusCode: http.StatusCreated, Body: []byte("ok")}))
+
+	record, acquired, err = s.Acquire(ctx, "k", "other")
+	require.NoError(t, err)
+	assert.False(t, acquired)
+	assert.Equal(t, "fp", record.Fingerprint)
+	require.NotNil(t, record.Response)
+	assert.Equal(t, http.StatusCreated, record.Response.StatusCode)
+	assert.Equal(t, []byte("ok"), record.Response.Body)
+}
+
+func TestMemoryStore_ExpiredKeyCanBeReused(t *testing.T) {
+	now := time.Now()
+	s := newTestMemoryStore(&now)
+	ctx := context.Background()
+
+	_, _, err := s.Acquire(ctx, "k", "fp")
+	require.NoError(t, err)
+	require.NoError(t, s.Complete(ctx, "k", Response{StatusCode: http.StatusCreated}))
+
+	now = now.Add(time.Hour)
+	record, acquired, err := s.Acquire(ctx, "k", "new")
+	require.NoError(t, err)
+	assert.True(t, acquired)
+	assert.Equal(t, "new", record.Fingerprint)
+}
+
+func TestMemoryStore_StaleLockIsTakenOver(t *testing.T) {
+	now := time.Now()
+	s := newTestMemoryStore(&now)
+	ctx := context.Background()
+
+	_, _, err := s.Acquire(ctx, "k", "fp")
+	require.NoError(t, err)
+
+	now = now.Add(30 * time.Second)
+	_, acquired, err := s.Acquire(ctx, "k", "fp")
+	require.NoError(t, err)
+	assert.False(t, acquired)
+
+	now = now.Add(time.Minute)
+	_, acquired, err = s.Acquire(ctx, "k", "fp")
+	require.NoError(t, err)
+	assert.True(t, acquired)
+}
+
+func TestMemoryStore_ReleaseKeepsCompletedRecords(t *testing.T) {
+	now := time.Now()
+	s := newTestMemoryStore(&now)
+	ctx := context.Background()
+
+	_, _, err := s.Acquire(ctx, "in-flight", "fp")
+	require.NoError(t, err)
+	_, _, err = s.Acquire(ctx, "done", "fp")
+	require.NoError(t, err)
+	require.NoError(t, s.Complete(ctx, "done", Response{StatusCode: http.StatusOK}))
+
+	require.NoError(t, s.Release(ctx, "in-flight"))
+	require.NoError(t, s.Release(ctx, "done"))
+
+	assert.NotContains(t, s.records, "in-flight")
+	assert.Contains(t, s.records, "done")
+}
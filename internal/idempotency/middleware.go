@@ -0,0 +1,187 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/logging"
+	"github.com/shayesteh1hs/DrAppointment/internal/middleware"
+)
+
+const (
+	Header         = "Idempotency-Key"
+	ReplayedHeader = "Idempotent-Replayed"
+)
+
+var validKey = regexp.MustCompile(`^[\x21-\x7E]{1,255}$`)
+
+// replayedHeaders are the response headers stored with the body. Others,
+// such as X-Request-ID or RateLimit-*, describe the retry itself.
+var replayedHeaders = []string{"Content-Type", "Location", "ETag", "Last-Modified", "Cache-Control"}
+
+// ScopeFunc identifies the caller an Idempotency-Key belongs to, so callers
+// never share responses by picking the same key. An empty scope leaves the
+// request unaffected by the middleware.
+type ScopeFunc func(c *gin.Context) string
+
+// ByClient scopes keys to the bearer token the caller authenticates with,
+// or to its client IP, as resolved by gin's trusted proxy settings, when it
+// sends none.
+func ByClient() ScopeFunc {
+	return func(c *gin.Context) string {
+		if token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok && token != "" {
+			return "token:" + token
+		}
+		if ip := c.ClientIP(); ip != "" {
+			return "ip:" + ip
+		}
+		return ""
+	}
+}
+
+// Middleware makes POST requests carrying an Idempotency-Key header safe to
+// retry. The first response per key, caller (see ScopeFunc) and path is
+// stored and replayed to retries with the same body. A retry while the
+// first request is still running gets 409, one with a different body 422.
+// Responses worth retrying (429 and 5xx) are not stored. Requests without
+// the header are not affected.
+func Middleware(store Store, scope ScopeFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		idempotencyKey := c.GetHeader(Header)
+		if c.Request.Method != http.MethodPost || idempotencyKey == "" {
+			c.Next()
+			return
+		}
+
+		caller := scope(c)
+		if caller == "" {
+			c.Next()
+			return
+		}
+
+		if !validKey.MatchString(idempotencyKey) {
+			abort(c, http.StatusBadRequest, "Idempotency-Key must be 1 to 255 printable ASCII characters")
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			abort(c, http.StatusBadRequest, "Failed to read request body")
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := c.Request.Context()
+		key := hash(caller, c.Request.Method, c.Request.URL.Path, idempotencyKey)
+		fingerprint := hash(c.Request.Method, c.Request.URL.Path, string(body))
+
+		record, acquired, err := store.Acquire(ctx, key, fingerprint)
+		if err != nil {
+			logging.FromContext(ctx).Error("failed to acquire idempotency key", "error", err)
+			c.Header("Retry-After", "1")
+			abort(c, http.StatusServiceUnavailable, "Service temporarily unavailable")
+			return
+		}
+
+		if !acquired {
+			switch {
+			case record.Fingerprint != fingerprint:
+				abort(c, http.StatusUnprocessableEntity, "Idempotency-Key was already used with a different request")
+			case record.Response == nil:
+				c.Header("Retry-After", "1")
+				abort(c, http.StatusConflict, "A request with this Idempotency-Key is still being processed")
+			default:
+				replay(c, record.Response)
+			}
+			return
+		}
+
+		writer := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+
+		completed := false
+		defer func() {
+			// Runs on panics too, so a crashed handler does not hold the key
+			if !completed {
+				release(ctx, store, key)
+			}
+		}()
+
+		c.Next()
+
+		status := c.Writer.Status()
+		if status == http.StatusTooManyRequests || status >= http.StatusInternalServerError {
+			return
+		}
+
+		response := Response{StatusCode: status, Header: http.Header{}, Body: writer.body.Bytes()}
+		for _, name := range replayedHeaders {
+			if values := c.Writer.Header().Values(name); len(values) > 0 {
+				response.Header[name] = values
+			}
+		}
+		if err := store.Complete(context.WithoutCancel(ctx), key, response); err != nil {
+			logging.FromContext(ctx).Error("failed to store idempotent response", "error", err)
+			return
+		}
+		completed = true
+	}
+}
+
+func replay(c *gin.Context, response *Response) {
+	for name, values := range response.Header {
+		c.Writer.Header()[name] = values
+	}
+	c.Header(ReplayedHeader, "true")
+	c.Status(response.StatusCode)
+	if _, err := c.Writer.Write(response.Body); err != nil {
+		logging.FromContext(c.Request.Context()).Warn("failed to write replayed response", "error", err)
+	}
+	c.Abort()
+}
+
+func release(ctx context.Context, store Store, key string) {
+	if err := store.Release(context.WithoutCancel(ctx), key); err != nil {
+		logging.FromContext(ctx).Error("failed to release idempotency key", "error", err)
+	}
+}
+
+func abort(c *gin.Context, status int, message string) {
+	c.AbortWithStatusJSON(status, middleware.ErrorResponse{
+		Status:  status,
+		Message: message,
+	})
+}
+
+func hash(parts ...string) string {
+	h := sha256.New()
+	for _, part := range parts {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// recordingWriter keeps a copy of the response body for storage.
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
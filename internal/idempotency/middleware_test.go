@@ -0,0 +1,197 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type failingStore struct{ MemoryStore }
+
+func (*failingStore) Acquire(context.Context, string, string) (Record, bool, error) {
+	return Record{}, false, errors.New("store down")
+}
+
+func setupRouter(store Store, handler gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware(store, func(c *gin.Context) string {
+		return c.GetHeader("X-User")
+	}))
+	router.POST("/appointments", handler)
+	return router
+}
+
+func post(router *gin.Engine, key, user, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/appointments", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(Header, key)
+	}
+	req.Header.Set("X-User", user)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func countingHandler(calls *atomic.Int32) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		n := calls.Add(1)
+		c.Header("Location", "/appointments/1")
+		c.JSON(http.StatusCreated, gin.H{"call": n})
+	}
+}
+
+func TestMiddleware_ReplaysFirstResponse(t *testing.T) {
+	var calls atomic.Int32
+	router := setupRouter(NewMemoryStore(time.Hour, time.Minute), countingHandler(&calls))
+
+	first := post(router, "key-1", "u1", `{"slot":"a"}`)
+	require.Equal(t, http.StatusCreated, first.Code)
+	assert.Empty(t, first.Header().Get(ReplayedHeader))
+
+	retry := post(router, "key-1", "u1", `{"slot":"a"}`)
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.Equal(t, first.Body.String(), retry.Body.String())
+	assert.Equal(t, "/appointments/1", retry.Header().Get("Location"))
+	assert.Equal(t, "true", retry.Header().Get(ReplayedHeader))
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestMiddleware_ScopesKeysPerUser(t *testing.T) {
+	var calls atomic.Int32
+	router := setupRouter(NewMemoryStore(time.Hour, time.Minute), countingHandler(&calls))
+
+	post(router, "key-1", "u1", `{}`)
+	w := post(router, "key-1", "u2", `{}`)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Empty(t, w.Header().Get(ReplayedHeader))
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestMiddleware_SkipsRequestsWithoutScope(t *testing.T) {
+	var calls atomic.Int32
+	router := setupRouter(NewMemoryStore(time.Hour, time.Minute), countingHandler(&calls))
+
+	post(router, "key-1", "", `{}`)
+	w := post(router, "key-1", "", `{}`)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Empty(t, w.Header().Get(ReplayedHeader))
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestByClient(t *testing.T) {
+	var calls atomic.Int32
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware(NewMemoryStore(time.Hour, time.Minute), ByClient()))
+	router.POST("/appointments", countingHandler(&calls))
+
+	send := func(remoteAddr, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/appointments", strings.NewReader(`{}`))
+		req.RemoteAddr = remoteAddr
+		req.Header.Set(Header, "key-1")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	send("192.0.2.1:1234", "")
+	assert.Equal(t, "true", send("192.0.2.1:5678", "").Header().Get(ReplayedHeader))
+	// Another client reusing the key gets its own response
+	assert.Empty(t, send("192.0.2.2:1234", "").Header().Get(ReplayedHeader))
+	// So does a caller with its own token behind the same address
+	assert.Empty(t, send("192.0.2.1:1234", "token-a").Header().Get(ReplayedHeader))
+	assert.Equal(t, "true", send("192.0.2.9:1234", "token-a").Header().Get(ReplayedHeader))
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestMiddleware_RejectsDifferentBody(t *testing.T) {
+	var calls atomic.Int32
+	router := setupRouter(NewMemoryStore(time.Hour, time.Minute), countingHandler(&calls))
+
+	post(router, "key-1", "u1", `{"slot":"a"}`)
+	w := post(router, "key-1", "u1", `{"slot":"b"}`)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestMiddleware_RejectsConcurrentDuplicate(t *testing.T) {
+	started := make(chan struct{})
+	finish := make(chan struct{})
+	router := setupRouter(NewMemoryStore(time.Hour, time.Minute), func(c *gin.Context) {
+		close(started)
+		<-finish
+		c.Status(http.StatusCreated)
+	})
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- post(router, "key-1", "u1", `{}`) }()
+	<-started
+
+	w := post(router, "key-1", "u1", `{}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+
+	close(finish)
+	assert.Equal(t, http.StatusCreated, (<-done).Code)
+}
+
+func TestMiddleware_DoesNotStoreServerErrors(t *testing.T) {
+	var calls atomic.Int32
+	router := setupRouter(NewMemoryStore(time.Hour, time.Minute), func(c *gin.Context) {
+		if calls.Add(1) == 1 {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		c.Status(http.StatusCreated)
+	})
+
+	assert.Equal(t, http.StatusInternalServerError, post(router, "key-1", "u1", `{}`).Code)
+	assert.Equal(t, http.StatusCreated, post(router, "key-1", "u1", `{}`).Code)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestMiddleware_IgnoresRequestsWithoutKey(t *testing.T) {
+	var calls atomic.Int32
+	router := setupRouter(NewMemoryStore(time.Hour, time.Minute), countingHandler(&calls))
+
+	post(router, "", "u1", `{}`)
+	post(router, "", "u1", `{}`)
+
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestMiddleware_InvalidKey(t *testing.T) {
+	var calls atomic.Int32
+	router := setupRouter(NewMemoryStore(time.Hour, time.Minute), countingHandler(&calls))
+
+	w := post(router, strings.Repeat("k", 256), "u1", `{}`)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Zero(t, calls.Load())
+}
+
+func TestMiddleware_StoreFailure(t *testing.T) {
+	var calls atomic.Int32
+	router := setupRouter(&failingStore{}, countingHandler(&calls))
+
+	w := post(router, "key-1", "u1", `{}`)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Zero(t, calls.Load())
+}
@@ -0,0 +1,151 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/database"
+	"github.com/shayesteh1hs/DrAppointment/internal/logging"
+)
+
+// acquireQuery claims the key unless a live record exists. Expired records
+// and in-flight claims older than the lock timeout ($4) are overwritten.
+// No row is returned when the key is held by someone else.
+const acquireQuery = `INSERT INTO idempotency_keys AS k (key, fingerprint, locked_at, expires_at)
+VALUES ($1, $2, now(), now() + $3::float8 * interval '1 second')
+ON CONFLICT (key) DO UPDATE SET
+    fingerprint = EXCLUDED.fingerprint,
+    status_code = NULL,
+    response_headers = NULL,
+    response_body = NULL,
+    locked_at = EXCLUDED.locked_at,
+    expires_at = EXCLUDED.expires_at
+WHERE k.expires_at <= now() OR (k.status_code IS NULL AND k.locked_at <= now() - $4::float8 * interval '1 second')
+RETURNING key`
+
+const selectQuery = "SELECT fingerprint, status_code, response_headers, response_body FROM idempotency_keys WHERE key = $1"
+
+// cleanupInterval bounds how often expired records are deleted.
+const cleanupInterval = 10 * time.Minute
+
+// PostgresStore shares records between all instances through the
+// idempotency_keys table.
+type PostgresStore struct {
+	db          database.Querier
+	ttl         time.Duration
+	lockTimeout time.Duration
+	lastCleanup atomic.Int64
+}
+
+func NewPostgresStore(db database.Querier, ttl, lockTimeout time.Duration) *PostgresStore {
+	s := &PostgresStore{db: db, ttl: ttl, lockTimeout: lockTimeout}
+	s.lastCleanup.Store(time.Now().UnixNano())
+	return s
+}
+
+func (s *PostgresStore) Acquire(ctx context.Context, key, fingerprint string) (Record, bool, error) {
+	defer s.maybeCleanup(ctx)
+
+	// A record can expire and be deleted between the two statements, in
+	// which case claiming it again succeeds.
+	for range 2 {
+		var claimed string
+		err := s.db.QueryRowContext(ctx, acquireQuery, key, fingerprint, s.ttl.Seconds(), s.lockTimeout.Seconds()).Scan(&claimed)
+		if err == nil {
+			return Record{Fingerprint: fingerprint}, true, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return Record{}, false, fmt.Errorf("failed to acquire idempotency key: %w", err)
+		}
+
+		// Another request holds the key; a replica may not have its record yet
+		record, err := s.get(database.WithPrimary(ctx), key)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		return record, false, err
+	}
+
+	return Record{}, false, errors.New("failed to acquire idempotency key: record changed concurrently")
+}
+
+func (s *PostgresStore) get(ctx context.Context, key string) (Record, error) {
+	var (
+		record     Record
+		statusCode sql.NullInt64
+		headers    []byte
+		body       []byte
+	)
+	err := s.db.QueryRowContext(ctx, selectQuery, key).Scan(&record.Fingerprint, &statusCode, &headers, &body)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Record{}, err
+	}
+	if err != nil {
+		return Record{}, fmt.Errorf("failed to get idempotency record: %w", err)
+	}
+
+	if statusCode.Valid {
+		response := &Response{StatusCode: int(statusCode.Int64), Header: http.Header{}, Body: body}
+		if len(headers) > 0 {
+			if err := json.Unmarshal(headers, &response.Header); err != nil {
+				return Record{}, fmt.Errorf("failed to decode idempotency response headers: %w", err)
+			}
+		}
+		record.Response = response
+	}
+	return record, nil
+}
+
+func (s *PostgresStore) Complete(ctx context.Context, key string, response Response) error {
+	headers, err := json.Marshal(response.Header)
+	if err != nil {
+		return fmt.Errorf("failed to encode idempotency response headers: %w", err)
+	}
+
+	_, err = s.db.ExecContext(ctx, "UPDATE idempotency_keys SET status_code = $2, response_headers = $3, response_body = $4 WHERE key = $1",
+		key, response.StatusCode, headers, response.Body)
+	if err != nil {
+		return fmt.Errorf("failed to store idempotency response: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) Release(ctx context.Context, key string) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE key = $1 AND status_code IS NULL", key)
+	if err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
+
+// maybeCleanup lets at most one request per cleanupInterval, across
+// goroutines of this instance, delete expired records in the background.
+func (s *PostgresStore) maybeCleanup(ctx context.Context) {
+	last := s.lastCleanup.Load()
+	now := time.Now().UnixNano()
+	if time.Duration(now-last) < cleanupInterval || !s.lastCleanup.CompareAndSwap(last, now) {
+		return
+	}
+
+	go func() {
+		ctx := context.WithoutCancel(ctx)
+		if err := s.Cleanup(ctx); err != nil {
+			logging.FromContext(ctx).Warn("idempotency cleanup failed", "error", err)
+		}
+	}()
+}
+
+// Cleanup deletes expired records.
+func (s *PostgresStore) Cleanup(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE expires_at <= now()")
+	if err != nil {
+		return fmt.Errorf("failed to clean up idempotency keys: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/database"
+)
+
+func TestPostgresStore_Acquire_Claimed(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	s := NewPostgresStore(db, time.Hour, time.Minute)
+
+	mock.ExpectQuery(regexp.QuoteMeta(acquireQuery)).
+		WithArgs("k", "fp", float64(3600), float64(60)).
+		WillReturnRows(sqlmock.NewRows([]string{"key"}).AddRow("k"))
+
+	record, acquired, err := s.Acquire(context.Background(), "k", "fp")
+	require.NoError(t, err)
+	assert.True(t, acquired)
+	assert.Equal(t, "fp", record.Fingerprint)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStore_Acquire_ReturnsExistingResponse(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	s := NewPostgresStore(db, time.Hour, time.Minute)
+
+	mock.ExpectQuery(regexp.QuoteMeta(acquireQuery)).
+		WithArgs("k", "fp", float64(3600), float64(60)).
+		WillReturnRows(sqlmock.NewRows([]string{"key"}))
+	mock.ExpectQuery(regexp.QuoteMeta(selectQuery)).
+		WithArgs("k").
+		WillReturnRows(sqlmock.NewRows([]string{"fingerprint", "status_code", "response_headers", "response_body"}).
+			AddRow("fp", 201, []byte(`{"Content-Type":["application/json"]}`), []byte(`{"id":1}`)))
+
+	record, acquired, err := s.Acquire(context.Background(), "k", "fp")
+	require.NoError(t, err)
+	assert.False(t, acquired)
+	require.NotNil(t, record.Response)
+	assert.Equal(t, http.StatusCreated, record.Response.StatusCode)
+	assert.Equal(t, "application/json", record.Response.Header.Get("Content-Type"))
+	assert.Equal(t, []byte(`{"id":1}`), record.Response.Body)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStore_Acquire_ReadsHeldKeyFromPrimary(t *testing.T) {
+	primary, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer primary.Close()
+	replica, replicaMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer replica.Close()
+
+	db := database.NewWithReplicas(primary, replica)
+	s := NewPostgresStore(db, time.Hour, time.Minute)
+
+	mock.ExpectQuery(regexp.QuoteMeta(acquireQuery)).
+		WillReturnRows(sqlmock.NewRows([]string{"key"}))
+	// The claim was just lost, so the record may not have reached the replica
+	mock.ExpectQuery(regexp.QuoteMeta(selectQuery)).
+		WithArgs("k").
+		WillReturnRows(sqlmock.NewRows([]string{"fingerprint", "status_code", "response_headers", "response_body"}).
+			AddRow("fp", nil, nil, nil))
+	// Other reads still go to the replica
+	replicaMock.ExpectQuery(regexp.QuoteMeta("SELECT count(*) FROM doctors")).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+	_, acquired, err := s.Acquire(context.Background(), "k", "fp")
+	require.NoError(t, err)
+	assert.False(t, acquired)
+
+	var count int
+	require.NoError(t, db.QueryRowContext(context.Background(), "SELECT count(*) FROM doctors").Scan(&count))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.NoError(t, replicaMock.ExpectationsWereMet())
+}
+
+func TestPostgresStore_Acquire_InFlight(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	s := NewPostgresStore(db, time.Hour, time.Minute)
+
+	mock.ExpectQuery(regexp.QuoteMeta(acquireQuery)).
+		WillReturnRows(sqlmock.NewRows([]string{"key"}))
+	mock.ExpectQuery(regexp.QuoteMeta(selectQuery)).
+		WithArgs("k").
+		WillReturnRows(sqlmock.NewRows([]string{"fingerprint", "status_code", "response_headers", "response_body"}).
+			AddRow("fp", nil, nil, nil))
+
+	record, acquired, err := s.Acquire(context.Background(), "k", "fp")
+	require.NoError(t, err)
+	assert.False(t, acquired)
+	assert.Nil(t, record.Response)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStore_CompleteAndRelease(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	s := NewPostgresStore(db, time.Hour, time.Minute)
+
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE idempotency_keys SET status_code = $2, response_headers = $3, response_body = $4 WHERE key = $1")).
+		WithArgs("k", 201, []byte(`{"Location":["/appointments/1"]}`), []byte("created")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM idempotency_keys WHERE key = $1 AND status_code IS NULL")).
+		WithArgs("other").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(t, s.Complete(context.Background(), "k", Response{
+		StatusCode: http.StatusCreated,
+		Header:     http.Header{"Location": {"/appointments/1"}},
+		Body:       []byte("created"),
+	}))
+	require.NoError(t, s.Release(context.Background(), "other"))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,102 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultMaxAttempts is how often a job is run before it is dead-lettered,
+// unless it sets MaxAttempts.
+const DefaultMaxAttempts = 5
+
+type Status string
+
+const (
+	// StatusPending jobs wait for RunAt, for their first or next attempt
+	StatusPending Status = "pending"
+	// StatusRunning jobs are leased by a worker until their lease expires
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	// StatusDead jobs failed permanently or ran out of attempts. They are
+	// kept for inspection and never run again.
+	StatusDead Status = "dead"
+)
+
+// Job is a unit of background work of a registered kind.
+type Job struct {
+	ID          uuid.UUID
+	Kind        string
+	Payload     json.RawMessage
+	Status      Status
+	Attempts    int
+	MaxAttempts int
+	RunAt       time.Time
+	LastError   string
+	// UniqueKey, when set, makes enqueueing idempotent: a job is not added
+	// while one with the same key exists, including finished ones until
+	// they are cleaned up.
+	UniqueKey string
+	CreatedAt time.Time
+}
+
+// New builds a job of kind with payload encoded as JSON, due now.
+func New(kind string, payload any) (Job, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return Job{}, fmt.Errorf("failed to encode %s job payload: %w", kind, err)
+	}
+	id, err := uuid.NewV7()
+	if err != nil {
+		return Job{}, fmt.Errorf("failed to generate job id: %w", err)
+	}
+	return Job{
+		ID:          id,
+		Kind:        kind,
+		Payload:     data,
+		Status:      StatusPending,
+		MaxAttempts: DefaultMaxAttempts,
+	}, nil
+}
+
+// Decode unmarshals the job's payload into v.
+func (j Job) Decode(v any) error {
+	if err := json.Unmarshal(j.Payload, v); err != nil {
+		return Permanent(fmt.Errorf("invalid %s job payload: %w", j.Kind, err))
+	}
+	return nil
+}
+
+// Handler runs jobs of one kind. Returning an error retries the job with
+// backoff; wrap it with Permanent to dead-letter the job at once.
+type Handler func(ctx context.Context, job Job) error
+
+// Enqueuer adds jobs. Store implements it; enqueueing with a context bound
+// to a database transaction makes the job part of that transaction.
+type Enqueuer interface {
+	// Enqueue adds job and reports whether it was added, false when a job
+	// with the same unique key exists.
+	Enqueue(ctx context.Context, job Job) (bool, error)
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent marks a job error as not worth retrying.
+func Permanent(err error) error {
+	return permanentError{err: err}
+}
+
+// IsPermanent reports whether err was marked with Permanent.
+func IsPermanent(err error) bool {
+	var p permanentError
+	return errors.As(err, &p)
+}
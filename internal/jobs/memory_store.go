@@ -0,0 +1,174 @@
+package jobs
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type memoryJob struct {
+	Job
+	lockedUntil time.Time
+	finishedAt  time.Time
+}
+
+// MemoryStore keeps jobs in process memory, for tests and single instance
+// deployments. Jobs are lost on restart.
+type MemoryStore struct {
+	mu         sync.Mutex
+	jobs       map[uuid.UUID]*memoryJob
+	uniqueKeys map[string]struct{}
+	now        func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		jobs:       make(map[uuid.UUID]*memoryJob),
+		uniqueKeys: make(map[string]struct{}),
+		now:        time.Now,
+	}
+}
+
+func (s *MemoryStore) Enqueue(ctx context.Context, job Job) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if job.UniqueKey != "" {
+		if _, ok := s.uniqueKeys[job.UniqueKey]; ok {
+			return false, nil
+		}
+		s.uniqueKeys[job.UniqueKey] = struct{}{}
+	}
+
+	now := s.now()
+	if job.RunAt.IsZero() {
+		job.RunAt = now
+	}
+	if job.MaxAttempts == 0 {
+		job.MaxAttempts = DefaultMaxAttempts
+	}
+	job.Status, job.CreatedAt = StatusPending, now
+	s.jobs[job.ID] = &memoryJob{Job: job}
+	return true, nil
+}
+
+func (s *MemoryStore) Claim(ctx context.Context, kinds []string, limit int, lease time.Duration) ([]Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	var due []*memoryJob
+	for _, job := range s.jobs {
+		if !slices.Contains(kinds, job.Kind) {
+			continue
+		}
+		if (job.Status == StatusPending && !job.RunAt.After(now)) ||
+			(job.Status == StatusRunning && !job.lockedUntil.After(now)) {
+			due = append(due, job)
+		}
+	}
+	slices.SortFunc(due, func(a, b *memoryJob) int { return a.RunAt.Compare(b.RunAt) })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	claimed := make([]Job, 0, len(due))
+	for _, job := range due {
+		job.Status = StatusRunning
+		job.Attempts++
+		job.lockedUntil = now.Add(lease)
+		claimed = append(claimed, job.Job)
+	}
+	return claimed, nil
+}
+
+func (s *MemoryStore) Complete(ctx context.Context, claimed Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.leased(claimed)
+	if !ok {
+		return ErrLeaseLost
+	}
+	job.Status = StatusSucceeded
+	job.lockedUntil, job.finishedAt = time.Time{}, s.now()
+	return nil
+}
+
+func (s *MemoryStore) Retry(ctx context.Context, claimed Job, runAt time.Time, lastError string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.leased(claimed)
+	if !ok {
+		return ErrLeaseLost
+	}
+	job.Status, job.RunAt, job.LastError = StatusPending, runAt, lastError
+	job.lockedUntil = time.Time{}
+	return nil
+}
+
+func (s *MemoryStore) Bury(ctx context.Context, claimed Job, lastError string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.leased(claimed)
+	if !ok {
+		return ErrLeaseLost
+	}
+	job.Status, job.LastError = StatusDead, lastError
+	job.lockedUntil, job.finishedAt = time.Time{}, s.now()
+	return nil
+}
+
+// leased returns the stored job while it is still leased by the claim
+// that returned claimed.
+func (s *MemoryStore) leased(claimed Job) (*memoryJob, bool) {
+	job, ok := s.jobs[claimed.ID]
+	if !ok || job.Status != StatusRunning || job.Attempts != claimed.Attempts {
+		return nil, false
+	}
+	return job, true
+}
+
+func (s *MemoryStore) DeleteSucceeded(ctx context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deleted int64
+	for id, job := range s.jobs {
+		if job.Status == StatusSucceeded && job.finishedAt.Before(before) {
+			delete(s.jobs, id)
+			delete(s.uniqueKeys, job.UniqueKey)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// Get returns a copy of the job with id, for tests and inspection.
+func (s *MemoryStore) Get(id uuid.UUID) (Job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return job.Job, true
+}
+
+// Jobs returns a copy of every job, for tests and inspection.
+func (s *MemoryStore) Jobs() []Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobs := make([]Job, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, job.Job)
+	}
+	return jobs
+}
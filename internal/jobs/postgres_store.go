@@ -0,0 +1,156 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/database"
+)
+
+const (
+	// enqueueQuery returns no row when a job with the same unique key exists.
+	enqueueQuery = `INSERT INTO jobs (id, kind, payload, max_attempts, run_at, unique_key)
+VALUES ($1, $2, $3, $4, COALESCE($5, now()), $6)
+ON CONFLICT (unique_key) WHERE unique_key IS NOT NULL DO NOTHING
+RETURNING id`
+
+	// claimQuery leases due jobs for $3 seconds. Rows locked by another
+	// worker's claim are skipped rather than waited on.
+	claimQuery = `UPDATE jobs AS j
+SET status = 'running', attempts = j.attempts + 1, locked_until = now() + $3::float8 * interval '1 second', updated_at = now()
+FROM (
+    SELECT id FROM jobs
+    WHERE kind = ANY($1)
+      AND ((status = 'pending' AND run_at <= now()) OR (status = 'running' AND locked_until <= now()))
+    ORDER BY run_at
+    LIMIT $2
+    FOR UPDATE SKIP LOCKED
+) AS due
+WHERE j.id = due.id
+RETURNING j.id, j.kind, j.payload, j.status, j.attempts, j.max_attempts, j.run_at, j.last_error, j.unique_key, j.created_at`
+
+	// completeQuery, retryQuery and buryQuery only update the row while it
+	// is still leased by the claim that counted attempt $2; every claim
+	// increments attempts.
+	completeQuery = "UPDATE jobs SET status = 'succeeded', locked_until = NULL, finished_at = now(), updated_at = now() WHERE id = $1 AND status = 'running' AND attempts = $2"
+
+	retryQuery = "UPDATE jobs SET status = 'pending', run_at = $3, last_error = $4, locked_until = NULL, updated_at = now() WHERE id = $1 AND status = 'running' AND attempts = $2"
+
+	buryQuery = "UPDATE jobs SET status = 'dead', last_error = $3, locked_until = NULL, finished_at = now(), updated_at = now() WHERE id = $1 AND status = 'running' AND attempts = $2"
+
+	deleteSucceededQuery = "DELETE FROM jobs WHERE status = 'succeeded' AND finished_at < $1"
+)
+
+// PostgresStore shares the queue between all instances through the jobs
+// table.
+type PostgresStore struct {
+	db database.Querier
+}
+
+func NewPostgresStore(db database.Querier) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+func (s *PostgresStore) Enqueue(ctx context.Context, job Job) (bool, error) {
+	maxAttempts := job.MaxAttempts
+	if maxAttempts == 0 {
+		maxAttempts = DefaultMaxAttempts
+	}
+
+	var id uuid.UUID
+	err := s.db.QueryRowContext(ctx, enqueueQuery,
+		job.ID, job.Kind, []byte(job.Payload), maxAttempts, nullTime(job.RunAt), nullString(job.UniqueKey)).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to enqueue %s job: %w", job.Kind, err)
+	}
+	return true, nil
+}
+
+func (s *PostgresStore) Claim(ctx context.Context, kinds []string, limit int, lease time.Duration) ([]Job, error) {
+	rows, err := s.db.QueryContext(ctx, claimQuery, pq.Array(kinds), limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var claimed []Job
+	for rows.Next() {
+		var (
+			job       Job
+			payload   []byte
+			uniqueKey sql.NullString
+		)
+		err := rows.Scan(&job.ID, &job.Kind, &payload, &job.Status, &job.Attempts, &job.MaxAttempts,
+			&job.RunAt, &job.LastError, &uniqueKey, &job.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan job: %w", err)
+		}
+		job.Payload, job.UniqueKey = payload, uniqueKey.String
+		claimed = append(claimed, job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to claim jobs: %w", err)
+	}
+	return claimed, nil
+}
+
+func (s *PostgresStore) Complete(ctx context.Context, job Job) error {
+	result, err := s.db.ExecContext(ctx, completeQuery, job.ID, job.Attempts)
+	if err != nil {
+		return fmt.Errorf("failed to complete job: %w", err)
+	}
+	return leaseHeld(result)
+}
+
+func (s *PostgresStore) Retry(ctx context.Context, job Job, runAt time.Time, lastError string) error {
+	result, err := s.db.ExecContext(ctx, retryQuery, job.ID, job.Attempts, runAt, lastError)
+	if err != nil {
+		return fmt.Errorf("failed to reschedule job: %w", err)
+	}
+	return leaseHeld(result)
+}
+
+func (s *PostgresStore) Bury(ctx context.Context, job Job, lastError string) error {
+	result, err := s.db.ExecContext(ctx, buryQuery, job.ID, job.Attempts, lastError)
+	if err != nil {
+		return fmt.Errorf("failed to dead-letter job: %w", err)
+	}
+	return leaseHeld(result)
+}
+
+func (s *PostgresStore) DeleteSucceeded(ctx context.Context, before time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx, deleteSucceededQuery, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete succeeded jobs: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// leaseHeld returns ErrLeaseLost when an outcome update matched no row.
+func leaseHeld(result sql.Result) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to record job outcome: %w", err)
+	}
+	if n == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
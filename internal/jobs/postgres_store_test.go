@@ -0,0 +1,105 @@
+package jobs
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresStore_Enqueue(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	job, err := New("appointment.reminder", map[string]string{"appointment_id": "a"})
+	require.NoError(t, err)
+	job.UniqueKey = "appointment.reminder:a:24h"
+
+	mock.ExpectQuery(regexp.QuoteMeta(enqueueQuery)).
+		WithArgs(job.ID, "appointment.reminder", []byte(`{"appointment_id":"a"}`), DefaultMaxAttempts, nil, "appointment.reminder:a:24h").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(job.ID))
+
+	added, err := NewPostgresStore(db).Enqueue(context.Background(), job)
+	require.NoError(t, err)
+	assert.True(t, added)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStore_Enqueue_Duplicate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	job, err := New("tick", nil)
+	require.NoError(t, err)
+	job.UniqueKey = "tick@2026-10-18T09:00:00Z"
+
+	mock.ExpectQuery(regexp.QuoteMeta(enqueueQuery)).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	added, err := NewPostgresStore(db).Enqueue(context.Background(), job)
+	require.NoError(t, err)
+	assert.False(t, added)
+}
+
+func TestPostgresStore_Claim(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	id := uuid.New()
+	now := time.Date(2026, time.October, 18, 9, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(regexp.QuoteMeta(claimQuery)).
+		WithArgs(pq.Array([]string{"a", "b"}), 4, float64(300)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "kind", "payload", "status", "attempts", "max_attempts", "run_at", "last_error", "unique_key", "created_at"}).
+			AddRow(id, "a", []byte(`{}`), "running", 1, 5, now, "", nil, now))
+
+	claimed, err := NewPostgresStore(db).Claim(context.Background(), []string{"a", "b"}, 4, 5*time.Minute)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	assert.Equal(t, id, claimed[0].ID)
+	assert.Equal(t, StatusRunning, claimed[0].Status)
+	assert.Equal(t, 1, claimed[0].Attempts)
+	assert.Empty(t, claimed[0].UniqueKey)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStore_RetryAndBury(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	job := Job{ID: uuid.New(), Attempts: 2}
+	runAt := time.Date(2026, time.October, 18, 9, 1, 0, 0, time.UTC)
+	mock.ExpectExec(regexp.QuoteMeta(retryQuery)).WithArgs(job.ID, 2, runAt, "timeout").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(buryQuery)).WithArgs(job.ID, 2, "invalid payload").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	s := NewPostgresStore(db)
+	require.NoError(t, s.Retry(context.Background(), job, runAt, "timeout"))
+	require.NoError(t, s.Bury(context.Background(), job, "invalid payload"))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStore_CompleteAfterLeaseLost(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	job := Job{ID: uuid.New(), Attempts: 1}
+	// Another worker claimed the job again, so attempts moved on to 2
+	mock.ExpectExec(regexp.QuoteMeta(completeQuery)).WithArgs(job.ID, 1).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = NewPostgresStore(db).Complete(context.Background(), job)
+	assert.ErrorIs(t, err, ErrLeaseLost)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,232 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/database"
+	"github.com/shayesteh1hs/DrAppointment/internal/logging"
+	"github.com/shayesteh1hs/DrAppointment/internal/tracing"
+)
+
+const cleanupKind = "jobs.cleanup"
+
+// Options tune how a Runner picks up and retries jobs.
+type Options struct {
+	// Concurrency is how many jobs run at once on this instance
+	Concurrency int
+	// PollInterval is how often the queue is checked for due jobs
+	PollInterval time.Duration
+	// Lease is how long a claimed job is reserved for its worker, and so
+	// the most a single run may take. Jobs whose lease expires are run
+	// again elsewhere.
+	Lease time.Duration
+	// RetryBackoff is the wait after the first failed run; it doubles with
+	// each further failure up to MaxBackoff
+	RetryBackoff time.Duration
+	MaxBackoff   time.Duration
+	// Retention is how long succeeded jobs are kept; dead jobs are kept
+	// until deleted by hand
+	Retention time.Duration
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Concurrency:  4,
+		PollInterval: time.Second,
+		Lease:        5 * time.Minute,
+		RetryBackoff: 30 * time.Second,
+		MaxBackoff:   time.Hour,
+		Retention:    7 * 24 * time.Hour,
+	}
+}
+
+type schedule struct {
+	kind     string
+	interval time.Duration
+	next     time.Time
+}
+
+// Runner runs queued jobs with registered handlers. Every instance may run
+// one; jobs are leased so each runs on one instance at a time, and recurring
+// jobs are enqueued once per interval across all of them.
+type Runner struct {
+	store     Store
+	opts      Options
+	handlers  map[string]Handler
+	schedules []*schedule
+	now       func() time.Time
+}
+
+func NewRunner(store Store, opts Options) *Runner {
+	r := &Runner{store: store, opts: opts, handlers: make(map[string]Handler), now: time.Now}
+	r.Every(cleanupKind, time.Hour, r.cleanup)
+	return r
+}
+
+// Register sets the handler of jobs of kind. Handlers must be registered
+// before Run.
+func (r *Runner) Register(kind string, h Handler) {
+	r.handlers[kind] = h
+}
+
+// Every registers h and runs it once per interval, at multiples of interval
+// since the Unix epoch.
+func (r *Runner) Every(kind string, interval time.Duration, h Handler) {
+	r.Register(kind, h)
+	r.schedules = append(r.schedules, &schedule{kind: kind, interval: interval})
+}
+
+// Run processes jobs until ctx is canceled, then waits for running jobs to
+// finish. Jobs are not interrupted by ctx; each is bounded by the lease.
+func (r *Runner) Run(ctx context.Context) {
+	kinds := slices.Sorted(maps.Keys(r.handlers))
+	sem := make(chan struct{}, r.opts.Concurrency)
+	finished := make(chan struct{}, 1)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	ticker := time.NewTicker(r.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		r.enqueueScheduled(ctx)
+
+		if free := cap(sem) - len(sem); free > 0 {
+			claimed, err := r.store.Claim(ctx, kinds, free, r.opts.Lease)
+			if err != nil && ctx.Err() == nil {
+				logging.FromContext(ctx).Error("failed to claim jobs", "error", err)
+			}
+			for _, job := range claimed {
+				sem <- struct{}{}
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					r.execute(context.WithoutCancel(ctx), job)
+					<-sem
+					select {
+					case finished <- struct{}{}:
+					default:
+					}
+				}()
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-finished:
+		}
+	}
+}
+
+// enqueueScheduled adds the current run of every recurring job whose
+// interval has started. The unique key keeps other instances from adding it
+// again.
+func (r *Runner) enqueueScheduled(ctx context.Context) {
+	now := r.now()
+	for _, s := range r.schedules {
+		if now.Before(s.next) {
+			continue
+		}
+		slot := now.Truncate(s.interval)
+
+		job, err := New(s.kind, struct{}{})
+		if err == nil {
+			job.RunAt = slot
+			job.UniqueKey = s.kind + "@" + slot.UTC().Format(time.RFC3339)
+			// The next run follows shortly, so a failed run is not retried
+			job.MaxAttempts = 1
+			_, err = r.store.Enqueue(ctx, job)
+		}
+		if err != nil {
+			if ctx.Err() == nil {
+				logging.FromContext(ctx).Error("failed to schedule job", "kind", s.kind, "error", err)
+			}
+			continue
+		}
+		s.next = slot.Add(s.interval)
+	}
+}
+
+// execute runs a claimed job and records the outcome.
+func (r *Runner) execute(ctx context.Context, job Job) {
+	ctx, span := tracing.Start(ctx, "Job "+job.Kind)
+	span.SetAttributes(
+		attribute.String("job.id", job.ID.String()),
+		attribute.Int("job.attempt", job.Attempts),
+	)
+	logger := logging.FromContext(ctx).With("job_id", job.ID, "kind", job.Kind, "attempt", job.Attempts)
+
+	err := r.run(ctx, job)
+	tracing.End(span, err)
+
+	switch {
+	case err == nil:
+		err = r.store.Complete(ctx, job)
+	case IsPermanent(err) || job.Attempts >= job.MaxAttempts:
+		logger.Error("job failed, moved to dead letter", "error", err)
+		err = r.store.Bury(ctx, job, err.Error())
+	default:
+		runAt := r.now().Add(r.backoff(job.Attempts))
+		logger.Warn("job failed, will retry", "retry_at", runAt, "error", err)
+		err = r.store.Retry(ctx, job, runAt, err.Error())
+	}
+	switch {
+	case errors.Is(err, ErrLeaseLost):
+		// The run outlasted its lease and the job was claimed again; the
+		// newer claim records the outcome
+		logger.Warn("job lease lost, outcome discarded")
+	case err != nil:
+		// The lease expires and the job runs again
+		logger.Error("failed to record job outcome", "error", err)
+	}
+}
+
+func (r *Runner) run(ctx context.Context, job Job) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, r.opts.Lease)
+	defer cancel()
+	defer func() {
+		if p := recover(); p != nil {
+			err = Permanent(fmt.Errorf("job panicked: %v", p))
+		}
+	}()
+
+	if job.Attempts > job.MaxAttempts {
+		// Its worker died during the last allowed attempt
+		return Permanent(fmt.Errorf("lease expired on the last of %d attempts", job.MaxAttempts))
+	}
+	// Reads after the job's own writes stay on the primary
+	return r.handlers[job.Kind](database.WithSession(ctx), job)
+}
+
+// backoff is the wait after the given number of failed runs.
+func (r *Runner) backoff(attempts int) time.Duration {
+	wait := r.opts.RetryBackoff
+	for range attempts - 1 {
+		wait *= 2
+		if wait >= r.opts.MaxBackoff {
+			return r.opts.MaxBackoff
+		}
+	}
+	return min(wait, r.opts.MaxBackoff)
+}
+
+func (r *Runner) cleanup(ctx context.Context, _ Job) error {
+	deleted, err := r.store.DeleteSucceeded(ctx, r.now().Add(-r.opts.Retention))
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		logging.FromContext(ctx).Info("deleted succeeded jobs", "count", deleted)
+	}
+	return nil
+}
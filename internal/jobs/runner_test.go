@@ -0,0 +1,257 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testOptions() Options {
+	opts := DefaultOptions()
+	opts.PollInterval = 5 * time.Millisecond
+	opts.RetryBackoff = time.Millisecond
+	opts.MaxBackoff = 10 * time.Millisecond
+	opts.Lease = time.Second
+	return opts
+}
+
+// startRunner runs r until the test ends and returns a function stopping it
+// early, which returns once Run has.
+func startRunner(t *testing.T, r *Runner) func() {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.Run(ctx)
+	}()
+	stop := func() {
+		cancel()
+		<-done
+	}
+	t.Cleanup(stop)
+	return stop
+}
+
+func enqueue(t *testing.T, store Store, kind string, payload any) uuid.UUID {
+	t.Helper()
+	job, err := New(kind, payload)
+	require.NoError(t, err)
+	added, err := store.Enqueue(context.Background(), job)
+	require.NoError(t, err)
+	require.True(t, added)
+	return job.ID
+}
+
+func waitForStatus(t *testing.T, store *MemoryStore, id uuid.UUID, status Status) Job {
+	t.Helper()
+	var job Job
+	require.Eventually(t, func() bool {
+		job, _ = store.Get(id)
+		return job.Status == status
+	}, 2*time.Second, time.Millisecond)
+	return job
+}
+
+func TestRunner_RunsJobs(t *testing.T) {
+	store := NewMemoryStore()
+	r := NewRunner(store, testOptions())
+
+	got := make(chan string, 1)
+	r.Register("greet", func(ctx context.Context, job Job) error {
+		var payload struct{ Name string }
+		if err := job.Decode(&payload); err != nil {
+			return err
+		}
+		got <- payload.Name
+		return nil
+	})
+	startRunner(t, r)
+
+	id := enqueue(t, store, "greet", map[string]string{"Name": "Sara"})
+	job := waitForStatus(t, store, id, StatusSucceeded)
+	assert.Equal(t, 1, job.Attempts)
+	assert.Equal(t, "Sara", <-got)
+}
+
+func TestRunner_RetriesWithBackoff(t *testing.T) {
+	store := NewMemoryStore()
+	r := NewRunner(store, testOptions())
+
+	var calls atomic.Int32
+	r.Register("flaky", func(ctx context.Context, job Job) error {
+		if calls.Add(1) < 3 {
+			return errors.New("temporarily unavailable")
+		}
+		return nil
+	})
+	startRunner(t, r)
+
+	id := enqueue(t, store, "flaky", nil)
+	job := waitForStatus(t, store, id, StatusSucceeded)
+	assert.Equal(t, 3, job.Attempts)
+	assert.Equal(t, "temporarily unavailable", job.LastError)
+}
+
+func TestRunner_DeadLettersAfterMaxAttempts(t *testing.T) {
+	store := NewMemoryStore()
+	r := NewRunner(store, testOptions())
+	r.Register("broken", func(ctx context.Context, job Job) error { return errors.New("always fails") })
+	startRunner(t, r)
+
+	job, err := New("broken", nil)
+	require.NoError(t, err)
+	job.MaxAttempts = 2
+	_, err = store.Enqueue(context.Background(), job)
+	require.NoError(t, err)
+
+	job = waitForStatus(t, store, job.ID, StatusDead)
+	assert.Equal(t, 2, job.Attempts)
+	assert.Equal(t, "always fails", job.LastError)
+}
+
+func TestRunner_PermanentErrorsAndPanicsAreNotRetried(t *testing.T) {
+	store := NewMemoryStore()
+	r := NewRunner(store, testOptions())
+	r.Register("invalid", func(ctx context.Context, job Job) error { return Permanent(errors.New("bad input")) })
+	r.Register("panics", func(ctx context.Context, job Job) error { panic("boom") })
+	startRunner(t, r)
+
+	invalid := waitForStatus(t, store, enqueue(t, store, "invalid", nil), StatusDead)
+	assert.Equal(t, 1, invalid.Attempts)
+
+	panicked := waitForStatus(t, store, enqueue(t, store, "panics", nil), StatusDead)
+	assert.Equal(t, 1, panicked.Attempts)
+	assert.Contains(t, panicked.LastError, "boom")
+}
+
+func TestRunner_ShutdownWaitsForRunningJobs(t *testing.T) {
+	store := NewMemoryStore()
+	r := NewRunner(store, testOptions())
+
+	started, release := make(chan struct{}), make(chan struct{})
+	r.Register("slow", func(ctx context.Context, job Job) error {
+		close(started)
+		select {
+		case <-release:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	stop := startRunner(t, r)
+
+	id := enqueue(t, store, "slow", nil)
+	<-started
+
+	stopped := make(chan struct{})
+	go func() {
+		stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		t.Fatal("Run returned while a job was running")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+	<-stopped
+	job, _ := store.Get(id)
+	assert.Equal(t, StatusSucceeded, job.Status)
+}
+
+func TestRunner_EnqueuesRecurringJobsOncePerInterval(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Date(2026, time.October, 18, 9, 0, 30, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	// Two instances share the store
+	runners := []*Runner{NewRunner(store, testOptions()), NewRunner(store, testOptions())}
+	for _, r := range runners {
+		r.now = func() time.Time { return now }
+		r.Every("tick", time.Minute, func(ctx context.Context, job Job) error { return nil })
+		r.enqueueScheduled(context.Background())
+		r.enqueueScheduled(context.Background())
+	}
+
+	claimed, err := store.Claim(context.Background(), []string{"tick"}, 10, time.Hour)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	assert.Equal(t, "tick@2026-10-18T09:00:00Z", claimed[0].UniqueKey)
+	assert.Equal(t, 1, claimed[0].MaxAttempts)
+
+	now = now.Add(time.Minute)
+	runners[0].enqueueScheduled(context.Background())
+	claimed, err = store.Claim(context.Background(), []string{"tick"}, 10, time.Hour)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	assert.Equal(t, "tick@2026-10-18T09:01:00Z", claimed[0].UniqueKey)
+}
+
+func TestMemoryStore_ReclaimsExpiredLeases(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Date(2026, time.October, 18, 9, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	id := enqueue(t, store, "work", nil)
+	claimed, err := store.Claim(ctx, []string{"work"}, 10, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+
+	claimed, err = store.Claim(ctx, []string{"work"}, 10, time.Minute)
+	require.NoError(t, err)
+	assert.Empty(t, claimed, "the job is leased")
+
+	now = now.Add(time.Minute)
+	claimed, err = store.Claim(ctx, []string{"work"}, 10, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	assert.Equal(t, id, claimed[0].ID)
+	assert.Equal(t, 2, claimed[0].Attempts)
+}
+
+func TestMemoryStore_RejectsOutcomeAfterLeaseLost(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Date(2026, time.October, 18, 9, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	id := enqueue(t, store, "work", nil)
+	first, err := store.Claim(ctx, []string{"work"}, 10, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, first, 1)
+
+	// The first run outlasts its lease and the job is claimed again
+	now = now.Add(time.Minute)
+	second, err := store.Claim(ctx, []string{"work"}, 10, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, second, 1)
+
+	assert.ErrorIs(t, store.Complete(ctx, first[0]), ErrLeaseLost)
+	assert.ErrorIs(t, store.Retry(ctx, first[0], now, "timeout"), ErrLeaseLost)
+	assert.ErrorIs(t, store.Bury(ctx, first[0], "timeout"), ErrLeaseLost)
+	job, _ := store.Get(id)
+	assert.Equal(t, StatusRunning, job.Status)
+
+	require.NoError(t, store.Complete(ctx, second[0]))
+	job, _ = store.Get(id)
+	assert.Equal(t, StatusSucceeded, job.Status)
+	assert.ErrorIs(t, store.Complete(ctx, second[0]), ErrLeaseLost, "the job is no longer running")
+}
+
+func TestRunner_Backoff(t *testing.T) {
+	r := NewRunner(nil, Options{RetryBackoff: 30 * time.Second, MaxBackoff: 3 * time.Minute})
+
+	assert.Equal(t, 30*time.Second, r.backoff(1))
+	assert.Equal(t, time.Minute, r.backoff(2))
+	assert.Equal(t, 3*time.Minute, r.backoff(4))
+	assert.Equal(t, 3*time.Minute, r.backoff(40))
+}
@@ -0,0 +1,30 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrLeaseLost is returned when recording the outcome of a job whose lease
+// passed to another claim in the meantime.
+var ErrLeaseLost = errors.New("job lease lost")
+
+// Store persists jobs and hands them out to workers.
+type Store interface {
+	Enqueuer
+	// Claim leases up to limit due jobs of the given kinds for lease and
+	// counts the attempt. Pending jobs are due at RunAt; running jobs whose
+	// lease expired, because their worker died, are due again.
+	Claim(ctx context.Context, kinds []string, limit int, lease time.Duration) ([]Job, error)
+	// Complete, Retry and Bury record the outcome of job as returned by
+	// Claim. They return ErrLeaseLost, and change nothing, once the lease
+	// expired and the job was claimed again.
+	Complete(ctx context.Context, job Job) error
+	// Retry returns a claimed job to pending until runAt.
+	Retry(ctx context.Context, job Job, runAt time.Time, lastError string) error
+	// Bury moves a job to the dead-letter state.
+	Bury(ctx context.Context, job Job, lastError string) error
+	// DeleteSucceeded removes jobs that succeeded before the given time.
+	DeleteSucceeded(ctx context.Context, before time.Time) (int64, error)
+}
@@ -0,0 +1,75 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+
+	_ "golang.org/x/image/webp"
+)
+
+// maxImagePixels bounds the decoded size of uploads so a small, highly
+// compressed file cannot exhaust memory when it is processed.
+const maxImagePixels = 40_000_000
+
+var (
+	ErrTooLarge        = errors.New("image is too large")
+	ErrUnsupportedType = errors.New("unsupported image type, use JPEG, PNG, GIF or WebP")
+	ErrInvalidImage    = errors.New("image data is corrupt")
+)
+
+// imageExtensions maps the content types accepted for upload to the
+// extension used in storage keys.
+var imageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// Image is a validated upload. ContentType is sniffed from the data, the
+// client-supplied type and file name are never trusted.
+type Image struct {
+	Data        []byte
+	ContentType string
+	Width       int
+	Height      int
+}
+
+// Ext is the file extension matching the image's content type.
+func (i Image) Ext() string {
+	return imageExtensions[i.ContentType]
+}
+
+// ReadImage reads at most maxBytes from r and checks that the data is an
+// image of a supported type whose header decodes.
+func ReadImage(r io.Reader, maxBytes int64) (Image, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxBytes+1))
+	if err != nil {
+		return Image{}, fmt.Errorf("failed to read image: %w", err)
+	}
+	if int64(len(data)) > maxBytes {
+		return Image{}, ErrTooLarge
+	}
+
+	contentType := http.DetectContentType(data)
+	if _, ok := imageExtensions[contentType]; !ok {
+		return Image{}, ErrUnsupportedType
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Image{}, ErrInvalidImage
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxImagePixels {
+		return Image{}, ErrTooLarge
+	}
+
+	return Image{Data: data, ContentType: contentType, Width: cfg.Width, Height: cfg.Height}, nil
+}
@@ -0,0 +1,47 @@
+package media
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func pngBytes(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	img.Set(0, 0, color.RGBA{R: 255, A: 255})
+
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestReadImage(t *testing.T) {
+	data := pngBytes(t, 4, 3)
+
+	img, err := ReadImage(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", img.ContentType)
+	assert.Equal(t, ".png", img.Ext())
+	assert.Equal(t, 4, img.Width)
+	assert.Equal(t, 3, img.Height)
+}
+
+func TestReadImage_Rejects(t *testing.T) {
+	data := pngBytes(t, 4, 3)
+
+	_, err := ReadImage(bytes.NewReader(data), int64(len(data)-1))
+	assert.ErrorIs(t, err, ErrTooLarge)
+
+	_, err = ReadImage(strings.NewReader("<svg xmlns='http://www.w3.org/2000/svg'></svg>"), 1024)
+	assert.ErrorIs(t, err, ErrUnsupportedType)
+
+	_, err = ReadImage(bytes.NewReader(data[:20]), 1024)
+	assert.ErrorIs(t, err, ErrInvalidImage)
+}
@@ -0,0 +1,42 @@
+package media
+
+import (
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSigner(t *testing.T) {
+	now := time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)
+	signer := NewSigner([]byte("0123456789abcdef0123456789abcdef"), 15*time.Minute)
+	signer.now = func() time.Time { return now }
+
+	base, _ := url.Parse("https://api.example.com/media/private/labs/a.pdf")
+	signed := signer.Sign(base, "private/labs/a.pdf")
+	assert.Equal(t, "https://api.example.com/media/private/labs/a.pdf", base.String())
+	assert.Equal(t, strconv.FormatInt(now.Add(15*time.Minute).Unix(), 10), signed.Query().Get("expires"))
+
+	expiry, err := signer.Verify("private/labs/a.pdf", signed.Query())
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(15*time.Minute), expiry.UTC())
+
+	// A signature only grants access to the key it was issued for
+	_, err = signer.Verify("private/labs/b.pdf", signed.Query())
+	assert.ErrorIs(t, err, ErrSignatureInvalid)
+
+	tampered := signed.Query()
+	tampered.Set("expires", "1892325700")
+	_, err = signer.Verify("private/labs/a.pdf", tampered)
+	assert.ErrorIs(t, err, ErrSignatureInvalid)
+
+	_, err = signer.Verify("private/labs/a.pdf", url.Values{})
+	assert.ErrorIs(t, err, ErrSignatureInvalid)
+
+	now = now.Add(15 * time.Minute)
+	_, err = signer.Verify("private/labs/a.pdf", signed.Query())
+	assert.ErrorIs(t, err, ErrLinkExpired)
+}
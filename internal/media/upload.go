@@ -0,0 +1,49 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/logging"
+	"github.com/shayesteh1hs/DrAppointment/internal/storage"
+)
+
+// Store saves img under dir with a fresh file name, along with its resized
+// variants, and returns its key. Keys are never reused, so stored objects can
+// be cached forever.
+func Store(ctx context.Context, store storage.Storage, dir string, img Image) (string, error) {
+	key := dir + "/" + uuid.NewString() + img.Ext()
+	if err := store.Put(ctx, key, bytes.NewReader(img.Data), int64(len(img.Data)), img.ContentType); err != nil {
+		return "", err
+	}
+	// Variants missing here are generated when first requested
+	if err := StoreVariants(ctx, store, key, img); err != nil {
+		logging.FromContext(ctx).Warn("failed to generate image variants", "key", key, "error", err)
+	}
+	return key, nil
+}
+
+// Discard deletes a key that is no longer referenced and its variants,
+// provided it lives under dir; keys from before uploads existed are left
+// alone. Failures only leave orphaned objects behind, so they are logged
+// rather than returned.
+func Discard(ctx context.Context, store storage.Storage, dir, key string) {
+	if !strings.HasPrefix(key, dir+"/") {
+		return
+	}
+
+	keys := []string{key}
+	for _, size := range VariantSizes {
+		for _, f := range VariantFormats {
+			keys = append(keys, VariantKey(key, size, f))
+		}
+	}
+	for _, k := range keys {
+		if err := store.Delete(ctx, k); err != nil {
+			logging.FromContext(ctx).Warn("failed to delete replaced media", "key", k, "error", err)
+		}
+	}
+}
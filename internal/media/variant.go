@@ -0,0 +1,188 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"maps"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/HugoSmits86/nativewebp"
+	"golang.org/x/image/draw"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/logging"
+	"github.com/shayesteh1hs/DrAppointment/internal/storage"
+)
+
+const (
+	variantJPEGQuality = 82
+	// maxVariantSourceBytes bounds originals read back for on-demand
+	// variants; uploads are limited further by configuration.
+	maxVariantSourceBytes = 32 << 20
+)
+
+// Format is an encoding resized variants are generated in.
+type Format struct {
+	Name        string
+	Ext         string
+	ContentType string
+	encode      func(w io.Writer, img image.Image) error
+}
+
+var (
+	// WebP variants are lossless; the stdlib has no lossy WebP encoder.
+	WebP = Format{Name: "webp", Ext: ".webp", ContentType: "image/webp", encode: encodeWebP}
+	JPEG = Format{Name: "jpeg", Ext: ".jpg", ContentType: "image/jpeg", encode: encodeJPEG}
+)
+
+var (
+	// VariantSizes are the bounding boxes, in pixels, variants are fitted
+	// into. Images are never upscaled.
+	VariantSizes = []int{64, 128, 512}
+	// VariantFormats are the encodings every size is generated in.
+	VariantFormats = []Format{WebP, JPEG}
+)
+
+// VariantKey is the storage key of the variant of the image at key. It is
+// derived from the original key so variants can be generated on demand.
+func VariantKey(key string, size int, f Format) string {
+	return key + "." + strconv.Itoa(size) + f.Ext
+}
+
+// ParseVariantKey splits a key built by VariantKey into the original key,
+// size and format. ok is false for any other key.
+func ParseVariantKey(variantKey string) (key string, size int, f Format, ok bool) {
+	ext := path.Ext(variantKey)
+	i := slices.IndexFunc(VariantFormats, func(f Format) bool { return f.Ext == ext })
+	if i < 0 {
+		return "", 0, Format{}, false
+	}
+	f = VariantFormats[i]
+
+	rest := strings.TrimSuffix(variantKey, ext)
+	dot := strings.LastIndexByte(rest, '.')
+	if dot < 0 {
+		return "", 0, Format{}, false
+	}
+	size, err := strconv.Atoi(rest[dot+1:])
+	if err != nil || !slices.Contains(VariantSizes, size) {
+		return "", 0, Format{}, false
+	}
+
+	key = rest[:dot]
+	if !HasVariants(key) {
+		return "", 0, Format{}, false
+	}
+	return key, size, f, true
+}
+
+// HasVariants reports whether the object at key is an image variants can be
+// generated from, judging by its extension.
+func HasVariants(key string) bool {
+	return slices.Contains(slices.Collect(maps.Values(imageExtensions)), path.Ext(key))
+}
+
+// StoreVariants generates and stores every variant of img, which is stored
+// at key.
+func StoreVariants(ctx context.Context, store storage.Storage, key string, img Image) error {
+	src, _, err := image.Decode(bytes.NewReader(img.Data))
+	if err != nil {
+		return ErrInvalidImage
+	}
+
+	for _, size := range VariantSizes {
+		resized := Resize(src, size)
+		for _, f := range VariantFormats {
+			data, err := encode(resized, f)
+			if err != nil {
+				return err
+			}
+			variantKey := VariantKey(key, size, f)
+			if err := store.Put(ctx, variantKey, bytes.NewReader(data), int64(len(data)), f.ContentType); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// LoadVariant generates a missing variant from its original image and stores
+// it for the next request. It returns storage.ErrNotFound when variantKey is
+// not a variant key or the original does not exist.
+func LoadVariant(ctx context.Context, store storage.Storage, variantKey string) ([]byte, Format, error) {
+	key, size, f, ok := ParseVariantKey(variantKey)
+	if !ok {
+		return nil, Format{}, storage.ErrNotFound
+	}
+
+	obj, err := store.Get(ctx, key)
+	if err != nil {
+		return nil, Format{}, err
+	}
+	defer func() { _ = obj.Body.Close() }()
+
+	img, err := ReadImage(obj.Body, maxVariantSourceBytes)
+	if err != nil {
+		return nil, Format{}, fmt.Errorf("failed to read %s: %w", key, err)
+	}
+	src, _, err := image.Decode(bytes.NewReader(img.Data))
+	if err != nil {
+		return nil, Format{}, fmt.Errorf("failed to decode %s: %w", key, err)
+	}
+
+	data, err := encode(Resize(src, size), f)
+	if err != nil {
+		return nil, Format{}, err
+	}
+	if err := store.Put(ctx, variantKey, bytes.NewReader(data), int64(len(data)), f.ContentType); err != nil {
+		// The variant is still served; the next request retries the write
+		logging.FromContext(ctx).Warn("failed to store image variant", "key", variantKey, "error", err)
+	}
+	return data, f, nil
+}
+
+// Resize scales src down to fit a size x size box, keeping its aspect ratio.
+func Resize(src image.Image, size int) image.Image {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= size && h <= size {
+		return src
+	}
+
+	if w >= h {
+		w, h = size, max(1, h*size/w)
+	} else {
+		w, h = max(1, w*size/h), size
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, b, draw.Src, nil)
+	return dst
+}
+
+func encode(img image.Image, f Format) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := f.encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode %s variant: %w", f.Name, err)
+	}
+	return buf.Bytes(), nil
+}
+
+func encodeWebP(w io.Writer, img image.Image) error {
+	return nativewebp.Encode(w, img, nil)
+}
+
+// encodeJPEG flattens transparent areas onto white, as JPEG has no alpha.
+func encodeJPEG(w io.Writer, img image.Image) error {
+	b := img.Bounds()
+	flat := image.NewRGBA(b)
+	draw.Draw(flat, b, image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(flat, b, img, b.Min, draw.Over)
+	return jpeg.Encode(w, flat, &jpeg.Options{Quality: variantJPEGQuality})
+}
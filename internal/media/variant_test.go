@@ -0,0 +1,100 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	_ "golang.org/x/image/webp"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/storage"
+)
+
+func TestParseVariantKey(t *testing.T) {
+	variantKey := VariantKey("doctors/a/b.png", 128, WebP)
+	assert.Equal(t, "doctors/a/b.png.128.webp", variantKey)
+
+	key, size, f, ok := ParseVariantKey(variantKey)
+	require.True(t, ok)
+	assert.Equal(t, "doctors/a/b.png", key)
+	assert.Equal(t, 128, size)
+	assert.Equal(t, WebP.Name, f.Name)
+
+	for _, k := range []string{"doctors/a/b.png", "doctors/a/b.jpg", "doctors/a/b.png.100.webp", "doctors/a/b.pdf.64.jpg", "b.64.webp"} {
+		_, _, _, ok := ParseVariantKey(k)
+		assert.False(t, ok, k)
+	}
+}
+
+func TestResize(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 1000, 500))
+
+	assert.Equal(t, image.Rect(0, 0, 128, 64), Resize(src, 128).Bounds())
+	assert.Equal(t, image.Rect(0, 0, 64, 128), Resize(image.NewRGBA(image.Rect(0, 0, 500, 1000)), 128).Bounds())
+	// Smaller images are never upscaled
+	assert.Same(t, src, Resize(src, 1024))
+}
+
+func TestStore_GeneratesVariants(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewLocalStorage(t.TempDir())
+
+	data := pngBytes(t, 600, 300)
+	img, err := ReadImage(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+
+	key, err := Store(ctx, store, "specialties/x", img)
+	require.NoError(t, err)
+
+	for _, size := range VariantSizes {
+		for _, f := range VariantFormats {
+			obj, err := store.Get(ctx, VariantKey(key, size, f))
+			require.NoError(t, err, "%d %s", size, f.Name)
+			cfg, format, err := image.DecodeConfig(obj.Body)
+			_ = obj.Body.Close()
+			require.NoError(t, err)
+			assert.Equal(t, f.Name, format)
+			assert.Equal(t, min(size, 600), cfg.Width)
+		}
+	}
+
+	Discard(ctx, store, "specialties/x", key)
+	_, err = store.Get(ctx, VariantKey(key, 64, JPEG))
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	_, err = store.Get(ctx, key)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+}
+
+func TestLoadVariant_GeneratesAndStoresMissingVariant(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewLocalStorage(t.TempDir())
+
+	data := pngBytes(t, 200, 100)
+	require.NoError(t, store.Put(ctx, "specialties/legacy.png", bytes.NewReader(data), int64(len(data)), "image/png"))
+
+	variantKey := VariantKey("specialties/legacy.png", 64, JPEG)
+	out, f, err := LoadVariant(ctx, store, variantKey)
+	require.NoError(t, err)
+	assert.Equal(t, JPEG.Name, f.Name)
+
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(out))
+	require.NoError(t, err)
+	assert.Equal(t, "jpeg", format)
+	assert.Equal(t, [2]int{64, 32}, [2]int{cfg.Width, cfg.Height})
+
+	obj, err := store.Get(ctx, variantKey)
+	require.NoError(t, err)
+	stored, _ := io.ReadAll(obj.Body)
+	_ = obj.Body.Close()
+	assert.Equal(t, out, stored)
+
+	_, _, err = LoadVariant(ctx, store, VariantKey("specialties/missing.png", 64, JPEG))
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	_, _, err = LoadVariant(ctx, store, "specialties/legacy.png")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+}
@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"database/sql"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/cache"
+)
+
+// NewDBStatsCollectors exposes sql.DB.Stats() pool gauges for every pool,
+// labelled with db_name set to the pool's name (primary or replica host).
+func NewDBStatsCollectors(pools map[string]*sql.DB) []prometheus.Collector {
+	cs := make([]prometheus.Collector, 0, len(pools))
+	for name, db := range pools {
+		cs = append(cs, collectors.NewDBStatsCollector(db, name))
+	}
+	return cs
+}
+
+type cacheStatser interface {
+	Stats() cache.Stats
+}
+
+type cacheCollector struct {
+	cache     cacheStatser
+	hits      *prometheus.Desc
+	misses    *prometheus.Desc
+	evictions *prometheus.Desc
+	size      *prometheus.Desc
+}
+
+// NewCacheCollector exposes the hit/miss statistics of a caching repository
+// under the given cache label.
+func NewCacheCollector(name string, c cacheStatser) prometheus.Collector {
+	labels := prometheus.Labels{"cache": name}
+	return &cacheCollector{
+		cache: c,
+		hits: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "cache", "hits_total"),
+			"Number of cache lookups served from memory.",
+			nil, labels,
+		),
+		misses: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "cache", "misses_total"),
+			"Number of cache lookups that went to the backing store.",
+			nil, labels,
+		),
+		evictions: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "cache", "evictions_total"),
+			"Number of entries evicted to respect the cache capacity.",
+			nil, labels,
+		),
+		size: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "cache", "entries"),
+			"Number of entries currently cached.",
+			nil, labels,
+		),
+	}
+}
+
+func (c *cacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.hits
+	ch <- c.misses
+	ch <- c.evictions
+	ch <- c.size
+}
+
+func (c *cacheCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.cache.Stats()
+	ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(stats.Hits))
+	ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(stats.Misses))
+	ch <- prometheus.MustNewConstMetric(c.evictions, prometheus.CounterValue, float64(stats.Evictions))
+	ch <- prometheus.MustNewConstMetric(c.size, prometheus.GaugeValue, float64(stats.Size))
+}
@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "drgo"
+
+// Registry holds every collector exposed on /metrics. A dedicated registry
+// keeps third-party packages from leaking metrics through the default one.
+var Registry = prometheus.NewRegistry()
+
+// Business event counters. Services increment these directly once the
+// change is committed.
+var (
+	AppointmentsBooked = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "appointments_booked_total",
+		Help:      "Number of appointments booked.",
+	})
+	AppointmentsCancelled = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "appointments_cancelled_total",
+		Help:      "Number of appointments cancelled.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequestDuration,
+		httpRequestsInFlight,
+		AppointmentsBooked,
+		AppointmentsCancelled,
+	)
+}
+
+// Register adds collectors to Registry. Registering an identical collector
+// twice is ignored so router setup can run more than once (e.g. in tests).
+func Register(cs ...prometheus.Collector) {
+	for _, c := range cs {
+		if err := Registry.Register(c); err != nil {
+			var alreadyRegistered prometheus.AlreadyRegisteredError
+			if errors.As(err, &alreadyRegistered) {
+				continue
+			}
+			slog.Error("failed to register metrics collector", "error", err)
+		}
+	}
+}
+
+// Handler serves Registry in the Prometheus text exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
@@ -0,0 +1,75 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/cache"
+)
+
+func scrape(t *testing.T) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	return w.Body.String()
+}
+
+func TestMiddleware_LabelsByRouteTemplate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware())
+	router.GET("/doctors/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/doctors/123", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/does-not-exist", nil))
+
+	body := scrape(t)
+	assert.Contains(t, body, `drgo_http_request_duration_seconds_count{method="GET",route="/doctors/:id",status="200"} 1`)
+	assert.Contains(t, body, `drgo_http_request_duration_seconds_count{method="GET",route="unmatched",status="404"} 1`)
+	assert.NotContains(t, body, "/doctors/123")
+}
+
+func TestBusinessCounters(t *testing.T) {
+	before := testutil.ToFloat64(AppointmentsBooked)
+	AppointmentsBooked.Inc()
+	assert.Equal(t, before+1, testutil.ToFloat64(AppointmentsBooked))
+
+	assert.Contains(t, scrape(t), "drgo_appointments_cancelled_total")
+}
+
+func TestDBStatsCollectors(t *testing.T) {
+	db, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	Register(NewDBStatsCollectors(map[string]*sql.DB{"primary": db})...)
+
+	assert.Contains(t, scrape(t), `go_sql_open_connections{db_name="primary"}`)
+}
+
+type fakeCache struct{ stats cache.Stats }
+
+func (f fakeCache) Stats() cache.Stats { return f.stats }
+
+func TestCacheCollector(t *testing.T) {
+	Register(
+		NewCacheCollector("doctor", fakeCache{stats: cache.Stats{Hits: 3, Misses: 1, Size: 1}}),
+		NewCacheCollector("specialty", fakeCache{stats: cache.Stats{Hits: 5}}),
+	)
+
+	body := scrape(t)
+	assert.Contains(t, body, `drgo_cache_hits_total{cache="doctor"} 3`)
+	assert.Contains(t, body, `drgo_cache_misses_total{cache="doctor"} 1`)
+	assert.Contains(t, body, `drgo_cache_hits_total{cache="specialty"} 5`)
+	assert.True(t, strings.Contains(body, `drgo_cache_entries{cache="doctor"} 1`))
+}
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireToken only lets requests through that carry token as a bearer
+// token in the Authorization header. It guards operator routes until there
+// is user authentication.
+func RequireToken(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		given, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || token == "" || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			c.Header("WWW-Authenticate", `Bearer realm="admin"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, ErrorResponse{
+				Status:  http.StatusUnauthorized,
+				Message: "Missing or invalid API token",
+			})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,17 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/database"
+)
+
+// DBSession gives each request its own read-your-writes scope, so reads
+// after the request's own writes skip lagging replicas without holding
+// back other requests.
+func DBSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(database.WithSession(c.Request.Context()))
+		c.Next()
+	}
+}
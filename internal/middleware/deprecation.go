@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DeprecationOptions struct {
+	// Since is when the route was deprecated.
+	Since time.Time
+	// Sunset is when the route stops working; zero omits the header.
+	Sunset time.Time
+	// Successor returns the replacement URL for the request, sent as a
+	// successor-version link. Nil omits the link.
+	Successor func(c *gin.Context) string
+}
+
+// Deprecated marks responses of a route group with the Deprecation (RFC
+// 9745) and Sunset (RFC 8594) headers, so clients can detect and log use of
+// routes that are going away.
+func Deprecated(opts DeprecationOptions) gin.HandlerFunc {
+	deprecation := "@" + strconv.FormatInt(opts.Since.Unix(), 10)
+	var sunset string
+	if !opts.Sunset.IsZero() {
+		sunset = opts.Sunset.UTC().Format(http.TimeFormat)
+	}
+
+	return func(c *gin.Context) {
+		c.Header("Deprecation", deprecation)
+		if sunset != "" {
+			c.Header("Sunset", sunset)
+		}
+		if opts.Successor != nil {
+			c.Writer.Header().Add("Link", "<"+opts.Successor(c)+">; rel=\"successor-version\"")
+		}
+		c.Next()
+	}
+}
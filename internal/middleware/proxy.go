@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/utils"
+)
+
+// ForwardedHeaders records X-Forwarded-Proto and X-Forwarded-Host for
+// requests whose direct peer is one of trustedProxies (IPs or CIDRs), so
+// links and HSTS reflect what the client saw. Headers from any other peer
+// are ignored since clients could spoof them.
+func ForwardedHeaders(trustedProxies []string) (gin.HandlerFunc, error) {
+	prefixes, err := parsePrefixes(trustedProxies)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(c *gin.Context) {
+		if len(prefixes) == 0 || !isTrusted(c.Request.RemoteAddr, prefixes) {
+			c.Next()
+			return
+		}
+
+		if proto := strings.ToLower(firstValue(c.GetHeader("X-Forwarded-Proto"))); proto == "http" || proto == "https" {
+			c.Set(utils.ForwardedProtoKey, proto)
+		}
+		if host := firstValue(c.GetHeader("X-Forwarded-Host")); host != "" && !strings.ContainsAny(host, "/\\@ ") {
+			c.Set(utils.ForwardedHostKey, host)
+		}
+
+		c.Next()
+	}, nil
+}
+
+func parsePrefixes(values []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(values))
+	for _, value := range values {
+		if strings.Contains(value, "/") {
+			prefix, err := netip.ParsePrefix(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", value, err)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+
+		addr, err := netip.ParseAddr(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", value, err)
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
+func isTrusted(remoteAddr string, prefixes []netip.Prefix) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// firstValue returns the client-most entry of a comma separated header that
+// proxies may have appended to.
+func firstValue(header string) string {
+	value, _, _ := strings.Cut(header, ",")
+	return strings.TrimSpace(value)
+}
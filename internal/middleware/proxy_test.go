@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/utils"
+)
+
+func setupProxyRouter(t *testing.T, trustedProxies []string) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	forwarded, err := ForwardedHeaders(trustedProxies)
+	require.NoError(t, err)
+
+	router := gin.New()
+	router.Use(forwarded, SecurityHeaders(SecurityHeadersOptions{HSTSMaxAge: time.Hour}))
+	router.GET("/api/medical/doctors", func(c *gin.Context) {
+		c.String(http.StatusOK, utils.BuildBaseURL(c))
+	})
+	return router
+}
+
+func forwardedRequest(remoteAddr string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/api/medical/doctors?page=2", nil)
+	req.RemoteAddr = remoteAddr
+	req.Host = "10.0.0.5:8000"
+	req.Header.Set("X-Forwarded-Proto", "https")
+	req.Header.Set("X-Forwarded-Host", "api.example.com, internal.lb")
+	return req
+}
+
+func TestForwardedHeaders_TrustedProxy(t *testing.T) {
+	router := setupProxyRouter(t, []string{"10.0.0.0/8"})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, forwardedRequest("10.1.2.3:51000"))
+
+	assert.Equal(t, "https://api.example.com/api/medical/doctors", w.Body.String())
+	assert.Equal(t, "max-age=3600; includeSubDomains", w.Header().Get("Strict-Transport-Security"))
+	assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+}
+
+func TestForwardedHeaders_UntrustedPeerIsIgnored(t *testing.T) {
+	router := setupProxyRouter(t, []string{"10.0.0.0/8"})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, forwardedRequest("203.0.113.7:51000"))
+
+	assert.Equal(t, "http://10.0.0.5:8000/api/medical/doctors", w.Body.String())
+	assert.Empty(t, w.Header().Get("Strict-Transport-Security"))
+}
+
+func TestForwardedHeaders_TLSWithoutProxy(t *testing.T) {
+	router := setupProxyRouter(t, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/medical/doctors", nil)
+	req.Host = "api.example.com"
+	req.TLS = &tls.ConnectionState{}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, "https://api.example.com/api/medical/doctors", w.Body.String())
+	assert.NotEmpty(t, w.Header().Get("Strict-Transport-Security"))
+}
+
+func TestForwardedHeaders_InvalidProxy(t *testing.T) {
+	_, err := ForwardedHeaders([]string{"not-an-ip"})
+	assert.Error(t, err)
+}
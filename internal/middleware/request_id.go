@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"log/slog"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/logging"
+)
+
+const (
+	RequestIDHeader = "X-Request-ID"
+	RequestIDKey    = "request_id"
+)
+
+// Client supplied IDs are echoed into logs and headers, so only accept short
+// tokens without whitespace or control characters.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestID accepts the caller's X-Request-ID or generates one, echoes it in
+// the response and stores it, together with a logger carrying the request ID
+// and route (plus the trace ID when the tracing middleware ran first), in the
+// request context.
+func RequestID(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID.MatchString(requestID) {
+			requestID = uuid.NewString()
+		}
+
+		c.Set(RequestIDKey, requestID)
+		c.Header(RequestIDHeader, requestID)
+
+		requestLogger := logger.With(
+			slog.String("request_id", requestID),
+			slog.String("route", c.FullPath()),
+		)
+		if spanCtx := trace.SpanContextFromContext(c.Request.Context()); spanCtx.HasTraceID() {
+			requestLogger = requestLogger.With(slog.String("trace_id", spanCtx.TraceID().String()))
+		}
+
+		ctx := logging.WithRequestID(c.Request.Context(), requestID)
+		ctx = logging.WithLogger(ctx, requestLogger)
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+	}
+}
+
+func GetRequestID(c *gin.Context) string {
+	return c.GetString(RequestIDKey)
+}
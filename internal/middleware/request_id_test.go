@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/logging"
+)
+
+func setupLoggingRouter(buf *bytes.Buffer) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	logger := slog.New(slog.NewJSONHandler(buf, nil))
+
+	router := gin.New()
+	router.Use(RequestID(logger), RequestLogger(), Recovery())
+	router.GET("/doctors/:id", func(c *gin.Context) {
+		logging.FromContext(c.Request.Context()).Info("handler called")
+		c.JSON(http.StatusOK, gin.H{"request_id": logging.RequestIDFromContext(c.Request.Context())})
+	})
+	router.GET("/panic", func(c *gin.Context) {
+		panic("boom")
+	})
+	return router
+}
+
+func decodeLogLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var entries []map[string]any
+	decoder := json.NewDecoder(buf)
+	for decoder.More() {
+		var entry map[string]any
+		require.NoError(t, decoder.Decode(&entry))
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestRequestID_GeneratesID(t *testing.T) {
+	var buf bytes.Buffer
+	router := setupLoggingRouter(&buf)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/doctors/1", nil))
+
+	requestID := w.Header().Get(RequestIDHeader)
+	_, err := uuid.Parse(requestID)
+	require.NoError(t, err)
+	assert.Contains(t, w.Body.String(), requestID)
+}
+
+func TestRequestID_AcceptsClientID(t *testing.T) {
+	var buf bytes.Buffer
+	router := setupLoggingRouter(&buf)
+
+	req := httptest.NewRequest(http.MethodGet, "/doctors/1", nil)
+	req.Header.Set(RequestIDHeader, "client-req-42")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, "client-req-42", w.Header().Get(RequestIDHeader))
+}
+
+func TestRequestID_RejectsMalformedClientID(t *testing.T) {
+	var buf bytes.Buffer
+	router := setupLoggingRouter(&buf)
+
+	req := httptest.NewRequest(http.MethodGet, "/doctors/1", nil)
+	req.Header.Set(RequestIDHeader, "bad id\nwith newline")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.NotEqual(t, "bad id\nwith newline", w.Header().Get(RequestIDHeader))
+	assert.NotEmpty(t, w.Header().Get(RequestIDHeader))
+}
+
+func TestRequestLogger_LogsWithRequestContext(t *testing.T) {
+	var buf bytes.Buffer
+	router := setupLoggingRouter(&buf)
+
+	req := httptest.NewRequest(http.MethodGet, "/doctors/1", nil)
+	req.Header.Set(RequestIDHeader, "req-1")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	entries := decodeLogLines(t, &buf)
+	require.Len(t, entries, 2)
+
+	assert.Equal(t, "handler called", entries[0]["msg"])
+	assert.Equal(t, "req-1", entries[0]["request_id"])
+
+	access := entries[1]
+	assert.Equal(t, "request completed", access["msg"])
+	assert.Equal(t, "req-1", access["request_id"])
+	assert.Equal(t, "/doctors/:id", access["route"])
+	assert.Equal(t, float64(http.StatusOK), access["status"])
+	assert.Contains(t, access, "latency")
+}
+
+func TestRecovery_LogsPanicAsError(t *testing.T) {
+	var buf bytes.Buffer
+	router := setupLoggingRouter(&buf)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	entries := decodeLogLines(t, &buf)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "panic recovered", entries[0]["msg"])
+	assert.Equal(t, "ERROR", entries[1]["level"])
+}
@@ -0,0 +1,161 @@
+// Package money handles prices as whole amounts of a currency's smallest
+// unit, so no arithmetic on them is ever done in floating point.
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrUnknownCurrency  = errors.New("unknown currency")
+	ErrCurrencyMismatch = errors.New("amounts are in different currencies")
+	ErrOverflow         = errors.New("amount is out of range")
+	// ErrInexact is returned when converting an amount the target currency
+	// cannot represent, such as 15 rials in tomans.
+	ErrInexact = errors.New("amount cannot be converted exactly")
+)
+
+type Currency string
+
+const (
+	// IRR is the Iranian rial, the currency prices are stored in.
+	IRR Currency = "IRR"
+	// IRT is the toman, ten rials. It is not an ISO 4217 code but is what
+	// prices are usually quoted in.
+	IRT Currency = "IRT"
+)
+
+// rials is how many rials one unit of each currency is worth.
+var rials = map[Currency]int64{
+	IRR: 1,
+	IRT: 10,
+}
+
+// ParseCurrency reads a currency code, case-insensitively.
+func ParseCurrency(s string) (Currency, error) {
+	c := Currency(strings.ToUpper(strings.TrimSpace(s)))
+	if _, ok := rials[c]; !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnknownCurrency, s)
+	}
+	return c, nil
+}
+
+// Money is an amount of a currency in whole units.
+type Money struct {
+	Amount   int64
+	Currency Currency
+}
+
+func New(amount int64, currency Currency) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// Rials returns amount rials.
+func Rials(amount int64) Money {
+	return New(amount, IRR)
+}
+
+// In converts m to currency, failing rather than rounding when it has no
+// exact equivalent.
+func (m Money) In(currency Currency) (Money, error) {
+	from, ok := rials[m.Currency]
+	if !ok {
+		return Money{}, fmt.Errorf("%w: %q", ErrUnknownCurrency, m.Currency)
+	}
+	to, ok := rials[currency]
+	if !ok {
+		return Money{}, fmt.Errorf("%w: %q", ErrUnknownCurrency, currency)
+	}
+	if from == to {
+		return New(m.Amount, currency), nil
+	}
+
+	inRials, err := mul(m.Amount, from)
+	if err != nil {
+		return Money{}, err
+	}
+	if inRials%to != 0 {
+		return Money{}, fmt.Errorf("%w: %s in %s", ErrInexact, m, currency)
+	}
+	return New(inRials/to, currency), nil
+}
+
+// Add returns m + other, which must be in the same currency.
+func (m Money) Add(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+	}
+	if (other.Amount > 0 && m.Amount > math.MaxInt64-other.Amount) ||
+		(other.Amount < 0 && m.Amount < math.MinInt64-other.Amount) {
+		return Money{}, ErrOverflow
+	}
+	return New(m.Amount+other.Amount, m.Currency), nil
+}
+
+// Sub returns m - other, which must be in the same currency.
+func (m Money) Sub(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+	}
+	if (other.Amount < 0 && m.Amount > math.MaxInt64+other.Amount) ||
+		(other.Amount > 0 && m.Amount < math.MinInt64+other.Amount) {
+		return Money{}, ErrOverflow
+	}
+	return New(m.Amount-other.Amount, m.Currency), nil
+}
+
+// Percent returns percent of m, rounded towards zero.
+func (m Money) Percent(percent int64) (Money, error) {
+	product, err := mul(m.Amount, percent)
+	if err != nil {
+		return Money{}, err
+	}
+	return New(product/100, m.Currency), nil
+}
+
+// Compare returns -1, 0 or +1 as m is less than, equal to or greater than
+// other, which must be in the same currency.
+func (m Money) Compare(other Money) (int, error) {
+	if m.Currency != other.Currency {
+		return 0, fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+	}
+	switch {
+	case m.Amount < other.Amount:
+		return -1, nil
+	case m.Amount > other.Amount:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// String formats m with thousands separators, as in "1,500,000 IRR".
+func (m Money) String() string {
+	digits := strconv.FormatInt(m.Amount, 10)
+	sign := ""
+	if strings.HasPrefix(digits, "-") {
+		sign, digits = "-", digits[1:]
+	}
+	var b strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(d)
+	}
+	return sign + b.String() + " " + string(m.Currency)
+}
+
+func mul(a, b int64) (int64, error) {
+	if a == 0 || b == 0 {
+		return 0, nil
+	}
+	product := a * b
+	if product/b != a {
+		return 0, ErrOverflow
+	}
+	return product, nil
+}
@@ -0,0 +1,85 @@
+package money
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMoney_In(t *testing.T) {
+	tests := []struct {
+		name    string
+		from    Money
+		to      Currency
+		want    Money
+		wantErr error
+	}{
+		{name: "tomans to rials", from: New(150_000, IRT), to: IRR, want: Rials(1_500_000)},
+		{name: "rials to tomans", from: Rials(1_500_000), to: IRT, want: New(150_000, IRT)},
+		{name: "same currency", from: Rials(15), to: IRR, want: Rials(15)},
+		{name: "fraction of a toman", from: Rials(15), to: IRT, wantErr: ErrInexact},
+		{name: "overflow", from: New(math.MaxInt64/5, IRT), to: IRR, wantErr: ErrOverflow},
+		{name: "unknown currency", from: New(1, "USD"), to: IRR, wantErr: ErrUnknownCurrency},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.from.In(tt.to)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMoney_Add(t *testing.T) {
+	sum, err := Rials(1_000).Add(Rials(500))
+	require.NoError(t, err)
+	assert.Equal(t, Rials(1_500), sum)
+
+	_, err = Rials(1_000).Add(New(50, IRT))
+	assert.ErrorIs(t, err, ErrCurrencyMismatch)
+
+	_, err = Rials(math.MaxInt64).Add(Rials(1))
+	assert.ErrorIs(t, err, ErrOverflow)
+
+	diff, err := Rials(1_000).Sub(Rials(1_500))
+	require.NoError(t, err)
+	assert.Equal(t, Rials(-500), diff)
+
+	_, err = Rials(1_000).Sub(New(50, IRT))
+	assert.ErrorIs(t, err, ErrCurrencyMismatch)
+
+	_, err = Rials(math.MinInt64).Sub(Rials(1))
+	assert.ErrorIs(t, err, ErrOverflow)
+
+	fee, err := Rials(333).Percent(50)
+	require.NoError(t, err)
+	assert.Equal(t, Rials(166), fee, "rounded down")
+
+	_, err = Rials(math.MaxInt64).Percent(50)
+	assert.ErrorIs(t, err, ErrOverflow)
+
+	cmp, err := Rials(1_000).Compare(Rials(500))
+	require.NoError(t, err)
+	assert.Equal(t, 1, cmp)
+}
+
+func TestParseCurrency(t *testing.T) {
+	c, err := ParseCurrency(" irt ")
+	require.NoError(t, err)
+	assert.Equal(t, IRT, c)
+
+	_, err = ParseCurrency("USD")
+	assert.ErrorIs(t, err, ErrUnknownCurrency)
+}
+
+func TestMoney_String(t *testing.T) {
+	assert.Equal(t, "1,500,000 IRR", Rials(1_500_000).String())
+	assert.Equal(t, "150 IRT", New(150, IRT).String())
+	assert.Equal(t, "-1,000 IRR", Rials(-1_000).String())
+}
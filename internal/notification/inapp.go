@@ -0,0 +1,58 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// InboxMessage is a notification shown inside the application.
+type InboxMessage struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Subject   string
+	Body      string
+	ReadAt    *time.Time
+	CreatedAt time.Time
+}
+
+// Inbox stores in-app notifications per user.
+type Inbox interface {
+	Add(ctx context.Context, msg InboxMessage) error
+	// List returns up to limit of the user's messages, newest first.
+	List(ctx context.Context, userID uuid.UUID, limit int) ([]InboxMessage, error)
+	// MarkRead marks one of the user's messages read. Unknown and already
+	// read messages are ignored.
+	MarkRead(ctx context.Context, userID, id uuid.UUID) error
+}
+
+// InAppProvider delivers notifications to the recipient's inbox.
+type InAppProvider struct {
+	inbox Inbox
+	now   func() time.Time
+}
+
+func NewInAppProvider(inbox Inbox) *InAppProvider {
+	return &InAppProvider{inbox: inbox, now: time.Now}
+}
+
+func (p *InAppProvider) Channel() Channel { return ChannelInApp }
+
+func (p *InAppProvider) Send(ctx context.Context, msg Message) error {
+	if msg.UserID == uuid.Nil {
+		return Permanent(errors.New("in-app notification has no user"))
+	}
+	id, err := uuid.NewV7()
+	if err != nil {
+		return err
+	}
+	return p.inbox.Add(ctx, InboxMessage{
+		ID:        id,
+		UserID:    msg.UserID,
+		Subject:   msg.Subject,
+		Body:      msg.Body,
+		CreatedAt: p.now(),
+	})
+}
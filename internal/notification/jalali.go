@@ -0,0 +1,55 @@
+package notification
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+var jalaliMonths = [...]string{"فروردین", "اردیبهشت", "خرداد", "تیر", "مرداد", "شهریور", "مهر", "آبان", "آذر", "دی", "بهمن", "اسفند"}
+
+var persianDigits = strings.NewReplacer("0", "۰", "1", "۱", "2", "۲", "3", "۳", "4", "۴", "5", "۵", "6", "۶", "7", "۷", "8", "۸", "9", "۹")
+
+// toJalali converts a Gregorian date to the Solar Hijri calendar used in
+// Iran.
+func toJalali(t time.Time) (year, month, day int) {
+	gy, gm, gd := t.Date()
+	daysBeforeMonth := [...]int{0, 31, 59, 90, 120, 151, 181, 212, 243, 273, 304, 334}
+
+	gy2 := gy
+	if gm > 2 {
+		gy2++
+	}
+	days := 355666 + 365*gy + (gy2+3)/4 - (gy2+99)/100 + (gy2+399)/400 + gd + daysBeforeMonth[gm-1]
+
+	year = -1595 + 33*(days/12053)
+	days %= 12053
+	year += 4 * (days / 1461)
+	days %= 1461
+	if days > 365 {
+		year += (days - 1) / 365
+		days = (days - 1) % 365
+	}
+
+	if days < 186 {
+		return year, 1 + days/31, 1 + days%31
+	}
+	return year, 7 + (days-186)/30, 1 + (days-186)%30
+}
+
+// formatDate renders a date the way recipients in locale read it, e.g.
+// "۲۶ مهر ۱۴۰۵" or "Sunday, October 18, 2026".
+func formatDate(locale string, t time.Time) string {
+	if locale != "fa" {
+		return t.Format("Monday, January 2, 2006")
+	}
+	year, month, day := toJalali(t)
+	return persianDigits.Replace(strconv.Itoa(day) + " " + jalaliMonths[month-1] + " " + strconv.Itoa(year))
+}
+
+func formatTime(locale string, t time.Time) string {
+	if locale != "fa" {
+		return t.Format("15:04")
+	}
+	return persianDigits.Replace(t.Format("15:04"))
+}
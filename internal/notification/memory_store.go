@@ -0,0 +1,157 @@
+package notification
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MemoryStore keeps preferences and deliveries in process memory, for tests
+// and single instance deployments.
+type MemoryStore struct {
+	mu          sync.Mutex
+	preferences map[uuid.UUID]Preferences
+	deliveries  map[uuid.UUID]Delivery
+	now         func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		preferences: make(map[uuid.UUID]Preferences),
+		deliveries:  make(map[uuid.UUID]Delivery),
+		now:         time.Now,
+	}
+}
+
+func (s *MemoryStore) Preferences(ctx context.Context, userID uuid.UUID) (Preferences, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prefs := Preferences{}
+	for channel, enabled := range s.preferences[userID] {
+		prefs[channel] = enabled
+	}
+	return prefs, nil
+}
+
+func (s *MemoryStore) SetPreference(ctx context.Context, userID uuid.UUID, channel Channel, enabled bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.preferences[userID] == nil {
+		s.preferences[userID] = Preferences{}
+	}
+	s.preferences[userID][channel] = enabled
+	return nil
+}
+
+func (s *MemoryStore) CreateDelivery(ctx context.Context, d Delivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deliveries[d.ID] = d
+	return nil
+}
+
+func (s *MemoryStore) UpdateDelivery(ctx context.Context, d Delivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.deliveries[d.ID]
+	if !ok {
+		return ErrDeliveryNotFound
+	}
+	stored.Status = d.Status
+	stored.Attempts = d.Attempts
+	stored.LastError = d.LastError
+	stored.NextAttemptAt = d.NextAttemptAt
+	stored.SentAt = d.SentAt
+	s.deliveries[d.ID] = stored
+	return nil
+}
+
+func (s *MemoryStore) GetDelivery(ctx context.Context, id uuid.UUID) (Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, ok := s.deliveries[id]
+	if !ok {
+		return Delivery{}, ErrDeliveryNotFound
+	}
+	return d, nil
+}
+
+func (s *MemoryStore) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	var due []Delivery
+	for _, d := range s.deliveries {
+		if d.Status == StatusPending && !d.NextAttemptAt.After(now) {
+			due = append(due, d)
+		}
+	}
+	slices.SortFunc(due, func(a, b Delivery) int { return a.NextAttemptAt.Compare(b.NextAttemptAt) })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	for i := range due {
+		due[i].NextAttemptAt = now.Add(lease)
+		s.deliveries[due[i].ID] = due[i]
+	}
+	return due, nil
+}
+
+// MemoryInbox keeps in-app notifications in process memory.
+type MemoryInbox struct {
+	mu       sync.Mutex
+	messages []InboxMessage
+	now      func() time.Time
+}
+
+func NewMemoryInbox() *MemoryInbox {
+	return &MemoryInbox{now: time.Now}
+}
+
+func (i *MemoryInbox) Add(ctx context.Context, msg InboxMessage) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.messages = append(i.messages, msg)
+	return nil
+}
+
+func (i *MemoryInbox) List(ctx context.Context, userID uuid.UUID, limit int) ([]InboxMessage, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	var messages []InboxMessage
+	for _, msg := range slices.Backward(i.messages) {
+		if len(messages) == limit {
+			break
+		}
+		if msg.UserID == userID {
+			messages = append(messages, msg)
+		}
+	}
+	return messages, nil
+}
+
+func (i *MemoryInbox) MarkRead(ctx context.Context, userID, id uuid.UUID) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	for j := range i.messages {
+		msg := &i.messages[j]
+		if msg.ID == id && msg.UserID == userID && msg.ReadAt == nil {
+			now := i.now()
+			msg.ReadAt = &now
+		}
+	}
+	return nil
+}
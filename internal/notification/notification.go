@@ -0,0 +1,132 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Channel string
+
+const (
+	ChannelSMS   Channel = "sms"
+	ChannelEmail Channel = "email"
+	ChannelInApp Channel = "in_app"
+)
+
+// Channels lists every channel in the order notifications are sent.
+var Channels = []Channel{ChannelInApp, ChannelSMS, ChannelEmail}
+
+type Status string
+
+const (
+	// StatusPending deliveries are waiting for their first or next attempt
+	StatusPending Status = "pending"
+	StatusSent    Status = "sent"
+	// StatusFailed deliveries gave up after a permanent error or after
+	// running out of attempts
+	StatusFailed Status = "failed"
+)
+
+// Recipient is who a notification is for and how to reach them. Channels
+// without an address are skipped.
+type Recipient struct {
+	UserID uuid.UUID
+	Phone  string
+	Email  string
+	// Locale selects the template language, "fa" or "en"
+	Locale string
+}
+
+// Message is a rendered notification ready for a provider.
+type Message struct {
+	UserID  uuid.UUID
+	To      string
+	Subject string
+	Body    string
+}
+
+// Provider sends messages over one channel. Send returns an error wrapped
+// with Permanent when retrying cannot help, e.g. an invalid address.
+type Provider interface {
+	Channel() Channel
+	Send(ctx context.Context, msg Message) error
+}
+
+// Delivery tracks one notification sent over one channel.
+type Delivery struct {
+	ID            uuid.UUID
+	UserID        uuid.UUID
+	Channel       Channel
+	Template      string
+	Locale        string
+	To            string
+	Subject       string
+	Body          string
+	Status        Status
+	Attempts      int
+	LastError     string
+	NextAttemptAt time.Time
+	SentAt        *time.Time
+	CreatedAt     time.Time
+}
+
+func (d Delivery) message() Message {
+	return Message{UserID: d.UserID, To: d.To, Subject: d.Subject, Body: d.Body}
+}
+
+// Preferences are a user's channel settings. Channels without a setting are
+// enabled.
+type Preferences map[Channel]bool
+
+func (p Preferences) Enabled(channel Channel) bool {
+	enabled, ok := p[channel]
+	return !ok || enabled
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent marks a send error as not worth retrying.
+func Permanent(err error) error {
+	return permanentError{err: err}
+}
+
+// IsPermanent reports whether err was marked with Permanent.
+func IsPermanent(err error) bool {
+	var p permanentError
+	return errors.As(err, &p)
+}
+
+// ParseChannel validates a channel name from user input.
+func ParseChannel(name string) (Channel, error) {
+	for _, channel := range Channels {
+		if string(channel) == name {
+			return channel, nil
+		}
+	}
+	return "", fmt.Errorf("unknown notification channel %q", name)
+}
+
+// address is where the recipient is reached on channel, empty when they
+// can't be.
+func (r Recipient) address(channel Channel) string {
+	switch channel {
+	case ChannelSMS:
+		return r.Phone
+	case ChannelEmail:
+		return r.Email
+	case ChannelInApp:
+		if r.UserID != uuid.Nil {
+			return r.UserID.String()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,222 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/logging"
+	"github.com/shayesteh1hs/DrAppointment/internal/tracing"
+)
+
+// sendLease keeps a delivery from being claimed by another worker while one
+// attempt is in flight. It must exceed the longest provider timeout.
+const sendLease = 2 * time.Minute
+
+// Options tune retries of failed deliveries.
+type Options struct {
+	// MaxAttempts is how often a delivery is tried before it is failed
+	MaxAttempts int
+	// RetryBackoff is the wait after the first failed attempt; it doubles
+	// with each further attempt up to MaxBackoff
+	RetryBackoff time.Duration
+	MaxBackoff   time.Duration
+	// PollInterval is how often Run looks for deliveries due for a retry
+	PollInterval time.Duration
+	// BatchSize bounds the deliveries retried per poll
+	BatchSize int
+	// SendTimeout bounds one attempt at one provider
+	SendTimeout time.Duration
+}
+
+func DefaultOptions() Options {
+	return Options{
+		MaxAttempts:  5,
+		RetryBackoff: 30 * time.Second,
+		MaxBackoff:   time.Hour,
+		PollInterval: 15 * time.Second,
+		BatchSize:    50,
+		SendTimeout:  30 * time.Second,
+	}
+}
+
+// Notifier renders notifications, sends them over every channel the
+// recipient can be reached on and keeps retrying failed deliveries.
+type Notifier struct {
+	store     Store
+	templates *Templates
+	providers map[Channel]Provider
+	opts      Options
+	now       func() time.Time
+}
+
+func NewNotifier(store Store, templates *Templates, opts Options, providers ...Provider) *Notifier {
+	byChannel := make(map[Channel]Provider, len(providers))
+	for _, p := range providers {
+		byChannel[p.Channel()] = p
+	}
+	return &Notifier{store: store, templates: templates, providers: byChannel, opts: opts, now: time.Now}
+}
+
+// Notify sends the named template to the recipient over every channel that
+// has a provider, an address and is not disabled in the recipient's
+// preferences. Each channel gets its own delivery, attempted right away;
+// failed attempts are left to Run. The returned deliveries reflect the first
+// attempt.
+func (n *Notifier) Notify(ctx context.Context, to Recipient, template string, data any) (deliveries []Delivery, err error) {
+	ctx, span := tracing.Start(ctx, "Notifier.Notify")
+	defer func() { tracing.End(span, err) }()
+	span.SetAttributes(attribute.String("notification.template", template))
+
+	prefs := Preferences{}
+	if to.UserID != uuid.Nil {
+		if prefs, err = n.store.Preferences(ctx, to.UserID); err != nil {
+			return nil, err
+		}
+	}
+
+	var rendered *Rendered
+	var locale string
+	for _, channel := range Channels {
+		address := to.address(channel)
+		if address == "" || !prefs.Enabled(channel) || n.providers[channel] == nil {
+			continue
+		}
+
+		if rendered == nil {
+			r, used, err := n.templates.Render(template, to.Locale, data)
+			if err != nil {
+				return nil, err
+			}
+			rendered, locale = &r, used
+		}
+
+		d, err := n.newDelivery(to.UserID, channel, template, locale, address, *rendered)
+		if err != nil {
+			return nil, err
+		}
+		if err := n.store.CreateDelivery(ctx, d); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, n.attempt(ctx, d))
+	}
+	return deliveries, nil
+}
+
+func (n *Notifier) newDelivery(userID uuid.UUID, channel Channel, template, locale, to string, r Rendered) (Delivery, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return Delivery{}, fmt.Errorf("failed to generate delivery id: %w", err)
+	}
+	now := n.now()
+	return Delivery{
+		ID:       id,
+		UserID:   userID,
+		Channel:  channel,
+		Template: template,
+		Locale:   locale,
+		To:       to,
+		Subject:  r.Subject,
+		Body:     r.Body,
+		Status:   StatusPending,
+		// Claimed by this instance until the first attempt is recorded
+		NextAttemptAt: now.Add(sendLease),
+		CreatedAt:     now,
+	}, nil
+}
+
+// RetryDue attempts deliveries whose retry is due and returns how many it
+// attempted.
+func (n *Notifier) RetryDue(ctx context.Context) (attempted int, err error) {
+	ctx, span := tracing.Start(ctx, "Notifier.RetryDue")
+	defer func() { tracing.End(span, err) }()
+
+	due, err := n.store.ClaimDue(ctx, n.opts.BatchSize, sendLease)
+	if err != nil {
+		return 0, err
+	}
+	for _, d := range due {
+		n.attempt(ctx, d)
+	}
+	span.SetAttributes(attribute.Int("notification.attempted", len(due)))
+	return len(due), nil
+}
+
+// Run retries due deliveries every poll interval until ctx is canceled.
+// Polls find nothing to do most of the time, so they run here rather than
+// as recurring jobs that would add a row to the jobs table each time.
+func (n *Notifier) Run(ctx context.Context) {
+	ticker := time.NewTicker(n.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := n.RetryDue(ctx); err != nil && ctx.Err() == nil {
+				logging.FromContext(ctx).Error("failed to retry notifications", "error", err)
+			}
+		}
+	}
+}
+
+// attempt sends d once and records the outcome. Errors are recorded on the
+// delivery rather than returned.
+func (n *Notifier) attempt(ctx context.Context, d Delivery) Delivery {
+	ctx, span := tracing.Start(ctx, "Notifier.attempt")
+	span.SetAttributes(
+		attribute.String("notification.channel", string(d.Channel)),
+		attribute.String("notification.delivery_id", d.ID.String()),
+	)
+
+	sendCtx, cancel := context.WithTimeout(ctx, n.opts.SendTimeout)
+	sendErr := n.send(sendCtx, d)
+	cancel()
+
+	d.Attempts++
+	now := n.now()
+	switch {
+	case sendErr == nil:
+		d.Status, d.LastError, d.SentAt = StatusSent, "", &now
+	case IsPermanent(sendErr) || d.Attempts >= n.opts.MaxAttempts:
+		d.Status, d.LastError = StatusFailed, sendErr.Error()
+	default:
+		d.LastError = sendErr.Error()
+		d.NextAttemptAt = now.Add(n.backoff(d.Attempts))
+	}
+	tracing.End(span, sendErr)
+
+	logger := logging.FromContext(ctx).With("delivery_id", d.ID, "channel", d.Channel, "attempt", d.Attempts)
+	if sendErr != nil {
+		logger.Warn("notification attempt failed", "status", d.Status, "error", sendErr)
+	}
+	// The send can't be undone, so record it even if the caller gave up
+	if err := n.store.UpdateDelivery(context.WithoutCancel(ctx), d); err != nil {
+		logger.Error("failed to record notification attempt", "error", err)
+	}
+	return d
+}
+
+func (n *Notifier) send(ctx context.Context, d Delivery) error {
+	provider, ok := n.providers[d.Channel]
+	if !ok {
+		return Permanent(fmt.Errorf("no provider for channel %s", d.Channel))
+	}
+	return provider.Send(ctx, d.message())
+}
+
+// backoff is the wait after the given number of failed attempts.
+func (n *Notifier) backoff(attempts int) time.Duration {
+	wait := n.opts.RetryBackoff
+	for range attempts - 1 {
+		wait *= 2
+		if wait >= n.opts.MaxBackoff {
+			return n.opts.MaxBackoff
+		}
+	}
+	return min(wait, n.opts.MaxBackoff)
+}
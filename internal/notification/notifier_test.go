@@ -0,0 +1,192 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeProvider struct {
+	channel Channel
+	mu      sync.Mutex
+	sent    []Message
+	errs    []error
+}
+
+func (p *fakeProvider) Channel() Channel { return p.channel }
+
+func (p *fakeProvider) Send(ctx context.Context, msg Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.errs) > 0 {
+		err := p.errs[0]
+		p.errs = p.errs[1:]
+		if err != nil {
+			return err
+		}
+	}
+	p.sent = append(p.sent, msg)
+	return nil
+}
+
+func newTestNotifier(t *testing.T, providers ...Provider) (*Notifier, *MemoryStore, *time.Time) {
+	t.Helper()
+	now := time.Date(2026, time.October, 18, 9, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+
+	opts := DefaultOptions()
+	opts.MaxAttempts = 3
+	opts.RetryBackoff = time.Minute
+	n := NewNotifier(store, loadTestTemplates(t), opts, providers...)
+	n.now = func() time.Time { return now }
+	return n, store, &now
+}
+
+func testData() appointmentData {
+	return appointmentData{
+		PatientName: "Sara",
+		DoctorName:  "Dr. Ahmadi",
+		StartsAt:    time.Date(2026, time.October, 20, 6, 30, 0, 0, time.UTC),
+	}
+}
+
+func TestNotifier_Notify_SendsOverReachableChannels(t *testing.T) {
+	sms := &fakeProvider{channel: ChannelSMS}
+	email := &fakeProvider{channel: ChannelEmail}
+	inbox := NewMemoryInbox()
+	n, _, _ := newTestNotifier(t, sms, email, NewInAppProvider(inbox))
+
+	userID := uuid.New()
+	deliveries, err := n.Notify(context.Background(), Recipient{UserID: userID, Phone: "+989121234567", Locale: "en"}, "appointment_confirmed", testData())
+	require.NoError(t, err)
+
+	require.Len(t, deliveries, 2, "email is skipped without an address")
+	assert.Equal(t, ChannelInApp, deliveries[0].Channel)
+	assert.Equal(t, ChannelSMS, deliveries[1].Channel)
+	for _, d := range deliveries {
+		assert.Equal(t, StatusSent, d.Status)
+		assert.Equal(t, 1, d.Attempts)
+		assert.Equal(t, "en", d.Locale)
+	}
+
+	require.Len(t, sms.sent, 1)
+	assert.Equal(t, "+989121234567", sms.sent[0].To)
+	assert.Contains(t, sms.sent[0].Body, "Hello Sara")
+	assert.Empty(t, email.sent)
+
+	messages, err := inbox.List(context.Background(), userID, 10)
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.Equal(t, "Your appointment with Dr. Ahmadi is confirmed", messages[0].Subject)
+}
+
+func TestNotifier_Notify_HonorsPreferences(t *testing.T) {
+	sms := &fakeProvider{channel: ChannelSMS}
+	email := &fakeProvider{channel: ChannelEmail}
+	n, store, _ := newTestNotifier(t, sms, email)
+
+	userID := uuid.New()
+	require.NoError(t, store.SetPreference(context.Background(), userID, ChannelSMS, false))
+
+	deliveries, err := n.Notify(context.Background(), Recipient{UserID: userID, Phone: "+989121234567", Email: "sara@example.com"}, "appointment_reminder", testData())
+	require.NoError(t, err)
+
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, ChannelEmail, deliveries[0].Channel)
+	assert.Equal(t, "fa", deliveries[0].Locale)
+	assert.Empty(t, sms.sent)
+}
+
+func TestNotifier_RetriesWithBackoffUntilSent(t *testing.T) {
+	sms := &fakeProvider{channel: ChannelSMS, errs: []error{errors.New("gateway down"), errors.New("gateway down")}}
+	n, store, now := newTestNotifier(t, sms)
+	ctx := context.Background()
+
+	deliveries, err := n.Notify(ctx, Recipient{Phone: "+989121234567"}, "appointment_confirmed", testData())
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	d := deliveries[0]
+	assert.Equal(t, StatusPending, d.Status)
+	assert.Equal(t, "gateway down", d.LastError)
+	assert.Equal(t, now.Add(time.Minute), d.NextAttemptAt)
+
+	attempted, err := n.RetryDue(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, attempted, "the retry is not due yet")
+
+	*now = now.Add(time.Minute)
+	attempted, err = n.RetryDue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, attempted)
+
+	d, err = store.GetDelivery(ctx, d.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, d.Attempts)
+	assert.Equal(t, now.Add(2*time.Minute), d.NextAttemptAt, "the backoff doubles")
+
+	*now = now.Add(2 * time.Minute)
+	_, err = n.RetryDue(ctx)
+	require.NoError(t, err)
+
+	d, err = store.GetDelivery(ctx, d.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusSent, d.Status)
+	assert.Equal(t, 3, d.Attempts)
+	require.NotNil(t, d.SentAt)
+	assert.Len(t, sms.sent, 1)
+}
+
+func TestNotifier_FailsAfterMaxAttempts(t *testing.T) {
+	down := errors.New("gateway down")
+	sms := &fakeProvider{channel: ChannelSMS, errs: []error{down, down, down}}
+	n, store, now := newTestNotifier(t, sms)
+	ctx := context.Background()
+
+	deliveries, err := n.Notify(ctx, Recipient{Phone: "+989121234567"}, "appointment_confirmed", testData())
+	require.NoError(t, err)
+
+	for range 2 {
+		*now = now.Add(time.Hour)
+		_, err := n.RetryDue(ctx)
+		require.NoError(t, err)
+	}
+
+	d, err := store.GetDelivery(ctx, deliveries[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusFailed, d.Status)
+	assert.Equal(t, 3, d.Attempts)
+
+	*now = now.Add(time.Hour)
+	attempted, err := n.RetryDue(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, attempted, "failed deliveries are not retried")
+}
+
+func TestNotifier_PermanentErrorFailsImmediately(t *testing.T) {
+	sms := &fakeProvider{channel: ChannelSMS, errs: []error{Permanent(errors.New("invalid number"))}}
+	n, _, _ := newTestNotifier(t, sms)
+
+	deliveries, err := n.Notify(context.Background(), Recipient{Phone: "123"}, "appointment_confirmed", testData())
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, StatusFailed, deliveries[0].Status)
+	assert.Equal(t, 1, deliveries[0].Attempts)
+	assert.Equal(t, "invalid number", deliveries[0].LastError)
+}
+
+func TestNotifier_Backoff(t *testing.T) {
+	n := NewNotifier(nil, nil, Options{RetryBackoff: 30 * time.Second, MaxBackoff: 3 * time.Minute})
+
+	assert.Equal(t, 30*time.Second, n.backoff(1))
+	assert.Equal(t, time.Minute, n.backoff(2))
+	assert.Equal(t, 2*time.Minute, n.backoff(3))
+	assert.Equal(t, 3*time.Minute, n.backoff(4))
+	assert.Equal(t, 3*time.Minute, n.backoff(30))
+}
@@ -0,0 +1,223 @@
+package notification
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/database"
+)
+
+const deliveryColumns = "id, user_id, channel, template, locale, recipient, subject, body, status, attempts, last_error, next_attempt_at, sent_at, created_at"
+
+const (
+	selectPreferencesQuery = "SELECT channel, enabled FROM notification_preferences WHERE user_id = $1"
+
+	upsertPreferenceQuery = `INSERT INTO notification_preferences (user_id, channel, enabled) VALUES ($1, $2, $3)
+ON CONFLICT (user_id, channel) DO UPDATE SET enabled = EXCLUDED.enabled, updated_at = now()`
+
+	insertDeliveryQuery = "INSERT INTO notification_deliveries (" + deliveryColumns + ") " +
+		"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)"
+
+	updateDeliveryQuery = "UPDATE notification_deliveries SET status = $2, attempts = $3, last_error = $4, next_attempt_at = $5, sent_at = $6 WHERE id = $1"
+
+	selectDeliveryQuery = "SELECT " + deliveryColumns + " FROM notification_deliveries WHERE id = $1"
+
+	// claimDueQuery postpones due deliveries by the lease ($2) and returns
+	// them. Rows locked by another worker's claim are skipped, not waited on.
+	claimDueQuery = `UPDATE notification_deliveries AS d
+SET next_attempt_at = now() + $2::float8 * interval '1 second'
+FROM (
+    SELECT id FROM notification_deliveries
+    WHERE status = 'pending' AND next_attempt_at <= now()
+    ORDER BY next_attempt_at
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED
+) AS due
+WHERE d.id = due.id
+RETURNING d.id, d.user_id, d.channel, d.template, d.locale, d.recipient, d.subject, d.body, d.status, d.attempts, d.last_error, d.next_attempt_at, d.sent_at, d.created_at`
+)
+
+// PostgresStore shares preferences and deliveries between all instances, so
+// any of them can retry a failed delivery.
+type PostgresStore struct {
+	db database.Querier
+}
+
+func NewPostgresStore(db database.Querier) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+func (s *PostgresStore) Preferences(ctx context.Context, userID uuid.UUID) (Preferences, error) {
+	rows, err := s.db.QueryContext(ctx, selectPreferencesQuery, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification preferences: %w", err)
+	}
+	defer rows.Close()
+
+	prefs := Preferences{}
+	for rows.Next() {
+		var (
+			channel Channel
+			enabled bool
+		)
+		if err := rows.Scan(&channel, &enabled); err != nil {
+			return nil, fmt.Errorf("failed to scan notification preference: %w", err)
+		}
+		prefs[channel] = enabled
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get notification preferences: %w", err)
+	}
+	return prefs, nil
+}
+
+func (s *PostgresStore) SetPreference(ctx context.Context, userID uuid.UUID, channel Channel, enabled bool) error {
+	if _, err := s.db.ExecContext(ctx, upsertPreferenceQuery, userID, channel, enabled); err != nil {
+		return fmt.Errorf("failed to set notification preference: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) CreateDelivery(ctx context.Context, d Delivery) error {
+	_, err := s.db.ExecContext(ctx, insertDeliveryQuery,
+		d.ID, nullUUID(d.UserID), d.Channel, d.Template, d.Locale, d.To, d.Subject, d.Body,
+		d.Status, d.Attempts, d.LastError, d.NextAttemptAt, d.SentAt, d.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create notification delivery: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) UpdateDelivery(ctx context.Context, d Delivery) error {
+	result, err := s.db.ExecContext(ctx, updateDeliveryQuery, d.ID, d.Status, d.Attempts, d.LastError, d.NextAttemptAt, d.SentAt)
+	if err != nil {
+		return fmt.Errorf("failed to update notification delivery: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrDeliveryNotFound
+	}
+	return nil
+}
+
+func (s *PostgresStore) GetDelivery(ctx context.Context, id uuid.UUID) (Delivery, error) {
+	row := s.db.QueryRowContext(ctx, selectDeliveryQuery, id)
+	d, err := scanDelivery(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Delivery{}, ErrDeliveryNotFound
+	}
+	if err != nil {
+		return Delivery{}, fmt.Errorf("failed to get notification delivery: %w", err)
+	}
+	return d, nil
+}
+
+func (s *PostgresStore) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]Delivery, error) {
+	rows, err := s.db.QueryContext(ctx, claimDueQuery, limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim notification deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []Delivery
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan notification delivery: %w", err)
+		}
+		deliveries = append(deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to claim notification deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanDelivery(row scanner) (Delivery, error) {
+	var (
+		d      Delivery
+		userID uuid.NullUUID
+		sentAt sql.NullTime
+	)
+	err := row.Scan(&d.ID, &userID, &d.Channel, &d.Template, &d.Locale, &d.To, &d.Subject, &d.Body,
+		&d.Status, &d.Attempts, &d.LastError, &d.NextAttemptAt, &sentAt, &d.CreatedAt)
+	if err != nil {
+		return Delivery{}, err
+	}
+	d.UserID = userID.UUID
+	if sentAt.Valid {
+		d.SentAt = &sentAt.Time
+	}
+	return d, nil
+}
+
+func nullUUID(id uuid.UUID) uuid.NullUUID {
+	return uuid.NullUUID{UUID: id, Valid: id != uuid.Nil}
+}
+
+const (
+	insertInboxQuery = "INSERT INTO notification_inbox (id, user_id, subject, body, created_at) VALUES ($1, $2, $3, $4, $5)"
+
+	listInboxQuery = "SELECT id, user_id, subject, body, read_at, created_at FROM notification_inbox WHERE user_id = $1 ORDER BY created_at DESC, id DESC LIMIT $2"
+
+	markReadQuery = "UPDATE notification_inbox SET read_at = now() WHERE id = $1 AND user_id = $2 AND read_at IS NULL"
+)
+
+// PostgresInbox stores in-app notifications in the notification_inbox table.
+type PostgresInbox struct {
+	db database.Querier
+}
+
+func NewPostgresInbox(db database.Querier) *PostgresInbox {
+	return &PostgresInbox{db: db}
+}
+
+func (i *PostgresInbox) Add(ctx context.Context, msg InboxMessage) error {
+	_, err := i.db.ExecContext(ctx, insertInboxQuery, msg.ID, msg.UserID, msg.Subject, msg.Body, msg.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to add inbox message: %w", err)
+	}
+	return nil
+}
+
+func (i *PostgresInbox) List(ctx context.Context, userID uuid.UUID, limit int) ([]InboxMessage, error) {
+	rows, err := i.db.QueryContext(ctx, listInboxQuery, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list inbox messages: %w", err)
+	}
+	defer rows.Close()
+
+	var messages []InboxMessage
+	for rows.Next() {
+		var (
+			msg    InboxMessage
+			readAt sql.NullTime
+		)
+		if err := rows.Scan(&msg.ID, &msg.UserID, &msg.Subject, &msg.Body, &readAt, &msg.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan inbox message: %w", err)
+		}
+		if readAt.Valid {
+			msg.ReadAt = &readAt.Time
+		}
+		messages = append(messages, msg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list inbox messages: %w", err)
+	}
+	return messages, nil
+}
+
+func (i *PostgresInbox) MarkRead(ctx context.Context, userID, id uuid.UUID) error {
+	if _, err := i.db.ExecContext(ctx, markReadQuery, id, userID); err != nil {
+		return fmt.Errorf("failed to mark inbox message read: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package notification
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var deliveryRowColumns = []string{"id", "user_id", "channel", "template", "locale", "recipient", "subject", "body", "status", "attempts", "last_error", "next_attempt_at", "sent_at", "created_at"}
+
+func TestPostgresStore_Preferences(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	userID := uuid.New()
+	mock.ExpectQuery(regexp.QuoteMeta(selectPreferencesQuery)).
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"channel", "enabled"}).AddRow("sms", false).AddRow("email", true))
+
+	prefs, err := NewPostgresStore(db).Preferences(context.Background(), userID)
+	require.NoError(t, err)
+	assert.False(t, prefs.Enabled(ChannelSMS))
+	assert.True(t, prefs.Enabled(ChannelEmail))
+	assert.True(t, prefs.Enabled(ChannelInApp))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStore_SetPreference(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	userID := uuid.New()
+	mock.ExpectExec(regexp.QuoteMeta(upsertPreferenceQuery)).
+		WithArgs(userID, ChannelEmail, false).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(t, NewPostgresStore(db).SetPreference(context.Background(), userID, ChannelEmail, false))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStore_UpdateDelivery(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	sentAt := time.Date(2026, time.October, 18, 9, 0, 0, 0, time.UTC)
+	d := Delivery{ID: uuid.New(), Status: StatusSent, Attempts: 2, NextAttemptAt: sentAt, SentAt: &sentAt}
+	mock.ExpectExec(regexp.QuoteMeta(updateDeliveryQuery)).
+		WithArgs(d.ID, StatusSent, 2, "", sentAt, &sentAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(t, NewPostgresStore(db).UpdateDelivery(context.Background(), d))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStore_UpdateDelivery_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectExec(regexp.QuoteMeta(updateDeliveryQuery)).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = NewPostgresStore(db).UpdateDelivery(context.Background(), Delivery{ID: uuid.New()})
+	assert.ErrorIs(t, err, ErrDeliveryNotFound)
+}
+
+func TestPostgresStore_ClaimDue(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	id := uuid.New()
+	now := time.Date(2026, time.October, 18, 9, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(regexp.QuoteMeta(claimDueQuery)).
+		WithArgs(50, float64(120)).
+		WillReturnRows(sqlmock.NewRows(deliveryRowColumns).
+			AddRow(id, nil, "sms", "appointment_reminder", "fa", "+989121234567", "", "body", "pending", 1, "timeout", now, nil, now))
+
+	deliveries, err := NewPostgresStore(db).ClaimDue(context.Background(), 50, 2*time.Minute)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	d := deliveries[0]
+	assert.Equal(t, id, d.ID)
+	assert.Equal(t, uuid.Nil, d.UserID)
+	assert.Equal(t, ChannelSMS, d.Channel)
+	assert.Equal(t, StatusPending, d.Status)
+	assert.Equal(t, 1, d.Attempts)
+	assert.Equal(t, "timeout", d.LastError)
+	assert.Nil(t, d.SentAt)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStore_GetDelivery_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	id := uuid.New()
+	mock.ExpectQuery(regexp.QuoteMeta(selectDeliveryQuery)).WithArgs(id).WillReturnRows(sqlmock.NewRows(deliveryRowColumns))
+
+	_, err = NewPostgresStore(db).GetDelivery(context.Background(), id)
+	assert.ErrorIs(t, err, ErrDeliveryNotFound)
+}
+
+func TestPostgresInbox_List(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	userID, id := uuid.New(), uuid.New()
+	now := time.Date(2026, time.October, 18, 9, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(regexp.QuoteMeta(listInboxQuery)).
+		WithArgs(userID, 20).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "subject", "body", "read_at", "created_at"}).
+			AddRow(id, userID, "subject", "body", now, now))
+
+	messages, err := NewPostgresInbox(db).List(context.Background(), userID, 20)
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.Equal(t, id, messages[0].ID)
+	require.NotNil(t, messages[0].ReadAt)
+	assert.Equal(t, now, *messages[0].ReadAt)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,85 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// SMSConfig addresses an HTTP SMS gateway.
+type SMSConfig struct {
+	// BaseURL of the gateway API; messages are posted to <BaseURL>/messages
+	BaseURL string
+	APIKey  string
+	// Sender is the line number or sender ID messages are sent from
+	Sender string
+}
+
+// SMSProvider sends text messages through a JSON HTTP gateway:
+//
+//	POST <base URL>/messages
+//	Authorization: Bearer <API key>
+//	{"from": "<sender>", "to": "<phone>", "text": "<body>"}
+//
+// Any 2xx response means the gateway accepted the message.
+type SMSProvider struct {
+	cfg    SMSConfig
+	client *http.Client
+}
+
+func NewSMSProvider(cfg SMSConfig, client *http.Client) (*SMSProvider, error) {
+	if cfg.BaseURL == "" {
+		return nil, errors.New("sms base url is required")
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	cfg.BaseURL = strings.TrimSuffix(cfg.BaseURL, "/")
+	return &SMSProvider{cfg: cfg, client: client}, nil
+}
+
+func (p *SMSProvider) Channel() Channel { return ChannelSMS }
+
+func (p *SMSProvider) Send(ctx context.Context, msg Message) error {
+	if msg.To == "" {
+		return Permanent(errors.New("sms recipient has no phone number"))
+	}
+
+	payload, err := json.Marshal(map[string]string{"from": p.cfg.Sender, "to": msg.To, "text": msg.Body})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.cfg.BaseURL+"/messages", bytes.NewReader(payload))
+	if err != nil {
+		return Permanent(fmt.Errorf("failed to build sms request: %w", err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.cfg.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.cfg.APIKey)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send sms: %w", err)
+	}
+	defer func() {
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	err = fmt.Errorf("sms gateway returned %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+	// Client errors are final, except those that ask to come back later
+	if resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusRequestTimeout {
+		return Permanent(err)
+	}
+	return err
+}
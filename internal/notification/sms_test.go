@@ -0,0 +1,59 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSMSProvider_Send(t *testing.T) {
+	var got map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/v1/messages", r.URL.Path)
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	p, err := NewSMSProvider(SMSConfig{BaseURL: server.URL + "/v1/", APIKey: "secret", Sender: "3000"}, server.Client())
+	require.NoError(t, err)
+
+	err = p.Send(context.Background(), Message{To: "+989121234567", Subject: "ignored", Body: "نوبت شما تأیید شد"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"from": "3000", "to": "+989121234567", "text": "نوبت شما تأیید شد"}, got)
+}
+
+func TestSMSProvider_Send_Errors(t *testing.T) {
+	tests := []struct {
+		status    int
+		permanent bool
+	}{
+		{http.StatusBadRequest, true},
+		{http.StatusUnauthorized, true},
+		{http.StatusTooManyRequests, false},
+		{http.StatusBadGateway, false},
+	}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "nope", tt.status)
+			}))
+			defer server.Close()
+
+			p, err := NewSMSProvider(SMSConfig{BaseURL: server.URL}, server.Client())
+			require.NoError(t, err)
+
+			err = p.Send(context.Background(), Message{To: "+989121234567", Body: "hi"})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "nope")
+			assert.Equal(t, tt.permanent, IsPermanent(err))
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"time"
+)
+
+// SMTPConfig addresses a mail submission server.
+type SMTPConfig struct {
+	Host string
+	Port int
+	// Username and Password authenticate with PLAIN when set, which net/smtp
+	// only allows over TLS or to localhost
+	Username string
+	Password string
+	// From is the sender address, optionally with a display name
+	From string
+}
+
+// SMTPProvider sends plain text emails, upgrading the connection with
+// STARTTLS when the server offers it.
+type SMTPProvider struct {
+	cfg  SMTPConfig
+	from *mail.Address
+	now  func() time.Time
+}
+
+func NewSMTPProvider(cfg SMTPConfig) (*SMTPProvider, error) {
+	if cfg.Host == "" {
+		return nil, errors.New("smtp host is required")
+	}
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid smtp from address %q: %w", cfg.From, err)
+	}
+	return &SMTPProvider{cfg: cfg, from: from, now: time.Now}, nil
+}
+
+func (p *SMTPProvider) Channel() Channel { return ChannelEmail }
+
+func (p *SMTPProvider) Send(ctx context.Context, msg Message) error {
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return Permanent(fmt.Errorf("invalid email recipient %q: %w", msg.To, err))
+	}
+
+	addr := net.JoinHostPort(p.cfg.Host, strconv.Itoa(p.cfg.Port))
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to smtp server: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, p.cfg.Host)
+	if err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("failed to greet smtp server: %w", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: p.cfg.Host}); err != nil {
+			return fmt.Errorf("failed to start tls: %w", err)
+		}
+	}
+	if p.cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", p.cfg.Username, p.cfg.Password, p.cfg.Host)); err != nil {
+			return smtpError("authenticate", err)
+		}
+	}
+
+	if err := client.Mail(p.from.Address); err != nil {
+		return smtpError("send", err)
+	}
+	if err := client.Rcpt(to.Address); err != nil {
+		return smtpError("send", err)
+	}
+	w, err := client.Data()
+	if err != nil {
+		return smtpError("send", err)
+	}
+	if _, err := w.Write(p.compose(to, msg)); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return smtpError("send", err)
+	}
+	return client.Quit()
+}
+
+// compose builds a UTF-8 message; the body is base64 encoded so Persian text
+// passes through servers that are not 8-bit clean.
+func (p *SMTPProvider) compose(to *mail.Address, msg Message) []byte {
+	var b bytes.Buffer
+	header := func(name, value string) { b.WriteString(name + ": " + value + "\r\n") }
+	header("From", p.from.String())
+	header("To", to.String())
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", p.now().Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=utf-8")
+	header("Content-Transfer-Encoding", "base64")
+	b.WriteString("\r\n")
+
+	encoded := base64.StdEncoding.EncodeToString([]byte(msg.Body))
+	for len(encoded) > 76 {
+		b.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	b.WriteString(encoded + "\r\n")
+	return b.Bytes()
+}
+
+// smtpError marks permanent (5xx) replies, such as an unknown mailbox, as
+// not worth retrying.
+func smtpError(action string, err error) error {
+	err = fmt.Errorf("failed to %s email: %w", action, err)
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) && protoErr.Code >= 500 {
+		return Permanent(err)
+	}
+	return err
+}
@@ -0,0 +1,159 @@
+package notification
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"io"
+	"mime"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSMTPServer accepts one message per connection and rejects recipients
+// at unknown.example.com.
+type fakeSMTPServer struct {
+	listener net.Listener
+	messages chan string
+}
+
+func newFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &fakeSMTPServer{listener: listener, messages: make(chan string, 1)}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeSMTPServer) config() SMTPConfig {
+	addr := s.listener.Addr().(*net.TCPAddr)
+	return SMTPConfig{Host: addr.IP.String(), Port: addr.Port, From: "DrAppointment <noreply@example.com>"}
+}
+
+func (s *fakeSMTPServer) serve(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { _, _ = io.WriteString(conn, line+"\r\n") }
+
+	reply("220 localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(command, "RCPT") && strings.Contains(command, "UNKNOWN.EXAMPLE.COM"):
+			reply("550 no such user")
+		case strings.HasPrefix(command, "DATA"):
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			s.messages <- data.String()
+			reply("250 queued")
+		case strings.HasPrefix(command, "QUIT"):
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+func TestSMTPProvider_Send(t *testing.T) {
+	server := newFakeSMTPServer(t)
+	p, err := NewSMTPProvider(server.config())
+	require.NoError(t, err)
+	p.now = func() time.Time { return time.Date(2026, time.October, 18, 9, 0, 0, 0, time.UTC) }
+
+	body := strings.Repeat("نوبت شما با دکتر احمدی تأیید شد. ", 5)
+	err = p.Send(context.Background(), Message{To: "sara@example.com", Subject: "تأیید نوبت", Body: body})
+	require.NoError(t, err)
+
+	var raw string
+	select {
+	case raw = <-server.messages:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message received")
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(raw))
+	require.NoError(t, err)
+	assert.Equal(t, `"DrAppointment" <noreply@example.com>`, msg.Header.Get("From"))
+	assert.Equal(t, "<sara@example.com>", msg.Header.Get("To"))
+	assert.Equal(t, "text/plain; charset=utf-8", msg.Header.Get("Content-Type"))
+	assert.Equal(t, "Sun, 18 Oct 2026 09:00:00 +0000", msg.Header.Get("Date"))
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, "تأیید نوبت", subject)
+
+	encoded, err := io.ReadAll(msg.Body)
+	require.NoError(t, err)
+	for _, line := range strings.Split(strings.TrimSpace(string(encoded)), "\r\n") {
+		assert.LessOrEqual(t, len(line), 76)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(string(encoded), "\r\n", ""))
+	require.NoError(t, err)
+	assert.Equal(t, body, string(decoded))
+}
+
+func TestSMTPProvider_Send_RejectedRecipientIsPermanent(t *testing.T) {
+	server := newFakeSMTPServer(t)
+	p, err := NewSMTPProvider(server.config())
+	require.NoError(t, err)
+
+	err = p.Send(context.Background(), Message{To: "nobody@unknown.example.com", Subject: "hi", Body: "hi"})
+	require.Error(t, err)
+	assert.True(t, IsPermanent(err))
+}
+
+func TestSMTPProvider_Send_UnreachableServerIsRetried(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	require.NoError(t, listener.Close())
+
+	p, err := NewSMTPProvider(SMTPConfig{Host: "127.0.0.1", Port: port, From: "noreply@example.com"})
+	require.NoError(t, err)
+
+	err = p.Send(context.Background(), Message{To: "sara@example.com", Subject: "hi", Body: "hi"})
+	require.Error(t, err)
+	assert.False(t, IsPermanent(err))
+}
+
+func TestSMTPProvider_Send_InvalidAddressIsPermanent(t *testing.T) {
+	p, err := NewSMTPProvider(SMTPConfig{Host: "127.0.0.1", Port: 25, From: "noreply@example.com"})
+	require.NoError(t, err)
+
+	err = p.Send(context.Background(), Message{To: "not an address", Body: "hi"})
+	assert.True(t, IsPermanent(err))
+}
@@ -0,0 +1,27 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrDeliveryNotFound = errors.New("notification delivery not found")
+
+// Store persists channel preferences and deliveries.
+type Store interface {
+	Preferences(ctx context.Context, userID uuid.UUID) (Preferences, error)
+	SetPreference(ctx context.Context, userID uuid.UUID, channel Channel, enabled bool) error
+
+	CreateDelivery(ctx context.Context, d Delivery) error
+	// UpdateDelivery saves the outcome of an attempt: status, attempts, last
+	// error, next attempt and sent time.
+	UpdateDelivery(ctx context.Context, d Delivery) error
+	GetDelivery(ctx context.Context, id uuid.UUID) (Delivery, error)
+	// ClaimDue returns up to limit pending deliveries whose next attempt is
+	// due and postpones them by lease, so concurrent workers skip them while
+	// they are being sent.
+	ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]Delivery, error)
+}
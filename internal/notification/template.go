@@ -0,0 +1,95 @@
+package notification
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"text/template"
+	"time"
+	// Messages are rendered in a named zone, which minimal containers
+	// without a zoneinfo database could not load otherwise
+	_ "time/tzdata"
+)
+
+// DefaultLocale is used for recipients without a locale or with one no
+// template is written in.
+const DefaultLocale = "fa"
+
+//go:embed templates/*.tmpl
+var templateFiles embed.FS
+
+// Rendered is a template rendered for one recipient.
+type Rendered struct {
+	Subject string
+	Body    string
+}
+
+// Templates holds the message templates in templates/, one file per
+// template and locale named <template>.<locale>.tmpl, each defining a
+// "subject" and a "body". Templates can format times with date and time,
+// which use the recipient's calendar and digits in the configured zone.
+type Templates struct {
+	byName map[string]map[string]*template.Template
+}
+
+func LoadTemplates(zone *time.Location) (*Templates, error) {
+	files, err := fs.Glob(templateFiles, "templates/*.tmpl")
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Templates{byName: map[string]map[string]*template.Template{}}
+	for _, file := range files {
+		name, locale, ok := strings.Cut(strings.TrimSuffix(path.Base(file), ".tmpl"), ".")
+		if !ok {
+			return nil, fmt.Errorf("template %s is not named <template>.<locale>.tmpl", file)
+		}
+
+		tmpl, err := template.New(name).Option("missingkey=error").Funcs(template.FuncMap{
+			"date": func(at time.Time) string { return formatDate(locale, at.In(zone)) },
+			"time": func(at time.Time) string { return formatTime(locale, at.In(zone)) },
+		}).ParseFS(templateFiles, file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse template %s: %w", file, err)
+		}
+		for _, part := range []string{"subject", "body"} {
+			if tmpl.Lookup(part) == nil {
+				return nil, fmt.Errorf("template %s does not define %q", file, part)
+			}
+		}
+
+		if t.byName[name] == nil {
+			t.byName[name] = map[string]*template.Template{}
+		}
+		t.byName[name][locale] = tmpl
+	}
+	return t, nil
+}
+
+// Render renders the named template in locale, falling back to
+// DefaultLocale.
+func (t *Templates) Render(name, locale string, data any) (Rendered, string, error) {
+	locales, ok := t.byName[name]
+	if !ok {
+		return Rendered{}, "", fmt.Errorf("unknown notification template %q", name)
+	}
+	tmpl, ok := locales[locale]
+	if !ok {
+		locale = DefaultLocale
+		if tmpl, ok = locales[locale]; !ok {
+			return Rendered{}, "", fmt.Errorf("notification template %q has no %q version", name, DefaultLocale)
+		}
+	}
+
+	var subject, body bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Rendered{}, "", fmt.Errorf("failed to render %s subject: %w", name, err)
+	}
+	if err := tmpl.ExecuteTemplate(&body, "body", data); err != nil {
+		return Rendered{}, "", fmt.Errorf("failed to render %s body: %w", name, err)
+	}
+	return Rendered{Subject: strings.TrimSpace(subject.String()), Body: strings.TrimSpace(body.String())}, locale, nil
+}
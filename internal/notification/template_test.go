@@ -0,0 +1,75 @@
+package notification
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type appointmentData struct {
+	PatientName string
+	DoctorName  string
+	StartsAt    time.Time
+}
+
+func loadTestTemplates(t *testing.T) *Templates {
+	t.Helper()
+	tehran, err := time.LoadLocation("Asia/Tehran")
+	require.NoError(t, err)
+	templates, err := LoadTemplates(tehran)
+	require.NoError(t, err)
+	return templates
+}
+
+func TestTemplates_Render(t *testing.T) {
+	templates := loadTestTemplates(t)
+	data := appointmentData{
+		PatientName: "Sara",
+		DoctorName:  "Dr. Ahmadi",
+		StartsAt:    time.Date(2026, time.October, 18, 6, 30, 0, 0, time.UTC),
+	}
+
+	en, locale, err := templates.Render("appointment_confirmed", "en", data)
+	require.NoError(t, err)
+	assert.Equal(t, "en", locale)
+	assert.Equal(t, "Your appointment with Dr. Ahmadi is confirmed", en.Subject)
+	assert.Equal(t, "Hello Sara, your appointment with Dr. Ahmadi on Sunday, October 18, 2026 at 10:00 is confirmed.", en.Body)
+
+	fa, locale, err := templates.Render("appointment_confirmed", "fa", data)
+	require.NoError(t, err)
+	assert.Equal(t, "fa", locale)
+	assert.Contains(t, fa.Body, "۲۶ مهر ۱۴۰۵ ساعت ۱۰:۰۰")
+
+	// Unknown locales fall back to Persian
+	_, locale, err = templates.Render("appointment_reminder", "de", data)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultLocale, locale)
+}
+
+func TestTemplates_RenderErrors(t *testing.T) {
+	templates := loadTestTemplates(t)
+
+	_, _, err := templates.Render("unknown", "en", nil)
+	assert.EqualError(t, err, `unknown notification template "unknown"`)
+
+	_, _, err = templates.Render("appointment_reminder", "en", map[string]any{"PatientName": "Sara"})
+	assert.Error(t, err)
+}
+
+func TestToJalali(t *testing.T) {
+	tests := []struct {
+		date             time.Time
+		year, month, day int
+	}{
+		{time.Date(2026, time.March, 21, 0, 0, 0, 0, time.UTC), 1405, 1, 1},
+		{time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC), 1405, 7, 26},
+		{time.Date(2025, time.March, 20, 0, 0, 0, 0, time.UTC), 1403, 12, 30},
+		{time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC), 1402, 12, 10},
+	}
+	for _, tt := range tests {
+		year, month, day := toJalali(tt.date)
+		assert.Equal(t, [3]int{tt.year, tt.month, tt.day}, [3]int{year, month, day}, tt.date.Format(time.DateOnly))
+	}
+}
@@ -0,0 +1,248 @@
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Operation documents one route. Paths use gin syntax (":id", "*path") and
+// are relative to the prefix given to Document.Add.
+type Operation struct {
+	Method      string
+	Path        string
+	ID          string
+	Summary     string
+	Description string
+	Tags        []string
+	Deprecated  bool
+	// PathParams gives the Go type of path parameters by name, e.g.
+	// uuid.UUID{}. Parameters not listed are plain strings.
+	PathParams map[string]any
+	// Query lists structs bound from the query string.
+	Query []any
+	// Body is the JSON request body, or a Request for other content.
+	Body any
+	// Responses maps status codes to the JSON body type, a Response for
+	// other content, or nil for responses without a body.
+	Responses map[int]any
+}
+
+// OneOf documents a JSON body that takes one of several shapes, e.g.
+// depending on the query parameters.
+type OneOf []any
+
+// Request describes a non-JSON request body, e.g. a multipart form whose
+// file fields are multipart.FileHeader values.
+type Request struct {
+	ContentType string
+	Body        any
+}
+
+// Response describes a non-JSON or specially described response.
+type Response struct {
+	Description string
+	ContentType string
+	Body        any
+	Headers     map[string]string
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type Tag struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas,omitempty"`
+}
+
+// Document is an OpenAPI 3.1 document built from route registrations.
+type Document struct {
+	OpenAPI    string                                 `json:"openapi"`
+	Info       Info                                   `json:"info"`
+	Tags       []Tag                                  `json:"tags,omitempty"`
+	Paths      map[string]map[string]*operationObject `json:"paths"`
+	Components Components                             `json:"components"`
+
+	gen *generator
+}
+
+type operationObject struct {
+	OperationID string                     `json:"operationId,omitempty"`
+	Summary     string                     `json:"summary,omitempty"`
+	Description string                     `json:"description,omitempty"`
+	Tags        []string                   `json:"tags,omitempty"`
+	Deprecated  bool                       `json:"deprecated,omitempty"`
+	Parameters  []*Parameter               `json:"parameters,omitempty"`
+	RequestBody *requestBody               `json:"requestBody,omitempty"`
+	Responses   map[string]*responseObject `json:"responses"`
+}
+
+type requestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]mediaType `json:"content"`
+}
+
+type responseObject struct {
+	Description string                  `json:"description"`
+	Headers     map[string]headerObject `json:"headers,omitempty"`
+	Content     map[string]mediaType    `json:"content,omitempty"`
+}
+
+type headerObject struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type mediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+func New(info Info) *Document {
+	gen := newGenerator()
+	return &Document{
+		OpenAPI:    "3.1.0",
+		Info:       info,
+		Paths:      make(map[string]map[string]*operationObject),
+		Components: Components{Schemas: gen.schemas},
+		gen:        gen,
+	}
+}
+
+func (d *Document) AddTag(name, description string) {
+	d.Tags = append(d.Tags, Tag{Name: name, Description: description})
+}
+
+// Add documents operations registered under the gin group prefix.
+func (d *Document) Add(prefix string, ops ...Operation) {
+	for _, op := range ops {
+		path := ToOpenAPIPath(joinPaths(prefix, op.Path))
+		if d.Paths[path] == nil {
+			d.Paths[path] = make(map[string]*operationObject)
+		}
+		d.Paths[path][strings.ToLower(op.Method)] = d.operation(path, op)
+	}
+}
+
+// AddResponse documents a response every operation under prefix can return,
+// e.g. 429 from a rate limiting middleware on that group.
+func (d *Document) AddResponse(prefix string, status int, body any) {
+	prefix = ToOpenAPIPath(prefix)
+	for path, item := range d.Paths {
+		if !strings.HasPrefix(path, prefix) {
+			continue
+		}
+		for _, op := range item {
+			if _, ok := op.Responses[statusKey(status)]; !ok {
+				op.Responses[statusKey(status)] = d.response(status, body)
+			}
+		}
+	}
+}
+
+func (d *Document) operation(path string, op Operation) *operationObject {
+	o := &operationObject{
+		OperationID: op.ID,
+		Summary:     op.Summary,
+		Description: op.Description,
+		Tags:        op.Tags,
+		Deprecated:  op.Deprecated,
+		Responses:   make(map[string]*responseObject, len(op.Responses)),
+	}
+
+	for _, name := range pathParams.FindAllStringSubmatch(path, -1) {
+		s := &Schema{Type: "string"}
+		if v, ok := op.PathParams[name[1]]; ok {
+			s = d.gen.schema(reflect.TypeOf(v))
+		}
+		o.Parameters = append(o.Parameters, &Parameter{Name: name[1], In: "path", Required: true, Schema: s})
+	}
+	for _, query := range op.Query {
+		o.Parameters = append(o.Parameters, d.gen.queryParameters(reflect.TypeOf(query))...)
+	}
+
+	if op.Body != nil {
+		r, ok := op.Body.(Request)
+		if !ok {
+			r = Request{ContentType: "application/json", Body: op.Body}
+		}
+		o.RequestBody = &requestBody{
+			Required: true,
+			Content:  map[string]mediaType{r.ContentType: {Schema: d.schema(r.Body)}},
+		}
+	}
+
+	for status, body := range op.Responses {
+		o.Responses[statusKey(status)] = d.response(status, body)
+	}
+	return o
+}
+
+func (d *Document) schema(body any) *Schema {
+	oneOf, ok := body.(OneOf)
+	if !ok {
+		return d.gen.schema(reflect.TypeOf(body))
+	}
+
+	s := &Schema{}
+	for _, b := range oneOf {
+		s.OneOf = append(s.OneOf, d.gen.schema(reflect.TypeOf(b)))
+	}
+	return s
+}
+
+func (d *Document) response(status int, body any) *responseObject {
+	r, ok := body.(Response)
+	if !ok {
+		r = Response{Body: body}
+	}
+	if r.Description == "" {
+		r.Description = http.StatusText(status)
+	}
+	if r.ContentType == "" {
+		r.ContentType = "application/json"
+	}
+
+	o := &responseObject{Description: r.Description}
+	if r.Body != nil {
+		o.Content = map[string]mediaType{r.ContentType: {Schema: d.schema(r.Body)}}
+	}
+	for name, description := range r.Headers {
+		if o.Headers == nil {
+			o.Headers = make(map[string]headerObject)
+		}
+		o.Headers[name] = headerObject{Description: description, Schema: &Schema{Type: "string"}}
+	}
+	return o
+}
+
+var (
+	ginParams  = regexp.MustCompile(`/[:*]([A-Za-z0-9_]+)`)
+	pathParams = regexp.MustCompile(`\{([A-Za-z0-9_]+)\}`)
+)
+
+// ToOpenAPIPath converts gin path parameters (":id", "*path") to OpenAPI
+// templates ("{id}", "{path}"). A colon inside a segment is kept as is, so
+// custom methods such as "/doctors:batchGet" are documented literally.
+func ToOpenAPIPath(path string) string {
+	return ginParams.ReplaceAllString(path, "/{$1}")
+}
+
+func joinPaths(prefix, path string) string {
+	if path == "" {
+		return prefix
+	}
+	return strings.TrimSuffix(prefix, "/") + "/" + strings.TrimPrefix(path, "/")
+}
+
+func statusKey(status int) string {
+	return strconv.Itoa(status)
+}
@@ -0,0 +1,131 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type address struct {
+	City string `json:"city"`
+}
+
+type page[T any] struct {
+	Items []T     `json:"items"`
+	Next  *string `json:"next"`
+}
+
+type doctor struct {
+	ID        uuid.UUID         `json:"id"`
+	Name      string            `json:"name" doc:"Full name"`
+	Address   *address          `json:"address,omitempty"`
+	Tags      map[string]string `json:"tags,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	Secret    string            `json:"-"`
+}
+
+type listQuery struct {
+	Page  int    `form:"page,default=1" binding:"min=1"`
+	Limit int    `form:"limit,default=10" binding:"min=1,max=100"`
+	Sort  string `form:"sort" binding:"required,oneof=name created_at"`
+	Skip  string `form:"-"`
+}
+
+func TestDocument_Schemas(t *testing.T) {
+	doc := New(Info{Title: "test", Version: "1"})
+	doc.Add("/api", Operation{
+		Method:    http.MethodGet,
+		Path:      "/doctors",
+		Responses: map[int]any{http.StatusOK: page[doctor]{}},
+	})
+
+	schemas := doc.Components.Schemas
+	require.Contains(t, schemas, "OpenapiPageOpenapiDoctor")
+	require.Contains(t, schemas, "OpenapiDoctor")
+
+	d := schemas["OpenapiDoctor"]
+	assert.Equal(t, []string{"id", "name", "created_at"}, d.Required)
+	assert.Equal(t, "uuid", d.Properties["id"].Format)
+	assert.Equal(t, "date-time", d.Properties["created_at"].Format)
+	assert.Equal(t, "Full name", d.Properties["name"].Description)
+	assert.NotContains(t, d.Properties, "Secret")
+	assert.Equal(t, "#/components/schemas/OpenapiAddress", d.Properties["address"].AnyOf[0].Ref)
+	assert.Equal(t, "string", d.Properties["tags"].AdditionalProperties.Type)
+
+	p := schemas["OpenapiPageOpenapiDoctor"]
+	assert.Equal(t, "#/components/schemas/OpenapiDoctor", p.Properties["items"].Items.Ref)
+	assert.Equal(t, []string{"string", "null"}, p.Properties["next"].Type)
+}
+
+func TestDocument_Parameters(t *testing.T) {
+	doc := New(Info{Title: "test", Version: "1"})
+	doc.Add("/api/doctors", Operation{
+		Method:     http.MethodGet,
+		Path:       "/:id/slots",
+		PathParams: map[string]any{"id": uuid.UUID{}},
+		Query:      []any{listQuery{}},
+		Responses:  map[int]any{http.StatusNoContent: nil},
+	})
+
+	op := doc.Paths["/api/doctors/{id}/slots"]["get"]
+	require.NotNil(t, op)
+	require.Len(t, op.Parameters, 4)
+
+	id := op.Parameters[0]
+	assert.Equal(t, "path", id.In)
+	assert.True(t, id.Required)
+	assert.Equal(t, "uuid", id.Schema.Format)
+
+	page := op.Parameters[1]
+	assert.Equal(t, int64(1), page.Schema.Default)
+	assert.Equal(t, 1.0, *page.Schema.Minimum)
+
+	limit := op.Parameters[2]
+	assert.Equal(t, 100.0, *limit.Schema.Maximum)
+
+	sort := op.Parameters[3]
+	assert.True(t, sort.Required)
+	assert.Equal(t, []any{"name", "created_at"}, sort.Schema.Enum)
+
+	assert.Nil(t, op.Responses["204"].Content)
+}
+
+func TestDocument_AddResponse(t *testing.T) {
+	doc := New(Info{Title: "test", Version: "1"})
+	doc.Add("/api/medical", Operation{Method: http.MethodGet, Path: "/doctors", Responses: map[int]any{http.StatusOK: doctor{}}})
+	doc.Add("/", Operation{Method: http.MethodGet, Path: "/livez", Responses: map[int]any{http.StatusOK: nil}})
+
+	doc.AddResponse("/api/medical", http.StatusTooManyRequests, Response{Body: address{}, Headers: map[string]string{"Retry-After": "seconds"}})
+
+	assert.Contains(t, doc.Paths["/api/medical/doctors"]["get"].Responses, "429")
+	assert.NotContains(t, doc.Paths["/livez"]["get"].Responses, "429")
+
+	body, err := json.Marshal(doc)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `"openapi":"3.1.0"`)
+}
+
+func TestToOpenAPIPath(t *testing.T) {
+	assert.Equal(t, "/doctors/{id}", ToOpenAPIPath("/doctors/:id"))
+	assert.Equal(t, "/media/{path}", ToOpenAPIPath("/media/*path"))
+	assert.Equal(t, "/doctors:batchGet", ToOpenAPIPath("/doctors:batchGet"))
+}
+
+func TestDocument_OneOfResponse(t *testing.T) {
+	doc := New(Info{Title: "test", Version: "1"})
+	doc.Add("/api", Operation{
+		Method:    http.MethodGet,
+		Path:      "/doctors",
+		Responses: map[int]any{http.StatusOK: OneOf{page[doctor]{}, []doctor{}}},
+	})
+
+	s := doc.Paths["/api/doctors"]["get"].Responses["200"].Content["application/json"].Schema
+	require.Len(t, s.OneOf, 2)
+	assert.Equal(t, "#/components/schemas/OpenapiPageOpenapiDoctor", s.OneOf[0].Ref)
+	assert.Equal(t, "array", s.OneOf[1].Type)
+}
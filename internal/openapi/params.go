@@ -0,0 +1,127 @@
+package openapi
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// queryParameters describes the fields of a struct bound with
+// c.ShouldBindQuery: names and defaults come from `form` tags and
+// constraints from `binding` tags.
+func (g *generator) queryParameters(t reflect.Type) []*Parameter {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	var params []*Parameter
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("form")
+		if tag == "-" {
+			continue
+		}
+		if f.Anonymous && tag == "" && f.Type.Kind() == reflect.Struct {
+			params = append(params, g.queryParameters(f.Type)...)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = f.Name
+		}
+
+		s := g.schema(f.Type)
+		if s.Ref == "" {
+			cp := *s
+			s = &cp
+		}
+		if def, ok := strings.CutPrefix(opts, "default="); ok {
+			s.Default = parseScalar(f.Type, def)
+		}
+
+		param := &Parameter{Name: name, In: "query", Description: f.Tag.Get("doc"), Schema: s}
+		applyBinding(param, f.Type, f.Tag.Get("binding"))
+		params = append(params, param)
+	}
+	return params
+}
+
+// applyBinding maps the validator rules the API relies on to schema
+// keywords. Other rules are left to the description.
+func applyBinding(param *Parameter, t reflect.Type, binding string) {
+	if binding == "" {
+		return
+	}
+
+	s := param.Schema
+	sized := t.Kind() == reflect.String || t.Kind() == reflect.Slice
+	for _, rule := range strings.Split(binding, ",") {
+		key, value, _ := strings.Cut(rule, "=")
+		switch key {
+		case "required":
+			param.Required = true
+		case "min", "gte", "max", "lte":
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			lower := key == "min" || key == "gte"
+			switch {
+			case sized && t.Kind() == reflect.String && lower:
+				s.MinLength = intPtr(int(n))
+			case sized && t.Kind() == reflect.String:
+				s.MaxLength = intPtr(int(n))
+			case sized && lower:
+				s.MinItems = intPtr(int(n))
+			case sized:
+				s.MaxItems = intPtr(int(n))
+			case lower:
+				s.Minimum = &n
+			default:
+				s.Maximum = &n
+			}
+		case "oneof":
+			for _, option := range strings.Fields(value) {
+				s.Enum = append(s.Enum, parseScalar(t, option))
+			}
+		}
+	}
+}
+
+func parseScalar(t reflect.Type, raw string) any {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n, err := strconv.ParseInt(raw, 10, 64); err == nil {
+			return n
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if n, err := strconv.ParseUint(raw, 10, 64); err == nil {
+			return n
+		}
+	case reflect.Float32, reflect.Float64:
+		if n, err := strconv.ParseFloat(raw, 64); err == nil {
+			return n
+		}
+	case reflect.Bool:
+		if b, err := strconv.ParseBool(raw); err == nil {
+			return b
+		}
+	}
+	return raw
+}
+
+func intPtr(n int) *int {
+	return &n
+}
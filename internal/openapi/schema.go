@@ -0,0 +1,234 @@
+package openapi
+
+import (
+	"encoding/json"
+	"mime/multipart"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Schema is the JSON Schema (2020-12 dialect, as used by OpenAPI 3.1) subset
+// the generator emits.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 any                `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Default              any                `json:"default,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	uuidType       = reflect.TypeOf(uuid.UUID{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+	fileType       = reflect.TypeOf(multipart.FileHeader{})
+)
+
+// generator turns Go types into schemas, collecting named structs as
+// reusable components.
+type generator struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+func newGenerator() *generator {
+	return &generator{
+		schemas: make(map[string]*Schema),
+		names:   make(map[reflect.Type]string),
+	}
+}
+
+func (g *generator) schema(t reflect.Type) *Schema {
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case uuidType:
+		return &Schema{Type: "string", Format: "uuid"}
+	case rawMessageType:
+		return &Schema{}
+	case fileType:
+		return &Schema{Type: "string", Format: "binary"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return nullable(g.schema(t.Elem()))
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case reflect.Struct:
+		return g.component(t)
+	default:
+		return &Schema{}
+	}
+}
+
+// component registers a named struct under #/components/schemas and returns
+// a reference to it. Anonymous structs are inlined.
+func (g *generator) component(t reflect.Type) *Schema {
+	if t.Name() == "" {
+		return g.object(t)
+	}
+
+	if name, ok := g.names[t]; ok {
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+
+	name := g.uniqueName(schemaName(t))
+	g.names[t] = name
+	// Reserve the name first so recursive types terminate
+	g.schemas[name] = &Schema{}
+	*g.schemas[name] = *g.object(t)
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+func (g *generator) uniqueName(name string) string {
+	unique := name
+	for i := 2; ; i++ {
+		if _, taken := g.schemas[unique]; !taken {
+			return unique
+		}
+		unique = name + strconv.Itoa(i)
+	}
+}
+
+func (g *generator) object(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	g.addFields(s, t)
+	return s
+}
+
+func (g *generator) addFields(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, omitEmpty, skip := jsonName(f)
+		if skip {
+			continue
+		}
+
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				g.addFields(s, ft)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		fs := g.schema(f.Type)
+		if desc := f.Tag.Get("doc"); desc != "" {
+			fs = withDescription(fs, desc)
+		}
+		s.Properties[name] = fs
+		if !omitEmpty {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+func jsonName(f reflect.StructField) (name string, omitEmpty, skip bool) {
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", false, true
+	}
+	name, opts, _ := strings.Cut(tag, ",")
+	for _, opt := range strings.Split(opts, ",") {
+		if opt == "omitempty" || opt == "omitzero" {
+			omitEmpty = true
+		}
+	}
+	return name, omitEmpty, false
+}
+
+func nullable(s *Schema) *Schema {
+	if typ, ok := s.Type.(string); ok && s.Ref == "" {
+		cp := *s
+		cp.Type = []string{typ, "null"}
+		return &cp
+	}
+	return &Schema{AnyOf: []*Schema{s, {Type: "null"}}}
+}
+
+// withDescription attaches a description without mutating a shared
+// component; $ref siblings are allowed in OpenAPI 3.1.
+func withDescription(s *Schema, description string) *Schema {
+	cp := *s
+	cp.Description = description
+	return &cp
+}
+
+var genericArgs = regexp.MustCompile(`\[(.*)\]$`)
+
+// schemaName qualifies the type with its package so that e.g.
+// doctor.ListItemDTO and specialty.ListItemDTO become DoctorListItemDTO and
+// SpecialtyListItemDTO. Generic instantiations append their type arguments.
+func schemaName(t reflect.Type) string {
+	pkg := t.PkgPath()
+	pkg = pkg[strings.LastIndex(pkg, "/")+1:]
+
+	name := t.Name()
+	var args string
+	if m := genericArgs.FindStringSubmatch(name); m != nil {
+		name = strings.TrimSuffix(name, m[0])
+		for _, arg := range strings.Split(m[1], ",") {
+			arg = arg[strings.LastIndex(arg, "/")+1:]
+			argPkg, argName, found := strings.Cut(arg, ".")
+			if !found {
+				argName, argPkg = argPkg, ""
+			}
+			args += capitalize(argPkg) + capitalize(argName)
+		}
+	}
+
+	if strings.HasPrefix(strings.ToLower(name), pkg) {
+		return capitalize(name) + args
+	}
+	return capitalize(pkg) + capitalize(name) + args
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
@@ -15,8 +15,8 @@ import (
 var _ Params = (*LimitOffsetParams)(nil)
 
 type LimitOffsetParams struct {
-	Page         int        `form:"page,default=1" binding:"min=1"`
-	Limit        int        `form:"limit,default=10" binding:"min=1,max=100"`
+	Page         int        `form:"page,default=1" binding:"min=1" doc:"1-based page number"`
+	Limit        int        `form:"limit,default=10" binding:"min=1,max=100" doc:"Items per page"`
 	BaseURL      string     `form:"-"`
 	ClientParams url.Values `form:"-"`
 }
@@ -0,0 +1,59 @@
+package payment
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/money"
+)
+
+const fakeAuthorityPrefix = "FAKE-"
+
+// FakeProvider approves every payment without taking money, for local
+// runs and tests. Its checkout sends the payer straight back to the
+// callback URL as having paid.
+type FakeProvider struct{}
+
+func NewFakeProvider() *FakeProvider {
+	return &FakeProvider{}
+}
+
+func (p *FakeProvider) Name() string { return "fake" }
+
+func (p *FakeProvider) Checkout(ctx context.Context, req CheckoutRequest) (Checkout, error) {
+	callback, err := url.Parse(req.CallbackURL)
+	if err != nil {
+		return Checkout{}, fmt.Errorf("invalid callback url: %w", err)
+	}
+	authority := fakeAuthorityPrefix + req.PaymentID.String()
+	query := callback.Query()
+	query.Set("Authority", authority)
+	query.Set("Status", "OK")
+	callback.RawQuery = query.Encode()
+	return Checkout{Authority: authority, RedirectURL: callback.String()}, nil
+}
+
+func (p *FakeProvider) ParseCallback(values url.Values) (Callback, error) {
+	return parseAuthorityCallback(values)
+}
+
+func (p *FakeProvider) Verify(ctx context.Context, authority string, amount money.Money) (Verification, error) {
+	id, err := uuid.Parse(strings.TrimPrefix(authority, fakeAuthorityPrefix))
+	if err != nil || !strings.HasPrefix(authority, fakeAuthorityPrefix) {
+		return Verification{}, fmt.Errorf("%w: unknown authority %q", ErrDeclined, authority)
+	}
+	ref := id.String()
+	return Verification{RefID: "FAKE-" + strings.ToUpper(ref[len(ref)-8:]), CardPAN: "000000******0000"}, nil
+}
+
+func (p *FakeProvider) Refund(ctx context.Context, req RefundRequest) (string, error) {
+	if !strings.HasPrefix(req.Authority, fakeAuthorityPrefix) {
+		return "", fmt.Errorf("%w: unknown authority %q", ErrDeclined, req.Authority)
+	}
+	ref := req.RefundID.String()
+	return "FAKE-R-" + strings.ToUpper(ref[len(ref)-8:]), nil
+}
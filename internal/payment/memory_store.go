@@ -0,0 +1,221 @@
+package payment
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/money"
+)
+
+// MemoryStore keeps payments in process memory, for tests. GetForUpdate
+// and GetRefundForUpdate lock nothing.
+type MemoryStore struct {
+	mu       sync.Mutex
+	payments map[uuid.UUID]Payment
+	deposits map[uuid.UUID]money.Money
+	policies map[uuid.UUID]CancellationPolicy
+	refunds  map[uuid.UUID]Refund
+	ledger   []LedgerEntry
+	now      func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		payments: make(map[uuid.UUID]Payment),
+		deposits: make(map[uuid.UUID]money.Money),
+		policies: make(map[uuid.UUID]CancellationPolicy),
+		refunds:  make(map[uuid.UUID]Refund),
+		now:      time.Now,
+	}
+}
+
+// SetDeposit sets the deposit a doctor takes, zero for none.
+func (s *MemoryStore) SetDeposit(doctorID uuid.UUID, amount money.Money) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if amount.Amount == 0 {
+		delete(s.deposits, doctorID)
+		return
+	}
+	s.deposits[doctorID] = amount
+}
+
+// SetCancellationPolicy sets a doctor's cancellation policy.
+func (s *MemoryStore) SetCancellationPolicy(doctorID uuid.UUID, policy CancellationPolicy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.policies[doctorID] = policy
+}
+
+func (s *MemoryStore) Create(ctx context.Context, p Payment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p.CreatedAt, p.UpdatedAt = s.now(), s.now()
+	s.payments[p.ID] = p
+	return nil
+}
+
+func (s *MemoryStore) Get(ctx context.Context, id uuid.UUID) (Payment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.payments[id]
+	if !ok {
+		return Payment{}, ErrPaymentNotFound
+	}
+	return p, nil
+}
+
+func (s *MemoryStore) GetForUpdate(ctx context.Context, id uuid.UUID) (Payment, error) {
+	return s.Get(ctx, id)
+}
+
+func (s *MemoryStore) Update(ctx context.Context, p Payment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.payments[p.ID]
+	if !ok {
+		return ErrPaymentNotFound
+	}
+	stored.Status, stored.Authority, stored.RedirectURL = p.Status, p.Authority, p.RedirectURL
+	stored.RefID, stored.CardPAN, stored.LastError, stored.PaidAt = p.RefID, p.CardPAN, p.LastError, p.PaidAt
+	stored.UpdatedAt = s.now()
+	s.payments[p.ID] = stored
+	return nil
+}
+
+func (s *MemoryStore) ListByAppointment(ctx context.Context, appointmentID uuid.UUID) ([]Payment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var list []Payment
+	for _, p := range s.payments {
+		if p.AppointmentID == appointmentID {
+			list = append(list, p)
+		}
+	}
+	slices.SortFunc(list, func(a, b Payment) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return list, nil
+}
+
+func (s *MemoryStore) ListExpired(ctx context.Context, now time.Time, after Payment, limit int) ([]Payment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	byExpiry := func(a, b Payment) int {
+		if c := a.ExpiresAt.Compare(b.ExpiresAt); c != 0 {
+			return c
+		}
+		return bytes.Compare(a.ID[:], b.ID[:])
+	}
+	var expired []Payment
+	for _, p := range s.payments {
+		if p.Status == StatusPending && p.ExpiresAt.Before(now) && byExpiry(p, after) > 0 {
+			expired = append(expired, p)
+		}
+	}
+	slices.SortFunc(expired, byExpiry)
+	if len(expired) > limit {
+		expired = expired[:limit]
+	}
+	return expired, nil
+}
+
+func (s *MemoryStore) Deposit(ctx context.Context, doctorID uuid.UUID) (money.Money, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if amount, ok := s.deposits[doctorID]; ok {
+		return amount, nil
+	}
+	return money.Rials(0), nil
+}
+
+func (s *MemoryStore) CancellationPolicy(ctx context.Context, doctorID uuid.UUID) (CancellationPolicy, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if policy, ok := s.policies[doctorID]; ok {
+		return policy, nil
+	}
+	return DefaultCancellationPolicy, nil
+}
+
+func (s *MemoryStore) CreateRefund(ctx context.Context, r Refund) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r.CreatedAt, r.UpdatedAt = s.now(), s.now()
+	s.refunds[r.ID] = r
+	return nil
+}
+
+func (s *MemoryStore) GetRefundForUpdate(ctx context.Context, id uuid.UUID) (Refund, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.refunds[id]
+	if !ok {
+		return Refund{}, ErrRefundNotFound
+	}
+	return r, nil
+}
+
+func (s *MemoryStore) UpdateRefund(ctx context.Context, r Refund) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.refunds[r.ID]
+	if !ok {
+		return ErrRefundNotFound
+	}
+	stored.Status, stored.RefID, stored.LastError, stored.RefundedAt = r.Status, r.RefID, r.LastError, r.RefundedAt
+	stored.UpdatedAt = s.now()
+	s.refunds[r.ID] = stored
+	return nil
+}
+
+func (s *MemoryStore) ListRefunds(ctx context.Context, appointmentID uuid.UUID) ([]Refund, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var list []Refund
+	for _, r := range s.refunds {
+		if r.AppointmentID == appointmentID {
+			list = append(list, r)
+		}
+	}
+	slices.SortFunc(list, func(a, b Refund) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return list, nil
+}
+
+func (s *MemoryStore) AddLedgerEntry(ctx context.Context, e LedgerEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e.CreatedAt = s.now()
+	s.ledger = append(s.ledger, e)
+	return nil
+}
+
+func (s *MemoryStore) Ledger(ctx context.Context, appointmentID uuid.UUID) ([]LedgerEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var entries []LedgerEntry
+	for _, e := range s.ledger {
+		if e.AppointmentID == appointmentID {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
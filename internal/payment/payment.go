@@ -0,0 +1,66 @@
+// Package payment takes appointment deposits through redirect-based
+// gateways such as Zarinpal and IDPay: a payment is registered with the
+// gateway, the payer is redirected to it, and the gateway sends them back to
+// a callback URL, after which the payment is verified with the gateway
+// before it counts as paid.
+package payment
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/money"
+)
+
+var (
+	ErrPaymentNotFound = errors.New("payment not found")
+	ErrRefundNotFound  = errors.New("refund not found")
+	// ErrInvalidCallback is returned for callbacks that don't belong to the
+	// payment they were sent for or can't be read.
+	ErrInvalidCallback = errors.New("invalid payment callback")
+	// ErrDeclined is returned by Provider.Verify when the gateway reports
+	// the payment as not made, and by Provider.Refund when it refuses the
+	// refund; retrying cannot help.
+	ErrDeclined = errors.New("payment declined")
+)
+
+type Status string
+
+const (
+	// StatusPending payments are waiting for the payer to come back from
+	// the gateway
+	StatusPending Status = "pending"
+	StatusPaid    Status = "paid"
+	// StatusFailed payments were cancelled by the payer, declined by the
+	// gateway or could not be started
+	StatusFailed Status = "failed"
+	// StatusExpired payments were not completed in time
+	StatusExpired Status = "expired"
+)
+
+// Payment is a deposit taken for an appointment.
+type Payment struct {
+	ID            uuid.UUID
+	AppointmentID uuid.UUID
+	// Provider is the Name of the provider the payment was made with
+	Provider string
+	Amount   money.Money
+	Status   Status
+	// Authority identifies the payment at the gateway
+	Authority string
+	// RedirectURL is the gateway page the payer is sent to
+	RedirectURL string
+	// RefID is the gateway's reference number of a verified payment,
+	// shown to the payer as the receipt number
+	RefID string
+	// CardPAN is the masked card number the payment was made with
+	CardPAN   string
+	LastError string
+	// ExpiresAt is when a pending payment stops being accepted
+	ExpiresAt time.Time
+	PaidAt    *time.Time
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
@@ -0,0 +1,269 @@
+package payment
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/database"
+	"github.com/shayesteh1hs/DrAppointment/internal/money"
+)
+
+const paymentColumns = "id, appointment_id, provider, amount, currency, status, authority, redirect_url, ref_id, card_pan, last_error, expires_at, paid_at, created_at, updated_at"
+
+const (
+	insertPaymentQuery = "INSERT INTO payments (id, appointment_id, provider, amount, currency, status, authority, redirect_url, expires_at) " +
+		"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)"
+
+	selectPaymentQuery = "SELECT " + paymentColumns + " FROM payments WHERE id = $1"
+
+	selectPaymentForUpdateQuery = selectPaymentQuery + " FOR UPDATE"
+
+	updatePaymentQuery = "UPDATE payments SET status = $2, authority = $3, redirect_url = $4, ref_id = $5, card_pan = $6, last_error = $7, paid_at = $8 WHERE id = $1"
+
+	listAppointmentPaymentsQuery = "SELECT " + paymentColumns + " FROM payments WHERE appointment_id = $1 ORDER BY created_at, id"
+
+	listExpiredQuery = "SELECT " + paymentColumns + " FROM payments " +
+		"WHERE status = 'pending' AND expires_at < $1 AND (expires_at, id) > ($2, $3) ORDER BY expires_at, id LIMIT $4"
+
+	selectDepositQuery = "SELECT amount FROM doctor_deposits WHERE doctor_id = $1"
+
+	selectPolicyQuery = "SELECT full_refund_hours, late_fee_percent FROM cancellation_policies WHERE doctor_id = $1"
+)
+
+const refundColumns = "id, payment_id, appointment_id, amount, fee, currency, status, ref_id, last_error, refunded_at, created_at, updated_at"
+
+const (
+	insertRefundQuery = "INSERT INTO refunds (id, payment_id, appointment_id, amount, fee, currency, status) " +
+		"VALUES ($1, $2, $3, $4, $5, $6, $7)"
+
+	selectRefundForUpdateQuery = "SELECT " + refundColumns + " FROM refunds WHERE id = $1 FOR UPDATE"
+
+	updateRefundQuery = "UPDATE refunds SET status = $2, ref_id = $3, last_error = $4, refunded_at = $5 WHERE id = $1"
+
+	listRefundsQuery = "SELECT " + refundColumns + " FROM refunds WHERE appointment_id = $1 ORDER BY created_at, id"
+
+	insertLedgerEntryQuery = "INSERT INTO ledger_entries (id, appointment_id, payment_id, refund_id, kind, amount, currency, reference) " +
+		"VALUES ($1, $2, $3, $4, $5, $6, $7, $8)"
+
+	listLedgerQuery = "SELECT id, appointment_id, payment_id, refund_id, kind, amount, currency, reference, created_at " +
+		"FROM ledger_entries WHERE appointment_id = $1 ORDER BY created_at, id"
+)
+
+// PostgresStore keeps payments in the payments table and reads deposits
+// from doctor_deposits.
+type PostgresStore struct {
+	db database.Querier
+}
+
+func NewPostgresStore(db database.Querier) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanPayment(row scanner) (Payment, error) {
+	var p Payment
+	err := row.Scan(&p.ID, &p.AppointmentID, &p.Provider, &p.Amount.Amount, &p.Amount.Currency, &p.Status, &p.Authority,
+		&p.RedirectURL, &p.RefID, &p.CardPAN, &p.LastError, &p.ExpiresAt, &p.PaidAt, &p.CreatedAt, &p.UpdatedAt)
+	return p, err
+}
+
+func (s *PostgresStore) Create(ctx context.Context, p Payment) error {
+	_, err := s.db.ExecContext(ctx, insertPaymentQuery, p.ID, p.AppointmentID, p.Provider, p.Amount.Amount, p.Amount.Currency,
+		p.Status, p.Authority, p.RedirectURL, p.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to create payment: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) Get(ctx context.Context, id uuid.UUID) (Payment, error) {
+	return s.get(ctx, selectPaymentQuery, id)
+}
+
+func (s *PostgresStore) GetForUpdate(ctx context.Context, id uuid.UUID) (Payment, error) {
+	return s.get(ctx, selectPaymentForUpdateQuery, id)
+}
+
+func (s *PostgresStore) get(ctx context.Context, query string, id uuid.UUID) (Payment, error) {
+	p, err := scanPayment(s.db.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Payment{}, ErrPaymentNotFound
+	}
+	if err != nil {
+		return Payment{}, fmt.Errorf("failed to get payment: %w", err)
+	}
+	return p, nil
+}
+
+func (s *PostgresStore) Update(ctx context.Context, p Payment) error {
+	result, err := s.db.ExecContext(ctx, updatePaymentQuery, p.ID, p.Status, p.Authority, p.RedirectURL, p.RefID,
+		p.CardPAN, p.LastError, p.PaidAt)
+	if err != nil {
+		return fmt.Errorf("failed to update payment: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrPaymentNotFound
+	}
+	return nil
+}
+
+func (s *PostgresStore) ListByAppointment(ctx context.Context, appointmentID uuid.UUID) ([]Payment, error) {
+	return s.query(ctx, listAppointmentPaymentsQuery, appointmentID)
+}
+
+func (s *PostgresStore) ListExpired(ctx context.Context, now time.Time, after Payment, limit int) ([]Payment, error) {
+	return s.query(ctx, listExpiredQuery, now, after.ExpiresAt, after.ID, limit)
+}
+
+func (s *PostgresStore) query(ctx context.Context, query string, args ...any) ([]Payment, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list payments: %w", err)
+	}
+	defer rows.Close()
+
+	var list []Payment
+	for rows.Next() {
+		p, err := scanPayment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan payment: %w", err)
+		}
+		list = append(list, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list payments: %w", err)
+	}
+	return list, nil
+}
+
+// Deposit reads the doctor's deposit, which is stored in rials.
+func (s *PostgresStore) Deposit(ctx context.Context, doctorID uuid.UUID) (money.Money, error) {
+	var amount int64
+	err := s.db.QueryRowContext(ctx, selectDepositQuery, doctorID).Scan(&amount)
+	if errors.Is(err, sql.ErrNoRows) {
+		return money.Rials(0), nil
+	}
+	if err != nil {
+		return money.Money{}, fmt.Errorf("failed to get doctor deposit: %w", err)
+	}
+	return money.Rials(amount), nil
+}
+
+func (s *PostgresStore) CancellationPolicy(ctx context.Context, doctorID uuid.UUID) (CancellationPolicy, error) {
+	var hours, percent int
+	err := s.db.QueryRowContext(ctx, selectPolicyQuery, doctorID).Scan(&hours, &percent)
+	if errors.Is(err, sql.ErrNoRows) {
+		return DefaultCancellationPolicy, nil
+	}
+	if err != nil {
+		return CancellationPolicy{}, fmt.Errorf("failed to get cancellation policy: %w", err)
+	}
+	return CancellationPolicy{FullRefundBefore: time.Duration(hours) * time.Hour, LateFeePercent: percent}, nil
+}
+
+func scanRefund(row scanner) (Refund, error) {
+	var r Refund
+	err := row.Scan(&r.ID, &r.PaymentID, &r.AppointmentID, &r.Amount.Amount, &r.Fee.Amount, &r.Amount.Currency,
+		&r.Status, &r.RefID, &r.LastError, &r.RefundedAt, &r.CreatedAt, &r.UpdatedAt)
+	r.Fee.Currency = r.Amount.Currency
+	return r, err
+}
+
+func (s *PostgresStore) CreateRefund(ctx context.Context, r Refund) error {
+	_, err := s.db.ExecContext(ctx, insertRefundQuery, r.ID, r.PaymentID, r.AppointmentID, r.Amount.Amount, r.Fee.Amount,
+		r.Amount.Currency, r.Status)
+	if err != nil {
+		return fmt.Errorf("failed to create refund: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) GetRefundForUpdate(ctx context.Context, id uuid.UUID) (Refund, error) {
+	r, err := scanRefund(s.db.QueryRowContext(ctx, selectRefundForUpdateQuery, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Refund{}, ErrRefundNotFound
+	}
+	if err != nil {
+		return Refund{}, fmt.Errorf("failed to get refund: %w", err)
+	}
+	return r, nil
+}
+
+func (s *PostgresStore) UpdateRefund(ctx context.Context, r Refund) error {
+	result, err := s.db.ExecContext(ctx, updateRefundQuery, r.ID, r.Status, r.RefID, r.LastError, r.RefundedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update refund: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrRefundNotFound
+	}
+	return nil
+}
+
+func (s *PostgresStore) ListRefunds(ctx context.Context, appointmentID uuid.UUID) ([]Refund, error) {
+	rows, err := s.db.QueryContext(ctx, listRefundsQuery, appointmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list refunds: %w", err)
+	}
+	defer rows.Close()
+
+	var list []Refund
+	for rows.Next() {
+		r, err := scanRefund(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan refund: %w", err)
+		}
+		list = append(list, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list refunds: %w", err)
+	}
+	return list, nil
+}
+
+func (s *PostgresStore) AddLedgerEntry(ctx context.Context, e LedgerEntry) error {
+	_, err := s.db.ExecContext(ctx, insertLedgerEntryQuery, e.ID, e.AppointmentID, e.PaymentID, e.RefundID, e.Kind,
+		e.Amount.Amount, e.Amount.Currency, e.Reference)
+	if err != nil {
+		return fmt.Errorf("failed to add ledger entry: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) Ledger(ctx context.Context, appointmentID uuid.UUID) ([]LedgerEntry, error) {
+	rows, err := s.db.QueryContext(ctx, listLedgerQuery, appointmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ledger entries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []LedgerEntry
+	for rows.Next() {
+		var e LedgerEntry
+		if err := rows.Scan(&e.ID, &e.AppointmentID, &e.PaymentID, &e.RefundID, &e.Kind, &e.Amount.Amount,
+			&e.Amount.Currency, &e.Reference, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan ledger entry: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list ledger entries: %w", err)
+	}
+	return entries, nil
+}
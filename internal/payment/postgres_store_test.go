@@ -0,0 +1,102 @@
+package payment
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/database"
+	"github.com/shayesteh1hs/DrAppointment/internal/money"
+)
+
+var testTime = time.Date(2026, time.October, 18, 9, 0, 0, 0, time.UTC)
+
+func TestPostgresStore_Deposit(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db := database.New(sqlDB)
+	defer db.Close()
+	store := NewPostgresStore(db)
+	doctorID := uuid.New()
+
+	mock.ExpectQuery(regexp.QuoteMeta(selectDepositQuery)).
+		WithArgs(doctorID).
+		WillReturnRows(sqlmock.NewRows([]string{"amount"}).AddRow(500_000))
+	amount, err := store.Deposit(context.Background(), doctorID)
+	require.NoError(t, err)
+	assert.Equal(t, money.Rials(500_000), amount)
+
+	mock.ExpectQuery(regexp.QuoteMeta(selectDepositQuery)).
+		WithArgs(doctorID).
+		WillReturnRows(sqlmock.NewRows([]string{"amount"}))
+	amount, err = store.Deposit(context.Background(), doctorID)
+	require.NoError(t, err, "doctors without a deposit take bookings straight away")
+	assert.Zero(t, amount.Amount)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStore_ListExpired(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db := database.New(sqlDB)
+	defer db.Close()
+
+	id, appointmentID := uuid.New(), uuid.New()
+	mock.ExpectQuery(regexp.QuoteMeta(listExpiredQuery)).
+		WithArgs(testTime, time.Time{}, uuid.Nil, 100).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "appointment_id", "provider", "amount", "currency", "status", "authority",
+			"redirect_url", "ref_id", "card_pan", "last_error", "expires_at", "paid_at", "created_at", "updated_at"}).
+			AddRow(id, appointmentID, "fake", 500_000, "IRR", "pending", "FAKE-1", "https://pay.example.com", "", "", "",
+				testTime.Add(-time.Minute), nil, testTime.Add(-16*time.Minute), testTime.Add(-16*time.Minute)))
+
+	expired, err := NewPostgresStore(db).ListExpired(context.Background(), testTime, Payment{}, 100)
+	require.NoError(t, err)
+	require.Len(t, expired, 1)
+	assert.Equal(t, id, expired[0].ID)
+	assert.Equal(t, appointmentID, expired[0].AppointmentID)
+	assert.Equal(t, money.Rials(500_000), expired[0].Amount)
+	assert.Equal(t, StatusPending, expired[0].Status)
+	assert.Nil(t, expired[0].PaidAt)
+
+	// The next page starts after the last payment listed
+	mock.ExpectQuery(regexp.QuoteMeta(listExpiredQuery)).
+		WithArgs(testTime, testTime.Add(-time.Minute), id, 100).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	expired, err = NewPostgresStore(db).ListExpired(context.Background(), testTime, expired[0], 100)
+	require.NoError(t, err)
+	assert.Empty(t, expired)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStore_CancellationPolicy(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db := database.New(sqlDB)
+	defer db.Close()
+	store := NewPostgresStore(db)
+	doctorID := uuid.New()
+
+	mock.ExpectQuery(regexp.QuoteMeta(selectPolicyQuery)).
+		WithArgs(doctorID).
+		WillReturnRows(sqlmock.NewRows([]string{"full_refund_hours", "late_fee_percent"}).AddRow(48, 20))
+	policy, err := store.CancellationPolicy(context.Background(), doctorID)
+	require.NoError(t, err)
+	assert.Equal(t, CancellationPolicy{FullRefundBefore: 48 * time.Hour, LateFeePercent: 20}, policy)
+
+	mock.ExpectQuery(regexp.QuoteMeta(selectPolicyQuery)).
+		WithArgs(doctorID).
+		WillReturnRows(sqlmock.NewRows([]string{"full_refund_hours", "late_fee_percent"}))
+	policy, err = store.CancellationPolicy(context.Background(), doctorID)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultCancellationPolicy, policy)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,83 @@
+package payment
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/google/uuid"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/money"
+)
+
+// CheckoutRequest registers a payment with a gateway.
+type CheckoutRequest struct {
+	PaymentID   uuid.UUID
+	Amount      money.Money
+	Description string
+	// CallbackURL is where the gateway sends the payer back to
+	CallbackURL string
+	// Mobile and Email prefill the gateway's form when set
+	Mobile string
+	Email  string
+}
+
+// Checkout is a payment registered with a gateway.
+type Checkout struct {
+	Authority   string
+	RedirectURL string
+}
+
+// Callback is what the gateway reports when sending the payer back. It is
+// not proof of payment, as anyone can make the same request; the payment
+// must be verified.
+type Callback struct {
+	Authority string
+	// OK is false when the payer cancelled or the payment failed
+	OK bool
+}
+
+// Verification is the gateway's confirmation of a payment.
+type Verification struct {
+	RefID   string
+	CardPAN string
+}
+
+// RefundRequest returns part or all of a verified payment.
+type RefundRequest struct {
+	RefundID uuid.UUID
+	// Authority and RefID identify the payment refunded
+	Authority   string
+	RefID       string
+	Amount      money.Money
+	Description string
+}
+
+// Provider is a redirect-based payment gateway.
+type Provider interface {
+	// Name is stored with every payment made with the provider
+	Name() string
+	// Checkout registers a payment and returns the page to send the payer to
+	Checkout(ctx context.Context, req CheckoutRequest) (Checkout, error)
+	// ParseCallback reads the query or form values of a request to the
+	// callback URL, returning ErrInvalidCallback when they are malformed
+	ParseCallback(values url.Values) (Callback, error)
+	// Verify confirms and settles a payment of amount. Payments the
+	// gateway reports as unpaid return an error wrapping ErrDeclined;
+	// verifying a payment again succeeds.
+	Verify(ctx context.Context, authority string, amount money.Money) (Verification, error)
+	// Refund returns amount of a verified payment to the payer and returns
+	// the gateway's reference of the refund. Refunds the gateway refuses
+	// return an error wrapping ErrDeclined.
+	Refund(ctx context.Context, req RefundRequest) (string, error)
+}
+
+// parseAuthorityCallback reads the Authority and Status parameters
+// Zarinpal-style gateways call back with; Status is "OK" for a payment
+// made.
+func parseAuthorityCallback(values url.Values) (Callback, error) {
+	authority := values.Get("Authority")
+	if authority == "" {
+		return Callback{}, ErrInvalidCallback
+	}
+	return Callback{Authority: authority, OK: values.Get("Status") == "OK"}, nil
+}
@@ -0,0 +1,129 @@
+package payment
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/money"
+)
+
+// DefaultCancellationPolicy applies to doctors without a policy of their
+// own.
+var DefaultCancellationPolicy = CancellationPolicy{FullRefundBefore: 24 * time.Hour, LateFeePercent: 50}
+
+// CancellationPolicy decides how much of a paid deposit is refunded when
+// an appointment is cancelled.
+type CancellationPolicy struct {
+	// FullRefundBefore is how long before the visit cancellations are
+	// still refunded in full
+	FullRefundBefore time.Duration
+	// LateFeePercent of the deposit is kept for cancellations after
+	// FullRefundBefore; nothing is refunded once the visit has started,
+	// as for no-shows
+	LateFeePercent int
+}
+
+// Refund splits a deposit of amount for an appointment starting at
+// startsAt and cancelled at cancelledAt into the part refunded and the fee
+// kept, both in the deposit's currency. The fee is rounded down.
+func (p CancellationPolicy) Refund(amount money.Money, startsAt, cancelledAt time.Time) (refund, fee money.Money, err error) {
+	none := money.New(0, amount.Currency)
+	switch notice := startsAt.Sub(cancelledAt); {
+	case notice <= 0:
+		return none, amount, nil
+	case notice >= p.FullRefundBefore:
+		return amount, none, nil
+	}
+	if fee, err = amount.Percent(int64(p.LateFeePercent)); err != nil {
+		return money.Money{}, money.Money{}, err
+	}
+	if refund, err = amount.Sub(fee); err != nil {
+		return money.Money{}, money.Money{}, err
+	}
+	return refund, fee, nil
+}
+
+type RefundStatus string
+
+const (
+	// RefundPending refunds are waiting to be sent to the provider
+	RefundPending RefundStatus = "pending"
+	// RefundSending refunds were handed to the provider and its answer is
+	// not recorded yet. Refunds left sending by a crash are reconciled like
+	// unconfirmed ones.
+	RefundSending   RefundStatus = "sending"
+	RefundSucceeded RefundStatus = "succeeded"
+	// RefundFailed refunds were refused by the provider and must be
+	// settled by hand
+	RefundFailed RefundStatus = "failed"
+	// RefundUnconfirmed refunds got no clear answer from the provider, so
+	// they may or may not have been made. They are never sent again and
+	// must be reconciled with the provider by hand.
+	RefundUnconfirmed RefundStatus = "unconfirmed"
+)
+
+// Refund returns part or all of a paid deposit to the payer.
+type Refund struct {
+	ID            uuid.UUID
+	PaymentID     uuid.UUID
+	AppointmentID uuid.UUID
+	Amount        money.Money
+	// Fee is the part of the deposit kept under the cancellation policy,
+	// in the currency of Amount
+	Fee    money.Money
+	Status RefundStatus
+	// RefID is the provider's reference of a succeeded refund
+	RefID      string
+	LastError  string
+	RefundedAt *time.Time
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+type EntryKind string
+
+const (
+	// EntryCharge is a deposit paid by the patient
+	EntryCharge EntryKind = "charge"
+	// EntryRefund is money returned to the patient
+	EntryRefund EntryKind = "refund"
+)
+
+// LedgerEntry records money moving for an appointment. Entries are only
+// added, once the provider confirmed the movement.
+type LedgerEntry struct {
+	ID            uuid.UUID
+	AppointmentID uuid.UUID
+	PaymentID     uuid.UUID
+	// RefundID is set on refund entries
+	RefundID *uuid.UUID
+	Kind     EntryKind
+	// Amount is always positive
+	Amount money.Money
+	// Reference is the provider's reference number of the movement
+	Reference string
+	CreatedAt time.Time
+}
+
+// Balance is what the patient paid for an appointment net of refunds, in
+// the currency of its entries; rials when there are none.
+func Balance(entries []LedgerEntry) (money.Money, error) {
+	balance := money.Rials(0)
+	if len(entries) > 0 {
+		balance = money.New(0, entries[0].Amount.Currency)
+	}
+	for _, e := range entries {
+		var err error
+		switch e.Kind {
+		case EntryCharge:
+			balance, err = balance.Add(e.Amount)
+		case EntryRefund:
+			balance, err = balance.Sub(e.Amount)
+		}
+		if err != nil {
+			return money.Money{}, err
+		}
+	}
+	return balance, nil
+}
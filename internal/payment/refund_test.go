@@ -0,0 +1,60 @@
+package payment
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/money"
+)
+
+func TestCancellationPolicy_Refund(t *testing.T) {
+	startsAt := time.Date(2026, time.October, 20, 9, 0, 0, 0, time.UTC)
+	policy := CancellationPolicy{FullRefundBefore: 24 * time.Hour, LateFeePercent: 30}
+	tests := []struct {
+		name   string
+		notice time.Duration
+		refund int64
+		fee    int64
+	}{
+		{name: "well ahead", notice: 48 * time.Hour, refund: 500_000},
+		{name: "just in time", notice: 24 * time.Hour, refund: 500_000},
+		{name: "late", notice: 2 * time.Hour, refund: 350_000, fee: 150_000},
+		{name: "no-show", notice: -time.Hour, fee: 500_000},
+		{name: "at the start", notice: 0, fee: 500_000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			refund, fee, err := policy.Refund(money.Rials(500_000), startsAt, startsAt.Add(-tt.notice))
+			require.NoError(t, err)
+			assert.Equal(t, money.Rials(tt.refund), refund)
+			assert.Equal(t, money.Rials(tt.fee), fee)
+		})
+	}
+
+	refund, fee, err := CancellationPolicy{FullRefundBefore: time.Hour, LateFeePercent: 33}.Refund(money.New(100_001, money.IRT), startsAt, startsAt.Add(-time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, money.New(33_000, money.IRT), fee, "the fee is rounded down, in the deposit's currency")
+	assert.Equal(t, money.New(67_001, money.IRT), refund)
+}
+
+func TestBalance(t *testing.T) {
+	balance, err := Balance([]LedgerEntry{
+		{Kind: EntryCharge, Amount: money.Rials(500_000)},
+		{Kind: EntryRefund, Amount: money.Rials(300_000)},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, money.Rials(200_000), balance)
+
+	balance, err = Balance(nil)
+	require.NoError(t, err)
+	assert.Equal(t, money.Rials(0), balance)
+
+	_, err = Balance([]LedgerEntry{
+		{Kind: EntryCharge, Amount: money.Rials(500_000)},
+		{Kind: EntryRefund, Amount: money.New(30_000, money.IRT)},
+	})
+	assert.ErrorIs(t, err, money.ErrCurrencyMismatch)
+}
@@ -0,0 +1,47 @@
+package payment
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/money"
+)
+
+type Store interface {
+	Create(ctx context.Context, p Payment) error
+	Get(ctx context.Context, id uuid.UUID) (Payment, error)
+	// GetForUpdate is Get, locking the payment until the transaction in
+	// ctx ends so it is completed or expired once.
+	GetForUpdate(ctx context.Context, id uuid.UUID) (Payment, error)
+	// Update stores the status, gateway details and error of p.
+	Update(ctx context.Context, p Payment) error
+	// ListByAppointment returns the payments of an appointment, oldest
+	// first.
+	ListByAppointment(ctx context.Context, appointmentID uuid.UUID) ([]Payment, error)
+	// ListExpired returns up to limit pending payments that expired before
+	// now, ordered by expiry and ID, starting after the payment after; the
+	// zero Payment starts at the oldest. Nothing is locked; use
+	// GetForUpdate before settling one.
+	ListExpired(ctx context.Context, now time.Time, after Payment, limit int) ([]Payment, error)
+	// Deposit returns the deposit the doctor takes for a booking, zero when
+	// none.
+	Deposit(ctx context.Context, doctorID uuid.UUID) (money.Money, error)
+	// CancellationPolicy returns the doctor's cancellation policy,
+	// DefaultCancellationPolicy when they have none.
+	CancellationPolicy(ctx context.Context, doctorID uuid.UUID) (CancellationPolicy, error)
+
+	CreateRefund(ctx context.Context, r Refund) error
+	// GetRefundForUpdate returns a refund, locking it until the
+	// transaction in ctx ends so it is sent to the provider once.
+	GetRefundForUpdate(ctx context.Context, id uuid.UUID) (Refund, error)
+	// UpdateRefund stores the status, reference and error of r.
+	UpdateRefund(ctx context.Context, r Refund) error
+	// ListRefunds returns the refunds of an appointment, oldest first.
+	ListRefunds(ctx context.Context, appointmentID uuid.UUID) ([]Refund, error)
+
+	AddLedgerEntry(ctx context.Context, e LedgerEntry) error
+	// Ledger returns the entries of an appointment, oldest first.
+	Ledger(ctx context.Context, appointmentID uuid.UUID) ([]LedgerEntry, error)
+}
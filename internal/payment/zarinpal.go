@@ -0,0 +1,288 @@
+package payment
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/money"
+)
+
+// DefaultZarinpalBaseURL is Zarinpal's production gateway;
+// https://sandbox.zarinpal.com is its sandbox.
+const DefaultZarinpalBaseURL = "https://payment.zarinpal.com"
+
+// DefaultZarinpalRefundURL is the merchant API refunds are requested
+// through.
+const DefaultZarinpalRefundURL = "https://next.zarinpal.com/api/v4/graphql"
+
+// Zarinpal result codes; other codes are errors.
+const (
+	zarinpalSuccess         = 100
+	zarinpalAlreadyVerified = 101
+)
+
+// ZarinpalConfig addresses a Zarinpal merchant account.
+type ZarinpalConfig struct {
+	MerchantID string
+	// BaseURL defaults to DefaultZarinpalBaseURL
+	BaseURL string
+	// AccessToken authenticates refunds with the merchant API; refunds are
+	// declined without one
+	AccessToken string
+	// RefundURL defaults to DefaultZarinpalRefundURL
+	RefundURL string
+}
+
+// ZarinpalProvider takes payments through the Zarinpal v4 REST API:
+//
+//	POST <base URL>/pg/v4/payment/request.json  registers a payment
+//	GET  <base URL>/pg/StartPay/<authority>      is where the payer pays
+//	POST <base URL>/pg/v4/payment/verify.json   settles it after the callback
+//	POST <refund URL>                           AddRefund GraphQL mutation
+//
+// The payer is sent back to the callback URL with Authority and Status
+// (OK or NOK) query parameters.
+type ZarinpalProvider struct {
+	cfg    ZarinpalConfig
+	client *http.Client
+}
+
+func NewZarinpalProvider(cfg ZarinpalConfig, client *http.Client) (*ZarinpalProvider, error) {
+	if cfg.MerchantID == "" {
+		return nil, errors.New("zarinpal merchant id is required")
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = DefaultZarinpalBaseURL
+	}
+	if cfg.RefundURL == "" {
+		cfg.RefundURL = DefaultZarinpalRefundURL
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	cfg.BaseURL = strings.TrimSuffix(cfg.BaseURL, "/")
+	return &ZarinpalProvider{cfg: cfg, client: client}, nil
+}
+
+func (p *ZarinpalProvider) Name() string { return "zarinpal" }
+
+type zarinpalRequest struct {
+	MerchantID string `json:"merchant_id"`
+	// Amount is in rials
+	Amount      int64             `json:"amount"`
+	CallbackURL string            `json:"callback_url,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Authority   string            `json:"authority,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+}
+
+// zarinpalResponse holds data on success and errors otherwise; whichever
+// is unused is an empty array rather than an object.
+type zarinpalResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors json.RawMessage `json:"errors"`
+}
+
+type zarinpalResult struct {
+	Code      int    `json:"code"`
+	Message   string `json:"message"`
+	Authority string `json:"authority"`
+	RefID     int64  `json:"ref_id"`
+	CardPAN   string `json:"card_pan"`
+}
+
+// rials converts amount to the rials Zarinpal takes.
+func rials(amount money.Money) (int64, error) {
+	inRials, err := amount.In(money.IRR)
+	if err != nil {
+		return 0, fmt.Errorf("zarinpal takes rials: %w", err)
+	}
+	return inRials.Amount, nil
+}
+
+func (p *ZarinpalProvider) Checkout(ctx context.Context, req CheckoutRequest) (Checkout, error) {
+	amount, err := rials(req.Amount)
+	if err != nil {
+		return Checkout{}, err
+	}
+	metadata := map[string]string{"order_id": req.PaymentID.String()}
+	if req.Mobile != "" {
+		metadata["mobile"] = req.Mobile
+	}
+	if req.Email != "" {
+		metadata["email"] = req.Email
+	}
+	result, err := p.call(ctx, "/pg/v4/payment/request.json", zarinpalRequest{
+		MerchantID:  p.cfg.MerchantID,
+		Amount:      amount,
+		CallbackURL: req.CallbackURL,
+		Description: req.Description,
+		Metadata:    metadata,
+	})
+	if err != nil {
+		return Checkout{}, err
+	}
+	if result.Code != zarinpalSuccess || result.Authority == "" {
+		return Checkout{}, fmt.Errorf("zarinpal refused the payment: %d %s", result.Code, result.Message)
+	}
+	return Checkout{
+		Authority:   result.Authority,
+		RedirectURL: p.cfg.BaseURL + "/pg/StartPay/" + url.PathEscape(result.Authority),
+	}, nil
+}
+
+func (p *ZarinpalProvider) ParseCallback(values url.Values) (Callback, error) {
+	return parseAuthorityCallback(values)
+}
+
+func (p *ZarinpalProvider) Verify(ctx context.Context, authority string, amount money.Money) (Verification, error) {
+	inRials, err := rials(amount)
+	if err != nil {
+		return Verification{}, err
+	}
+	result, err := p.call(ctx, "/pg/v4/payment/verify.json", zarinpalRequest{
+		MerchantID: p.cfg.MerchantID,
+		Amount:     inRials,
+		Authority:  authority,
+	})
+	if err != nil {
+		return Verification{}, err
+	}
+	if result.Code != zarinpalSuccess && result.Code != zarinpalAlreadyVerified {
+		return Verification{}, fmt.Errorf("%w: zarinpal answered %d %s", ErrDeclined, result.Code, result.Message)
+	}
+	return Verification{RefID: strconv.FormatInt(result.RefID, 10), CardPAN: result.CardPAN}, nil
+}
+
+const zarinpalAddRefund = `mutation AddRefund($session_id: ID!, $amount: BigInteger!, $description: String, $method: InstantPayoutActionTypeEnum, $reason: RefundReasonEnum) {
+  resource: AddRefund(session_id: $session_id, amount: $amount, description: $description, method: $method, reason: $reason) {
+    id
+  }
+}`
+
+type zarinpalGraphQLRequest struct {
+	Query     string         `json:"query"`
+	Variables map[string]any `json:"variables"`
+}
+
+type zarinpalGraphQLResponse struct {
+	Data struct {
+		Resource *struct {
+			ID string `json:"id"`
+		} `json:"resource"`
+	} `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// Refund requests a refund to the payer's card through the merchant API;
+// Zarinpal knows the verified payment by its reference ID as session_id.
+// The refund ID is sent as the Idempotency-Key and in the description, so
+// a repeated request can be matched to the refund it repeats.
+func (p *ZarinpalProvider) Refund(ctx context.Context, req RefundRequest) (string, error) {
+	if p.cfg.AccessToken == "" {
+		return "", fmt.Errorf("%w: zarinpal refunds need an access token", ErrDeclined)
+	}
+	amount, err := rials(req.Amount)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrDeclined, err)
+	}
+	payload, err := json.Marshal(zarinpalGraphQLRequest{
+		Query: zarinpalAddRefund,
+		Variables: map[string]any{
+			"session_id":  req.RefID,
+			"amount":      amount,
+			"description": fmt.Sprintf("%s (refund %s)", req.Description, req.RefundID),
+			"method":      "CARD",
+			"reason":      "CUSTOMER_REQUEST",
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.cfg.RefundURL, bytes.NewReader(payload))
+	if err != nil {
+		return "", fmt.Errorf("failed to build zarinpal request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+p.cfg.AccessToken)
+	httpReq.Header.Set("Idempotency-Key", req.RefundID.String())
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return "", fmt.Errorf("failed to call zarinpal: %w", err)
+	}
+	defer func() {
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode >= 500 {
+		return "", fmt.Errorf("zarinpal returned %s", resp.Status)
+	}
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return "", fmt.Errorf("%w: zarinpal rejected the access token: %s", ErrDeclined, resp.Status)
+	}
+
+	var decoded zarinpalGraphQLResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&decoded); err != nil {
+		return "", fmt.Errorf("zarinpal returned %s with an unreadable body: %w", resp.Status, err)
+	}
+	if len(decoded.Errors) > 0 {
+		return "", fmt.Errorf("%w: zarinpal refused the refund: %s", ErrDeclined, decoded.Errors[0].Message)
+	}
+	if decoded.Data.Resource == nil || decoded.Data.Resource.ID == "" {
+		return "", fmt.Errorf("zarinpal returned %s without a refund", resp.Status)
+	}
+	return decoded.Data.Resource.ID, nil
+}
+
+// call posts body to path. Error codes in the response body are returned
+// as errors wrapping ErrDeclined, since they mean the request was
+// understood and refused.
+func (p *ZarinpalProvider) call(ctx context.Context, path string, body zarinpalRequest) (zarinpalResult, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return zarinpalResult{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.cfg.BaseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return zarinpalResult{}, fmt.Errorf("failed to build zarinpal request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return zarinpalResult{}, fmt.Errorf("failed to call zarinpal: %w", err)
+	}
+	defer func() {
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		_ = resp.Body.Close()
+	}()
+
+	var decoded zarinpalResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&decoded); err != nil {
+		return zarinpalResult{}, fmt.Errorf("zarinpal returned %s with an unreadable body: %w", resp.Status, err)
+	}
+	var result zarinpalResult
+	if json.Unmarshal(decoded.Errors, &result) == nil && result.Code != 0 {
+		return zarinpalResult{}, fmt.Errorf("%w: zarinpal answered %d %s", ErrDeclined, result.Code, result.Message)
+	}
+	if resp.StatusCode >= 300 {
+		return zarinpalResult{}, fmt.Errorf("zarinpal returned %s", resp.Status)
+	}
+	if err := json.Unmarshal(decoded.Data, &result); err != nil {
+		return zarinpalResult{}, fmt.Errorf("zarinpal returned unexpected data: %w", err)
+	}
+	return result, nil
+}
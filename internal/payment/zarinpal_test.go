@@ -0,0 +1,180 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/money"
+)
+
+const testMerchant = "3c5a8d1e-0000-4000-8000-000000000000"
+
+func zarinpalServer(t *testing.T, handle func(path string, req zarinpalRequest) (int, string)) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		var req zarinpalRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, testMerchant, req.MerchantID)
+		status, body := handle(r.URL.Path, req)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestZarinpalProvider_Checkout(t *testing.T) {
+	paymentID := uuid.New()
+	server := zarinpalServer(t, func(path string, req zarinpalRequest) (int, string) {
+		assert.Equal(t, "/pg/v4/payment/request.json", path)
+		assert.Equal(t, int64(500_000), req.Amount)
+		assert.Equal(t, "https://api.example.com/callback", req.CallbackURL)
+		assert.Equal(t, map[string]string{"order_id": paymentID.String(), "mobile": "09121234567"}, req.Metadata)
+		return http.StatusOK, `{"data":{"code":100,"message":"Success","authority":"A0000000000000000000000000000wwOGYpd"},"errors":[]}`
+	})
+
+	p, err := NewZarinpalProvider(ZarinpalConfig{MerchantID: testMerchant, BaseURL: server.URL + "/"}, server.Client())
+	require.NoError(t, err)
+
+	checkout, err := p.Checkout(context.Background(), CheckoutRequest{
+		PaymentID:   paymentID,
+		Amount:      money.New(50_000, money.IRT),
+		Description: "Deposit",
+		CallbackURL: "https://api.example.com/callback",
+		Mobile:      "09121234567",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "A0000000000000000000000000000wwOGYpd", checkout.Authority)
+	assert.Equal(t, server.URL+"/pg/StartPay/A0000000000000000000000000000wwOGYpd", checkout.RedirectURL)
+
+	_, err = p.Checkout(context.Background(), CheckoutRequest{PaymentID: paymentID, Amount: money.New(1, "USD")})
+	assert.ErrorIs(t, err, money.ErrUnknownCurrency)
+}
+
+func TestZarinpalProvider_Verify(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		want     Verification
+		declined bool
+		wantErr  bool
+	}{
+		{
+			name:   "paid",
+			status: http.StatusOK,
+			body:   `{"data":{"code":100,"message":"Verified","card_pan":"502229******5995","ref_id":201},"errors":[]}`,
+			want:   Verification{RefID: "201", CardPAN: "502229******5995"},
+		},
+		{
+			name:   "verified before",
+			status: http.StatusOK,
+			body:   `{"data":{"code":101,"message":"Verified","card_pan":"502229******5995","ref_id":201},"errors":[]}`,
+			want:   Verification{RefID: "201", CardPAN: "502229******5995"},
+		},
+		{
+			name:     "not paid",
+			status:   http.StatusUnprocessableEntity,
+			body:     `{"data":[],"errors":{"code":-51,"message":"Session is not valid, session is not active paid try.","validations":[]}}`,
+			declined: true,
+			wantErr:  true,
+		},
+		{
+			name:    "gateway down",
+			status:  http.StatusBadGateway,
+			body:    `<html>bad gateway</html>`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := zarinpalServer(t, func(path string, req zarinpalRequest) (int, string) {
+				assert.Equal(t, "/pg/v4/payment/verify.json", path)
+				assert.Equal(t, "A00000000000000000000000000000000001", req.Authority)
+				assert.Equal(t, int64(500_000), req.Amount)
+				return tt.status, tt.body
+			})
+			p, err := NewZarinpalProvider(ZarinpalConfig{MerchantID: testMerchant, BaseURL: server.URL}, server.Client())
+			require.NoError(t, err)
+
+			got, err := p.Verify(context.Background(), "A00000000000000000000000000000000001", money.Rials(500_000))
+			if !tt.wantErr {
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+				return
+			}
+			require.Error(t, err)
+			assert.Equal(t, tt.declined, errors.Is(err, ErrDeclined))
+		})
+	}
+}
+
+func TestZarinpalProvider_ParseCallback(t *testing.T) {
+	p, err := NewZarinpalProvider(ZarinpalConfig{MerchantID: testMerchant}, nil)
+	require.NoError(t, err)
+
+	got, err := p.ParseCallback(url.Values{"Authority": {"A1"}, "Status": {"OK"}})
+	require.NoError(t, err)
+	assert.Equal(t, Callback{Authority: "A1", OK: true}, got)
+
+	got, err = p.ParseCallback(url.Values{"Authority": {"A1"}, "Status": {"NOK"}})
+	require.NoError(t, err)
+	assert.False(t, got.OK)
+
+	_, err = p.ParseCallback(url.Values{"Status": {"OK"}})
+	assert.ErrorIs(t, err, ErrInvalidCallback)
+
+	_, err = NewZarinpalProvider(ZarinpalConfig{}, nil)
+	assert.Error(t, err)
+}
+
+func TestZarinpalProvider_Refund(t *testing.T) {
+	var (
+		got            zarinpalGraphQLRequest
+		idempotencyKey string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		idempotencyKey = r.Header.Get("Idempotency-Key")
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		if got.Variables["amount"] == float64(1) {
+			_, _ = w.Write([]byte(`{"data":{"resource":null},"errors":[{"message":"Amount is too low"}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":{"resource":{"id":"1009"}}}`))
+	}))
+	defer server.Close()
+
+	p, err := NewZarinpalProvider(ZarinpalConfig{MerchantID: testMerchant, AccessToken: "token", RefundURL: server.URL}, server.Client())
+	require.NoError(t, err)
+
+	refundID := uuid.New()
+	ref, err := p.Refund(context.Background(), RefundRequest{RefundID: refundID, Authority: "A1", RefID: "201", Amount: money.Rials(300_000), Description: "Refund"})
+	require.NoError(t, err)
+	assert.Equal(t, "1009", ref)
+	assert.Equal(t, refundID.String(), idempotencyKey)
+	assert.Equal(t, "Refund (refund "+refundID.String()+")", got.Variables["description"])
+	assert.Contains(t, got.Query, "AddRefund")
+	assert.Equal(t, "201", got.Variables["session_id"])
+	assert.Equal(t, float64(300_000), got.Variables["amount"])
+
+	_, err = p.Refund(context.Background(), RefundRequest{RefID: "201", Amount: money.Rials(1)})
+	assert.ErrorIs(t, err, ErrDeclined)
+
+	p, err = NewZarinpalProvider(ZarinpalConfig{MerchantID: testMerchant}, nil)
+	require.NoError(t, err)
+	_, err = p.Refund(context.Background(), RefundRequest{RefID: "201", Amount: money.Rials(300_000)})
+	assert.ErrorIs(t, err, ErrDeclined, "refunds need an access token")
+}
@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+// MemoryStore keeps buckets in process memory. Limits are per instance, so
+// use PostgresStore when running several replicas behind a load balancer.
+type MemoryStore struct {
+	mu          sync.Mutex
+	buckets     map[string]*bucket
+	idleTTL     time.Duration
+	lastCleanup time.Time
+	now         func() time.Time
+}
+
+// NewMemoryStore creates a store that forgets buckets untouched for idleTTL.
+// idleTTL should be at least the longest policy period; such buckets would
+// be full again anyway, so forgetting them changes no decision.
+func NewMemoryStore(idleTTL time.Duration) *MemoryStore {
+	return &MemoryStore{
+		buckets:     make(map[string]*bucket),
+		idleTTL:     idleTTL,
+		lastCleanup: time.Now(),
+		now:         time.Now,
+	}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.lastCleanup) >= s.idleTTL {
+		s.cleanup(now)
+	}
+
+	capacity := limit.capacity()
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, updatedAt: now}
+		s.buckets[key] = b
+	}
+
+	elapsed := now.Sub(b.updatedAt).Seconds()
+	if elapsed > 0 {
+		b.tokens = min(capacity, b.tokens+elapsed*limit.ratePerSecond())
+	}
+	b.updatedAt = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+
+	return newResult(allowed, b.tokens, limit), nil
+}
+
+func (s *MemoryStore) cleanup(now time.Time) {
+	cutoff := now.Add(-s.idleTTL)
+	for key, b := range s.buckets {
+		if b.updatedAt.Before(cutoff) {
+			delete(s.buckets, key)
+		}
+	}
+	s.lastCleanup = now
+}
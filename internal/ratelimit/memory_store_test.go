@@ -0,0 +1,98 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestMemoryStore(now *time.Time) *MemoryStore {
+	s := NewMemoryStore(time.Hour)
+	s.now = func() time.Time { return *now }
+	s.lastCleanup = *now
+	return s
+}
+
+func TestMemoryStore_Take_ExhaustsBurstThenDenies(t *testing.T) {
+	now := time.Now()
+	s := newTestMemoryStore(&now)
+	limit := Limit{Requests: 1, Per: time.Second, Burst: 3}
+
+	for i := 2; i >= 0; i-- {
+		result, err := s.Take(context.Background(), "k", limit)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, i, result.Remaining)
+	}
+
+	result, err := s.Take(context.Background(), "k", limit)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+	assert.Equal(t, time.Second, result.RetryAfter)
+	assert.Equal(t, 3*time.Second, result.ResetAfter)
+}
+
+func TestMemoryStore_Take_Refills(t *testing.T) {
+	now := time.Now()
+	s := newTestMemoryStore(&now)
+	limit := Limit{Requests: 2, Per: time.Second}
+
+	for range 2 {
+		_, err := s.Take(context.Background(), "k", limit)
+		require.NoError(t, err)
+	}
+	result, err := s.Take(context.Background(), "k", limit)
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+	assert.Equal(t, 500*time.Millisecond, result.RetryAfter)
+
+	now = now.Add(500 * time.Millisecond)
+	result, err = s.Take(context.Background(), "k", limit)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	// Refill is capped at the burst capacity
+	now = now.Add(time.Hour)
+	result, err = s.Take(context.Background(), "k", limit)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 1, result.Remaining)
+}
+
+func TestMemoryStore_Take_KeysAreIndependent(t *testing.T) {
+	now := time.Now()
+	s := newTestMemoryStore(&now)
+	limit := Limit{Requests: 1, Per: time.Minute}
+
+	result, err := s.Take(context.Background(), "a", limit)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	result, err = s.Take(context.Background(), "b", limit)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	result, err = s.Take(context.Background(), "a", limit)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+}
+
+func TestMemoryStore_Take_CleansUpIdleBuckets(t *testing.T) {
+	now := time.Now()
+	s := newTestMemoryStore(&now)
+	limit := Limit{Requests: 1, Per: time.Minute}
+
+	_, err := s.Take(context.Background(), "idle", limit)
+	require.NoError(t, err)
+
+	now = now.Add(2 * time.Hour)
+	_, err = s.Take(context.Background(), "active", limit)
+	require.NoError(t, err)
+
+	assert.NotContains(t, s.buckets, "idle")
+	assert.Contains(t, s.buckets, "active")
+}
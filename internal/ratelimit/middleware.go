@@ -0,0 +1,85 @@
+package ratelimit
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/logging"
+	"github.com/shayesteh1hs/DrAppointment/internal/middleware"
+)
+
+// KeyFunc extracts the identity a policy limits. Returning false skips
+// limiting for the request, e.g. when the identity is absent.
+type KeyFunc func(c *gin.Context) (string, bool)
+
+type Policy struct {
+	// Name namespaces the buckets so the same identity gets independent
+	// budgets under different policies.
+	Name  string
+	Limit Limit
+	Key   KeyFunc
+}
+
+// ByIP limits per client IP as resolved by gin's trusted proxy settings.
+func ByIP() KeyFunc {
+	return func(c *gin.Context) (string, bool) {
+		return "ip:" + c.ClientIP(), true
+	}
+}
+
+// Middleware enforces the policies in order; the first exhausted bucket
+// rejects the request with 429. The RateLimit-* headers describe the most
+// restrictive policy that applied. Store failures are logged and the request
+// is let through so an outage of the store does not take the API down.
+func Middleware(store Store, policies ...Policy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		var tightest *Result
+		for _, policy := range policies {
+			key, ok := policy.Key(c)
+			if !ok {
+				continue
+			}
+
+			result, err := store.Take(ctx, policy.Name+":"+key, policy.Limit)
+			if err != nil {
+				logging.FromContext(ctx).Error("rate limit store failed", "policy", policy.Name, "error", err)
+				continue
+			}
+
+			if !result.Allowed {
+				setHeaders(c, result)
+				c.Header("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+				c.AbortWithStatusJSON(http.StatusTooManyRequests, middleware.ErrorResponse{
+					Status:  http.StatusTooManyRequests,
+					Message: "Too many requests",
+				})
+				return
+			}
+
+			if tightest == nil || result.Remaining < tightest.Remaining {
+				tightest = &result
+			}
+		}
+
+		if tightest != nil {
+			setHeaders(c, *tightest)
+		}
+		c.Next()
+	}
+}
+
+func setHeaders(c *gin.Context, result Result) {
+	c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+	c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.ResetAfter)))
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
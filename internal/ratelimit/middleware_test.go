@@ -0,0 +1,85 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, Limit) (Result, error) {
+	return Result{}, errors.New("store down")
+}
+
+func setupRouter(store Store, policies ...Policy) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware(store, policies...))
+	router.GET("/doctors", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+func TestMiddleware_RejectsWhenExhausted(t *testing.T) {
+	router := setupRouter(NewMemoryStore(time.Hour), Policy{
+		Name:  "public",
+		Limit: Limit{Requests: 2, Per: time.Minute},
+		Key:   ByIP(),
+	})
+
+	for i := 1; i >= 0; i-- {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/doctors", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, strconv.Itoa(i), w.Header().Get("RateLimit-Remaining"))
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/doctors", nil))
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "30", w.Header().Get("Retry-After"))
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "60", w.Header().Get("RateLimit-Reset"))
+	assert.Contains(t, w.Body.String(), "Too many requests")
+}
+
+func TestMiddleware_SkipsPoliciesWithoutKey(t *testing.T) {
+	router := setupRouter(NewMemoryStore(time.Hour), Policy{
+		Name:  "partner",
+		Limit: Limit{Requests: 1, Per: time.Minute},
+		Key: func(c *gin.Context) (string, bool) {
+			partner := c.GetHeader("X-Partner-ID")
+			return "partner:" + partner, partner != ""
+		},
+	})
+
+	for range 3 {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/doctors", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("RateLimit-Limit"))
+	}
+}
+
+func TestMiddleware_FailsOpenOnStoreError(t *testing.T) {
+	router := setupRouter(failingStore{}, Policy{
+		Name:  "public",
+		Limit: Limit{Requests: 1, Per: time.Minute},
+		Key:   ByIP(),
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/doctors", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
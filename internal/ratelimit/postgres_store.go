@@ -0,0 +1,83 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/database"
+	"github.com/shayesteh1hs/DrAppointment/internal/logging"
+)
+
+// refilled is the bucket level after adding the tokens earned since the last
+// request, capped at the burst capacity ($2) using the refill rate ($3).
+const refilled = "LEAST($2::float8, b.tokens + GREATEST(0, EXTRACT(EPOCH FROM (now() - b.updated_at))) * $3::float8)"
+
+// takeQuery refills and consumes in a single atomic upsert so concurrent
+// instances never over-admit. The database clock is used to avoid skew
+// between application hosts.
+var takeQuery = fmt.Sprintf(`INSERT INTO rate_limit_buckets AS b (key, tokens, allowed, updated_at)
+VALUES ($1, $2::float8 - 1, true, now())
+ON CONFLICT (key) DO UPDATE SET
+    tokens = CASE WHEN %[1]s >= 1 THEN %[1]s - 1 ELSE %[1]s END,
+    allowed = %[1]s >= 1,
+    updated_at = now()
+RETURNING tokens, allowed`, refilled)
+
+// PostgresStore shares buckets between all instances through the
+// rate_limit_buckets table.
+type PostgresStore struct {
+	db          database.Querier
+	idleTTL     time.Duration
+	lastCleanup atomic.Int64
+}
+
+// NewPostgresStore creates a store that periodically deletes buckets
+// untouched for idleTTL, which should be at least the longest policy period.
+func NewPostgresStore(db database.Querier, idleTTL time.Duration) *PostgresStore {
+	s := &PostgresStore{db: db, idleTTL: idleTTL}
+	s.lastCleanup.Store(time.Now().UnixNano())
+	return s
+}
+
+func (s *PostgresStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	// Counting a request is no reason to serve its reads from the primary
+	ctx = database.WithoutSession(ctx)
+
+	var tokens float64
+	var allowed bool
+	err := s.db.QueryRowContext(ctx, takeQuery, key, limit.capacity(), limit.ratePerSecond()).Scan(&tokens, &allowed)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to take rate limit token: %w", err)
+	}
+
+	s.maybeCleanup(ctx)
+	return newResult(allowed, tokens, limit), nil
+}
+
+// maybeCleanup lets at most one request per idleTTL, across goroutines of
+// this instance, delete idle buckets in the background.
+func (s *PostgresStore) maybeCleanup(ctx context.Context) {
+	last := s.lastCleanup.Load()
+	now := time.Now().UnixNano()
+	if time.Duration(now-last) < s.idleTTL || !s.lastCleanup.CompareAndSwap(last, now) {
+		return
+	}
+
+	go func() {
+		ctx := context.WithoutCancel(ctx)
+		if err := s.Cleanup(ctx); err != nil {
+			logging.FromContext(ctx).Warn("rate limit cleanup failed", "error", err)
+		}
+	}()
+}
+
+// Cleanup deletes buckets untouched for longer than the idle TTL.
+func (s *PostgresStore) Cleanup(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM rate_limit_buckets WHERE updated_at < now() - $1::float8 * interval '1 second'", s.idleTTL.Seconds())
+	if err != nil {
+		return fmt.Errorf("failed to clean up rate limit buckets: %w", err)
+	}
+	return nil
+}
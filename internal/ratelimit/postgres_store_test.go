@@ -0,0 +1,120 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/database"
+)
+
+func TestPostgresStore_Take(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	s := NewPostgresStore(db, time.Hour)
+	limit := Limit{Requests: 10, Per: 10 * time.Second, Burst: 20}
+
+	mock.ExpectQuery(regexp.QuoteMeta(takeQuery)).
+		WithArgs("public:ip:10.0.0.1", float64(20), float64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"tokens", "allowed"}).AddRow(4.5, true))
+
+	result, err := s.Take(context.Background(), "public:ip:10.0.0.1", limit)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 10, result.Limit)
+	assert.Equal(t, 4, result.Remaining)
+	assert.Equal(t, 15500*time.Millisecond, result.ResetAfter)
+	assert.Zero(t, result.RetryAfter)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStore_Take_Denied(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	s := NewPostgresStore(db, time.Hour)
+	limit := Limit{Requests: 1, Per: time.Second}
+
+	mock.ExpectQuery(regexp.QuoteMeta(takeQuery)).
+		WithArgs("k", float64(1), float64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"tokens", "allowed"}).AddRow(0.25, false))
+
+	result, err := s.Take(context.Background(), "k", limit)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 750*time.Millisecond, result.RetryAfter)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStore_Take_Error(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	s := NewPostgresStore(db, time.Hour)
+
+	mock.ExpectQuery(regexp.QuoteMeta(takeQuery)).
+		WillReturnError(errors.New("connection refused"))
+
+	_, err = s.Take(context.Background(), "k", Limit{Requests: 1, Per: time.Second})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to take rate limit token")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStore_Cleanup(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	s := NewPostgresStore(db, time.Hour)
+
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM rate_limit_buckets WHERE updated_at < now() - $1::float8 * interval '1 second'")).
+		WithArgs(float64(3600)).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	require.NoError(t, s.Cleanup(context.Background()))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStore_Take_LeavesRequestReadsOnReplicas(t *testing.T) {
+	primary, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer primary.Close()
+	replica, replicaMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer replica.Close()
+
+	db := database.NewWithReplicas(primary, replica)
+	s := NewPostgresStore(db, time.Hour)
+
+	mock.ExpectQuery(regexp.QuoteMeta(takeQuery)).
+		WithArgs("k", 1.0, 1.0).
+		WillReturnRows(sqlmock.NewRows([]string{"tokens", "allowed"}).AddRow(0.0, true))
+	// The request the token was taken for still reads from the replica
+	replicaMock.ExpectQuery(regexp.QuoteMeta("SELECT count(*) FROM doctors")).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+	ctx := database.WithSession(context.Background())
+	result, err := s.Take(ctx, "k", Limit{Requests: 1, Per: time.Second})
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	var count int
+	require.NoError(t, db.QueryRowContext(ctx, "SELECT count(*) FROM doctors").Scan(&count))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.NoError(t, replicaMock.ExpectationsWereMet())
+}
@@ -0,0 +1,199 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/huandu/go-sqlbuilder"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/database"
+	"github.com/shayesteh1hs/DrAppointment/internal/entity/medical"
+	"github.com/shayesteh1hs/DrAppointment/internal/logging"
+	"github.com/shayesteh1hs/DrAppointment/internal/repository/medical/appointment"
+)
+
+const slotIndex = "idx_appointments_doctor_slot"
+
+var appointmentColumns = []string{
+	"id", "doctor_id", "patient_id", "patient_name", "patient_phone", "patient_email", "locale",
+	"starts_at", "ends_at", "status", "cancelled_at", "created_at", "updated_at",
+}
+
+const returningAppointment = `
+RETURNING id, doctor_id, patient_id, patient_name, patient_phone, patient_email, locale,
+    starts_at, ends_at, status, cancelled_at, created_at, updated_at`
+
+// The status changes only match appointments in the status they change
+// from; a missing row is told apart from one in another state by reading
+// it afterwards.
+const (
+	cancelQuery = `
+UPDATE appointments SET status = 'cancelled', cancelled_at = now()
+WHERE id = $1 AND status = 'booked'` + returningAppointment
+
+	confirmQuery = `
+UPDATE appointments SET status = 'booked'
+WHERE id = $1 AND status = 'pending_payment'` + returningAppointment
+
+	releaseQuery = `
+UPDATE appointments SET status = 'cancelled', cancelled_at = now()
+WHERE id = $1 AND status = 'pending_payment'` + returningAppointment
+)
+
+type appointmentRepository struct {
+	db database.Querier
+}
+
+func NewAppointmentRepository(db database.Querier) appointment.Repository {
+	return &appointmentRepository{db: db}
+}
+
+func (r *appointmentRepository) Create(ctx context.Context, appt *medical.Appointment) error {
+	ib := sqlbuilder.PostgreSQL.NewInsertBuilder()
+	ib.InsertInto("appointments")
+	ib.Cols("doctor_id", "patient_id", "patient_name", "patient_phone", "patient_email", "locale", "starts_at", "ends_at", "status")
+	ib.Values(appt.DoctorID, uuid.NullUUID{UUID: appt.PatientID, Valid: appt.PatientID != uuid.Nil},
+		appt.PatientName, appt.PatientPhone, appt.PatientEmail, appt.Locale, appt.StartsAt, appt.EndsAt, appt.Status)
+	ib.Returning("id", "created_at", "updated_at")
+
+	query, args := ib.Build()
+	err := r.db.QueryRowContext(ctx, query, args...).Scan(&appt.ID, &appt.CreatedAt, &appt.UpdatedAt)
+	if database.IsUniqueViolation(err, slotIndex) {
+		return appointment.ErrSlotTaken
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create appointment: %w", err)
+	}
+	return nil
+}
+
+func (r *appointmentRepository) GetByID(ctx context.Context, id uuid.UUID) (*medical.Appointment, error) {
+	sb := sqlbuilder.PostgreSQL.NewSelectBuilder()
+	sb.Select(appointmentColumns...)
+	sb.From("appointments")
+	sb.Where(sb.Equal("id", id))
+
+	query, args := sb.Build()
+	appt, err := scanAppointment(r.db.QueryRowContext(ctx, query, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, appointment.ErrAppointmentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan appointment: %w", err)
+	}
+	return appt, nil
+}
+
+func (r *appointmentRepository) Cancel(ctx context.Context, id uuid.UUID) (*medical.Appointment, error) {
+	return r.changeStatus(ctx, cancelQuery, id, appointment.ErrNotCancellable)
+}
+
+func (r *appointmentRepository) Confirm(ctx context.Context, id uuid.UUID) (*medical.Appointment, error) {
+	return r.changeStatus(ctx, confirmQuery, id, appointment.ErrNotPendingPayment)
+}
+
+func (r *appointmentRepository) Release(ctx context.Context, id uuid.UUID) (*medical.Appointment, error) {
+	return r.changeStatus(ctx, releaseQuery, id, appointment.ErrNotPendingPayment)
+}
+
+// changeStatus runs one of the status change queries, returning
+// wrongStatus when the appointment exists in another status.
+func (r *appointmentRepository) changeStatus(ctx context.Context, query string, id uuid.UUID, wrongStatus error) (*medical.Appointment, error) {
+	appt, err := scanAppointment(r.db.QueryRowContext(ctx, query, id))
+	if err == nil {
+		return appt, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to update appointment status: %w", err)
+	}
+
+	// The change may have lost to another one a replica has not seen yet
+	if _, err := r.GetByID(database.WithPrimary(ctx), id); err != nil {
+		return nil, err
+	}
+	return nil, wrongStatus
+}
+
+// ListActive may be served by a lagging replica; a slot it shows as free
+// can still turn out taken, which Create reports as ErrSlotTaken.
+func (r *appointmentRepository) ListActive(ctx context.Context, doctorIDs []uuid.UUID, from, to time.Time) ([]medical.Appointment, error) {
+	if len(doctorIDs) == 0 {
+		return []medical.Appointment{}, nil
+	}
+
+	sb := sqlbuilder.PostgreSQL.NewSelectBuilder()
+	sb.Select(appointmentColumns...)
+	sb.From("appointments")
+	sb.Where(
+		"doctor_id = ANY("+sb.Var(database.UUIDArray(doctorIDs))+")",
+		sb.NotEqual("status", medical.AppointmentCancelled),
+		sb.LessThan("starts_at", to),
+		sb.GreaterThan("ends_at", from),
+	)
+	sb.OrderBy("doctor_id", "starts_at")
+
+	query, args := sb.Build()
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list appointments: %w", err)
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			logging.FromContext(ctx).Warn("failed to close rows", "error", err)
+		}
+	}(rows)
+
+	appointments := []medical.Appointment{}
+	for rows.Next() {
+		appt, err := scanAppointment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan appointment: %w", err)
+		}
+		appointments = append(appointments, *appt)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list appointments: %w", err)
+	}
+	return appointments, nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAppointment(row scanner) (*medical.Appointment, error) {
+	var (
+		appt        medical.Appointment
+		patientID   uuid.NullUUID
+		cancelledAt sql.NullTime
+	)
+	err := row.Scan(
+		&appt.ID,
+		&appt.DoctorID,
+		&patientID,
+		&appt.PatientName,
+		&appt.PatientPhone,
+		&appt.PatientEmail,
+		&appt.Locale,
+		&appt.StartsAt,
+		&appt.EndsAt,
+		&appt.Status,
+		&cancelledAt,
+		&appt.CreatedAt,
+		&appt.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	appt.PatientID = patientID.UUID
+	if cancelledAt.Valid {
+		appt.CancelledAt = &cancelledAt.Time
+	}
+	return &appt, nil
+}
@@ -0,0 +1,204 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/entity/medical"
+	"github.com/shayesteh1hs/DrAppointment/internal/repository/medical/appointment"
+)
+
+const selectAppointmentQuery = "SELECT id, doctor_id, patient_id, patient_name, patient_phone, patient_email, locale, starts_at, ends_at, status, cancelled_at, created_at, updated_at FROM appointments WHERE id = $1"
+
+func setupMockDB(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	return db, mock
+}
+
+func appointmentRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "doctor_id", "patient_id", "patient_name", "patient_phone", "patient_email", "locale", "starts_at", "ends_at", "status", "cancelled_at", "created_at", "updated_at"})
+}
+
+func newAppointment() medical.Appointment {
+	startsAt := time.Date(2026, time.October, 20, 6, 30, 0, 0, time.UTC)
+	return medical.Appointment{
+		DoctorID:     uuid.MustParse("123e4567-e89b-12d3-a456-426614174000"),
+		PatientName:  "Sara",
+		PatientPhone: "+989121234567",
+		Locale:       "fa",
+		StartsAt:     startsAt,
+		EndsAt:       startsAt.Add(30 * time.Minute),
+		Status:       medical.AppointmentBooked,
+	}
+}
+
+func TestAppointmentPostgresRepository_Create(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	appt := newAppointment()
+	id := uuid.New()
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO appointments (doctor_id, patient_id, patient_name, patient_phone, patient_email, locale, starts_at, ends_at, status) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id, created_at, updated_at")).
+		WithArgs(appt.DoctorID, nil, "Sara", "+989121234567", "", "fa", appt.StartsAt, appt.EndsAt, medical.AppointmentBooked).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(id, now, now))
+
+	require.NoError(t, NewAppointmentRepository(db).Create(context.Background(), &appt))
+	assert.Equal(t, id, appt.ID)
+	assert.Equal(t, now, appt.CreatedAt)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAppointmentPostgresRepository_Create_SlotTaken(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectQuery("INSERT INTO appointments").
+		WillReturnError(&pq.Error{Code: "23505", Constraint: "idx_appointments_doctor_slot"})
+
+	appt := newAppointment()
+	err := NewAppointmentRepository(db).Create(context.Background(), &appt)
+	assert.ErrorIs(t, err, appointment.ErrSlotTaken)
+}
+
+func TestAppointmentPostgresRepository_GetByID(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	appt := newAppointment()
+	id, patientID := uuid.New(), uuid.New()
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta(selectAppointmentQuery)).
+		WithArgs(id).
+		WillReturnRows(appointmentRows().AddRow(id, appt.DoctorID, patientID, "Sara", "+989121234567", "sara@example.com", "en",
+			appt.StartsAt, appt.EndsAt, "booked", nil, now, now))
+
+	got, err := NewAppointmentRepository(db).GetByID(context.Background(), id)
+	require.NoError(t, err)
+	assert.Equal(t, id, got.ID)
+	assert.Equal(t, patientID, got.PatientID)
+	assert.Equal(t, "sara@example.com", got.PatientEmail)
+	assert.Equal(t, medical.AppointmentBooked, got.Status)
+	assert.Nil(t, got.CancelledAt)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAppointmentPostgresRepository_GetByID_NotFound(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta(selectAppointmentQuery)).WillReturnRows(appointmentRows())
+
+	_, err := NewAppointmentRepository(db).GetByID(context.Background(), uuid.New())
+	assert.ErrorIs(t, err, appointment.ErrAppointmentNotFound)
+}
+
+func TestAppointmentPostgresRepository_Cancel(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	appt := newAppointment()
+	id := uuid.New()
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta(cancelQuery)).
+		WithArgs(id).
+		WillReturnRows(appointmentRows().AddRow(id, appt.DoctorID, nil, "Sara", "+989121234567", "", "fa",
+			appt.StartsAt, appt.EndsAt, "cancelled", now, now, now))
+
+	got, err := NewAppointmentRepository(db).Cancel(context.Background(), id)
+	require.NoError(t, err)
+	assert.Equal(t, medical.AppointmentCancelled, got.Status)
+	require.NotNil(t, got.CancelledAt)
+	assert.Equal(t, uuid.Nil, got.PatientID)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAppointmentPostgresRepository_Cancel_AlreadyCancelled(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	appt := newAppointment()
+	id := uuid.New()
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta(cancelQuery)).WithArgs(id).WillReturnRows(appointmentRows())
+	mock.ExpectQuery(regexp.QuoteMeta(selectAppointmentQuery)).
+		WithArgs(id).
+		WillReturnRows(appointmentRows().AddRow(id, appt.DoctorID, nil, "Sara", "+989121234567", "", "fa",
+			appt.StartsAt, appt.EndsAt, "cancelled", now, now, now))
+
+	_, err := NewAppointmentRepository(db).Cancel(context.Background(), id)
+	assert.ErrorIs(t, err, appointment.ErrNotCancellable)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAppointmentPostgresRepository_Confirm(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	appt := newAppointment()
+	id := uuid.New()
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta(confirmQuery)).
+		WithArgs(id).
+		WillReturnRows(appointmentRows().AddRow(id, appt.DoctorID, nil, "Sara", "+989121234567", "", "fa",
+			appt.StartsAt, appt.EndsAt, "booked", nil, now, now))
+
+	got, err := NewAppointmentRepository(db).Confirm(context.Background(), id)
+	require.NoError(t, err)
+	assert.Equal(t, medical.AppointmentBooked, got.Status)
+
+	// Releasing it afterwards finds it booked
+	mock.ExpectQuery(regexp.QuoteMeta(releaseQuery)).WithArgs(id).WillReturnRows(appointmentRows())
+	mock.ExpectQuery(regexp.QuoteMeta(selectAppointmentQuery)).
+		WithArgs(id).
+		WillReturnRows(appointmentRows().AddRow(id, appt.DoctorID, nil, "Sara", "+989121234567", "", "fa",
+			appt.StartsAt, appt.EndsAt, "booked", nil, now, now))
+
+	_, err = NewAppointmentRepository(db).Release(context.Background(), id)
+	assert.ErrorIs(t, err, appointment.ErrNotPendingPayment)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAppointmentRepository_ListActive(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+	repo := NewAppointmentRepository(db)
+
+	appt := newAppointment()
+	appt.ID = uuid.New()
+	from := appt.StartsAt.Add(-time.Hour)
+	to := from.Add(14 * 24 * time.Hour)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, doctor_id, patient_id, patient_name, patient_phone, patient_email, locale, starts_at, ends_at, status, cancelled_at, created_at, updated_at FROM appointments WHERE doctor_id = ANY($1) AND status <> $2 AND starts_at < $3 AND ends_at > $4 ORDER BY doctor_id, starts_at")).
+		WithArgs("{\""+appt.DoctorID.String()+"\"}", medical.AppointmentCancelled, to, from).
+		WillReturnRows(appointmentRows().AddRow(appt.ID, appt.DoctorID, nil, appt.PatientName, appt.PatientPhone, "", appt.Locale, appt.StartsAt, appt.EndsAt, medical.AppointmentBooked, nil, appt.StartsAt, appt.StartsAt))
+
+	appts, err := repo.ListActive(context.Background(), []uuid.UUID{appt.DoctorID}, from, to)
+	require.NoError(t, err)
+	require.Len(t, appts, 1)
+	assert.Equal(t, appt.ID, appts[0].ID)
+	assert.Equal(t, appt.StartsAt, appts[0].StartsAt)
+	assert.Equal(t, medical.AppointmentBooked, appts[0].Status)
+
+	// No doctors, no query
+	appts, err = repo.ListActive(context.Background(), nil, from, to)
+	require.NoError(t, err)
+	assert.Empty(t, appts)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
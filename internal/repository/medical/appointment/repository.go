@@ -0,0 +1,40 @@
+package appointment
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shayesteh1hs/DrAppointment/internal/entity/medical"
+)
+
+var (
+	ErrAppointmentNotFound = errors.New("appointment not found")
+	// ErrSlotTaken is returned when the doctor already has an active
+	// appointment starting at the same time.
+	ErrSlotTaken = errors.New("appointment slot is already booked")
+	// ErrNotCancellable is returned when cancelling an appointment that is
+	// no longer booked.
+	ErrNotCancellable = errors.New("appointment cannot be cancelled")
+	// ErrNotPendingPayment is returned when confirming or releasing an
+	// appointment that is not waiting for its deposit.
+	ErrNotPendingPayment = errors.New("appointment is not pending payment")
+)
+
+type Repository interface {
+	// Create inserts appt and fills in its ID and timestamps.
+	Create(ctx context.Context, appt *medical.Appointment) error
+	GetByID(ctx context.Context, id uuid.UUID) (*medical.Appointment, error)
+	// Cancel moves a booked appointment to cancelled and returns it.
+	Cancel(ctx context.Context, id uuid.UUID) (*medical.Appointment, error)
+	// Confirm moves an appointment pending payment to booked and returns
+	// it.
+	Confirm(ctx context.Context, id uuid.UUID) (*medical.Appointment, error)
+	// Release cancels an appointment pending payment, freeing its slot, and
+	// returns it.
+	Release(ctx context.Context, id uuid.UUID) (*medical.Appointment, error)
+	// ListActive returns the booked and pending appointments of the doctors
+	// that overlap [from, to), by doctor and then start time.
+	ListActive(ctx context.Context, doctorIDs []uuid.UUID, from, to time.Time) ([]medical.Appointment, error)
+}
@@ -0,0 +1,126 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	appCache "github.com/shayesteh1hs/DrAppointment/internal/cache"
+	"github.com/shayesteh1hs/DrAppointment/internal/database"
+	"github.com/shayesteh1hs/DrAppointment/internal/entity/medical"
+	filter "github.com/shayesteh1hs/DrAppointment/internal/filter/medical"
+	"github.com/shayesteh1hs/DrAppointment/internal/pagination"
+	"github.com/shayesteh1hs/DrAppointment/internal/repository/medical/doctor"
+)
+
+// Repository is a read-through caching doctor.Repository. Write paths must call
+// Invalidate (or InvalidateAll) after changing a doctor so readers never see
+// stale rows for longer than the in-flight request. Reads that must see the
+// primary (see database.ReadsPrimary) bypass the cache.
+type Repository interface {
+	doctor.Repository
+	Invalidate(id uuid.UUID)
+	InvalidateAll()
+	Stats() appCache.Stats
+}
+
+type doctorRepository struct {
+	next doctor.Repository
+	byID *appCache.LRU[uuid.UUID, medical.Doctor]
+}
+
+func (r *doctorRepository) ListOffset(ctx context.Context, filters filter.DoctorQueryParam, params pagination.LimitOffsetParams) ([]medical.Doctor, error) {
+	return r.next.ListOffset(ctx, filters, params)
+}
+
+func (r *doctorRepository) Count(ctx context.Context, filters filter.DoctorQueryParam) (int, error) {
+	return r.next.Count(ctx, filters)
+}
+
+func (r *doctorRepository) GetByID(ctx context.Context, id uuid.UUID) (*medical.Doctor, error) {
+	if database.ReadsPrimary(ctx) {
+		return r.next.GetByID(ctx, id)
+	}
+
+	doc, err := r.byID.GetOrLoad(ctx, id, func(ctx context.Context) (medical.Doctor, error) {
+		doc, err := r.next.GetByID(ctx, id)
+		if err != nil {
+			return medical.Doctor{}, err
+		}
+		return *doc, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &doc, nil
+}
+
+// GetByIDs serves what it can from the by-ID cache and loads the remaining
+// doctors in a single call to the next repository.
+func (r *doctorRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]medical.Doctor, error) {
+	if database.ReadsPrimary(ctx) {
+		return r.next.GetByIDs(ctx, ids)
+	}
+
+	doctors := make([]medical.Doctor, 0, len(ids))
+	var missing []uuid.UUID
+	for _, id := range ids {
+		if doc, ok := r.byID.Get(id); ok {
+			doctors = append(doctors, doc)
+			continue
+		}
+		missing = append(missing, id)
+	}
+	if len(missing) == 0 {
+		return doctors, nil
+	}
+
+	loaded, err := r.next.GetByIDs(ctx, missing)
+	if err != nil {
+		return []medical.Doctor{}, err
+	}
+	for _, doc := range loaded {
+		r.byID.Set(doc.ID, doc)
+	}
+
+	return append(doctors, loaded...), nil
+}
+
+func (r *doctorRepository) UpdateAvatar(ctx context.Context, id uuid.UUID, key string, ifUpdatedAt time.Time) (string, error) {
+	previous, err := r.next.UpdateAvatar(ctx, id, key, ifUpdatedAt)
+	database.AfterCommit(ctx, func() { r.Invalidate(id) })
+	return previous, err
+}
+
+func (r *doctorRepository) ListOfferings(ctx context.Context, doctorIDs []uuid.UUID) ([]medical.Offering, error) {
+	return r.next.ListOfferings(ctx, doctorIDs)
+}
+
+func (r *doctorRepository) ListClinics(ctx context.Context, doctorIDs []uuid.UUID) ([]medical.DoctorClinic, error) {
+	return r.next.ListClinics(ctx, doctorIDs)
+}
+
+func (r *doctorRepository) ListWorkingHours(ctx context.Context, doctorIDs []uuid.UUID) ([]medical.WorkingHours, error) {
+	return r.next.ListWorkingHours(ctx, doctorIDs)
+}
+
+func (r *doctorRepository) Invalidate(id uuid.UUID) {
+	r.byID.Delete(id)
+}
+
+func (r *doctorRepository) InvalidateAll() {
+	r.byID.Purge()
+}
+
+func (r *doctorRepository) Stats() appCache.Stats {
+	return r.byID.Stats()
+}
+
+func NewDoctorRepository(next doctor.Repository, capacity int, ttl time.Duration) Repository {
+	return &doctorRepository{
+		next: next,
+		byID: appCache.NewLRU[uuid.UUID, medical.Doctor](capacity, ttl),
+	}
+}
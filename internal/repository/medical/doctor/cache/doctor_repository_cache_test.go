@@ -0,0 +1,196 @@
+package cache
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/database"
+	"github.com/shayesteh1hs/DrAppointment/internal/repository/medical/doctor"
+	"github.com/shayesteh1hs/DrAppointment/internal/repository/medical/doctor/postgres"
+)
+
+const getByIDQuery = "SELECT id, name, specialty_id, phone_number, avatar_key, description, created_at, updated_at FROM doctors WHERE id = $1"
+
+func doctorRows(id uuid.UUID, name string) *sqlmock.Rows {
+	now := time.Now()
+	return sqlmock.NewRows([]string{"id", "name", "specialty_id", "phone_number", "avatar_key", "description", "created_at", "updated_at"}).
+		AddRow(id, name, uuid.New(), "+1234567890", "", "", now, now)
+}
+
+func TestDoctorCacheRepository_GetByID_CachesHits(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewDoctorRepository(postgres.NewDoctorRepository(db), 10, time.Minute)
+	id := uuid.New()
+
+	mock.ExpectQuery(regexp.QuoteMeta(getByIDQuery)).
+		WithArgs(id).
+		WillReturnRows(doctorRows(id, "Dr. John Smith"))
+
+	for i := 0; i < 3; i++ {
+		doc, err := repo.GetByID(context.Background(), id)
+		require.NoError(t, err)
+		assert.Equal(t, "Dr. John Smith", doc.Name)
+	}
+
+	require.NoError(t, mock.ExpectationsWereMet())
+	stats := repo.Stats()
+	assert.Equal(t, uint64(2), stats.Hits)
+	assert.Equal(t, uint64(1), stats.Misses)
+}
+
+func TestDoctorCacheRepository_GetByID_Invalidate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewDoctorRepository(postgres.NewDoctorRepository(db), 10, time.Minute)
+	id := uuid.New()
+
+	mock.ExpectQuery(regexp.QuoteMeta(getByIDQuery)).
+		WithArgs(id).
+		WillReturnRows(doctorRows(id, "Dr. John Smith"))
+	mock.ExpectQuery(regexp.QuoteMeta(getByIDQuery)).
+		WithArgs(id).
+		WillReturnRows(doctorRows(id, "Dr. John Smith Jr."))
+
+	doc, err := repo.GetByID(context.Background(), id)
+	require.NoError(t, err)
+	assert.Equal(t, "Dr. John Smith", doc.Name)
+
+	repo.Invalidate(id)
+
+	doc, err = repo.GetByID(context.Background(), id)
+	require.NoError(t, err)
+	assert.Equal(t, "Dr. John Smith Jr.", doc.Name)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDoctorCacheRepository_GetByID_BypassedForPrimaryReads(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewDoctorRepository(postgres.NewDoctorRepository(db), 10, time.Minute)
+	id := uuid.New()
+
+	mock.ExpectQuery(regexp.QuoteMeta(getByIDQuery)).
+		WithArgs(id).
+		WillReturnRows(doctorRows(id, "Dr. John Smith"))
+	mock.ExpectQuery(regexp.QuoteMeta(getByIDQuery)).
+		WithArgs(id).
+		WillReturnRows(doctorRows(id, "Dr. John Smith Jr."))
+
+	_, err = repo.GetByID(context.Background(), id)
+	require.NoError(t, err)
+
+	doc, err := repo.GetByID(database.WithPrimary(context.Background()), id)
+	require.NoError(t, err)
+	assert.Equal(t, "Dr. John Smith Jr.", doc.Name)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDoctorCacheRepository_UpdateAvatar_InvalidatesAfterCommit(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+
+	db := database.New(sqlDB)
+	repo := NewDoctorRepository(postgres.NewDoctorRepository(db), 10, time.Minute)
+	id := uuid.New()
+
+	mock.ExpectQuery(regexp.QuoteMeta(getByIDQuery)).
+		WithArgs(id).
+		WillReturnRows(doctorRows(id, "Dr. John Smith"))
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("UPDATE doctors")).
+		WillReturnRows(sqlmock.NewRows([]string{"avatar_key", "exists"}).AddRow(nil, true))
+	mock.ExpectCommit()
+	mock.ExpectQuery(regexp.QuoteMeta(getByIDQuery)).
+		WithArgs(id).
+		WillReturnRows(doctorRows(id, "Dr. John Smith"))
+
+	_, err = repo.GetByID(context.Background(), id)
+	require.NoError(t, err)
+
+	err = db.InTx(context.Background(), func(ctx context.Context) error {
+		if _, err := repo.UpdateAvatar(ctx, id, "doctors/new.png", time.Time{}); err != nil {
+			return err
+		}
+		// Until the commit other readers are served the row that is still
+		// committed
+		_, err := repo.GetByID(context.Background(), id)
+		return err
+	})
+	require.NoError(t, err)
+
+	_, err = repo.GetByID(context.Background(), id)
+	require.NoError(t, err)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, uint64(1), repo.Stats().Hits)
+}
+
+func TestDoctorCacheRepository_GetByID_NotFoundIsNotCached(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewDoctorRepository(postgres.NewDoctorRepository(db), 10, time.Minute)
+	id := uuid.New()
+
+	mock.ExpectQuery(regexp.QuoteMeta(getByIDQuery)).
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(regexp.QuoteMeta(getByIDQuery)).
+		WithArgs(id).
+		WillReturnRows(doctorRows(id, "Dr. Jane Doe"))
+
+	_, err = repo.GetByID(context.Background(), id)
+	assert.ErrorIs(t, err, doctor.ErrDoctorNotFound)
+
+	doc, err := repo.GetByID(context.Background(), id)
+	require.NoError(t, err)
+	assert.Equal(t, "Dr. Jane Doe", doc.Name)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDoctorCacheRepository_GetByIDs_LoadsOnlyMisses(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewDoctorRepository(postgres.NewDoctorRepository(db), 10, time.Minute)
+	cached := uuid.New()
+	missing := uuid.New()
+
+	mock.ExpectQuery(regexp.QuoteMeta(getByIDQuery)).
+		WithArgs(cached).
+		WillReturnRows(doctorRows(cached, "Dr. John Smith"))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, name, specialty_id, phone_number, avatar_key, description, created_at, updated_at FROM doctors WHERE id = ANY($1)")).
+		WithArgs("{\"" + missing.String() + "\"}").
+		WillReturnRows(doctorRows(missing, "Dr. Jane Doe"))
+
+	_, err = repo.GetByID(context.Background(), cached)
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		doctors, err := repo.GetByIDs(context.Background(), []uuid.UUID{cached, missing})
+		require.NoError(t, err)
+		assert.Len(t, doctors, 2)
+	}
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -7,6 +7,7 @@ import (
 	"context"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/shayesteh1hs/DrAppointment/internal/entity/medical"
@@ -16,7 +17,10 @@ import (
 )
 
 type doctorRepository struct {
-	doctors []medical.Doctor
+	doctors      []medical.Doctor
+	offerings    []medical.Offering
+	clinics      []medical.DoctorClinic
+	workingHours []medical.WorkingHours
 }
 
 func (r *doctorRepository) ListOffset(ctx context.Context, filters filter.DoctorQueryParam, params pagination.LimitOffsetParams) ([]medical.Doctor, error) {
@@ -61,6 +65,40 @@ func (r *doctorRepository) GetByID(ctx context.Context, id uuid.UUID) (*medical.
 	return nil, doctor.ErrDoctorNotFound
 }
 
+func (r *doctorRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]medical.Doctor, error) {
+	wanted := make(map[uuid.UUID]struct{}, len(ids))
+	for _, id := range ids {
+		wanted[id] = struct{}{}
+	}
+
+	doctors := make([]medical.Doctor, 0, len(ids))
+	for _, doc := range r.doctors {
+		if _, ok := wanted[doc.ID]; ok {
+			doctors = append(doctors, doc)
+		}
+	}
+	return doctors, nil
+}
+
+func (r *doctorRepository) UpdateAvatar(ctx context.Context, id uuid.UUID, key string, ifUpdatedAt time.Time) (string, error) {
+	for i := range r.doctors {
+		if r.doctors[i].ID != id {
+			continue
+		}
+		if !ifUpdatedAt.IsZero() && !r.doctors[i].UpdatedAt.Equal(ifUpdatedAt) {
+			return "", doctor.ErrDoctorModified
+		}
+		var previous string
+		if r.doctors[i].Avatar != nil {
+			previous = r.doctors[i].Avatar.Path.String()
+		}
+		r.doctors[i].Avatar = medical.NewDoctorAvatar(key)
+		r.doctors[i].UpdatedAt = time.Now()
+		return previous, nil
+	}
+	return "", doctor.ErrDoctorNotFound
+}
+
 func (r *doctorRepository) Count(ctx context.Context, filters filter.DoctorQueryParam) (int, error) {
 	filteredDoctors := r.applyFilters(r.doctors, filters)
 	return len(filteredDoctors), nil
@@ -85,6 +123,10 @@ func (r *doctorRepository) applyFilters(doctors []medical.Doctor, filters filter
 			}
 		}
 
+		if !filters.MatchesOfferings(r.offeringsOf(doc.ID)) {
+			match = false
+		}
+
 		if match {
 			filtered = append(filtered, doc)
 		}
@@ -93,12 +135,72 @@ func (r *doctorRepository) applyFilters(doctors []medical.Doctor, filters filter
 	return filtered
 }
 
+func (r *doctorRepository) ListOfferings(ctx context.Context, doctorIDs []uuid.UUID) ([]medical.Offering, error) {
+	offerings := []medical.Offering{}
+	for _, id := range doctorIDs {
+		offerings = append(offerings, r.offeringsOf(id)...)
+	}
+	return offerings, nil
+}
+
+func (r *doctorRepository) offeringsOf(doctorID uuid.UUID) []medical.Offering {
+	var offerings []medical.Offering
+	for _, o := range r.offerings {
+		if o.DoctorID == doctorID {
+			offerings = append(offerings, o)
+		}
+	}
+	sort.Slice(offerings, func(i, j int) bool {
+		return offerings[i].Price.Amount < offerings[j].Price.Amount
+	})
+	return offerings
+}
+
+func (r *doctorRepository) ListClinics(ctx context.Context, doctorIDs []uuid.UUID) ([]medical.DoctorClinic, error) {
+	clinics := []medical.DoctorClinic{}
+	for _, id := range doctorIDs {
+		for _, c := range r.clinics {
+			if c.DoctorID == id {
+				clinics = append(clinics, c)
+			}
+		}
+	}
+	return clinics, nil
+}
+
+func (r *doctorRepository) ListWorkingHours(ctx context.Context, doctorIDs []uuid.UUID) ([]medical.WorkingHours, error) {
+	hours := []medical.WorkingHours{}
+	for _, id := range doctorIDs {
+		for _, w := range r.workingHours {
+			if w.DoctorID == id {
+				hours = append(hours, w)
+			}
+		}
+	}
+	return hours, nil
+}
+
+func (r *doctorRepository) AddClinic(c medical.DoctorClinic) {
+	r.clinics = append(r.clinics, c)
+}
+
+func (r *doctorRepository) AddWorkingHours(w medical.WorkingHours) {
+	r.workingHours = append(r.workingHours, w)
+}
+
+func (r *doctorRepository) AddOffering(o medical.Offering) {
+	r.offerings = append(r.offerings, o)
+}
+
 func (r *doctorRepository) AddDoctor(doc medical.Doctor) {
 	r.doctors = append(r.doctors, doc)
 }
 
 func (r *doctorRepository) Clear() {
 	r.doctors = []medical.Doctor{}
+	r.offerings = nil
+	r.clinics = nil
+	r.workingHours = nil
 }
 
 func NewDoctorRepository() doctor.Repository {
@@ -27,7 +27,7 @@ func setupDoctorMemoryRepo() *doctorRepository {
 				Name:        "Dr. John Smith",
 				SpecialtyID: uuid.MustParse("223e4567-e89b-12d3-a456-426614174000"),
 				PhoneNumber: "+1234567890",
-				AvatarURL:   "https://example.com/avatar1.jpg",
+				Avatar:      medical.NewDoctorAvatar("https://example.com/avatar1.jpg"),
 				Description: "Experienced cardiologist",
 				CreatedAt:   time.Now().Add(-24 * time.Hour),
 				UpdatedAt:   time.Now().Add(-24 * time.Hour),
@@ -37,7 +37,7 @@ func setupDoctorMemoryRepo() *doctorRepository {
 				Name:        "Dr. Jane Doe",
 				SpecialtyID: uuid.MustParse("223e4567-e89b-12d3-a456-426614174001"),
 				PhoneNumber: "+1234567891",
-				AvatarURL:   "https://example.com/avatar2.jpg",
+				Avatar:      medical.NewDoctorAvatar("https://example.com/avatar2.jpg"),
 				Description: "Skilled neurologist",
 				CreatedAt:   time.Now().Add(-12 * time.Hour),
 				UpdatedAt:   time.Now().Add(-12 * time.Hour),
@@ -47,7 +47,7 @@ func setupDoctorMemoryRepo() *doctorRepository {
 				Name:        "Dr. Alice Johnson",
 				SpecialtyID: uuid.MustParse("223e4567-e89b-12d3-a456-426614174000"),
 				PhoneNumber: "+1234567892",
-				AvatarURL:   "https://example.com/avatar3.jpg",
+				Avatar:      medical.NewDoctorAvatar("https://example.com/avatar3.jpg"),
 				Description: "Expert cardiologist",
 				CreatedAt:   time.Now().Add(-6 * time.Hour),
 				UpdatedAt:   time.Now().Add(-6 * time.Hour),
@@ -65,7 +65,7 @@ func NewDoctorRepositoryWithTestData() doctor.Repository {
 				Name:        "Dr. John Smith",
 				SpecialtyID: uuid.MustParse("223e4567-e89b-12d3-a456-426614174000"),
 				PhoneNumber: "+1234567890",
-				AvatarURL:   "https://example.com/avatar1.jpg",
+				Avatar:      medical.NewDoctorAvatar("https://example.com/avatar1.jpg"),
 				Description: "Experienced cardiologist",
 				CreatedAt:   baseTime.Add(-24 * time.Hour),
 				UpdatedAt:   baseTime.Add(-24 * time.Hour),
@@ -75,7 +75,7 @@ func NewDoctorRepositoryWithTestData() doctor.Repository {
 				Name:        "Dr. Jane Doe",
 				SpecialtyID: uuid.MustParse("223e4567-e89b-12d3-a456-426614174001"),
 				PhoneNumber: "+1234567891",
-				AvatarURL:   "https://example.com/avatar2.jpg",
+				Avatar:      medical.NewDoctorAvatar("https://example.com/avatar2.jpg"),
 				Description: "Skilled neurologist",
 				CreatedAt:   baseTime.Add(-12 * time.Hour),
 				UpdatedAt:   baseTime.Add(-12 * time.Hour),
@@ -234,7 +234,7 @@ func TestDoctorMemoryRepository_AddDoctor(t *testing.T) {
 		Name:        "Dr. Bob Wilson",
 		SpecialtyID: uuid.MustParse("223e4567-e89b-12d3-a456-426614174002"),
 		PhoneNumber: "+1234567893",
-		AvatarURL:   "https://example.com/avatar4.jpg",
+		Avatar:      medical.NewDoctorAvatar("https://example.com/avatar4.jpg"),
 		Description: "Dermatologist",
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
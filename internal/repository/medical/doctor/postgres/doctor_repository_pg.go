@@ -5,24 +5,64 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	"log"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/huandu/go-sqlbuilder"
 
+	"github.com/shayesteh1hs/DrAppointment/internal/database"
 	"github.com/shayesteh1hs/DrAppointment/internal/entity/medical"
 	filter "github.com/shayesteh1hs/DrAppointment/internal/filter/medical"
+	"github.com/shayesteh1hs/DrAppointment/internal/logging"
+	"github.com/shayesteh1hs/DrAppointment/internal/money"
 	"github.com/shayesteh1hs/DrAppointment/internal/pagination"
 	"github.com/shayesteh1hs/DrAppointment/internal/repository/medical/doctor"
 )
 
+// updateAvatarQuery swaps the avatar key and returns the previous one in a
+// single statement, locking the row so concurrent uploads cannot both see
+// the same previous key. When $3 is set the swap only happens if the row is
+// still at that updated_at, and the second column reports whether it did.
+const updateAvatarQuery = `
+WITH old AS (SELECT id, avatar_key, updated_at FROM doctors WHERE id = $2 FOR UPDATE),
+updated AS (
+	UPDATE doctors AS d SET avatar_key = $1
+	FROM old
+	WHERE d.id = old.id AND ($3::timestamptz IS NULL OR old.updated_at = $3)
+	RETURNING d.id
+)
+SELECT old.avatar_key, EXISTS (SELECT 1 FROM updated) FROM old`
+
+const listOfferingsQuery = `
+SELECT id, doctor_id, kind, name, duration_minutes, price, currency, created_at, updated_at
+FROM doctor_services
+WHERE doctor_id = ANY($1)
+ORDER BY doctor_id, price, name`
+
+const listClinicsQuery = `
+SELECT dc.doctor_id, c.id, c.name, c.address, c.city, c.phone_number, c.created_at, c.updated_at
+FROM doctor_clinics dc
+JOIN clinics c ON c.id = dc.clinic_id
+WHERE dc.doctor_id = ANY($1)
+ORDER BY dc.doctor_id, c.name`
+
+// listWorkingHoursQuery returns opening and closing times in seconds after
+// midnight.
+const listWorkingHoursQuery = `
+SELECT id, doctor_id, clinic_id, weekday,
+    EXTRACT(EPOCH FROM opens_at)::bigint, EXTRACT(EPOCH FROM closes_at)::bigint,
+    slot_minutes, time_zone, created_at, updated_at
+FROM doctor_working_hours
+WHERE doctor_id = ANY($1)
+ORDER BY doctor_id, weekday, opens_at`
+
 type doctorRepository struct {
-	db *sql.DB
+	db database.Querier
 }
 
 func (r *doctorRepository) ListOffset(ctx context.Context, filters filter.DoctorQueryParam, params pagination.LimitOffsetParams) ([]medical.Doctor, error) {
 	sb := sqlbuilder.PostgreSQL.NewSelectBuilder()
-	sb.Select("id", "name", "specialty_id", "phone_number", "avatar_url", "description", "created_at", "updated_at")
+	sb.Select("id", "name", "specialty_id", "phone_number", "avatar_key", "description", "created_at", "updated_at")
 	sb.From("doctors")
 	sb = filters.Apply(sb)
 	sb.Limit(params.Limit)
@@ -36,7 +76,7 @@ func (r *doctorRepository) ListOffset(ctx context.Context, filters filter.Doctor
 	defer func(rows *sql.Rows) {
 		err := rows.Close()
 		if err != nil {
-			log.Printf("failed to close rows: %v", err)
+			logging.FromContext(ctx).Warn("failed to close rows", "error", err)
 		}
 	}(rows)
 
@@ -65,7 +105,7 @@ func (r *doctorRepository) Count(ctx context.Context, filters filter.DoctorQuery
 
 func (r *doctorRepository) GetByID(ctx context.Context, id uuid.UUID) (*medical.Doctor, error) {
 	sb := sqlbuilder.PostgreSQL.NewSelectBuilder()
-	sb.Select("id", "name", "specialty_id", "phone_number", "avatar_url", "description", "created_at", "updated_at")
+	sb.Select("id", "name", "specialty_id", "phone_number", "avatar_key", "description", "created_at", "updated_at")
 	sb.From("doctors")
 	sb.Where(sb.Equal("id", id))
 
@@ -73,12 +113,13 @@ func (r *doctorRepository) GetByID(ctx context.Context, id uuid.UUID) (*medical.
 	row := r.db.QueryRowContext(ctx, query, args...)
 
 	var doc medical.Doctor
+	var avatarKey sql.NullString
 	err := row.Scan(
 		&doc.ID,
 		&doc.Name,
 		&doc.SpecialtyID,
 		&doc.PhoneNumber,
-		&doc.AvatarURL,
+		&avatarKey,
 		&doc.Description,
 		&doc.CreatedAt,
 		&doc.UpdatedAt,
@@ -89,20 +130,170 @@ func (r *doctorRepository) GetByID(ctx context.Context, id uuid.UUID) (*medical.
 		}
 		return nil, fmt.Errorf("failed to scan doctor: %w", err)
 	}
+	doc.Avatar = medical.NewDoctorAvatar(avatarKey.String)
 
 	return &doc, nil
 }
 
+func (r *doctorRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]medical.Doctor, error) {
+	if len(ids) == 0 {
+		return []medical.Doctor{}, nil
+	}
+
+	sb := sqlbuilder.PostgreSQL.NewSelectBuilder()
+	sb.Select("id", "name", "specialty_id", "phone_number", "avatar_key", "description", "created_at", "updated_at")
+	sb.From("doctors")
+	sb.Where("id = ANY(" + sb.Var(database.UUIDArray(ids)) + ")")
+
+	query, args := sb.Build()
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return []medical.Doctor{}, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			logging.FromContext(ctx).Warn("failed to close rows", "error", err)
+		}
+	}(rows)
+
+	doctors, err := r.scanDoctors(rows)
+	if err != nil {
+		return []medical.Doctor{}, err
+	}
+
+	return doctors, nil
+}
+
+func (r *doctorRepository) UpdateAvatar(ctx context.Context, id uuid.UUID, key string, ifUpdatedAt time.Time) (string, error) {
+	var previous sql.NullString
+	var updated bool
+	unmodifiedSince := sql.NullTime{Time: ifUpdatedAt, Valid: !ifUpdatedAt.IsZero()}
+	err := r.db.QueryRowContext(ctx, updateAvatarQuery, key, id, unmodifiedSince).Scan(&previous, &updated)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", doctor.ErrDoctorNotFound
+		}
+		return "", fmt.Errorf("failed to update doctor avatar: %w", err)
+	}
+	if !updated {
+		return "", doctor.ErrDoctorModified
+	}
+	return previous.String, nil
+}
+
+func (r *doctorRepository) ListOfferings(ctx context.Context, doctorIDs []uuid.UUID) ([]medical.Offering, error) {
+	if len(doctorIDs) == 0 {
+		return []medical.Offering{}, nil
+	}
+
+	rows, err := r.db.QueryContext(ctx, listOfferingsQuery, database.UUIDArray(doctorIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list doctor services: %w", err)
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			logging.FromContext(ctx).Warn("failed to close rows", "error", err)
+		}
+	}(rows)
+
+	offerings := []medical.Offering{}
+	for rows.Next() {
+		var o medical.Offering
+		var currency string
+		err := rows.Scan(&o.ID, &o.DoctorID, &o.Kind, &o.Name, &o.DurationMinutes, &o.Price.Amount, &currency, &o.CreatedAt, &o.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan doctor service: %w", err)
+		}
+		o.Price.Currency = money.Currency(currency)
+		offerings = append(offerings, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list doctor services: %w", err)
+	}
+	return offerings, nil
+}
+
+func (r *doctorRepository) ListClinics(ctx context.Context, doctorIDs []uuid.UUID) ([]medical.DoctorClinic, error) {
+	if len(doctorIDs) == 0 {
+		return []medical.DoctorClinic{}, nil
+	}
+
+	rows, err := r.db.QueryContext(ctx, listClinicsQuery, database.UUIDArray(doctorIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list doctor clinics: %w", err)
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			logging.FromContext(ctx).Warn("failed to close rows", "error", err)
+		}
+	}(rows)
+
+	clinics := []medical.DoctorClinic{}
+	for rows.Next() {
+		var c medical.DoctorClinic
+		err := rows.Scan(&c.DoctorID, &c.ID, &c.Name, &c.Address, &c.City, &c.PhoneNumber, &c.CreatedAt, &c.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan doctor clinic: %w", err)
+		}
+		clinics = append(clinics, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list doctor clinics: %w", err)
+	}
+	return clinics, nil
+}
+
+func (r *doctorRepository) ListWorkingHours(ctx context.Context, doctorIDs []uuid.UUID) ([]medical.WorkingHours, error) {
+	if len(doctorIDs) == 0 {
+		return []medical.WorkingHours{}, nil
+	}
+
+	rows, err := r.db.QueryContext(ctx, listWorkingHoursQuery, database.UUIDArray(doctorIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list doctor working hours: %w", err)
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			logging.FromContext(ctx).Warn("failed to close rows", "error", err)
+		}
+	}(rows)
+
+	hours := []medical.WorkingHours{}
+	for rows.Next() {
+		var (
+			w             medical.WorkingHours
+			clinicID      uuid.NullUUID
+			opens, closes int64
+		)
+		err := rows.Scan(&w.ID, &w.DoctorID, &clinicID, &w.Weekday, &opens, &closes, &w.SlotMinutes, &w.TimeZone, &w.CreatedAt, &w.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan doctor working hours: %w", err)
+		}
+		w.ClinicID = clinicID.UUID
+		w.Opens, w.Closes = time.Duration(opens)*time.Second, time.Duration(closes)*time.Second
+		hours = append(hours, w)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list doctor working hours: %w", err)
+	}
+	return hours, nil
+}
+
 func (r *doctorRepository) scanDoctors(rows *sql.Rows) ([]medical.Doctor, error) {
 	var doctors []medical.Doctor
 	for rows.Next() {
 		var doc medical.Doctor
+		var avatarKey sql.NullString
 		err := rows.Scan(
 			&doc.ID,
 			&doc.Name,
 			&doc.SpecialtyID,
 			&doc.PhoneNumber,
-			&doc.AvatarURL,
+			&avatarKey,
 			&doc.Description,
 			&doc.CreatedAt,
 			&doc.UpdatedAt,
@@ -110,6 +301,7 @@ func (r *doctorRepository) scanDoctors(rows *sql.Rows) ([]medical.Doctor, error)
 		if err != nil {
 			return nil, err
 		}
+		doc.Avatar = medical.NewDoctorAvatar(avatarKey.String)
 		doctors = append(doctors, doc)
 	}
 
@@ -120,6 +312,6 @@ func (r *doctorRepository) scanDoctors(rows *sql.Rows) ([]medical.Doctor, error)
 	return doctors, nil
 }
 
-func NewDoctorRepository(db *sql.DB) doctor.Repository {
+func NewDoctorRepository(db database.Querier) doctor.Repository {
 	return &doctorRepository{db: db}
 }
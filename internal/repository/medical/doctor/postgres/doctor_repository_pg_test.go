@@ -13,7 +13,10 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/shayesteh1hs/DrAppointment/internal/database"
+	"github.com/shayesteh1hs/DrAppointment/internal/entity/medical"
 	filter "github.com/shayesteh1hs/DrAppointment/internal/filter/medical"
+	"github.com/shayesteh1hs/DrAppointment/internal/money"
 	"github.com/shayesteh1hs/DrAppointment/internal/pagination"
 	"github.com/shayesteh1hs/DrAppointment/internal/repository/medical/doctor"
 )
@@ -44,9 +47,9 @@ func TestDoctorPostgresRepository_ListOffset_Success(t *testing.T) {
 	specialtyID2 := uuid.MustParse("223e4567-e89b-12d3-a456-426614174001")
 	now := time.Now()
 
-	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, name, specialty_id, phone_number, avatar_url, description, created_at, updated_at FROM doctors LIMIT $1 OFFSET $2")).
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, name, specialty_id, phone_number, avatar_key, description, created_at, updated_at FROM doctors LIMIT $1 OFFSET $2")).
 		WithArgs(10, 0).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "specialty_id", "phone_number", "avatar_url", "description", "created_at", "updated_at"}).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "specialty_id", "phone_number", "avatar_key", "description", "created_at", "updated_at"}).
 			AddRow(doctorID1, "Dr. John Smith", specialtyID1, "+1234567890", "https://example.com/avatar1.jpg", "Experienced cardiologist", now, now).
 			AddRow(doctorID2, "Dr. Jane Doe", specialtyID2, "+1234567891", "https://example.com/avatar2.jpg", "Skilled neurologist", now, now))
 
@@ -81,9 +84,9 @@ func TestDoctorPostgresRepository_ListOffset_WithNameFilter(t *testing.T) {
 	specialtyID := uuid.MustParse("223e4567-e89b-12d3-a456-426614174000")
 	now := time.Now()
 
-	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, name, specialty_id, phone_number, avatar_url, description, created_at, updated_at FROM doctors WHERE name LIKE $1 LIMIT $2 OFFSET $3")).
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, name, specialty_id, phone_number, avatar_key, description, created_at, updated_at FROM doctors WHERE name LIKE $1 LIMIT $2 OFFSET $3")).
 		WithArgs("%John%", 10, 0).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "specialty_id", "phone_number", "avatar_url", "description", "created_at", "updated_at"}).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "specialty_id", "phone_number", "avatar_key", "description", "created_at", "updated_at"}).
 			AddRow(doctorID, "Dr. John Smith", specialtyID, "+1234567890", "https://example.com/avatar1.jpg", "Experienced cardiologist", now, now))
 
 	result, err := repo.ListOffset(ctx, filters, params)
@@ -115,9 +118,9 @@ func TestDoctorPostgresRepository_ListOffset_WithSpecialtyFilter(t *testing.T) {
 	doctorID := uuid.MustParse("123e4567-e89b-12d3-a456-426614174000")
 	now := time.Now()
 
-	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, name, specialty_id, phone_number, avatar_url, description, created_at, updated_at FROM doctors WHERE specialty_id = $1 LIMIT $2 OFFSET $3")).
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, name, specialty_id, phone_number, avatar_key, description, created_at, updated_at FROM doctors WHERE specialty_id = $1 LIMIT $2 OFFSET $3")).
 		WithArgs(specialtyID.String(), 10, 0).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "specialty_id", "phone_number", "avatar_url", "description", "created_at", "updated_at"}).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "specialty_id", "phone_number", "avatar_key", "description", "created_at", "updated_at"}).
 			AddRow(doctorID, "Dr. John Smith", specialtyID, "+1234567890", "https://example.com/avatar1.jpg", "Experienced cardiologist", now, now))
 
 	result, err := repo.ListOffset(ctx, filters, params)
@@ -144,9 +147,9 @@ func TestDoctorPostgresRepository_ListOffset_EmptyResult(t *testing.T) {
 	filters := filter.DoctorQueryParam{}
 
 	// Mock select query returning empty result
-	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, name, specialty_id, phone_number, avatar_url, description, created_at, updated_at FROM doctors LIMIT $1 OFFSET $2")).
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, name, specialty_id, phone_number, avatar_key, description, created_at, updated_at FROM doctors LIMIT $1 OFFSET $2")).
 		WithArgs(10, 0).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "specialty_id", "phone_number", "avatar_url", "description", "created_at", "updated_at"}))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "specialty_id", "phone_number", "avatar_key", "description", "created_at", "updated_at"}))
 
 	result, err := repo.ListOffset(ctx, filters, params)
 	require.NoError(t, err)
@@ -170,7 +173,7 @@ func TestDoctorPostgresRepository_ListOffset_SelectError(t *testing.T) {
 	filters := filter.DoctorQueryParam{}
 
 	// Mock select query with error
-	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, name, specialty_id, phone_number, avatar_url, description, created_at, updated_at FROM doctors LIMIT $1 OFFSET $2")).
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, name, specialty_id, phone_number, avatar_key, description, created_at, updated_at FROM doctors LIMIT $1 OFFSET $2")).
 		WithArgs(10, 0).
 		WillReturnError(sql.ErrConnDone)
 
@@ -192,9 +195,9 @@ func TestDoctorPostgresRepository_GetByID_Success(t *testing.T) {
 	specialtyID := uuid.MustParse("223e4567-e89b-12d3-a456-426614174000")
 	now := time.Now()
 
-	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, name, specialty_id, phone_number, avatar_url, description, created_at, updated_at FROM doctors WHERE id = $1")).
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, name, specialty_id, phone_number, avatar_key, description, created_at, updated_at FROM doctors WHERE id = $1")).
 		WithArgs(doctorID).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "specialty_id", "phone_number", "avatar_url", "description", "created_at", "updated_at"}).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "specialty_id", "phone_number", "avatar_key", "description", "created_at", "updated_at"}).
 			AddRow(doctorID, "Dr. John Smith", specialtyID, "+1234567890", "https://example.com/avatar1.jpg", "Experienced cardiologist", now, now))
 
 	doctor, err := repo.GetByID(ctx, doctorID)
@@ -219,7 +222,7 @@ func TestDoctorPostgresRepository_GetByID_NotFound(t *testing.T) {
 
 	doctorID := uuid.MustParse("123e4567-e89b-12d3-a456-426614174000")
 
-	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, name, specialty_id, phone_number, avatar_url, description, created_at, updated_at FROM doctors WHERE id = $1")).
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, name, specialty_id, phone_number, avatar_key, description, created_at, updated_at FROM doctors WHERE id = $1")).
 		WithArgs(doctorID).
 		WillReturnError(sql.ErrNoRows)
 
@@ -241,9 +244,9 @@ func TestDoctorPostgresRepository_GetByID_ScanError(t *testing.T) {
 	doctorID := uuid.MustParse("123e4567-e89b-12d3-a456-426614174000")
 
 	// Return invalid data that will cause scan error
-	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, name, specialty_id, phone_number, avatar_url, description, created_at, updated_at FROM doctors WHERE id = $1")).
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, name, specialty_id, phone_number, avatar_key, description, created_at, updated_at FROM doctors WHERE id = $1")).
 		WithArgs(doctorID).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "specialty_id", "phone_number", "avatar_url", "description", "created_at", "updated_at"}).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "specialty_id", "phone_number", "avatar_key", "description", "created_at", "updated_at"}).
 			AddRow("invalid-uuid", "Dr. John Smith", "invalid-uuid", "+1234567890", "https://example.com/avatar1.jpg", "Experienced cardiologist", "invalid-time", "invalid-time"))
 
 	doctor, err := repo.GetByID(ctx, doctorID)
@@ -252,3 +255,256 @@ func TestDoctorPostgresRepository_GetByID_ScanError(t *testing.T) {
 
 	require.NoError(t, mock.ExpectationsWereMet())
 }
+
+func TestDoctorPostgresRepository_GetByIDs_Success(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	repo := NewDoctorRepository(db)
+	ctx := context.Background()
+
+	first := uuid.New()
+	second := uuid.New()
+	specialtyID := uuid.New()
+	now := time.Now()
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, name, specialty_id, phone_number, avatar_key, description, created_at, updated_at FROM doctors WHERE id = ANY($1)")).
+		WithArgs("{\"" + first.String() + "\",\"" + second.String() + "\"}").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "specialty_id", "phone_number", "avatar_key", "description", "created_at", "updated_at"}).
+			AddRow(second, "Dr. Jane Doe", specialtyID, "+1234567891", "", "", now, now))
+
+	doctors, err := repo.GetByIDs(ctx, []uuid.UUID{first, second})
+	require.NoError(t, err)
+	require.Len(t, doctors, 1)
+	assert.Equal(t, second, doctors[0].ID)
+	assert.Equal(t, "Dr. Jane Doe", doctors[0].Name)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDoctorPostgresRepository_GetByIDs_SelectError(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	repo := NewDoctorRepository(db)
+	id := uuid.New()
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, name, specialty_id, phone_number, avatar_key, description, created_at, updated_at FROM doctors WHERE id = ANY($1)")).
+		WillReturnError(errors.New("connection refused"))
+
+	doctors, err := repo.GetByIDs(context.Background(), []uuid.UUID{id})
+	require.Error(t, err)
+	assert.Empty(t, doctors)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDoctorPostgresRepository_UpdateAvatar(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	repo := NewDoctorRepository(db)
+	ctx := context.Background()
+
+	doctorID := uuid.MustParse("123e4567-e89b-12d3-a456-426614174000")
+
+	mock.ExpectQuery(regexp.QuoteMeta(updateAvatarQuery)).
+		WithArgs("doctors/new.png", doctorID, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"avatar_key", "exists"}).AddRow("doctors/old.png", true))
+
+	previous, err := repo.UpdateAvatar(ctx, doctorID, "doctors/new.png", time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, "doctors/old.png", previous)
+
+	mock.ExpectQuery(regexp.QuoteMeta(updateAvatarQuery)).
+		WithArgs("doctors/new.png", doctorID, nil).
+		WillReturnError(sql.ErrNoRows)
+
+	_, err = repo.UpdateAvatar(ctx, doctorID, "doctors/new.png", time.Time{})
+	assert.ErrorIs(t, err, doctor.ErrDoctorNotFound)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDoctorPostgresRepository_UpdateAvatar_Conditional(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	repo := NewDoctorRepository(db)
+	ctx := context.Background()
+
+	doctorID := uuid.MustParse("123e4567-e89b-12d3-a456-426614174000")
+	version := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(regexp.QuoteMeta(updateAvatarQuery)).
+		WithArgs("doctors/new.png", doctorID, version).
+		WillReturnRows(sqlmock.NewRows([]string{"avatar_key", "exists"}).AddRow("doctors/old.png", true))
+
+	previous, err := repo.UpdateAvatar(ctx, doctorID, "doctors/new.png", version)
+	require.NoError(t, err)
+	assert.Equal(t, "doctors/old.png", previous)
+
+	// The row moved past the version, so nothing was written
+	mock.ExpectQuery(regexp.QuoteMeta(updateAvatarQuery)).
+		WithArgs("doctors/new.png", doctorID, version).
+		WillReturnRows(sqlmock.NewRows([]string{"avatar_key", "exists"}).AddRow("doctors/other.png", false))
+
+	_, err = repo.UpdateAvatar(ctx, doctorID, "doctors/new.png", version)
+	assert.ErrorIs(t, err, doctor.ErrDoctorModified)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDoctorPostgresRepository_UpdateAvatar_ReadsOwnWriteWithReplicas(t *testing.T) {
+	primary, mock := setupMockDB(t)
+	defer primary.Close()
+	replica, replicaMock := setupMockDB(t)
+	defer replica.Close()
+
+	repo := NewDoctorRepository(database.NewWithReplicas(primary, replica))
+	id := uuid.MustParse("123e4567-e89b-12d3-a456-426614174000")
+	specialtyID := uuid.MustParse("223e4567-e89b-12d3-a456-426614174000")
+	now := time.Now()
+	getByIDQuery := "SELECT id, name, specialty_id, phone_number, avatar_key, description, created_at, updated_at FROM doctors WHERE id = $1"
+	doctorRows := func(avatar string) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "name", "specialty_id", "phone_number", "avatar_key", "description", "created_at", "updated_at"}).
+			AddRow(id, "Dr. John Smith", specialtyID, "+1234567890", avatar, "", now, now)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(updateAvatarQuery)).
+		WithArgs("doctors/new.png", id, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"avatar_key", "exists"}).AddRow("doctors/old.png", true))
+	// The uploader sees its new avatar even if the replica lags
+	mock.ExpectQuery(regexp.QuoteMeta(getByIDQuery)).
+		WithArgs(id).
+		WillReturnRows(doctorRows("doctors/new.png"))
+	replicaMock.ExpectQuery(regexp.QuoteMeta(getByIDQuery)).
+		WithArgs(id).
+		WillReturnRows(doctorRows("doctors/old.png"))
+
+	ctx := database.WithSession(context.Background())
+	_, err := repo.UpdateAvatar(ctx, id, "doctors/new.png", time.Time{})
+	require.NoError(t, err)
+
+	doc, err := repo.GetByID(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, "doctors/new.png", doc.Avatar.Path.String())
+
+	// Other requests keep reading from the replica
+	doc, err = repo.GetByID(database.WithSession(context.Background()), id)
+	require.NoError(t, err)
+	assert.Equal(t, "doctors/old.png", doc.Avatar.Path.String())
+
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.NoError(t, replicaMock.ExpectationsWereMet())
+}
+
+func TestDoctorPostgresRepository_ListOffset_WithFeeFilter(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	repo := NewDoctorRepository(db)
+	maxFee := int64(200000)
+	filters := filter.DoctorQueryParam{Service: medical.OfferingInPerson, MaxFee: &maxFee, FeeCurrency: "IRT"}
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, name, specialty_id, phone_number, avatar_key, description, created_at, updated_at FROM doctors WHERE EXISTS (SELECT 1 FROM doctor_services WHERE doctor_services.doctor_id = doctors.id AND doctor_services.kind = $1 AND doctor_services.price <= $2) LIMIT $3 OFFSET $4")).
+		WithArgs("in_person", int64(2000000), 10, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "specialty_id", "phone_number", "avatar_key", "description", "created_at", "updated_at"}))
+
+	result, err := repo.ListOffset(context.Background(), filters, pagination.LimitOffsetParams{Page: 1, Limit: 10})
+	require.NoError(t, err)
+	assert.Empty(t, result)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDoctorPostgresRepository_ListOfferings(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	repo := NewDoctorRepository(db)
+	doctorID := uuid.New()
+	offeringID := uuid.New()
+	now := time.Now()
+
+	mock.ExpectQuery(regexp.QuoteMeta(listOfferingsQuery)).
+		WithArgs("{\"" + doctorID.String() + "\"}").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "doctor_id", "kind", "name", "duration_minutes", "price", "currency", "created_at", "updated_at"}).
+			AddRow(offeringID, doctorID, "online", "Video visit", 15, int64(1500000), "IRR", now, now))
+
+	offerings, err := repo.ListOfferings(context.Background(), []uuid.UUID{doctorID})
+	require.NoError(t, err)
+	require.Len(t, offerings, 1)
+	assert.Equal(t, offeringID, offerings[0].ID)
+	assert.Equal(t, medical.OfferingOnline, offerings[0].Kind)
+	assert.Equal(t, 15, offerings[0].DurationMinutes)
+	assert.Equal(t, money.Rials(1500000), offerings[0].Price)
+
+	// No doctors, no query
+	offerings, err = repo.ListOfferings(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Empty(t, offerings)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDoctorPostgresRepository_ListClinics(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	repo := NewDoctorRepository(db)
+	doctorID := uuid.New()
+	clinicID := uuid.New()
+	now := time.Now()
+
+	mock.ExpectQuery(regexp.QuoteMeta(listClinicsQuery)).
+		WithArgs("{\"" + doctorID.String() + "\"}").
+		WillReturnRows(sqlmock.NewRows([]string{"doctor_id", "id", "name", "address", "city", "phone_number", "created_at", "updated_at"}).
+			AddRow(doctorID, clinicID, "Arad Clinic", "12 Valiasr St", "Tehran", "+982112345678", now, now))
+
+	clinics, err := repo.ListClinics(context.Background(), []uuid.UUID{doctorID})
+	require.NoError(t, err)
+	require.Len(t, clinics, 1)
+	assert.Equal(t, doctorID, clinics[0].DoctorID)
+	assert.Equal(t, clinicID, clinics[0].ID)
+	assert.Equal(t, "Arad Clinic", clinics[0].Name)
+	assert.Equal(t, "Tehran", clinics[0].City)
+
+	// No doctors, no query
+	clinics, err = repo.ListClinics(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Empty(t, clinics)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDoctorPostgresRepository_ListWorkingHours(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	repo := NewDoctorRepository(db)
+	doctorID := uuid.New()
+	clinicID := uuid.New()
+	now := time.Now()
+
+	mock.ExpectQuery(regexp.QuoteMeta(listWorkingHoursQuery)).
+		WithArgs("{\"" + doctorID.String() + "\"}").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "doctor_id", "clinic_id", "weekday", "opens_at", "closes_at", "slot_minutes", "time_zone", "created_at", "updated_at"}).
+			AddRow(uuid.New(), doctorID, clinicID.String(), 6, int64(9*3600), int64(12*3600+30*60), 30, "Asia/Tehran", now, now).
+			AddRow(uuid.New(), doctorID, nil, 0, int64(16*3600), int64(20*3600), 20, "Asia/Tehran", now, now))
+
+	hours, err := repo.ListWorkingHours(context.Background(), []uuid.UUID{doctorID})
+	require.NoError(t, err)
+	require.Len(t, hours, 2)
+	assert.Equal(t, clinicID, hours[0].ClinicID)
+	assert.Equal(t, time.Saturday, hours[0].Weekday)
+	assert.Equal(t, 9*time.Hour, hours[0].Opens)
+	assert.Equal(t, 12*time.Hour+30*time.Minute, hours[0].Closes)
+	assert.Equal(t, 30, hours[0].SlotMinutes)
+	assert.Equal(t, "Asia/Tehran", hours[0].TimeZone)
+	// Working hours without a clinic
+	assert.Equal(t, uuid.Nil, hours[1].ClinicID)
+	assert.Equal(t, time.Sunday, hours[1].Weekday)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -3,6 +3,7 @@ package doctor
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/shayesteh1hs/DrAppointment/internal/entity/medical"
@@ -12,8 +13,28 @@ import (
 
 var ErrDoctorNotFound = errors.New("doctor not found")
 
+// ErrDoctorModified reports that a conditional write found the doctor
+// changed since the version the caller based it on.
+var ErrDoctorModified = errors.New("doctor was modified")
+
 type Repository interface {
 	ListOffset(ctx context.Context, filters filter.DoctorQueryParam, params pagination.LimitOffsetParams) ([]medical.Doctor, error)
 	GetByID(ctx context.Context, id uuid.UUID) (*medical.Doctor, error)
+	// GetByIDs returns the doctors matching ids in no particular order;
+	// unknown IDs are silently skipped.
+	GetByIDs(ctx context.Context, ids []uuid.UUID) ([]medical.Doctor, error)
 	Count(ctx context.Context, filters filter.DoctorQueryParam) (int, error)
+	// UpdateAvatar stores the storage key of a new avatar and returns the
+	// key it replaced, empty when the doctor had none. A non-zero
+	// ifUpdatedAt makes the write conditional: it fails with
+	// ErrDoctorModified unless the doctor is still at that version.
+	UpdateAvatar(ctx context.Context, id uuid.UUID, key string, ifUpdatedAt time.Time) (string, error)
+	// ListOfferings returns the services of the doctors, by doctor and
+	// then price.
+	ListOfferings(ctx context.Context, doctorIDs []uuid.UUID) ([]medical.Offering, error)
+	// ListClinics returns the clinics the doctors practice at, by doctor
+	// and then clinic name.
+	ListClinics(ctx context.Context, doctorIDs []uuid.UUID) ([]medical.DoctorClinic, error)
+	// ListWorkingHours returns the weekly working hours of the doctors.
+	ListWorkingHours(ctx context.Context, doctorIDs []uuid.UUID) ([]medical.WorkingHours, error)
 }
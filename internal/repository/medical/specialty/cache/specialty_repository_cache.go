@@ -0,0 +1,152 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	appCache "github.com/shayesteh1hs/DrAppointment/internal/cache"
+	"github.com/shayesteh1hs/DrAppointment/internal/database"
+	"github.com/shayesteh1hs/DrAppointment/internal/entity/medical"
+	"github.com/shayesteh1hs/DrAppointment/internal/pagination"
+	"github.com/shayesteh1hs/DrAppointment/internal/repository/medical/specialty"
+)
+
+// Repository is a read-through caching specialty.Repository. Specialties
+// rarely change, so besides lookups by ID the list pages and the total count
+// are cached too; any invalidation drops those aggregate entries. Reads that
+// must see the primary (see database.ReadsPrimary) bypass the cache.
+type Repository interface {
+	specialty.Repository
+	Invalidate(id uuid.UUID)
+	InvalidateAll()
+	Stats() appCache.Stats
+}
+
+type pageKey struct {
+	Page  int
+	Limit int
+}
+
+type specialtyRepository struct {
+	next  specialty.Repository
+	byID  *appCache.LRU[uuid.UUID, medical.Specialty]
+	pages *appCache.LRU[pageKey, []medical.Specialty]
+	count *appCache.LRU[struct{}, int]
+}
+
+func (r *specialtyRepository) ListOffset(ctx context.Context, params pagination.LimitOffsetParams) ([]medical.Specialty, error) {
+	if database.ReadsPrimary(ctx) {
+		return r.next.ListOffset(ctx, params)
+	}
+
+	key := pageKey{Page: params.Page, Limit: params.Limit}
+	specialties, err := r.pages.GetOrLoad(ctx, key, func(ctx context.Context) ([]medical.Specialty, error) {
+		return r.next.ListOffset(ctx, params)
+	})
+	if err != nil {
+		return []medical.Specialty{}, err
+	}
+
+	// Hand out a copy so callers cannot mutate the cached page
+	result := make([]medical.Specialty, len(specialties))
+	copy(result, specialties)
+	return result, nil
+}
+
+func (r *specialtyRepository) Count(ctx context.Context) (int, error) {
+	if database.ReadsPrimary(ctx) {
+		return r.next.Count(ctx)
+	}
+
+	return r.count.GetOrLoad(ctx, struct{}{}, r.next.Count)
+}
+
+func (r *specialtyRepository) GetByID(ctx context.Context, id uuid.UUID) (*medical.Specialty, error) {
+	if database.ReadsPrimary(ctx) {
+		return r.next.GetByID(ctx, id)
+	}
+
+	spec, err := r.byID.GetOrLoad(ctx, id, func(ctx context.Context) (medical.Specialty, error) {
+		spec, err := r.next.GetByID(ctx, id)
+		if err != nil {
+			return medical.Specialty{}, err
+		}
+		return *spec, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &spec, nil
+}
+
+// GetByIDs serves what it can from the by-ID cache and loads the remaining
+// specialties in a single call to the next repository.
+func (r *specialtyRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]medical.Specialty, error) {
+	if database.ReadsPrimary(ctx) {
+		return r.next.GetByIDs(ctx, ids)
+	}
+
+	specialties := make([]medical.Specialty, 0, len(ids))
+	var missing []uuid.UUID
+	for _, id := range ids {
+		if spec, ok := r.byID.Get(id); ok {
+			specialties = append(specialties, spec)
+			continue
+		}
+		missing = append(missing, id)
+	}
+	if len(missing) == 0 {
+		return specialties, nil
+	}
+
+	loaded, err := r.next.GetByIDs(ctx, missing)
+	if err != nil {
+		return []medical.Specialty{}, err
+	}
+	for _, spec := range loaded {
+		r.byID.Set(spec.ID, spec)
+	}
+
+	return append(specialties, loaded...), nil
+}
+
+func (r *specialtyRepository) UpdateImage(ctx context.Context, id uuid.UUID, key string, ifUpdatedAt time.Time) (string, error) {
+	previous, err := r.next.UpdateImage(ctx, id, key, ifUpdatedAt)
+	database.AfterCommit(ctx, func() { r.Invalidate(id) })
+	return previous, err
+}
+
+func (r *specialtyRepository) Invalidate(id uuid.UUID) {
+	r.byID.Delete(id)
+	r.pages.Purge()
+	r.count.Purge()
+}
+
+func (r *specialtyRepository) InvalidateAll() {
+	r.byID.Purge()
+	r.pages.Purge()
+	r.count.Purge()
+}
+
+func (r *specialtyRepository) Stats() appCache.Stats {
+	var total appCache.Stats
+	for _, s := range []appCache.Stats{r.byID.Stats(), r.pages.Stats(), r.count.Stats()} {
+		total.Hits += s.Hits
+		total.Misses += s.Misses
+		total.Evictions += s.Evictions
+		total.Size += s.Size
+	}
+	return total
+}
+
+func NewSpecialtyRepository(next specialty.Repository, capacity int, ttl time.Duration) Repository {
+	return &specialtyRepository{
+		next:  next,
+		byID:  appCache.NewLRU[uuid.UUID, medical.Specialty](capacity, ttl),
+		pages: appCache.NewLRU[pageKey, []medical.Specialty](capacity, ttl),
+		count: appCache.NewLRU[struct{}, int](1, ttl),
+	}
+}
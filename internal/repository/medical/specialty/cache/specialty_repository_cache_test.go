@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/pagination"
+	"github.com/shayesteh1hs/DrAppointment/internal/repository/medical/specialty/postgres"
+)
+
+func specialtyRows(id uuid.UUID, name string) *sqlmock.Rows {
+	now := time.Now()
+	return sqlmock.NewRows([]string{"id", "name", "image_path", "created_at", "updated_at"}).
+		AddRow(id, name, "cardiology.jpg", now, now)
+}
+
+func TestSpecialtyCacheRepository_GetByID_CachesHits(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewSpecialtyRepository(postgres.NewSpecialtyRepository(db), 10, time.Minute)
+	id := uuid.New()
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, name, image_path, created_at, updated_at FROM specialties WHERE id = $1")).
+		WithArgs(id).
+		WillReturnRows(specialtyRows(id, "Cardiology"))
+
+	for i := 0; i < 2; i++ {
+		spec, err := repo.GetByID(context.Background(), id)
+		require.NoError(t, err)
+		assert.Equal(t, "Cardiology", spec.Name)
+	}
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSpecialtyCacheRepository_ListAndCount_InvalidatedTogether(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewSpecialtyRepository(postgres.NewSpecialtyRepository(db), 10, time.Minute)
+	params := pagination.LimitOffsetParams{Page: 1, Limit: 10}
+	id := uuid.New()
+
+	listQuery := regexp.QuoteMeta("SELECT id, name, image_path, created_at, updated_at FROM specialties LIMIT $1 OFFSET $2")
+	countQuery := regexp.QuoteMeta("SELECT count(*) FROM specialties")
+
+	mock.ExpectQuery(listQuery).WithArgs(10, 0).WillReturnRows(specialtyRows(id, "Cardiology"))
+	mock.ExpectQuery(countQuery).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery(listQuery).WithArgs(10, 0).WillReturnRows(specialtyRows(id, "Cardiology"))
+	mock.ExpectQuery(countQuery).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	for i := 0; i < 2; i++ {
+		items, err := repo.ListOffset(context.Background(), params)
+		require.NoError(t, err)
+		assert.Len(t, items, 1)
+
+		count, err := repo.Count(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 1, count)
+	}
+
+	repo.Invalidate(id)
+
+	_, err = repo.ListOffset(context.Background(), params)
+	require.NoError(t, err)
+	_, err = repo.Count(context.Background())
+	require.NoError(t, err)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSpecialtyCacheRepository_GetByIDs_LoadsOnlyMisses(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewSpecialtyRepository(postgres.NewSpecialtyRepository(db), 10, time.Minute)
+	cached := uuid.New()
+	missing := uuid.New()
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, name, image_path, created_at, updated_at FROM specialties WHERE id = $1")).
+		WithArgs(cached).
+		WillReturnRows(specialtyRows(cached, "Cardiology"))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, name, image_path, created_at, updated_at FROM specialties WHERE id = ANY($1)")).
+		WithArgs("{\"" + missing.String() + "\"}").
+		WillReturnRows(specialtyRows(missing, "Neurology"))
+
+	_, err = repo.GetByID(context.Background(), cached)
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		specialties, err := repo.GetByIDs(context.Background(), []uuid.UUID{cached, missing})
+		require.NoError(t, err)
+		assert.Len(t, specialties, 2)
+	}
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
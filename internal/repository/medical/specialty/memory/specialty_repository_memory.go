@@ -64,6 +64,40 @@ func (r *specialtyRepository) GetByID(ctx context.Context, id uuid.UUID) (*medic
 	return nil, specialty.ErrSpecialtyNotFound
 }
 
+func (r *specialtyRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]medical.Specialty, error) {
+	wanted := make(map[uuid.UUID]struct{}, len(ids))
+	for _, id := range ids {
+		wanted[id] = struct{}{}
+	}
+
+	specialties := make([]medical.Specialty, 0, len(ids))
+	for _, spec := range r.specialties {
+		if _, ok := wanted[spec.ID]; ok {
+			specialties = append(specialties, spec)
+		}
+	}
+	return specialties, nil
+}
+
+func (r *specialtyRepository) UpdateImage(ctx context.Context, id uuid.UUID, key string, ifUpdatedAt time.Time) (string, error) {
+	for i := range r.specialties {
+		if r.specialties[i].ID != id {
+			continue
+		}
+		if !ifUpdatedAt.IsZero() && !r.specialties[i].UpdatedAt.Equal(ifUpdatedAt) {
+			return "", specialty.ErrSpecialtyModified
+		}
+		var previous string
+		if r.specialties[i].ImagePath != nil {
+			previous = r.specialties[i].ImagePath.Path.String()
+		}
+		r.specialties[i].ImagePath = medical.NewSpecialtyImage(key)
+		r.specialties[i].UpdatedAt = time.Now()
+		return previous, nil
+	}
+	return "", specialty.ErrSpecialtyNotFound
+}
+
 func (r *specialtyRepository) Count(ctx context.Context) (int, error) {
 	return len(r.specialties), nil
 }
@@ -5,18 +5,34 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	"log"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/huandu/go-sqlbuilder"
 
+	"github.com/shayesteh1hs/DrAppointment/internal/database"
 	"github.com/shayesteh1hs/DrAppointment/internal/entity/medical"
+	"github.com/shayesteh1hs/DrAppointment/internal/logging"
 	"github.com/shayesteh1hs/DrAppointment/internal/pagination"
 	"github.com/shayesteh1hs/DrAppointment/internal/repository/medical/specialty"
 )
 
+// updateImageQuery swaps the image key and returns the previous one in a
+// single statement, locking the row so concurrent uploads cannot both see
+// the same previous key. When $3 is set the swap only happens if the row is
+// still at that updated_at, and the second column reports whether it did.
+const updateImageQuery = `
+WITH old AS (SELECT id, image_path, updated_at FROM specialties WHERE id = $2 FOR UPDATE),
+updated AS (
+	UPDATE specialties AS s SET image_path = $1, updated_at = now()
+	FROM old
+	WHERE s.id = old.id AND ($3::timestamptz IS NULL OR old.updated_at = $3)
+	RETURNING s.id
+)
+SELECT old.image_path, EXISTS (SELECT 1 FROM updated) FROM old`
+
 type specialtyRepository struct {
-	db *sql.DB
+	db database.Querier
 }
 
 func (r *specialtyRepository) ListOffset(ctx context.Context, params pagination.LimitOffsetParams) ([]medical.Specialty, error) {
@@ -34,7 +50,7 @@ func (r *specialtyRepository) ListOffset(ctx context.Context, params pagination.
 	defer func(rows *sql.Rows) {
 		err := rows.Close()
 		if err != nil {
-			log.Printf("failed to close rows: %v", err)
+			logging.FromContext(ctx).Warn("failed to close rows", "error", err)
 		}
 	}(rows)
 
@@ -92,6 +108,53 @@ func (r *specialtyRepository) GetByID(ctx context.Context, id uuid.UUID) (*medic
 	return &spec, nil
 }
 
+func (r *specialtyRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]medical.Specialty, error) {
+	if len(ids) == 0 {
+		return []medical.Specialty{}, nil
+	}
+
+	sb := sqlbuilder.PostgreSQL.NewSelectBuilder()
+	sb.Select("id", "name", "image_path", "created_at", "updated_at")
+	sb.From("specialties")
+	sb.Where("id = ANY(" + sb.Var(database.UUIDArray(ids)) + ")")
+
+	query, args := sb.Build()
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return []medical.Specialty{}, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			logging.FromContext(ctx).Warn("failed to close rows", "error", err)
+		}
+	}(rows)
+
+	specialties, err := r.scanSpecialties(rows)
+	if err != nil {
+		return []medical.Specialty{}, err
+	}
+
+	return specialties, nil
+}
+
+func (r *specialtyRepository) UpdateImage(ctx context.Context, id uuid.UUID, key string, ifUpdatedAt time.Time) (string, error) {
+	var previous sql.NullString
+	var updated bool
+	unmodifiedSince := sql.NullTime{Time: ifUpdatedAt, Valid: !ifUpdatedAt.IsZero()}
+	err := r.db.QueryRowContext(ctx, updateImageQuery, key, id, unmodifiedSince).Scan(&previous, &updated)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", specialty.ErrSpecialtyNotFound
+		}
+		return "", fmt.Errorf("failed to update specialty image: %w", err)
+	}
+	if !updated {
+		return "", specialty.ErrSpecialtyModified
+	}
+	return previous.String, nil
+}
+
 func (r *specialtyRepository) scanSpecialties(rows *sql.Rows) ([]medical.Specialty, error) {
 	var specialties []medical.Specialty
 	for rows.Next() {
@@ -122,6 +185,6 @@ func (r *specialtyRepository) scanSpecialties(rows *sql.Rows) ([]medical.Special
 	return specialties, nil
 }
 
-func NewSpecialtyRepository(db *sql.DB) specialty.Repository {
+func NewSpecialtyRepository(db database.Querier) specialty.Repository {
 	return &specialtyRepository{db: db}
 }
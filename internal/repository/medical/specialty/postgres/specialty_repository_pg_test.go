@@ -13,6 +13,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/shayesteh1hs/DrAppointment/internal/database"
 	"github.com/shayesteh1hs/DrAppointment/internal/pagination"
 	"github.com/shayesteh1hs/DrAppointment/internal/repository/medical/specialty"
 )
@@ -291,3 +292,133 @@ func TestSpecialtyPostgresRepository_ScanSpecialties_RowsError(t *testing.T) {
 
 	require.NoError(t, mock.ExpectationsWereMet())
 }
+
+func TestSpecialtyPostgresRepository_GetByIDs_Success(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	repo := NewSpecialtyRepository(db)
+	ctx := context.Background()
+
+	first := uuid.MustParse("223e4567-e89b-12d3-a456-426614174000")
+	second := uuid.MustParse("223e4567-e89b-12d3-a456-426614174001")
+	now := time.Now()
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, name, image_path, created_at, updated_at FROM specialties WHERE id = ANY($1)")).
+		WithArgs("{\"" + first.String() + "\",\"" + second.String() + "\"}").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "image_path", "created_at", "updated_at"}).
+			AddRow(second, "Neurology", nil, now, now).
+			AddRow(first, "Cardiology", "cardiology.jpg", now, now))
+
+	specialties, err := repo.GetByIDs(ctx, []uuid.UUID{first, second})
+	require.NoError(t, err)
+	require.Len(t, specialties, 2)
+	assert.Equal(t, "Neurology", specialties[0].Name)
+	assert.Nil(t, specialties[0].ImagePath)
+	assert.Equal(t, "Cardiology", specialties[1].Name)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSpecialtyPostgresRepository_GetByIDs_EmptySkipsQuery(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	repo := NewSpecialtyRepository(db)
+
+	specialties, err := repo.GetByIDs(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Empty(t, specialties)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSpecialtyPostgresRepository_UpdateImage(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	repo := NewSpecialtyRepository(db)
+	ctx := context.Background()
+
+	specialtyID := uuid.MustParse("123e4567-e89b-12d3-a456-426614174000")
+
+	mock.ExpectQuery(regexp.QuoteMeta(updateImageQuery)).
+		WithArgs("specialties/new.png", specialtyID, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"image_path", "exists"}).AddRow(nil, true))
+
+	previous, err := repo.UpdateImage(ctx, specialtyID, "specialties/new.png", time.Time{})
+	require.NoError(t, err)
+	assert.Empty(t, previous)
+
+	mock.ExpectQuery(regexp.QuoteMeta(updateImageQuery)).
+		WithArgs("specialties/new.png", specialtyID, nil).
+		WillReturnError(sql.ErrNoRows)
+
+	_, err = repo.UpdateImage(ctx, specialtyID, "specialties/new.png", time.Time{})
+	assert.ErrorIs(t, err, specialty.ErrSpecialtyNotFound)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSpecialtyPostgresRepository_UpdateImage_Conditional(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	repo := NewSpecialtyRepository(db)
+	ctx := context.Background()
+
+	specialtyID := uuid.MustParse("123e4567-e89b-12d3-a456-426614174000")
+	version := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// The row moved past the version, so nothing was written
+	mock.ExpectQuery(regexp.QuoteMeta(updateImageQuery)).
+		WithArgs("specialties/new.png", specialtyID, version).
+		WillReturnRows(sqlmock.NewRows([]string{"image_path", "exists"}).AddRow("specialties/other.png", false))
+
+	_, err := repo.UpdateImage(ctx, specialtyID, "specialties/new.png", version)
+	assert.ErrorIs(t, err, specialty.ErrSpecialtyModified)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSpecialtyPostgresRepository_UpdateImage_WithReplicas(t *testing.T) {
+	primary, mock := setupMockDB(t)
+	defer primary.Close()
+	replica, replicaMock := setupMockDB(t)
+	defer replica.Close()
+
+	repo := NewSpecialtyRepository(database.NewWithReplicas(primary, replica))
+	id := uuid.MustParse("123e4567-e89b-12d3-a456-426614174000")
+	version := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	getByIDQuery := "SELECT id, name, image_path, created_at, updated_at FROM specialties WHERE id = $1"
+	specialtyRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "name", "image_path", "created_at", "updated_at"}).
+			AddRow(id, "Cardiology", nil, version, version)
+	}
+
+	// The specialty is read for its version from the replica, but the
+	// conditional swap checks that version on the primary
+	replicaMock.ExpectQuery(regexp.QuoteMeta(getByIDQuery)).
+		WithArgs(id).
+		WillReturnRows(specialtyRows())
+	mock.ExpectQuery(regexp.QuoteMeta(updateImageQuery)).
+		WithArgs("specialties/new.png", id, version).
+		WillReturnRows(sqlmock.NewRows([]string{"image_path", "exists"}).AddRow(nil, false))
+	// The swap left the replica in rotation for the client's next read
+	replicaMock.ExpectQuery(regexp.QuoteMeta(getByIDQuery)).
+		WithArgs(id).
+		WillReturnRows(specialtyRows())
+
+	spec, err := repo.GetByID(context.Background(), id)
+	require.NoError(t, err)
+
+	_, err = repo.UpdateImage(context.Background(), id, "specialties/new.png", spec.UpdatedAt)
+	assert.ErrorIs(t, err, specialty.ErrSpecialtyModified)
+
+	_, err = repo.GetByID(context.Background(), id)
+	require.NoError(t, err)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.NoError(t, replicaMock.ExpectationsWereMet())
+}
@@ -3,6 +3,7 @@ package specialty
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/shayesteh1hs/DrAppointment/internal/entity/medical"
@@ -11,8 +12,20 @@ import (
 
 var ErrSpecialtyNotFound = errors.New("specialty not found")
 
+// ErrSpecialtyModified reports that a conditional write found the specialty
+// changed since the version the caller based it on.
+var ErrSpecialtyModified = errors.New("specialty was modified")
+
 type Repository interface {
 	ListOffset(ctx context.Context, params pagination.LimitOffsetParams) ([]medical.Specialty, error)
 	Count(ctx context.Context) (int, error)
 	GetByID(ctx context.Context, id uuid.UUID) (*medical.Specialty, error)
+	// GetByIDs returns the specialties matching ids in no particular order;
+	// unknown IDs are silently skipped.
+	GetByIDs(ctx context.Context, ids []uuid.UUID) ([]medical.Specialty, error)
+	// UpdateImage stores the storage key of a new image and returns the key
+	// it replaced, empty when the specialty had none. A non-zero
+	// ifUpdatedAt makes the write conditional: it fails with
+	// ErrSpecialtyModified unless the specialty is still at that version.
+	UpdateImage(ctx context.Context, id uuid.UUID, key string, ifUpdatedAt time.Time) (string, error)
 }
@@ -0,0 +1,72 @@
+package router
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/swaggest/swgui/v5emb"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/conditional"
+	"github.com/shayesteh1hs/DrAppointment/internal/logging"
+	"github.com/shayesteh1hs/DrAppointment/internal/openapi"
+)
+
+// docsUIPolicy relaxes the API's Content-Security-Policy for the bundled
+// Swagger UI, which uses inline scripts and styles but no external assets.
+const docsUIPolicy = "default-src 'self'; script-src 'self' 'unsafe-inline'; style-src 'self' 'unsafe-inline'; img-src 'self' data:; frame-ancestors 'none'"
+
+func newAPIDocument() *openapi.Document {
+	doc := openapi.New(openapi.Info{
+		Title:       "DrGo API",
+		Version:     "1.0.0",
+		Description: "Doctor appointment booking API.",
+	})
+	doc.AddTag("Doctors", "Doctor profiles and search")
+	doc.AddTag("Specialties", "Medical specialties")
+	doc.AddTag("Appointments", "Booking visits with doctors")
+	doc.AddTag("Webhooks", "Event notifications to partner systems, for administrators")
+	doc.AddTag("Media", "Uploaded images")
+	doc.AddTag("Health", "Probes and metrics for operators")
+	doc.AddTag("Docs", "This document and its viewer")
+	return doc
+}
+
+// registerDocs serves the OpenAPI document at /api/openapi.json and the
+// Swagger UI, with its assets embedded in the binary, at /api/docs/.
+func registerDocs(api *gin.RouterGroup, doc *openapi.Document) {
+	specPath := api.BasePath() + "/openapi.json"
+	uiPath := api.BasePath() + "/docs/"
+
+	doc.Add(api.BasePath(),
+		openapi.Operation{
+			Method:    http.MethodGet,
+			Path:      "/openapi.json",
+			ID:        "openapi",
+			Summary:   "OpenAPI document",
+			Tags:      []string{"Docs"},
+			Responses: map[int]any{http.StatusOK: openapi.Response{Description: "OpenAPI 3.1 document", Body: map[string]any{}}, http.StatusNotModified: nil},
+		},
+		openapi.Operation{
+			Method:    http.MethodGet,
+			Path:      "/docs/*any",
+			ID:        "docsUI",
+			Summary:   "Interactive API documentation",
+			Tags:      []string{"Docs"},
+			Responses: map[int]any{http.StatusOK: openapi.Response{Description: "Swagger UI page or asset", ContentType: "text/html", Body: ""}},
+		},
+	)
+
+	api.GET("/openapi.json", func(c *gin.Context) {
+		if err := conditional.JSON(c, http.StatusOK, doc, time.Time{}); err != nil {
+			logging.FromContext(c.Request.Context()).Error("failed to write OpenAPI document", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render OpenAPI document"})
+		}
+	})
+
+	ui := v5emb.New(doc.Info.Title, specPath, uiPath)
+	api.GET("/docs/*any", func(c *gin.Context) {
+		c.Header("Content-Security-Policy", docsUIPolicy)
+		ui.ServeHTTP(c.Writer, c.Request)
+	})
+}
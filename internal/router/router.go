@@ -1,24 +1,114 @@
 package router
 
 import (
-	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	webhookApi "github.com/shayesteh1hs/DrAppointment/internal/api/admin/webhook"
+	healthApi "github.com/shayesteh1hs/DrAppointment/internal/api/health"
+	mediaApi "github.com/shayesteh1hs/DrAppointment/internal/api/media"
+	appointmentApi "github.com/shayesteh1hs/DrAppointment/internal/api/medical/appointment"
 	"github.com/shayesteh1hs/DrAppointment/internal/api/medical/doctor"
 	"github.com/shayesteh1hs/DrAppointment/internal/api/medical/specialty"
+	"github.com/shayesteh1hs/DrAppointment/internal/config"
+	"github.com/shayesteh1hs/DrAppointment/internal/database"
+	"github.com/shayesteh1hs/DrAppointment/internal/events"
+	"github.com/shayesteh1hs/DrAppointment/internal/health"
+	"github.com/shayesteh1hs/DrAppointment/internal/idempotency"
+	"github.com/shayesteh1hs/DrAppointment/internal/media"
+	"github.com/shayesteh1hs/DrAppointment/internal/metrics"
+	"github.com/shayesteh1hs/DrAppointment/internal/openapi"
+	"github.com/shayesteh1hs/DrAppointment/internal/payment"
+	"github.com/shayesteh1hs/DrAppointment/internal/ratelimit"
+	appointmentService "github.com/shayesteh1hs/DrAppointment/internal/service/medical/appointment"
 	doctor2 "github.com/shayesteh1hs/DrAppointment/internal/service/medical/doctor"
 	medicalService "github.com/shayesteh1hs/DrAppointment/internal/service/medical/specialty"
+	"github.com/shayesteh1hs/DrAppointment/internal/storage"
+	"github.com/shayesteh1hs/DrAppointment/internal/tracing"
+	"github.com/shayesteh1hs/DrAppointment/internal/utils"
+	"github.com/shayesteh1hs/DrAppointment/internal/webhook"
 
 	"github.com/shayesteh1hs/DrAppointment/internal/middleware"
+	appointmentPostgres "github.com/shayesteh1hs/DrAppointment/internal/repository/medical/appointment/postgres"
+	doctorCache "github.com/shayesteh1hs/DrAppointment/internal/repository/medical/doctor/cache"
 	doctorPostgres "github.com/shayesteh1hs/DrAppointment/internal/repository/medical/doctor/postgres"
+	specialtyCache "github.com/shayesteh1hs/DrAppointment/internal/repository/medical/specialty/cache"
 	specialtyPostgres "github.com/shayesteh1hs/DrAppointment/internal/repository/medical/specialty/postgres"
 )
 
-func SetupRouter(db *sql.DB) *gin.Engine {
-	r := gin.Default()
-	r.Use(middleware.ErrorHandler())
+// legacyRoutesDeprecatedAt is when the unversioned /api/medical routes were
+// superseded by /api/v1/medical.
+var legacyRoutesDeprecatedAt = time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)
+
+// SetupRouter builds the HTTP API. Services record the domain events of
+// their changes in outbox; webhooks backs the admin webhook routes and
+// payments takes the deposits of bookings.
+func SetupRouter(db *database.DB, cfg *config.Config, healthRegistry *health.Registry, outbox events.Outbox, webhooks webhook.Service, payments payment.Provider) (*gin.Engine, error) {
+	r := gin.New()
+
+	// ClientIP (rate limits, access logs) only honors X-Forwarded-For from
+	// these proxies; gin trusts every peer by default.
+	if err := r.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("failed to set trusted proxies: %w", err)
+	}
+	forwardedHeaders, err := middleware.ForwardedHeaders(cfg.Server.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Use(
+		tracing.Middleware(),
+		forwardedHeaders,
+		middleware.RequestID(slog.Default()),
+		middleware.RequestLogger(),
+		metrics.Middleware(),
+		middleware.SecurityHeaders(cfg.Security.Options()),
+		middleware.CORS(cfg.CORS.Options()),
+		middleware.Recovery(),
+		middleware.ErrorHandler(),
+		middleware.DBSession(),
+	)
+
+	doc := newAPIDocument()
+
+	healthHandler := healthApi.NewHandler(healthRegistry)
+	healthHandler.RegisterRoutes(&r.RouterGroup)
+	doc.Add("/", healthHandler.Operations()...)
+
+	metrics.Register(metrics.NewDBStatsCollectors(db.Pools())...)
+	r.GET("/metrics", gin.WrapH(metrics.Handler()))
+	doc.Add("/", openapi.Operation{
+		Method:  http.MethodGet,
+		Path:    "/metrics",
+		ID:      "metrics",
+		Summary: "Prometheus metrics",
+		Tags:    []string{"Health"},
+		Responses: map[int]any{
+			http.StatusOK: openapi.Response{ContentType: "text/plain", Body: ""},
+		},
+	})
+
+	store, err := newStorage(cfg.Media)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := newURLSigner(cfg.Media)
+	if err != nil {
+		return nil, err
+	}
+	utils.SetURLSigner(signer)
+	mediaHandler := mediaApi.NewHandler(store, signer)
+	mediaHandler.RegisterRoutes(&r.RouterGroup)
+	doc.Add("/", mediaHandler.Operations()...)
 
 	api := r.Group("/api")
+	api.Use(idempotency.Middleware(newIdempotencyStore(db, cfg.Idempotency), idempotency.ByClient()))
 
 	api.GET("/", func(c *gin.Context) {
 		c.JSON(200, gin.H{
@@ -26,29 +116,156 @@ func SetupRouter(db *sql.DB) *gin.Engine {
 		})
 	})
 
-	api.GET("/health-check", func(c *gin.Context) {
-		c.JSON(200, gin.H{
-			"status": "ok",
-		})
-	})
+	// Kept for existing monitors, reports the same as /readyz
+	api.GET("/health-check", healthHandler.Readiness)
+	doc.Add(api.BasePath(),
+		openapi.Operation{
+			Method:    http.MethodGet,
+			Path:      "/",
+			ID:        "welcome",
+			Summary:   "Welcome message",
+			Responses: map[int]any{http.StatusOK: map[string]string{}},
+		},
+		openapi.Operation{
+			Method:      http.MethodGet,
+			Path:        "/health-check",
+			ID:          "healthCheck",
+			Summary:     "Readiness probe",
+			Description: "Deprecated alias of /readyz.",
+			Tags:        []string{"Health"},
+			Responses: map[int]any{
+				http.StatusOK:                 health.Report{},
+				http.StatusServiceUnavailable: health.Report{},
+			},
+		},
+	)
+
+	var medicalMiddleware []gin.HandlerFunc
+	if cfg.RateLimit.Enabled {
+		// One instance so every version shares the same budget
+		medicalMiddleware = append(medicalMiddleware, ratelimit.Middleware(newRateLimitStore(db, cfg.RateLimit), ratelimit.Policy{
+			Name:  "public",
+			Limit: cfg.RateLimit.Public.Limit(),
+			Key:   ratelimit.ByIP(),
+		}))
+	}
 
 	// Setup medical group routes
-	medicalGroup := api.Group("/medical")
-	setupMedicalRoutes(medicalGroup, db)
+	deposits := appointmentService.Payments{
+		Store:           payment.NewPostgresStore(db),
+		Provider:        payments,
+		Expiry:          cfg.Payment.Expiry,
+		CallbackBaseURL: cfg.PaymentCallbackURL(),
+	}
+	resources := newMedicalResources(db, cfg.Cache, store, int64(cfg.Media.MaxImageBytes), outbox, deposits, cfg.Payment.ReturnURL)
+	var mounts []mount
+	for _, version := range apiVersions {
+		mounts = append(mounts, mount{
+			group:    api.Group(versionPath(version)+"/medical", medicalMiddleware...),
+			version:  version,
+			idSuffix: fmt.Sprintf("V%d", version),
+		})
+	}
+
+	if cfg.API.LegacyRoutes {
+		// Validated when the configuration was loaded
+		sunset, _ := cfg.API.LegacySunsetDate()
+		legacyPath, successorPath := api.BasePath()+"/medical", mounts[0].group.BasePath()
+		deprecation := middleware.Deprecated(middleware.DeprecationOptions{
+			Since:  legacyRoutesDeprecatedAt,
+			Sunset: sunset,
+			Successor: func(c *gin.Context) string {
+				return strings.Replace(c.Request.URL.RequestURI(), legacyPath, successorPath, 1)
+			},
+		})
+		mounts = append(mounts, mount{
+			group:      api.Group("/medical", append(slices.Clone(medicalMiddleware), deprecation)...),
+			version:    1,
+			idSuffix:   "Legacy",
+			deprecated: true,
+		})
+	}
+
+	for _, m := range mounts {
+		registerResources(m, doc, resources)
+		if cfg.RateLimit.Enabled {
+			doc.AddResponse(m.group.BasePath(), http.StatusTooManyRequests, openapi.Response{
+				Body:    middleware.ErrorResponse{},
+				Headers: map[string]string{"Retry-After": "Seconds until the next request is allowed"},
+			})
+		}
+	}
+
+	if cfg.Admin.APIToken != "" {
+		admin := api.Group(versionPath(1)+"/admin", middleware.RequireToken(cfg.Admin.APIToken))
+		webhookHandler := webhookApi.NewHandler(webhooks)
+		webhookHandler.RegisterRoutes(admin)
+		doc.Add(admin.BasePath(), webhookHandler.Operations()...)
+		doc.AddResponse(admin.BasePath(), http.StatusUnauthorized, openapi.Response{
+			Body:    middleware.ErrorResponse{},
+			Headers: map[string]string{"WWW-Authenticate": "Bearer; send the admin API token in the Authorization header"},
+		})
+	} else {
+		slog.Info("admin.api_token is not set, the admin API is disabled")
+	}
 
-	return r
+	registerDocs(api, doc)
+
+	return r, nil
 }
 
-func setupMedicalRoutes(rg *gin.RouterGroup, db *sql.DB) {
+func newMedicalResources(db *database.DB, cacheConfig config.CacheConfig, store storage.Storage, maxImageBytes int64, outbox events.Outbox, payments appointmentService.Payments, paymentReturnURL string) []resource {
 	// Setup doctor routes
-	doctorRepo := doctorPostgres.NewDoctorRepository(db)
-	doctorService := doctor2.NewDoctorService(doctorRepo)
-	doctorHandler := doctor.NewHandler(doctorService)
-	doctorHandler.RegisterRoutes(rg)
+	doctorRepo := doctorCache.NewDoctorRepository(doctorPostgres.NewDoctorRepository(db), cacheConfig.Capacity, cacheConfig.TTL)
+	metrics.Register(metrics.NewCacheCollector("doctor", doctorRepo))
+	appointmentRepo := appointmentPostgres.NewAppointmentRepository(db)
+	doctorService := doctor2.NewDoctorService(doctorRepo, appointmentRepo, store, db, outbox)
 
 	// Setup specialty routes
-	specialtyRepo := specialtyPostgres.NewSpecialtyRepository(db)
-	specialtyService := medicalService.NewSpecialtyService(specialtyRepo)
-	specialtyHandler := specialty.NewSpecialtyHandler(specialtyService)
-	specialtyHandler.RegisterRoutes(rg)
+	specialtyRepo := specialtyCache.NewSpecialtyRepository(specialtyPostgres.NewSpecialtyRepository(db), cacheConfig.Capacity, cacheConfig.TTL)
+	metrics.Register(metrics.NewCacheCollector("specialty", specialtyRepo))
+	specialtyService := medicalService.NewSpecialtyService(specialtyRepo, store)
+
+	// Setup appointment routes
+	bookingService := appointmentService.NewAppointmentService(appointmentRepo, doctorRepo, db, outbox, payments)
+
+	return []resource{
+		{
+			handlers: map[int]routeHandler{1: doctor.NewHandler(doctorService, specialtyService, maxImageBytes)},
+		},
+		{
+			handlers: map[int]routeHandler{1: specialty.NewSpecialtyHandler(specialtyService, maxImageBytes)},
+		},
+		{
+			handlers: map[int]routeHandler{1: appointmentApi.NewHandler(bookingService, paymentReturnURL)},
+		},
+	}
+}
+
+func newStorage(cfg config.MediaConfig) (storage.Storage, error) {
+	if cfg.Storage == "s3" {
+		return storage.NewS3Storage(cfg.S3.Options(), &http.Client{Timeout: 30 * time.Second})
+	}
+	return storage.NewLocalStorage(cfg.LocalDir), nil
+}
+
+func newURLSigner(cfg config.MediaConfig) (*media.Signer, error) {
+	if cfg.SigningKey == "" {
+		return nil, errors.New("media.signing_key is required")
+	}
+	return media.NewSigner([]byte(cfg.SigningKey), cfg.SignedURLTTL), nil
+}
+
+func newRateLimitStore(db *database.DB, cfg config.RateLimitConfig) ratelimit.Store {
+	if cfg.Store == "postgres" {
+		return ratelimit.NewPostgresStore(db, cfg.IdleTTL())
+	}
+	return ratelimit.NewMemoryStore(cfg.IdleTTL())
+}
+
+func newIdempotencyStore(db *database.DB, cfg config.IdempotencyConfig) idempotency.Store {
+	if cfg.Store == "memory" {
+		return idempotency.NewMemoryStore(cfg.TTL, cfg.LockTimeout)
+	}
+	return idempotency.NewPostgresStore(db, cfg.TTL, cfg.LockTimeout)
 }
@@ -0,0 +1,367 @@
+package router
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"image"
+	imagepng "image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/config"
+	"github.com/shayesteh1hs/DrAppointment/internal/database"
+	"github.com/shayesteh1hs/DrAppointment/internal/events"
+	"github.com/shayesteh1hs/DrAppointment/internal/health"
+	"github.com/shayesteh1hs/DrAppointment/internal/jobs"
+	"github.com/shayesteh1hs/DrAppointment/internal/openapi"
+	"github.com/shayesteh1hs/DrAppointment/internal/payment"
+	"github.com/shayesteh1hs/DrAppointment/internal/webhook"
+)
+
+func setupTestRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	r, _ := setupTestRouterWithMock(t)
+	return r
+}
+
+const testAdminToken = "0123456789abcdef0123456789abcdef"
+
+func setupTestRouterWithMock(t *testing.T) (*gin.Engine, sqlmock.Sqlmock) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db := database.New(sqlDB)
+	t.Cleanup(func() { _ = db.Close() })
+
+	cfg := config.Default()
+	cfg.Media.LocalDir = t.TempDir()
+	cfg.Media.SigningKey = "0123456789abcdef0123456789abcdef"
+	cfg.Admin.APIToken = testAdminToken
+	webhookStore := webhook.NewMemoryStore()
+	dispatcher := webhook.NewDispatcher(webhookStore, jobs.NewMemoryStore(), http.DefaultClient, webhook.DefaultOptions())
+	r, err := SetupRouter(db, cfg, health.NewRegistry(time.Second), events.NewMemoryStore(), webhook.NewService(webhookStore, dispatcher), payment.NewFakeProvider())
+	require.NoError(t, err)
+	return r, mock
+}
+
+type openAPIDocument struct {
+	OpenAPI string                                `json:"openapi"`
+	Paths   map[string]map[string]json.RawMessage `json:"paths"`
+}
+
+// Every route must appear in /api/openapi.json; add an Operation next to
+// the RegisterRoutes call when adding an endpoint.
+func TestOpenAPI_DocumentsEveryRoute(t *testing.T) {
+	r := setupTestRouter(t)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var doc openAPIDocument
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+	assert.Equal(t, "3.1.0", doc.OpenAPI)
+
+	for _, route := range r.Routes() {
+		_, ok := doc.Paths[openapi.ToOpenAPIPath(route.Path)][strings.ToLower(route.Method)]
+		assert.True(t, ok, "%s %s is not documented", route.Method, route.Path)
+	}
+}
+
+func TestOpenAPI_ServesUI(t *testing.T) {
+	r := setupTestRouter(t)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/docs/", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "/api/openapi.json")
+	assert.Contains(t, w.Header().Get("Content-Security-Policy"), "'unsafe-inline'")
+}
+
+func TestVersionedRoutes(t *testing.T) {
+	r := setupTestRouter(t)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/medical/doctors/not-a-uuid", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Empty(t, w.Header().Get("Deprecation"))
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/medical/doctors/not-a-uuid?x=1", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "@1792281600", w.Header().Get("Deprecation"))
+	assert.Equal(t, `</api/v1/medical/doctors/not-a-uuid?x=1>; rel="successor-version"`, w.Header().Get("Link"))
+}
+
+func TestAdminRoutes_RequireToken(t *testing.T) {
+	r := setupTestRouter(t)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/admin/webhooks", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/webhooks", nil)
+	req.Header.Set("Authorization", "Bearer "+testAdminToken)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"items":[]}`, w.Body.String())
+}
+
+const doctorsByIDsQuery = "SELECT id, name, specialty_id, phone_number, avatar_key, description, created_at, updated_at FROM doctors WHERE id = ANY($1)"
+
+type batchResult struct {
+	Items   []map[string]any `json:"items"`
+	Missing []string         `json:"missing"`
+}
+
+func TestDoctorsBatchGet(t *testing.T) {
+	first := uuid.MustParse("123e4567-e89b-12d3-a456-426614174000")
+	second := uuid.MustParse("123e4567-e89b-12d3-a456-426614174001")
+	unknown := uuid.MustParse("123e4567-e89b-12d3-a456-426614174002")
+
+	rows := func() *sqlmock.Rows {
+		now := time.Now()
+		return sqlmock.NewRows([]string{"id", "name", "specialty_id", "phone_number", "avatar_key", "description", "created_at", "updated_at"}).
+			AddRow(first, "Dr. John Smith", uuid.New(), "+1234567890", "", "", now, now).
+			AddRow(second, "Dr. Jane Doe", uuid.New(), "+1234567891", "", "", now, now)
+	}
+
+	tests := []struct {
+		name    string
+		request func() *http.Request
+	}{
+		{
+			name: "query string",
+			request: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/api/v1/medical/doctors?fields=name&ids="+
+					strings.Join([]string{second.String(), unknown.String(), first.String()}, ","), nil)
+			},
+		},
+		{
+			name: "custom method",
+			request: func() *http.Request {
+				body := `{"ids":["` + second.String() + `","` + unknown.String() + `","` + first.String() + `"]}`
+				req := httptest.NewRequest(http.MethodPost, "/api/v1/medical/doctors:batchGet?fields=name", strings.NewReader(body))
+				req.Header.Set("Content-Type", "application/json")
+				return req
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, mock := setupTestRouterWithMock(t)
+			mock.ExpectQuery(regexp.QuoteMeta(doctorsByIDsQuery)).WillReturnRows(rows())
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, tt.request())
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+			var result batchResult
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+			require.Len(t, result.Items, 2)
+			assert.Equal(t, map[string]any{"id": second.String(), "name": "Dr. Jane Doe"}, result.Items[0])
+			assert.Equal(t, map[string]any{"id": first.String(), "name": "Dr. John Smith"}, result.Items[1])
+			assert.Equal(t, []string{unknown.String()}, result.Missing)
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestDoctorsBatchGet_RejectsInvalidInput(t *testing.T) {
+	r := setupTestRouter(t)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/medical/doctors?ids=not-a-uuid", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/medical/doctors:batchGet", strings.NewReader(`{"ids":[]}`))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/medical/doctors:unknown", strings.NewReader(`{}`)))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestSpecialtyImageUpload(t *testing.T) {
+	r, mock := setupTestRouterWithMock(t)
+	id := uuid.MustParse("223e4567-e89b-12d3-a456-426614174000")
+
+	var png bytes.Buffer
+	require.NoError(t, imagepng.Encode(&png, image.NewRGBA(image.Rect(0, 0, 4, 4))))
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("image", "cardio.bin")
+	require.NoError(t, err)
+	_, err = part.Write(png.Bytes())
+	require.NoError(t, err)
+	require.NoError(t, form.Close())
+
+	key := &capturedArg{}
+	mock.ExpectQuery(regexp.QuoteMeta("UPDATE specialties")).
+		WithArgs(key, id, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"image_path", "exists"}).AddRow(nil, true))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, name, image_path, created_at, updated_at FROM specialties WHERE id = $1")).
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "image_path", "created_at", "updated_at"}).
+			AddRow(id, "Cardiology", nil, time.Now(), time.Now()))
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/medical/specialties/"+id.String()+"/image", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	// The extension follows the sniffed type, not the uploaded file name
+	assert.Regexp(t, `^specialties/`+id.String()+`/[0-9a-f-]{36}\.png$`, key.value)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/media/"+key.value, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+	assert.Equal(t, png.Bytes(), w.Body.Bytes())
+
+	// Resized variants are generated with the upload
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/media/"+key.value+".64.webp", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/webp", w.Header().Get("Content-Type"))
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/media/specialties/missing.png", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestSpecialtyImageUpload_ChecksPreconditions(t *testing.T) {
+	r, mock := setupTestRouterWithMock(t)
+	id := uuid.MustParse("223e4567-e89b-12d3-a456-426614174000")
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	_, err := form.CreateFormFile("image", "cardio.png")
+	require.NoError(t, err)
+	require.NoError(t, form.Close())
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, name, image_path, created_at, updated_at FROM specialties WHERE id = $1")).
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "image_path", "created_at", "updated_at"}).
+			AddRow(id, "Cardiology", nil, time.Now(), time.Now()))
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/medical/specialties/"+id.String()+"/image", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("If-Match", `"stale"`)
+	r.ServeHTTP(w, req)
+
+	// The image is neither read nor stored
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code, w.Body.String())
+	assert.NotEmpty(t, w.Header().Get("ETag"))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSpecialtyImageUpload_RejectsWritesRacingThePrecondition(t *testing.T) {
+	r, mock := setupTestRouterWithMock(t)
+	id := uuid.MustParse("223e4567-e89b-12d3-a456-426614174000")
+	updatedAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	var png bytes.Buffer
+	require.NoError(t, imagepng.Encode(&png, image.NewRGBA(image.Rect(0, 0, 4, 4))))
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("image", "cardio.png")
+	require.NoError(t, err)
+	_, err = part.Write(png.Bytes())
+	require.NoError(t, err)
+	require.NoError(t, form.Close())
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, name, image_path, created_at, updated_at FROM specialties WHERE id = $1")).
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "image_path", "created_at", "updated_at"}).
+			AddRow(id, "Cardiology", nil, updatedAt, updatedAt))
+	// Another upload lands between the check and the write
+	mock.ExpectQuery(regexp.QuoteMeta("UPDATE specialties")).
+		WithArgs(sqlmock.AnyArg(), id, updatedAt).
+		WillReturnRows(sqlmock.NewRows([]string{"image_path", "exists"}).AddRow("specialties/other.png", false))
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/medical/specialties/"+id.String()+"/image", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("If-Unmodified-Since", updatedAt.Format(http.TimeFormat))
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code, w.Body.String())
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+// capturedArg matches any string argument and remembers it.
+type capturedArg struct {
+	value string
+}
+
+func (a *capturedArg) Match(v driver.Value) bool {
+	a.value, _ = v.(string)
+	return true
+}
+
+func TestSpecialtyImageUpload_RejectsNonImages(t *testing.T) {
+	r := setupTestRouter(t)
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("image", "cardio.png")
+	require.NoError(t, err)
+	_, err = part.Write([]byte("%PDF-1.7 not an image"))
+	require.NoError(t, err)
+	require.NoError(t, form.Close())
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/medical/specialties/"+uuid.NewString()+"/image", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+}
+
+func TestSpecialtyImageSrcset(t *testing.T) {
+	r, mock := setupTestRouterWithMock(t)
+	id := uuid.MustParse("223e4567-e89b-12d3-a456-426614174000")
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, name, image_path, created_at, updated_at FROM specialties WHERE id = $1")).
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "image_path", "created_at", "updated_at"}).
+			AddRow(id, "Cardiology", "cardio.png", time.Now(), time.Now()))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/medical/specialties/"+id.String(), nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp struct {
+		ImageURL    string                       `json:"image_url"`
+		ImageSrcset map[string]map[string]string `json:"image_srcset"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, resp.ImageURL+".128.webp", resp.ImageSrcset["webp"]["128"])
+	assert.Equal(t, resp.ImageURL+".512.jpg", resp.ImageSrcset["jpeg"]["512"])
+	assert.Len(t, resp.ImageSrcset["jpeg"], 3)
+}
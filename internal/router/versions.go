@@ -0,0 +1,78 @@
+package router
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/middleware"
+	"github.com/shayesteh1hs/DrAppointment/internal/openapi"
+)
+
+// apiVersions are mounted at /api/v<N>. A new version is added here together
+// with the handlers of the resources that change in it.
+var apiVersions = []int{1}
+
+// routeHandler is implemented by the handler of every API resource.
+type routeHandler interface {
+	RegisterRoutes(router *gin.RouterGroup)
+	Operations() []openapi.Operation
+}
+
+// resource holds a handler per API version the resource changed in, all
+// built on the same service. A version without its own handler serves the
+// closest earlier one, so e.g. a v2 doctors handler can be introduced while
+// v2 serves specialties unchanged.
+type resource struct {
+	handlers map[int]routeHandler
+	// deprecated marks versions of the resource superseded by a later one
+	deprecated map[int]middleware.DeprecationOptions
+}
+
+func (r resource) handler(version int) (routeHandler, bool) {
+	for v := version; v > 0; v-- {
+		if h, ok := r.handlers[v]; ok {
+			return h, true
+		}
+	}
+	return nil, false
+}
+
+// mount is a route group serving one API version of a set of resources.
+type mount struct {
+	group   *gin.RouterGroup
+	version int
+	// idSuffix keeps operation IDs unique across mounts, e.g. "V1"
+	idSuffix string
+	// deprecated is set when the whole group is deprecated
+	deprecated bool
+}
+
+// registerResources registers and documents the handler each resource has
+// for the mount's version.
+func registerResources(m mount, doc *openapi.Document, resources []resource) {
+	for _, r := range resources {
+		h, ok := r.handler(m.version)
+		if !ok {
+			continue
+		}
+
+		group, deprecated := m.group, m.deprecated
+		if opts, ok := r.deprecated[m.version]; ok {
+			group = m.group.Group("", middleware.Deprecated(opts))
+			deprecated = true
+		}
+		h.RegisterRoutes(group)
+
+		ops := h.Operations()
+		for i := range ops {
+			ops[i].ID += m.idSuffix
+			ops[i].Deprecated = ops[i].Deprecated || deprecated
+		}
+		doc.Add(group.BasePath(), ops...)
+	}
+}
+
+func versionPath(version int) string {
+	return fmt.Sprintf("/v%d", version)
+}
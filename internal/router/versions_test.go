@@ -0,0 +1,69 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/middleware"
+	"github.com/shayesteh1hs/DrAppointment/internal/openapi"
+)
+
+type fakeHandler struct {
+	body string
+}
+
+func (h fakeHandler) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/things", func(c *gin.Context) {
+		c.String(http.StatusOK, h.body)
+	})
+}
+
+func (h fakeHandler) Operations() []openapi.Operation {
+	return []openapi.Operation{{Method: http.MethodGet, Path: "/things", ID: "listThings", Responses: map[int]any{http.StatusOK: ""}}}
+}
+
+func TestRegisterResources_FallsBackToEarlierVersion(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	doc := openapi.New(openapi.Info{Title: "test", Version: "1"})
+
+	resources := []resource{
+		{
+			handlers:   map[int]routeHandler{1: fakeHandler{"things v1"}, 2: fakeHandler{"things v2"}},
+			deprecated: map[int]middleware.DeprecationOptions{1: {Since: time.Unix(0, 0)}},
+		},
+	}
+	others := []resource{{handlers: map[int]routeHandler{1: fakeHandler{"others v1"}}}}
+
+	for _, version := range []int{1, 2} {
+		registerResources(mount{group: r.Group(versionPath(version) + "/a"), version: version, idSuffix: versionPath(version)}, doc, resources)
+		registerResources(mount{group: r.Group(versionPath(version) + "/b"), version: version, idSuffix: versionPath(version)}, doc, others)
+	}
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	w := get("/v1/a/things")
+	assert.Equal(t, "things v1", w.Body.String())
+	assert.Equal(t, "@0", w.Header().Get("Deprecation"))
+
+	w = get("/v2/a/things")
+	assert.Equal(t, "things v2", w.Body.String())
+	assert.Empty(t, w.Header().Get("Deprecation"))
+
+	assert.Equal(t, "others v1", get("/v2/b/things").Body.String())
+
+	require.Contains(t, doc.Paths, "/v1/a/things")
+	assert.True(t, doc.Paths["/v1/a/things"]["get"].Deprecated)
+	assert.False(t, doc.Paths["/v2/a/things"]["get"].Deprecated)
+	assert.Equal(t, "listThings/v2", doc.Paths["/v2/b/things"]["get"].OperationID)
+}
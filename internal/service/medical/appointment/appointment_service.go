@@ -0,0 +1,139 @@
+package appointment
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/database"
+	"github.com/shayesteh1hs/DrAppointment/internal/entity/medical"
+	"github.com/shayesteh1hs/DrAppointment/internal/events"
+	"github.com/shayesteh1hs/DrAppointment/internal/metrics"
+	"github.com/shayesteh1hs/DrAppointment/internal/payment"
+	"github.com/shayesteh1hs/DrAppointment/internal/repository/medical/appointment"
+	"github.com/shayesteh1hs/DrAppointment/internal/repository/medical/doctor"
+	"github.com/shayesteh1hs/DrAppointment/internal/tracing"
+)
+
+var ErrStartsInPast = errors.New("appointment must start in the future")
+
+type Service interface {
+	// Book creates a booked appointment and records an AppointmentBooked
+	// event in the same transaction. When the doctor takes a deposit the
+	// appointment is pending payment instead, and the payment the payer
+	// must complete is returned with it.
+	Book(ctx context.Context, appt medical.Appointment) (*medical.Appointment, *payment.Payment, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*medical.Appointment, error)
+	// Cancel cancels a booked appointment and records an
+	// AppointmentCancelled event in the same transaction. A paid deposit
+	// is refunded under the doctor's cancellation policy; the refund
+	// returned, if any, is sent to the provider in the background.
+	Cancel(ctx context.Context, id uuid.UUID) (*medical.Appointment, *payment.Refund, error)
+	// CompletePayment handles the provider's callback for a payment,
+	// booking its appointment once the provider verifies it.
+	CompletePayment(ctx context.Context, paymentID uuid.UUID, callback url.Values) (*payment.Payment, error)
+	// Ledger returns the money paid and refunded for an appointment,
+	// oldest first.
+	Ledger(ctx context.Context, id uuid.UUID) ([]payment.LedgerEntry, error)
+}
+
+type appointmentService struct {
+	repo     appointment.Repository
+	doctors  doctor.Repository
+	tx       database.Transactor
+	outbox   events.Outbox
+	payments Payments
+	now      func() time.Time
+}
+
+func NewAppointmentService(repo appointment.Repository, doctors doctor.Repository, tx database.Transactor, outbox events.Outbox, payments Payments) Service {
+	return &appointmentService{
+		repo:     repo,
+		doctors:  doctors,
+		tx:       tx,
+		outbox:   outbox,
+		payments: payments,
+		now:      time.Now,
+	}
+}
+
+func (s *appointmentService) Book(ctx context.Context, appt medical.Appointment) (booked *medical.Appointment, pay *payment.Payment, err error) {
+	ctx, span := tracing.Start(ctx, "AppointmentService.Book")
+	defer func() { tracing.End(span, err) }()
+
+	if !appt.StartsAt.After(s.now()) {
+		return nil, nil, ErrStartsInPast
+	}
+	if _, err := s.doctors.GetByID(ctx, appt.DoctorID); err != nil {
+		return nil, nil, err
+	}
+	deposit, err := s.payments.Store.Deposit(ctx, appt.DoctorID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if deposit.Amount > 0 {
+		return s.bookWithDeposit(ctx, appt, deposit)
+	}
+
+	appt.Status = medical.AppointmentBooked
+	err = s.tx.InTx(ctx, func(ctx context.Context) error {
+		if err := s.repo.Create(ctx, &appt); err != nil {
+			return err
+		}
+		return s.record(ctx, events.AppointmentBooked, appt)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	metrics.AppointmentsBooked.Inc()
+	return &appt, nil, nil
+}
+
+func (s *appointmentService) GetByID(ctx context.Context, id uuid.UUID) (appt *medical.Appointment, err error) {
+	ctx, span := tracing.Start(ctx, "AppointmentService.GetByID")
+	defer func() { tracing.End(span, err) }()
+
+	return s.repo.GetByID(ctx, id)
+}
+
+// Cancel cancels a booked appointment. Its pending reminders are dropped
+// when they come due.
+func (s *appointmentService) Cancel(ctx context.Context, id uuid.UUID) (appt *medical.Appointment, refund *payment.Refund, err error) {
+	ctx, span := tracing.Start(ctx, "AppointmentService.Cancel")
+	defer func() { tracing.End(span, err) }()
+
+	err = s.tx.InTx(ctx, func(ctx context.Context) error {
+		cancelled, err := s.repo.Cancel(ctx, id)
+		if err != nil {
+			return err
+		}
+		appt = cancelled
+		if refund, err = s.refundDeposit(ctx, *appt); err != nil {
+			return err
+		}
+		return s.record(ctx, events.AppointmentCancelled, *appt)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	metrics.AppointmentsCancelled.Inc()
+	return appt, refund, nil
+}
+
+// record adds an event about appt to the outbox.
+func (s *appointmentService) record(ctx context.Context, eventType string, appt medical.Appointment) error {
+	event, err := events.New(eventType, appt.ID, events.Appointment{
+		AppointmentID: appt.ID,
+		DoctorID:      appt.DoctorID,
+		StartsAt:      appt.StartsAt,
+		EndsAt:        appt.EndsAt,
+		Status:        string(appt.Status),
+	})
+	if err != nil {
+		return err
+	}
+	return s.outbox.Add(ctx, event)
+}
@@ -0,0 +1,301 @@
+package appointment
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/entity/medical"
+	"github.com/shayesteh1hs/DrAppointment/internal/events"
+	"github.com/shayesteh1hs/DrAppointment/internal/jobs"
+	"github.com/shayesteh1hs/DrAppointment/internal/metrics"
+	"github.com/shayesteh1hs/DrAppointment/internal/notification"
+	"github.com/shayesteh1hs/DrAppointment/internal/payment"
+	"github.com/shayesteh1hs/DrAppointment/internal/repository/medical/appointment"
+	"github.com/shayesteh1hs/DrAppointment/internal/repository/medical/doctor"
+)
+
+type fakeAppointmentRepository struct {
+	appointments map[uuid.UUID]medical.Appointment
+}
+
+func (r *fakeAppointmentRepository) Create(ctx context.Context, appt *medical.Appointment) error {
+	appt.ID = uuid.New()
+	r.appointments[appt.ID] = *appt
+	return nil
+}
+
+func (r *fakeAppointmentRepository) GetByID(ctx context.Context, id uuid.UUID) (*medical.Appointment, error) {
+	appt, ok := r.appointments[id]
+	if !ok {
+		return nil, appointment.ErrAppointmentNotFound
+	}
+	return &appt, nil
+}
+
+func (r *fakeAppointmentRepository) Cancel(ctx context.Context, id uuid.UUID) (*medical.Appointment, error) {
+	appt, ok := r.appointments[id]
+	if !ok {
+		return nil, appointment.ErrAppointmentNotFound
+	}
+	if appt.Status != medical.AppointmentBooked {
+		return nil, appointment.ErrNotCancellable
+	}
+	appt.Status = medical.AppointmentCancelled
+	r.appointments[id] = appt
+	return &appt, nil
+}
+
+func (r *fakeAppointmentRepository) Confirm(ctx context.Context, id uuid.UUID) (*medical.Appointment, error) {
+	return r.changeStatus(id, medical.AppointmentBooked)
+}
+
+func (r *fakeAppointmentRepository) Release(ctx context.Context, id uuid.UUID) (*medical.Appointment, error) {
+	return r.changeStatus(id, medical.AppointmentCancelled)
+}
+
+func (r *fakeAppointmentRepository) ListActive(ctx context.Context, doctorIDs []uuid.UUID, from, to time.Time) ([]medical.Appointment, error) {
+	var appts []medical.Appointment
+	for _, appt := range r.appointments {
+		if slices.Contains(doctorIDs, appt.DoctorID) && appt.Status != medical.AppointmentCancelled &&
+			appt.StartsAt.Before(to) && appt.EndsAt.After(from) {
+			appts = append(appts, appt)
+		}
+	}
+	return appts, nil
+}
+
+func (r *fakeAppointmentRepository) changeStatus(id uuid.UUID, to medical.AppointmentStatus) (*medical.Appointment, error) {
+	appt, ok := r.appointments[id]
+	if !ok {
+		return nil, appointment.ErrAppointmentNotFound
+	}
+	if appt.Status != medical.AppointmentPendingPayment {
+		return nil, appointment.ErrNotPendingPayment
+	}
+	appt.Status = to
+	r.appointments[id] = appt
+	return &appt, nil
+}
+
+type fakeDoctorRepository struct {
+	doctor.Repository
+	doctors map[uuid.UUID]medical.Doctor
+}
+
+func (r *fakeDoctorRepository) GetByID(ctx context.Context, id uuid.UUID) (*medical.Doctor, error) {
+	doc, ok := r.doctors[id]
+	if !ok {
+		return nil, doctor.ErrDoctorNotFound
+	}
+	return &doc, nil
+}
+
+type noTx struct{}
+
+func (noTx) InTx(ctx context.Context, fn func(ctx context.Context) error) error { return fn(ctx) }
+
+type fakeNotifier struct {
+	sent []string
+	to   []notification.Recipient
+	data []any
+}
+
+func (n *fakeNotifier) Notify(ctx context.Context, to notification.Recipient, template string, data any) ([]notification.Delivery, error) {
+	n.sent = append(n.sent, template)
+	n.to = append(n.to, to)
+	n.data = append(n.data, data)
+	return nil, nil
+}
+
+type fixture struct {
+	service  *appointmentService
+	repo     *fakeAppointmentRepository
+	doctors  *fakeDoctorRepository
+	outbox   *events.MemoryStore
+	queue    *jobs.MemoryStore
+	payments *payment.MemoryStore
+	doctorID uuid.UUID
+	now      time.Time
+}
+
+func setup() fixture {
+	doctorID := uuid.New()
+	f := fixture{
+		repo:     &fakeAppointmentRepository{appointments: map[uuid.UUID]medical.Appointment{}},
+		doctors:  &fakeDoctorRepository{doctors: map[uuid.UUID]medical.Doctor{doctorID: {ID: doctorID, Name: "Dr. Ahmadi"}}},
+		outbox:   events.NewMemoryStore(),
+		queue:    jobs.NewMemoryStore(),
+		payments: payment.NewMemoryStore(),
+		doctorID: doctorID,
+		now:      time.Date(2026, time.October, 18, 9, 0, 0, 0, time.UTC),
+	}
+	f.service = NewAppointmentService(f.repo, f.doctors, noTx{}, f.outbox, Payments{
+		Store:           f.payments,
+		Provider:        payment.NewFakeProvider(),
+		Expiry:          15 * time.Minute,
+		CallbackBaseURL: "https://api.example.com/api/v1/medical/payments",
+	}).(*appointmentService)
+	f.service.now = func() time.Time { return f.now }
+	return f
+}
+
+func (f fixture) newAppointment(startsAt time.Time) medical.Appointment {
+	return medical.Appointment{
+		DoctorID:     f.doctorID,
+		PatientName:  "Sara",
+		PatientPhone: "+989121234567",
+		Locale:       "en",
+		StartsAt:     startsAt,
+		EndsAt:       startsAt.Add(30 * time.Minute),
+	}
+}
+
+func jobByKey(queue *jobs.MemoryStore, key string) (jobs.Job, bool) {
+	for _, job := range queue.Jobs() {
+		if job.UniqueKey == key {
+			return job, true
+		}
+	}
+	return jobs.Job{}, false
+}
+
+// bookedEvent is the AppointmentBooked event of an appointment starting at
+// startsAt.
+func bookedEvent(t *testing.T, startsAt time.Time) events.Event {
+	t.Helper()
+	id := uuid.New()
+	event, err := events.New(events.AppointmentBooked, id, events.Appointment{AppointmentID: id, StartsAt: startsAt})
+	require.NoError(t, err)
+	return event
+}
+
+func TestAppointmentService_Book_RecordsEvent(t *testing.T) {
+	f := setup()
+	startsAt := f.now.Add(3 * 24 * time.Hour)
+	booked := testutil.ToFloat64(metrics.AppointmentsBooked)
+	cancelled := testutil.ToFloat64(metrics.AppointmentsCancelled)
+
+	appt, pay, err := f.service.Book(context.Background(), f.newAppointment(startsAt))
+	require.NoError(t, err)
+	assert.Nil(t, pay, "the doctor takes no deposit")
+	assert.Equal(t, booked+1, testutil.ToFloat64(metrics.AppointmentsBooked))
+	assert.Equal(t, medical.AppointmentBooked, appt.Status)
+	assert.NotEqual(t, uuid.Nil, appt.ID)
+
+	recorded := f.outbox.Events()
+	require.Len(t, recorded, 1)
+	assert.Equal(t, events.AppointmentBooked, recorded[0].Type)
+	assert.Equal(t, appt.ID, recorded[0].AggregateID)
+
+	var data events.Appointment
+	require.NoError(t, recorded[0].Decode(&data))
+	assert.Equal(t, events.Appointment{
+		AppointmentID: appt.ID,
+		DoctorID:      f.doctorID,
+		StartsAt:      startsAt,
+		EndsAt:        startsAt.Add(30 * time.Minute),
+		Status:        "booked",
+	}, data)
+
+	_, refund, err := f.service.Cancel(context.Background(), appt.ID)
+	require.NoError(t, err)
+	assert.Nil(t, refund)
+	recorded = f.outbox.Events()
+	require.Len(t, recorded, 2)
+	assert.Equal(t, events.AppointmentCancelled, recorded[1].Type)
+	assert.Equal(t, cancelled+1, testutil.ToFloat64(metrics.AppointmentsCancelled))
+}
+
+func TestQueueNotifications(t *testing.T) {
+	queue := jobs.NewMemoryStore()
+	handler := QueueNotifications(queue)
+	startsAt := time.Now().Add(3 * 24 * time.Hour)
+	event := bookedEvent(t, startsAt)
+	prefix := NotificationJob + ":" + event.AggregateID.String()
+
+	require.NoError(t, handler(context.Background(), event))
+
+	confirmation, ok := jobByKey(queue, prefix+":confirmation")
+	require.True(t, ok)
+	assert.False(t, confirmation.RunAt.After(time.Now()), "the confirmation is due right away")
+
+	day, ok := jobByKey(queue, prefix+":24h")
+	require.True(t, ok)
+	assert.WithinDuration(t, startsAt.Add(-24*time.Hour), day.RunAt, 0)
+
+	twoHours, ok := jobByKey(queue, prefix+":2h")
+	require.True(t, ok)
+	assert.WithinDuration(t, startsAt.Add(-2*time.Hour), twoHours.RunAt, 0)
+
+	// Redelivering the event queues nothing new
+	require.NoError(t, handler(context.Background(), event))
+	assert.Len(t, queue.Jobs(), 3)
+}
+
+func TestQueueNotifications_SkipsRemindersThatWouldBeLate(t *testing.T) {
+	queue := jobs.NewMemoryStore()
+	event := bookedEvent(t, time.Now().Add(5*time.Hour))
+	prefix := NotificationJob + ":" + event.AggregateID.String()
+
+	require.NoError(t, QueueNotifications(queue)(context.Background(), event))
+
+	_, ok := jobByKey(queue, prefix+":24h")
+	assert.False(t, ok)
+	_, ok = jobByKey(queue, prefix+":2h")
+	assert.True(t, ok)
+}
+
+func TestAppointmentService_Book_Validates(t *testing.T) {
+	f := setup()
+
+	_, _, err := f.service.Book(context.Background(), f.newAppointment(f.now.Add(-time.Minute)))
+	assert.ErrorIs(t, err, ErrStartsInPast)
+
+	appt := f.newAppointment(f.now.Add(time.Hour))
+	appt.DoctorID = uuid.New()
+	_, _, err = f.service.Book(context.Background(), appt)
+	assert.ErrorIs(t, err, doctor.ErrDoctorNotFound)
+}
+
+func TestNotificationJobHandler(t *testing.T) {
+	f := setup()
+	notifier := &fakeNotifier{}
+	handler := NotificationJobHandler(f.repo, f.doctors, notifier)
+
+	startsAt := time.Now().Add(48 * time.Hour)
+	appt, _, err := f.service.Book(context.Background(), f.newAppointment(startsAt))
+	require.NoError(t, err)
+
+	job, err := newNotificationJob(appt.ID, ReminderTemplate, "24h")
+	require.NoError(t, err)
+	require.NoError(t, handler(context.Background(), job))
+
+	require.Equal(t, []string{ReminderTemplate}, notifier.sent)
+	assert.Equal(t, "+989121234567", notifier.to[0].Phone)
+	assert.Equal(t, "en", notifier.to[0].Locale)
+	assert.Equal(t, notificationData{PatientName: "Sara", DoctorName: "Dr. Ahmadi", StartsAt: startsAt}, notifier.data[0])
+
+	// Reminders of cancelled appointments are dropped
+	_, _, err = f.service.Cancel(context.Background(), appt.ID)
+	require.NoError(t, err)
+	require.NoError(t, handler(context.Background(), job))
+	assert.Len(t, notifier.sent, 1)
+}
+
+func TestNotificationJobHandler_UnknownAppointmentIsPermanent(t *testing.T) {
+	f := setup()
+	handler := NotificationJobHandler(f.repo, f.doctors, &fakeNotifier{})
+
+	job, err := newNotificationJob(uuid.New(), ReminderTemplate, "2h")
+	require.NoError(t, err)
+
+	err = handler(context.Background(), job)
+	assert.True(t, jobs.IsPermanent(err))
+}
@@ -0,0 +1,144 @@
+package appointment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/database"
+	"github.com/shayesteh1hs/DrAppointment/internal/entity/medical"
+	"github.com/shayesteh1hs/DrAppointment/internal/events"
+	"github.com/shayesteh1hs/DrAppointment/internal/jobs"
+	"github.com/shayesteh1hs/DrAppointment/internal/logging"
+	"github.com/shayesteh1hs/DrAppointment/internal/notification"
+	"github.com/shayesteh1hs/DrAppointment/internal/repository/medical/appointment"
+	"github.com/shayesteh1hs/DrAppointment/internal/repository/medical/doctor"
+)
+
+const (
+	// NotificationJob is the job kind sending a notification about one
+	// appointment.
+	NotificationJob = "appointment.notification"
+	// NotificationSubscriber is the name QueueNotifications subscribes to
+	// AppointmentBooked under.
+	NotificationSubscriber = "appointment.notifications"
+)
+
+const (
+	ConfirmationTemplate = "appointment_confirmed"
+	ReminderTemplate     = "appointment_reminder"
+)
+
+// ReminderLeads are how long before the visit reminders are sent.
+var ReminderLeads = []time.Duration{24 * time.Hour, 2 * time.Hour}
+
+// Notifier sends templated notifications; *notification.Notifier
+// implements it.
+type Notifier interface {
+	Notify(ctx context.Context, to notification.Recipient, template string, data any) ([]notification.Delivery, error)
+}
+
+type notificationPayload struct {
+	AppointmentID uuid.UUID `json:"appointment_id"`
+	Template      string    `json:"template"`
+}
+
+// notificationData is what the appointment templates render.
+type notificationData struct {
+	PatientName string
+	DoctorName  string
+	StartsAt    time.Time
+}
+
+// newNotificationJob builds the job sending template for an appointment.
+// name tells the jobs of one appointment apart, so each is queued once.
+func newNotificationJob(appointmentID uuid.UUID, template, name string) (jobs.Job, error) {
+	job, err := jobs.New(NotificationJob, notificationPayload{AppointmentID: appointmentID, Template: template})
+	if err != nil {
+		return jobs.Job{}, err
+	}
+	job.UniqueKey = NotificationJob + ":" + appointmentID.String() + ":" + name
+	return job, nil
+}
+
+// QueueNotifications handles AppointmentBooked by queuing the confirmation
+// right away and a reminder ReminderLeads before the visit, skipping
+// reminders that would be due already. Jobs are queued once per
+// appointment, however often the event is delivered.
+func QueueNotifications(queue jobs.Enqueuer) events.Handler {
+	return func(ctx context.Context, event events.Event) error {
+		var appt events.Appointment
+		if err := event.Decode(&appt); err != nil {
+			return jobs.Permanent(err)
+		}
+
+		confirmation, err := newNotificationJob(appt.AppointmentID, ConfirmationTemplate, "confirmation")
+		if err != nil {
+			return err
+		}
+		if _, err := queue.Enqueue(ctx, confirmation); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		for _, lead := range ReminderLeads {
+			runAt := appt.StartsAt.Add(-lead)
+			if !runAt.After(now) {
+				continue
+			}
+			reminder, err := newNotificationJob(appt.AppointmentID, ReminderTemplate, fmt.Sprintf("%dh", int(lead.Hours())))
+			if err != nil {
+				return err
+			}
+			reminder.RunAt = runAt
+			if _, err := queue.Enqueue(ctx, reminder); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// NotificationJobHandler sends the notifications queued by
+// QueueNotifications. Those of appointments that were cancelled or have
+// started by the time they run are dropped.
+func NotificationJobHandler(repo appointment.Repository, doctors doctor.Repository, notifier Notifier) jobs.Handler {
+	return func(ctx context.Context, job jobs.Job) error {
+		var payload notificationPayload
+		if err := job.Decode(&payload); err != nil {
+			return err
+		}
+
+		appt, err := repo.GetByID(database.WithPrimary(ctx), payload.AppointmentID)
+		if errors.Is(err, appointment.ErrAppointmentNotFound) {
+			return jobs.Permanent(err)
+		}
+		if err != nil {
+			return err
+		}
+		if appt.Status != medical.AppointmentBooked || !time.Now().Before(appt.StartsAt) {
+			logging.FromContext(ctx).Info("skipping notification", "appointment_id", appt.ID, "template", payload.Template, "status", appt.Status)
+			return nil
+		}
+
+		doc, err := doctors.GetByID(ctx, appt.DoctorID)
+		if err != nil {
+			return err
+		}
+
+		recipient := notification.Recipient{
+			UserID: appt.PatientID,
+			Phone:  appt.PatientPhone,
+			Email:  appt.PatientEmail,
+			Locale: appt.Locale,
+		}
+		data := notificationData{PatientName: appt.PatientName, DoctorName: doc.Name, StartsAt: appt.StartsAt}
+		// Failed deliveries are retried by the notifier, not by rerunning
+		// the job, so channels that succeeded are not sent twice
+		_, err = notifier.Notify(ctx, recipient, payload.Template, data)
+		return err
+	}
+}
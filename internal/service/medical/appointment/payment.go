@@ -0,0 +1,348 @@
+package appointment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/database"
+	"github.com/shayesteh1hs/DrAppointment/internal/entity/medical"
+	"github.com/shayesteh1hs/DrAppointment/internal/events"
+	"github.com/shayesteh1hs/DrAppointment/internal/jobs"
+	"github.com/shayesteh1hs/DrAppointment/internal/logging"
+	"github.com/shayesteh1hs/DrAppointment/internal/metrics"
+	"github.com/shayesteh1hs/DrAppointment/internal/money"
+	"github.com/shayesteh1hs/DrAppointment/internal/payment"
+	"github.com/shayesteh1hs/DrAppointment/internal/repository/medical/appointment"
+	"github.com/shayesteh1hs/DrAppointment/internal/tracing"
+)
+
+// PaymentExpiryJob is the recurring job releasing the slots of bookings
+// whose deposit was not paid in time.
+const PaymentExpiryJob = "appointment.payment_expiry"
+
+// ErrPaymentUnavailable is returned by Book when the payment provider
+// could not start a payment; the slot is released again.
+var ErrPaymentUnavailable = errors.New("payment provider is unavailable")
+
+// Payments takes the deposits of doctors who require one before a booking
+// is confirmed.
+type Payments struct {
+	Store    payment.Store
+	Provider payment.Provider
+	// Expiry is how long a booking holds its slot waiting for the deposit
+	Expiry time.Duration
+	// CallbackBaseURL is the absolute URL of the payment routes; payers are
+	// sent back to <CallbackBaseURL>/<payment ID>/callback
+	CallbackBaseURL string
+}
+
+func (p Payments) callbackURL(id uuid.UUID) string {
+	return strings.TrimSuffix(p.CallbackBaseURL, "/") + "/" + id.String() + "/callback"
+}
+
+// bookWithDeposit holds the slot in pending payment and starts the payment
+// of the deposit.
+func (s *appointmentService) bookWithDeposit(ctx context.Context, appt medical.Appointment, deposit money.Money) (*medical.Appointment, *payment.Payment, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, nil, err
+	}
+	pay := payment.Payment{
+		ID:        id,
+		Provider:  s.payments.Provider.Name(),
+		Amount:    deposit,
+		Status:    payment.StatusPending,
+		ExpiresAt: s.now().Add(s.payments.Expiry),
+	}
+
+	appt.Status = medical.AppointmentPendingPayment
+	err = s.tx.InTx(ctx, func(ctx context.Context) error {
+		if err := s.repo.Create(ctx, &appt); err != nil {
+			return err
+		}
+		pay.AppointmentID = appt.ID
+		return s.payments.Store.Create(ctx, pay)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The provider is called outside the transaction so a slow gateway
+	// doesn't hold it open; the pending rows are released if it fails
+	checkout, err := s.payments.Provider.Checkout(ctx, payment.CheckoutRequest{
+		PaymentID:   pay.ID,
+		Amount:      pay.Amount,
+		Description: fmt.Sprintf("Deposit for appointment %s", appt.ID),
+		CallbackURL: s.payments.callbackURL(pay.ID),
+		Mobile:      appt.PatientPhone,
+		Email:       appt.PatientEmail,
+	})
+	if err != nil {
+		pay.Status, pay.LastError = payment.StatusFailed, err.Error()
+		if releaseErr := s.releasePayment(ctx, pay); releaseErr != nil {
+			logging.FromContext(ctx).Error("failed to release appointment after checkout failed", "appointment_id", appt.ID, "error", releaseErr)
+		}
+		return nil, nil, fmt.Errorf("%w: %w", ErrPaymentUnavailable, err)
+	}
+
+	pay.Authority, pay.RedirectURL = checkout.Authority, checkout.RedirectURL
+	if err := s.payments.Store.Update(ctx, pay); err != nil {
+		return nil, nil, err
+	}
+	return &appt, &pay, nil
+}
+
+// CompletePayment verifies a payment the payer came back from. The
+// provider is called without holding the payment's lock, so a slow gateway
+// holds no transaction open; its answer is applied under the lock unless a
+// concurrent callback or the expiry job settled the payment first.
+// Callbacks for payments settled already return them as they are.
+func (s *appointmentService) CompletePayment(ctx context.Context, paymentID uuid.UUID, values url.Values) (pay *payment.Payment, err error) {
+	ctx, span := tracing.Start(ctx, "AppointmentService.CompletePayment")
+	defer func() { tracing.End(span, err) }()
+
+	callback, err := s.payments.Provider.ParseCallback(values)
+	if err != nil {
+		return nil, err
+	}
+	p, err := s.payments.Store.Get(database.WithPrimary(ctx), paymentID)
+	if err != nil {
+		return nil, err
+	}
+	if p.Status != payment.StatusPending {
+		return &p, nil
+	}
+	if callback.Authority != p.Authority {
+		return nil, payment.ErrInvalidCallback
+	}
+
+	outcome := settlement{status: payment.StatusFailed, reason: "payment was cancelled or failed at the gateway"}
+	if callback.OK {
+		// The slot stays held until the payment is settled, so a payment
+		// the gateway confirms is booked even after ExpiresAt
+		if outcome, err = s.verify(ctx, p); err != nil {
+			// Left pending; the payer can reload the callback page
+			return nil, err
+		}
+	}
+
+	p, booked, err := s.settlePayment(ctx, paymentID, outcome)
+	if err != nil {
+		return nil, err
+	}
+	if booked {
+		metrics.AppointmentsBooked.Inc()
+	}
+	return &p, nil
+}
+
+// settlement is how a pending payment ended.
+type settlement struct {
+	// status is StatusPaid, StatusFailed or StatusExpired
+	status       payment.Status
+	verification payment.Verification
+	// reason is stored as the LastError of payments not paid
+	reason string
+}
+
+// verify asks the provider whether p was paid. Payments it declines settle
+// as failed; other errors leave the payment pending.
+func (s *appointmentService) verify(ctx context.Context, p payment.Payment) (settlement, error) {
+	verification, err := s.payments.Provider.Verify(ctx, p.Authority, p.Amount)
+	if errors.Is(err, payment.ErrDeclined) {
+		return settlement{status: payment.StatusFailed, reason: err.Error()}, nil
+	}
+	if err != nil {
+		return settlement{}, err
+	}
+	return settlement{status: payment.StatusPaid, verification: verification}, nil
+}
+
+// settlePayment applies outcome to a pending payment, locking it so a
+// callback and the expiry job settle it once. Paid payments book their
+// appointment, or are refunded in full when its slot was released in the
+// meantime; others release the slot. Payments settled already are returned
+// as they are. booked reports whether the appointment was booked.
+func (s *appointmentService) settlePayment(ctx context.Context, id uuid.UUID, outcome settlement) (pay payment.Payment, booked bool, err error) {
+	err = s.tx.InTx(ctx, func(ctx context.Context) error {
+		booked = false
+		p, err := s.payments.Store.GetForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		pay = p
+		if p.Status != payment.StatusPending {
+			return nil
+		}
+		if outcome.status != payment.StatusPaid {
+			p.Status, p.LastError = outcome.status, outcome.reason
+			pay = p
+			return s.releasePayment(ctx, p)
+		}
+
+		now := s.now()
+		p.Status, p.RefID, p.CardPAN, p.PaidAt = payment.StatusPaid, outcome.verification.RefID, outcome.verification.CardPAN, &now
+		pay = p
+		if err := s.payments.Store.Update(ctx, p); err != nil {
+			return err
+		}
+		err = addLedgerEntry(ctx, s.payments.Store, payment.LedgerEntry{
+			AppointmentID: p.AppointmentID,
+			PaymentID:     p.ID,
+			Kind:          payment.EntryCharge,
+			Amount:        p.Amount,
+			Reference:     p.RefID,
+		})
+		if err != nil {
+			return err
+		}
+		appt, err := s.repo.Confirm(ctx, p.AppointmentID)
+		if errors.Is(err, appointment.ErrNotPendingPayment) {
+			return s.refundUnbooked(ctx, p)
+		}
+		if err != nil {
+			return err
+		}
+		booked = true
+		return s.record(ctx, events.AppointmentBooked, *appt)
+	})
+	if err != nil {
+		return payment.Payment{}, false, err
+	}
+	return pay, booked, nil
+}
+
+// refundUnbooked returns the whole of a payment that went through after
+// its appointment's slot was released. The refund is sent by IssueRefunds
+// when the cancellation event is published.
+func (s *appointmentService) refundUnbooked(ctx context.Context, p payment.Payment) error {
+	appt, err := s.repo.GetByID(ctx, p.AppointmentID)
+	if err != nil {
+		return err
+	}
+	id, err := uuid.NewV7()
+	if err != nil {
+		return err
+	}
+	err = s.payments.Store.CreateRefund(ctx, payment.Refund{
+		ID:            id,
+		PaymentID:     p.ID,
+		AppointmentID: p.AppointmentID,
+		Amount:        p.Amount,
+		Fee:           money.New(0, p.Amount.Currency),
+		Status:        payment.RefundPending,
+	})
+	if err != nil {
+		return err
+	}
+	logging.FromContext(ctx).Warn("payment went through after its booking was released, refunding it", "payment_id", p.ID, "appointment_id", p.AppointmentID)
+	return s.record(ctx, events.AppointmentCancelled, *appt)
+}
+
+// releasePayment stores a payment that ended without being paid and frees
+// its appointment's slot.
+func (s *appointmentService) releasePayment(ctx context.Context, p payment.Payment) error {
+	return s.tx.InTx(ctx, func(ctx context.Context) error {
+		if err := s.payments.Store.Update(ctx, p); err != nil {
+			return err
+		}
+		return releaseAppointment(ctx, s.repo, p.AppointmentID)
+	})
+}
+
+func releaseAppointment(ctx context.Context, repo appointment.Repository, id uuid.UUID) error {
+	_, err := repo.Release(ctx, id)
+	if errors.Is(err, appointment.ErrNotPendingPayment) {
+		return nil
+	}
+	return err
+}
+
+// unverifiedPaymentTimeout is how long past its expiry a payment the
+// provider can't answer for is kept pending before it expires anyway.
+const unverifiedPaymentTimeout = 24 * time.Hour
+
+// ExpirePayments handles PaymentExpiryJob, settling pending payments past
+// their expiry. Each is verified with the provider first, since the payer
+// may have paid without coming back to the callback: paid ones book their
+// appointment and the rest expire, releasing the slot. Payments the
+// provider can't answer for stay pending until a later run, and expire
+// once unverifiedPaymentTimeout has passed, to be reconciled with the
+// provider by hand. Every run goes through all expired payments, so those
+// left pending don't hold back the rest.
+func ExpirePayments(repo appointment.Repository, tx database.Transactor, outbox events.Outbox, store payment.Store, provider payment.Provider) jobs.Handler {
+	const batchSize = 100
+	s := &appointmentService{
+		repo:     repo,
+		tx:       tx,
+		outbox:   outbox,
+		payments: Payments{Store: store, Provider: provider},
+		now:      time.Now,
+	}
+	return func(ctx context.Context, _ jobs.Job) error {
+		var (
+			after                 payment.Payment
+			settled, paid, missed int
+		)
+		now := s.now()
+		for {
+			expired, err := store.ListExpired(database.WithPrimary(ctx), now, after, batchSize)
+			if err != nil {
+				return err
+			}
+			for _, p := range expired {
+				outcome, ok := s.expiredOutcome(ctx, p, now)
+				if !ok {
+					missed++
+					continue
+				}
+				_, booked, err := s.settlePayment(ctx, p.ID, outcome)
+				if err != nil {
+					return err
+				}
+				settled++
+				if booked {
+					metrics.AppointmentsBooked.Inc()
+					paid++
+				}
+			}
+			if len(expired) < batchSize {
+				break
+			}
+			after = expired[len(expired)-1]
+		}
+		if settled > 0 || missed > 0 {
+			logging.FromContext(ctx).Info("settled expired bookings", "count", settled, "paid", paid, "unverified", missed)
+		}
+		return nil
+	}
+}
+
+// expiredOutcome verifies an expired payment with the provider and returns
+// how to settle it, or false to leave it pending for a later run.
+func (s *appointmentService) expiredOutcome(ctx context.Context, p payment.Payment, now time.Time) (settlement, bool) {
+	outcome := settlement{status: payment.StatusFailed}
+	// Payments without an authority never reached the gateway
+	if p.Authority != "" {
+		var err error
+		if outcome, err = s.verify(ctx, p); err != nil {
+			logger := logging.FromContext(ctx).With("payment_id", p.ID, "error", err)
+			if now.Sub(p.ExpiresAt) < unverifiedPaymentTimeout {
+				logger.Warn("failed to verify expired payment, will retry")
+				return settlement{}, false
+			}
+			logger.Error("failed to verify expired payment in time, expiring it; it must be reconciled with the provider")
+			return settlement{status: payment.StatusExpired, reason: "provider could not confirm the payment: " + err.Error()}, true
+		}
+	}
+	if outcome.status != payment.StatusPaid {
+		outcome.status, outcome.reason = payment.StatusExpired, "payment was not completed in time"
+	}
+	return outcome, true
+}
@@ -0,0 +1,377 @@
+package appointment
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/entity/medical"
+	"github.com/shayesteh1hs/DrAppointment/internal/events"
+	"github.com/shayesteh1hs/DrAppointment/internal/jobs"
+	"github.com/shayesteh1hs/DrAppointment/internal/money"
+	"github.com/shayesteh1hs/DrAppointment/internal/payment"
+)
+
+var deposit = money.Rials(500_000)
+
+// flakyProvider is the fake provider failing checkouts or verifications.
+type flakyProvider struct {
+	*payment.FakeProvider
+	checkoutErr error
+	verifyErr   error
+	// verifyErrFor, when set, fails the verifications of authorities it
+	// returns an error for
+	verifyErrFor func(authority string) error
+	// onVerify runs before each verification
+	onVerify  func()
+	refundErr error
+	// onRefund runs before each refund
+	onRefund func()
+	refunds  []payment.RefundRequest
+}
+
+func (p *flakyProvider) Checkout(ctx context.Context, req payment.CheckoutRequest) (payment.Checkout, error) {
+	if p.checkoutErr != nil {
+		return payment.Checkout{}, p.checkoutErr
+	}
+	return p.FakeProvider.Checkout(ctx, req)
+}
+
+func (p *flakyProvider) Verify(ctx context.Context, authority string, amount money.Money) (payment.Verification, error) {
+	if p.onVerify != nil {
+		p.onVerify()
+	}
+	if p.verifyErr != nil {
+		return payment.Verification{}, p.verifyErr
+	}
+	if p.verifyErrFor != nil {
+		if err := p.verifyErrFor(authority); err != nil {
+			return payment.Verification{}, err
+		}
+	}
+	return p.FakeProvider.Verify(ctx, authority, amount)
+}
+
+func (p *flakyProvider) Refund(ctx context.Context, req payment.RefundRequest) (string, error) {
+	if p.onRefund != nil {
+		p.onRefund()
+	}
+	if p.refundErr != nil {
+		return "", p.refundErr
+	}
+	p.refunds = append(p.refunds, req)
+	return p.FakeProvider.Refund(ctx, req)
+}
+
+func setupWithDeposit() (fixture, *flakyProvider) {
+	f := setup()
+	f.payments.SetDeposit(f.doctorID, deposit)
+	provider := &flakyProvider{FakeProvider: payment.NewFakeProvider()}
+	f.service.payments.Provider = provider
+	return f, provider
+}
+
+// callback is what the fake provider sends the payer back with.
+func callback(t *testing.T, pay *payment.Payment) url.Values {
+	t.Helper()
+	redirect, err := url.Parse(pay.RedirectURL)
+	require.NoError(t, err)
+	return redirect.Query()
+}
+
+func TestBook_WithDepositWaitsForPayment(t *testing.T) {
+	f, _ := setupWithDeposit()
+
+	appt, pay, err := f.service.Book(context.Background(), f.newAppointment(f.now.Add(24*time.Hour)))
+	require.NoError(t, err)
+	assert.Equal(t, medical.AppointmentPendingPayment, appt.Status)
+	require.NotNil(t, pay)
+	assert.Equal(t, appt.ID, pay.AppointmentID)
+	assert.Equal(t, deposit, pay.Amount)
+	assert.Equal(t, payment.StatusPending, pay.Status)
+	assert.Equal(t, f.now.Add(15*time.Minute), pay.ExpiresAt)
+	assert.Contains(t, pay.RedirectURL, "https://api.example.com/api/v1/medical/payments/"+pay.ID.String()+"/callback?")
+	assert.Empty(t, f.outbox.Events(), "the booking is not announced before it is paid")
+
+	paid, err := f.service.CompletePayment(context.Background(), pay.ID, callback(t, pay))
+	require.NoError(t, err)
+	assert.Equal(t, payment.StatusPaid, paid.Status)
+	assert.NotEmpty(t, paid.RefID)
+	assert.NotNil(t, paid.PaidAt)
+
+	booked, err := f.service.GetByID(context.Background(), appt.ID)
+	require.NoError(t, err)
+	assert.Equal(t, medical.AppointmentBooked, booked.Status)
+	recorded := f.outbox.Events()
+	require.Len(t, recorded, 1)
+	assert.Equal(t, events.AppointmentBooked, recorded[0].Type)
+	ledger, err := f.service.Ledger(context.Background(), appt.ID)
+	require.NoError(t, err)
+	require.Len(t, ledger, 1)
+	assert.Equal(t, payment.EntryCharge, ledger[0].Kind)
+	assert.Equal(t, paid.RefID, ledger[0].Reference)
+
+	// Reloading the callback page changes nothing
+	again, err := f.service.CompletePayment(context.Background(), pay.ID, callback(t, pay))
+	require.NoError(t, err)
+	assert.Equal(t, paid.RefID, again.RefID)
+	assert.Len(t, f.outbox.Events(), 1)
+}
+
+func TestCompletePayment_ReleasesSlotWhenNotPaid(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    string
+		verifyErr error
+		want      payment.Status
+	}{
+		{name: "cancelled by the payer", status: "NOK", want: payment.StatusFailed},
+		{name: "declined by the gateway", status: "OK", verifyErr: payment.ErrDeclined, want: payment.StatusFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, provider := setupWithDeposit()
+			provider.verifyErr = tt.verifyErr
+			appt, pay, err := f.service.Book(context.Background(), f.newAppointment(f.now.Add(24*time.Hour)))
+			require.NoError(t, err)
+
+			values := callback(t, pay)
+			values.Set("Status", tt.status)
+			got, err := f.service.CompletePayment(context.Background(), pay.ID, values)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got.Status)
+			assert.NotEmpty(t, got.LastError)
+
+			released, err := f.service.GetByID(context.Background(), appt.ID)
+			require.NoError(t, err)
+			assert.Equal(t, medical.AppointmentCancelled, released.Status)
+			assert.Empty(t, f.outbox.Events())
+		})
+	}
+}
+
+func TestCompletePayment_ProviderErrorsLeavePaymentPending(t *testing.T) {
+	f, provider := setupWithDeposit()
+	provider.verifyErr = errors.New("connection reset")
+	_, pay, err := f.service.Book(context.Background(), f.newAppointment(f.now.Add(24*time.Hour)))
+	require.NoError(t, err)
+
+	_, err = f.service.CompletePayment(context.Background(), pay.ID, callback(t, pay))
+	require.Error(t, err)
+	stored, err := f.payments.Get(context.Background(), pay.ID)
+	require.NoError(t, err)
+	assert.Equal(t, payment.StatusPending, stored.Status)
+
+	provider.verifyErr = nil
+	paid, err := f.service.CompletePayment(context.Background(), pay.ID, callback(t, pay))
+	require.NoError(t, err)
+	assert.Equal(t, payment.StatusPaid, paid.Status)
+}
+
+func TestCompletePayment_RejectsForeignCallbacks(t *testing.T) {
+	f, _ := setupWithDeposit()
+	_, pay, err := f.service.Book(context.Background(), f.newAppointment(f.now.Add(24*time.Hour)))
+	require.NoError(t, err)
+
+	_, err = f.service.CompletePayment(context.Background(), pay.ID, url.Values{"Authority": {"FAKE-other"}, "Status": {"OK"}})
+	assert.ErrorIs(t, err, payment.ErrInvalidCallback)
+	_, err = f.service.CompletePayment(context.Background(), pay.ID, url.Values{})
+	assert.ErrorIs(t, err, payment.ErrInvalidCallback)
+}
+
+func TestBook_CheckoutFailureReleasesSlot(t *testing.T) {
+	f, provider := setupWithDeposit()
+	provider.checkoutErr = errors.New("gateway timeout")
+
+	_, _, err := f.service.Book(context.Background(), f.newAppointment(f.now.Add(24*time.Hour)))
+	assert.ErrorIs(t, err, ErrPaymentUnavailable)
+
+	require.Len(t, f.repo.appointments, 1)
+	for _, appt := range f.repo.appointments {
+		assert.Equal(t, medical.AppointmentCancelled, appt.Status)
+		payments, err := f.payments.ListByAppointment(context.Background(), appt.ID)
+		require.NoError(t, err)
+		require.Len(t, payments, 1)
+		assert.Equal(t, payment.StatusFailed, payments[0].Status)
+	}
+}
+
+func TestCompletePayment_KeepsPaymentSettledDuringVerification(t *testing.T) {
+	f, provider := setupWithDeposit()
+	appt, pay, err := f.service.Book(context.Background(), f.newAppointment(f.now.Add(24*time.Hour)))
+	require.NoError(t, err)
+
+	// The expiry job settles the payment while the callback verifies it
+	provider.onVerify = func() {
+		_, _, err := f.service.settlePayment(context.Background(), pay.ID, settlement{status: payment.StatusExpired, reason: "payment was not completed in time"})
+		require.NoError(t, err)
+	}
+	got, err := f.service.CompletePayment(context.Background(), pay.ID, callback(t, pay))
+	require.NoError(t, err)
+	assert.Equal(t, payment.StatusExpired, got.Status)
+	assert.Equal(t, medical.AppointmentCancelled, f.repo.appointments[appt.ID].Status)
+	assert.Empty(t, f.outbox.Events())
+}
+
+func TestCompletePayment_BooksPaymentVerifiedAfterExpiry(t *testing.T) {
+	f, _ := setupWithDeposit()
+	appt, pay, err := f.service.Book(context.Background(), f.newAppointment(f.now.Add(24*time.Hour)))
+	require.NoError(t, err)
+
+	// The payer comes back late, before the expiry job released the slot
+	f.now = f.now.Add(20 * time.Minute)
+	got, err := f.service.CompletePayment(context.Background(), pay.ID, callback(t, pay))
+	require.NoError(t, err)
+	assert.Equal(t, payment.StatusPaid, got.Status)
+	assert.Equal(t, medical.AppointmentBooked, f.repo.appointments[appt.ID].Status)
+}
+
+// bookExpired books an appointment whose payment expired an hour ago.
+func bookExpired(t *testing.T, f fixture) (*medical.Appointment, *payment.Payment) {
+	t.Helper()
+	return bookExpiredAgo(t, f, time.Hour)
+}
+
+// bookExpiredAgo books an appointment whose payment expired ago.
+func bookExpiredAgo(t *testing.T, f fixture, ago time.Duration) (*medical.Appointment, *payment.Payment) {
+	t.Helper()
+	f.service.now = func() time.Time { return time.Now().Add(-ago - 15*time.Minute) }
+	defer func() { f.service.now = time.Now }()
+	appt, pay, err := f.service.Book(context.Background(), f.newAppointment(time.Now().Add(24*time.Hour)))
+	require.NoError(t, err)
+	return appt, pay
+}
+
+func TestExpirePayments(t *testing.T) {
+	f, provider := setupWithDeposit()
+	expired, expiredPay := bookExpired(t, f)
+	f.service.now = time.Now
+	current, _, err := f.service.Book(context.Background(), f.newAppointment(time.Now().Add(48*time.Hour)))
+	require.NoError(t, err)
+
+	// The payer never paid
+	provider.verifyErr = payment.ErrDeclined
+	require.NoError(t, ExpirePayments(f.repo, noTx{}, f.outbox, f.payments, provider)(context.Background(), jobs.Job{}))
+
+	assert.Equal(t, medical.AppointmentCancelled, f.repo.appointments[expired.ID].Status)
+	assert.Equal(t, medical.AppointmentPendingPayment, f.repo.appointments[current.ID].Status)
+	pay, err := f.payments.Get(context.Background(), expiredPay.ID)
+	require.NoError(t, err)
+	assert.Equal(t, payment.StatusExpired, pay.Status)
+
+	// A late callback doesn't revive the booking
+	got, err := f.service.CompletePayment(context.Background(), pay.ID, callback(t, expiredPay))
+	require.NoError(t, err)
+	assert.Equal(t, payment.StatusExpired, got.Status)
+	assert.Equal(t, medical.AppointmentCancelled, f.repo.appointments[expired.ID].Status)
+}
+
+func TestExpirePayments_BooksPaymentsMadeWithoutCallback(t *testing.T) {
+	f, provider := setupWithDeposit()
+	appt, pay := bookExpired(t, f)
+
+	// The payer paid but never came back to the callback
+	require.NoError(t, ExpirePayments(f.repo, noTx{}, f.outbox, f.payments, provider)(context.Background(), jobs.Job{}))
+
+	stored, err := f.payments.Get(context.Background(), pay.ID)
+	require.NoError(t, err)
+	assert.Equal(t, payment.StatusPaid, stored.Status)
+	assert.NotEmpty(t, stored.RefID)
+	assert.Equal(t, medical.AppointmentBooked, f.repo.appointments[appt.ID].Status)
+	recorded := f.outbox.Events()
+	require.Len(t, recorded, 1)
+	assert.Equal(t, events.AppointmentBooked, recorded[0].Type)
+}
+
+func TestExpirePayments_LeavesUnverifiedPaymentsPending(t *testing.T) {
+	f, provider := setupWithDeposit()
+	appt, pay := bookExpired(t, f)
+
+	provider.verifyErr = errors.New("connection reset")
+	require.NoError(t, ExpirePayments(f.repo, noTx{}, f.outbox, f.payments, provider)(context.Background(), jobs.Job{}))
+
+	stored, err := f.payments.Get(context.Background(), pay.ID)
+	require.NoError(t, err)
+	assert.Equal(t, payment.StatusPending, stored.Status)
+	assert.Equal(t, medical.AppointmentPendingPayment, f.repo.appointments[appt.ID].Status)
+
+	// The next run settles it once the gateway answers
+	provider.verifyErr = payment.ErrDeclined
+	require.NoError(t, ExpirePayments(f.repo, noTx{}, f.outbox, f.payments, provider)(context.Background(), jobs.Job{}))
+	stored, err = f.payments.Get(context.Background(), pay.ID)
+	require.NoError(t, err)
+	assert.Equal(t, payment.StatusExpired, stored.Status)
+	assert.Equal(t, medical.AppointmentCancelled, f.repo.appointments[appt.ID].Status)
+}
+
+func TestExpirePayments_ExpiresPaymentsUnverifiedForTooLong(t *testing.T) {
+	f, provider := setupWithDeposit()
+	appt, pay := bookExpiredAgo(t, f, unverifiedPaymentTimeout+time.Minute)
+
+	provider.verifyErr = errors.New("connection reset")
+	require.NoError(t, ExpirePayments(f.repo, noTx{}, f.outbox, f.payments, provider)(context.Background(), jobs.Job{}))
+
+	stored, err := f.payments.Get(context.Background(), pay.ID)
+	require.NoError(t, err)
+	assert.Equal(t, payment.StatusExpired, stored.Status)
+	assert.Contains(t, stored.LastError, "connection reset")
+	assert.Equal(t, medical.AppointmentCancelled, f.repo.appointments[appt.ID].Status)
+}
+
+func TestExpirePayments_UnverifiedPaymentsDontHoldBackOthers(t *testing.T) {
+	f, provider := setupWithDeposit()
+	// More than a batch of older payments the provider can't answer for
+	for range 150 {
+		require.NoError(t, f.payments.Create(context.Background(), payment.Payment{
+			ID:            uuid.New(),
+			AppointmentID: uuid.New(),
+			Provider:      provider.Name(),
+			Amount:        deposit,
+			Status:        payment.StatusPending,
+			Authority:     "STUCK",
+			ExpiresAt:     time.Now().Add(-2 * time.Hour),
+		}))
+	}
+	provider.verifyErrFor = func(authority string) error {
+		if authority == "STUCK" {
+			return errors.New("connection reset")
+		}
+		return payment.ErrDeclined
+	}
+	appt, pay := bookExpired(t, f)
+
+	require.NoError(t, ExpirePayments(f.repo, noTx{}, f.outbox, f.payments, provider)(context.Background(), jobs.Job{}))
+
+	stored, err := f.payments.Get(context.Background(), pay.ID)
+	require.NoError(t, err)
+	assert.Equal(t, payment.StatusExpired, stored.Status)
+	assert.Equal(t, medical.AppointmentCancelled, f.repo.appointments[appt.ID].Status)
+}
+
+func TestExpirePayments_RefundsPaymentsForReleasedSlots(t *testing.T) {
+	f, provider := setupWithDeposit()
+	appt, pay := bookExpired(t, f)
+	released := f.repo.appointments[appt.ID]
+	released.Status = medical.AppointmentCancelled
+	f.repo.appointments[appt.ID] = released
+
+	require.NoError(t, ExpirePayments(f.repo, noTx{}, f.outbox, f.payments, provider)(context.Background(), jobs.Job{}))
+
+	stored, err := f.payments.Get(context.Background(), pay.ID)
+	require.NoError(t, err)
+	assert.Equal(t, payment.StatusPaid, stored.Status)
+	refunds, err := f.payments.ListRefunds(context.Background(), appt.ID)
+	require.NoError(t, err)
+	require.Len(t, refunds, 1)
+	assert.Equal(t, pay.Amount, refunds[0].Amount)
+	assert.Equal(t, payment.RefundPending, refunds[0].Status)
+	recorded := f.outbox.Events()
+	require.Len(t, recorded, 1)
+	assert.Equal(t, events.AppointmentCancelled, recorded[0].Type)
+}
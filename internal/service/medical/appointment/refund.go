@@ -0,0 +1,195 @@
+package appointment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/database"
+	"github.com/shayesteh1hs/DrAppointment/internal/entity/medical"
+	"github.com/shayesteh1hs/DrAppointment/internal/events"
+	"github.com/shayesteh1hs/DrAppointment/internal/jobs"
+	"github.com/shayesteh1hs/DrAppointment/internal/logging"
+	"github.com/shayesteh1hs/DrAppointment/internal/payment"
+	"github.com/shayesteh1hs/DrAppointment/internal/tracing"
+)
+
+// RefundSubscriber is the name IssueRefunds subscribes to
+// AppointmentCancelled under.
+const RefundSubscriber = "appointment.refunds"
+
+// refundDeposit records the refund of a cancelled appointment's paid
+// deposit under the doctor's cancellation policy. It returns nil when
+// nothing was paid or the policy keeps all of it.
+func (s *appointmentService) refundDeposit(ctx context.Context, appt medical.Appointment) (*payment.Refund, error) {
+	payments, err := s.payments.Store.ListByAppointment(ctx, appt.ID)
+	if err != nil {
+		return nil, err
+	}
+	i := slices.IndexFunc(payments, func(p payment.Payment) bool { return p.Status == payment.StatusPaid })
+	if i < 0 {
+		return nil, nil
+	}
+	paid := payments[i]
+
+	policy, err := s.payments.Store.CancellationPolicy(ctx, appt.DoctorID)
+	if err != nil {
+		return nil, err
+	}
+	amount, fee, err := policy.Refund(paid.Amount, appt.StartsAt, s.now())
+	if err != nil {
+		return nil, err
+	}
+	if amount.Amount == 0 {
+		return nil, nil
+	}
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+	refund := payment.Refund{
+		ID:            id,
+		PaymentID:     paid.ID,
+		AppointmentID: appt.ID,
+		Amount:        amount,
+		Fee:           fee,
+		Status:        payment.RefundPending,
+	}
+	if err := s.payments.Store.CreateRefund(ctx, refund); err != nil {
+		return nil, err
+	}
+	return &refund, nil
+}
+
+func (s *appointmentService) Ledger(ctx context.Context, id uuid.UUID) (entries []payment.LedgerEntry, err error) {
+	ctx, span := tracing.Start(ctx, "AppointmentService.Ledger")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := s.repo.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.payments.Store.Ledger(ctx, id)
+}
+
+// addLedgerEntry records e under a new ID.
+func addLedgerEntry(ctx context.Context, store payment.Store, e payment.LedgerEntry) error {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return err
+	}
+	e.ID = id
+	return store.AddLedgerEntry(ctx, e)
+}
+
+// IssueRefunds handles AppointmentCancelled by sending the appointment's
+// pending refunds to the provider. Refunds the provider declines are
+// marked failed and those it gives no clear answer for unconfirmed; both
+// are left to be settled by hand rather than sent again.
+func IssueRefunds(tx database.Transactor, store payment.Store, provider payment.Provider) events.Handler {
+	return func(ctx context.Context, event events.Event) error {
+		var appt events.Appointment
+		if err := event.Decode(&appt); err != nil {
+			return jobs.Permanent(err)
+		}
+
+		refunds, err := store.ListRefunds(database.WithPrimary(ctx), appt.AppointmentID)
+		if err != nil {
+			return err
+		}
+		for _, r := range refunds {
+			if r.Status != payment.RefundPending {
+				continue
+			}
+			if err := issueRefund(ctx, tx, store, provider, r.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// issueRefund sends one refund to the provider. The refund is moved to
+// sending and committed before the call, so concurrent or later deliveries
+// of the event never send it twice, and the provider's answer is recorded
+// afterwards. The refund ID goes with the request for the provider to
+// recognise a repeated one.
+//
+// Both writes commit on their own rather than joining the transaction the
+// event is consumed in: rolling that back must not make a refund already
+// sent look pending again.
+func issueRefund(ctx context.Context, tx database.Transactor, store payment.Store, provider payment.Provider, id uuid.UUID) error {
+	ctx = database.WithoutTx(ctx)
+	var (
+		r    payment.Refund
+		paid payment.Payment
+		send bool
+	)
+	err := tx.InTx(ctx, func(ctx context.Context) error {
+		send = false
+		var err error
+		if r, err = store.GetRefundForUpdate(ctx, id); err != nil {
+			return err
+		}
+		if r.Status != payment.RefundPending {
+			return nil
+		}
+		if paid, err = store.Get(ctx, r.PaymentID); err != nil {
+			return err
+		}
+		if paid.Provider != provider.Name() {
+			err = fmt.Errorf("%w: payment was made with %s", payment.ErrDeclined, paid.Provider)
+			logging.FromContext(ctx).Warn("refund declined, it must be settled by hand", "refund_id", r.ID, "error", err)
+			r.Status, r.LastError = payment.RefundFailed, err.Error()
+			return store.UpdateRefund(ctx, r)
+		}
+		r.Status = payment.RefundSending
+		send = true
+		return store.UpdateRefund(ctx, r)
+	})
+	if err != nil || !send {
+		return err
+	}
+
+	ref, err := provider.Refund(ctx, payment.RefundRequest{
+		RefundID:    r.ID,
+		Authority:   paid.Authority,
+		RefID:       paid.RefID,
+		Amount:      r.Amount,
+		Description: fmt.Sprintf("Refund for cancelled appointment %s", r.AppointmentID),
+	})
+	logger := logging.FromContext(ctx).With("refund_id", r.ID)
+	switch {
+	case errors.Is(err, payment.ErrDeclined):
+		logger.Warn("refund declined, it must be settled by hand", "error", err)
+		r.Status, r.LastError = payment.RefundFailed, err.Error()
+	case err != nil:
+		logger.Error("refund outcome unknown, it must be reconciled with the provider", "error", err)
+		r.Status, r.LastError = payment.RefundUnconfirmed, err.Error()
+	default:
+		now := time.Now()
+		r.Status, r.RefID, r.LastError, r.RefundedAt = payment.RefundSucceeded, ref, "", &now
+	}
+
+	// The refund can't be undone, so record it even if the caller gave up
+	return tx.InTx(context.WithoutCancel(ctx), func(ctx context.Context) error {
+		if err := store.UpdateRefund(ctx, r); err != nil {
+			return err
+		}
+		if r.Status != payment.RefundSucceeded {
+			return nil
+		}
+		return addLedgerEntry(ctx, store, payment.LedgerEntry{
+			AppointmentID: r.AppointmentID,
+			PaymentID:     r.PaymentID,
+			RefundID:      &r.ID,
+			Kind:          payment.EntryRefund,
+			Amount:        r.Amount,
+			Reference:     ref,
+		})
+	})
+}
@@ -0,0 +1,235 @@
+package appointment
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/database"
+	"github.com/shayesteh1hs/DrAppointment/internal/entity/medical"
+	"github.com/shayesteh1hs/DrAppointment/internal/events"
+	"github.com/shayesteh1hs/DrAppointment/internal/jobs"
+	"github.com/shayesteh1hs/DrAppointment/internal/money"
+	"github.com/shayesteh1hs/DrAppointment/internal/payment"
+)
+
+// balance is the balance of ledger.
+func balance(t *testing.T, ledger []payment.LedgerEntry) money.Money {
+	t.Helper()
+	b, err := payment.Balance(ledger)
+	require.NoError(t, err)
+	return b
+}
+
+// bookPaid books an appointment starting at startsAt and pays its deposit.
+func bookPaid(t *testing.T, f fixture, startsAt time.Time) medical.Appointment {
+	t.Helper()
+	appt, pay, err := f.service.Book(context.Background(), f.newAppointment(startsAt))
+	require.NoError(t, err)
+	_, err = f.service.CompletePayment(context.Background(), pay.ID, callback(t, pay))
+	require.NoError(t, err)
+	return *appt
+}
+
+func cancelledEvent(t *testing.T, f fixture) events.Event {
+	t.Helper()
+	recorded := f.outbox.Events()
+	require.NotEmpty(t, recorded)
+	event := recorded[len(recorded)-1]
+	require.Equal(t, events.AppointmentCancelled, event.Type)
+	return event
+}
+
+func TestCancel_RefundsDepositUnderPolicy(t *testing.T) {
+	f, provider := setupWithDeposit()
+	f.payments.SetCancellationPolicy(f.doctorID, payment.CancellationPolicy{FullRefundBefore: 24 * time.Hour, LateFeePercent: 40})
+	appt := bookPaid(t, f, f.now.Add(6*time.Hour))
+
+	cancelled, refund, err := f.service.Cancel(context.Background(), appt.ID)
+	require.NoError(t, err)
+	assert.Equal(t, medical.AppointmentCancelled, cancelled.Status)
+	require.NotNil(t, refund)
+	assert.Equal(t, money.Rials(300_000), refund.Amount)
+	assert.Equal(t, money.Rials(200_000), refund.Fee)
+	assert.Equal(t, payment.RefundPending, refund.Status)
+
+	handler := IssueRefunds(noTx{}, f.payments, provider)
+	require.NoError(t, handler(context.Background(), cancelledEvent(t, f)))
+	require.Len(t, provider.refunds, 1)
+	assert.Equal(t, money.Rials(300_000), provider.refunds[0].Amount)
+	assert.Equal(t, refund.ID, provider.refunds[0].RefundID)
+	assert.NotEmpty(t, provider.refunds[0].RefID)
+
+	refunds, err := f.payments.ListRefunds(context.Background(), appt.ID)
+	require.NoError(t, err)
+	require.Len(t, refunds, 1)
+	assert.Equal(t, payment.RefundSucceeded, refunds[0].Status)
+	assert.NotEmpty(t, refunds[0].RefID)
+	assert.NotNil(t, refunds[0].RefundedAt)
+
+	ledger, err := f.service.Ledger(context.Background(), appt.ID)
+	require.NoError(t, err)
+	require.Len(t, ledger, 2)
+	assert.Equal(t, payment.EntryRefund, ledger[1].Kind)
+	assert.Equal(t, &refund.ID, ledger[1].RefundID)
+	assert.Equal(t, money.Rials(200_000), balance(t, ledger))
+
+	// Redelivering the event refunds nothing more
+	require.NoError(t, handler(context.Background(), cancelledEvent(t, f)))
+	assert.Len(t, provider.refunds, 1)
+}
+
+func TestCancel_NoRefund(t *testing.T) {
+	t.Run("no deposit", func(t *testing.T) {
+		f := setup()
+		appt, _, err := f.service.Book(context.Background(), f.newAppointment(f.now.Add(48*time.Hour)))
+		require.NoError(t, err)
+		_, refund, err := f.service.Cancel(context.Background(), appt.ID)
+		require.NoError(t, err)
+		assert.Nil(t, refund)
+	})
+
+	t.Run("no-show", func(t *testing.T) {
+		f, _ := setupWithDeposit()
+		appt := bookPaid(t, f, f.now.Add(time.Hour))
+		f.service.now = func() time.Time { return f.now.Add(2 * time.Hour) }
+
+		_, refund, err := f.service.Cancel(context.Background(), appt.ID)
+		require.NoError(t, err)
+		assert.Nil(t, refund)
+		refunds, err := f.payments.ListRefunds(context.Background(), appt.ID)
+		require.NoError(t, err)
+		assert.Empty(t, refunds)
+	})
+}
+
+func TestIssueRefunds_ProviderErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		refundErr error
+		want      payment.RefundStatus
+	}{
+		{name: "declined", refundErr: payment.ErrDeclined, want: payment.RefundFailed},
+		{name: "answer lost", refundErr: errors.New("connection reset"), want: payment.RefundUnconfirmed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, provider := setupWithDeposit()
+			appt := bookPaid(t, f, f.now.Add(48*time.Hour))
+			_, refund, err := f.service.Cancel(context.Background(), appt.ID)
+			require.NoError(t, err)
+			require.NotNil(t, refund)
+			assert.Equal(t, deposit, refund.Amount, "a full refund under the default policy")
+			handler := IssueRefunds(noTx{}, f.payments, provider)
+
+			provider.refundErr = tt.refundErr
+			require.NoError(t, handler(context.Background(), cancelledEvent(t, f)))
+			refunds, err := f.payments.ListRefunds(context.Background(), appt.ID)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, refunds[0].Status)
+			assert.NotEmpty(t, refunds[0].LastError)
+
+			// Redelivering the event doesn't send it again
+			calls := 0
+			provider.onRefund = func() { calls++ }
+			provider.refundErr = nil
+			require.NoError(t, handler(context.Background(), cancelledEvent(t, f)))
+			assert.Zero(t, calls)
+
+			ledger, err := f.service.Ledger(context.Background(), appt.ID)
+			require.NoError(t, err)
+			assert.Equal(t, deposit, balance(t, ledger), "no refund was recorded")
+		})
+	}
+}
+
+func TestIssueRefunds_CommitsSendingBeforeCallingProvider(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db := database.New(sqlDB)
+	defer db.Close()
+
+	appointmentID, paymentID, refundID := uuid.New(), uuid.New(), uuid.New()
+	event, err := events.New(events.AppointmentCancelled, appointmentID, events.Appointment{AppointmentID: appointmentID})
+	require.NoError(t, err)
+	now := time.Now()
+	refundRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "payment_id", "appointment_id", "amount", "fee", "currency", "status", "ref_id",
+			"last_error", "refunded_at", "created_at", "updated_at"}).
+			AddRow(refundID, paymentID, appointmentID, 300_000, 200_000, "IRR", "pending", "", "", nil, now, now)
+	}
+
+	// The relay publishes the event,
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT .+ FROM outbox_events").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "type", "aggregate_id", "data", "occurred_at"}).
+			AddRow(event.ID, event.Type, event.AggregateID, []byte(event.Data), event.OccurredAt))
+	mock.ExpectExec("UPDATE outbox_events SET published_at").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	// The runner starts with the hourly outbox cleanup
+	mock.ExpectExec("DELETE FROM outbox_events").WillReturnResult(sqlmock.NewResult(0, 0))
+	// and then delivers the event in the consuming transaction, in which the refund is
+	// marked sending in a transaction of its own
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO event_consumptions").WithArgs("refunds", event.ID).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("FROM refunds WHERE appointment_id").WithArgs(appointmentID).WillReturnRows(refundRows())
+	mock.ExpectBegin()
+	mock.ExpectQuery("FROM refunds WHERE id = .+ FOR UPDATE").WithArgs(refundID).WillReturnRows(refundRows())
+	mock.ExpectQuery("FROM payments WHERE id").WithArgs(paymentID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "appointment_id", "provider", "amount", "currency", "status", "authority",
+			"redirect_url", "ref_id", "card_pan", "last_error", "expires_at", "paid_at", "created_at", "updated_at"}).
+			AddRow(paymentID, appointmentID, "fake", 500_000, "IRR", "paid", "FAKE-"+paymentID.String(), "", "FAKE-1", "", "",
+				now, now, now, now))
+	mock.ExpectExec("UPDATE refunds SET status").WithArgs(refundID, "sending", "", "", nil).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	provider := &flakyProvider{FakeProvider: payment.NewFakeProvider()}
+	provider.onRefund = func() {
+		assert.NoError(t, mock.ExpectationsWereMet(), "the refund is committed as sending before the provider is called")
+
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE refunds SET status").WithArgs(refundID, "succeeded", sqlmock.AnyArg(), "", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO ledger_entries").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		// The consumption commits last
+		mock.ExpectCommit()
+	}
+
+	queue := jobs.NewMemoryStore()
+	relay := events.NewRelay(events.NewPostgresStore(db), queue, events.DefaultOptions())
+	relay.Subscribe("refunds", IssueRefunds(db, payment.NewPostgresStore(db), provider), events.AppointmentCancelled)
+	opts := jobs.DefaultOptions()
+	opts.Concurrency = 1
+	opts.PollInterval = 5 * time.Millisecond
+	runner := jobs.NewRunner(queue, opts)
+	relay.Register(runner)
+
+	published, err := relay.PublishPending(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, published)
+	delivery := queue.Jobs()[0]
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		runner.Run(ctx)
+	}()
+	require.Eventually(t, func() bool {
+		job, _ := queue.Get(delivery.ID)
+		return job.Status == jobs.StatusSucceeded
+	}, 2*time.Second, time.Millisecond)
+	cancel()
+	<-done
+
+	require.Len(t, provider.refunds, 1)
+	assert.Equal(t, refundID, provider.refunds[0].RefundID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -2,40 +2,159 @@ package doctor
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/shayesteh1hs/DrAppointment/internal/database"
+	"github.com/shayesteh1hs/DrAppointment/internal/events"
+	"github.com/shayesteh1hs/DrAppointment/internal/media"
 	"github.com/shayesteh1hs/DrAppointment/internal/pagination"
+	"github.com/shayesteh1hs/DrAppointment/internal/storage"
+	"github.com/shayesteh1hs/DrAppointment/internal/tracing"
 
 	"github.com/shayesteh1hs/DrAppointment/internal/entity/medical"
 	filter "github.com/shayesteh1hs/DrAppointment/internal/filter/medical"
+	"github.com/shayesteh1hs/DrAppointment/internal/repository/medical/appointment"
 	"github.com/shayesteh1hs/DrAppointment/internal/repository/medical/doctor"
 )
 
 type Service interface {
 	ListDoctorsOffset(ctx context.Context, filters filter.DoctorQueryParam, params pagination.LimitOffsetParams) ([]medical.Doctor, int, error)
 	GetByID(ctx context.Context, id uuid.UUID) (*medical.Doctor, error)
+	GetByIDs(ctx context.Context, ids []uuid.UUID) ([]medical.Doctor, []uuid.UUID, error)
+	// SetAvatar replaces the doctor's avatar. A non-zero ifUpdatedAt only
+	// lets it happen while the doctor is still at that version.
+	SetAvatar(ctx context.Context, id uuid.UUID, img media.Image, ifUpdatedAt time.Time) (*medical.Doctor, error)
+	// ListOfferings returns the services of the doctors, by doctor and
+	// then price.
+	ListOfferings(ctx context.Context, doctorIDs []uuid.UUID) ([]medical.Offering, error)
+	// ListClinics returns the clinics the doctors practice at, by doctor
+	// and then clinic name.
+	ListClinics(ctx context.Context, doctorIDs []uuid.UUID) ([]medical.DoctorClinic, error)
+	// NextAvailableSlots returns the earliest free slot of each doctor
+	// within the next two weeks; doctors without one are left out.
+	NextAvailableSlots(ctx context.Context, doctorIDs []uuid.UUID) (map[uuid.UUID]medical.Slot, error)
 }
 
 type doctorService struct {
-	repo doctor.Repository
+	repo         doctor.Repository
+	appointments appointment.Repository
+	store        storage.Storage
+	tx           database.Transactor
+	outbox       events.Outbox
+	now          func() time.Time
 }
 
-func NewDoctorService(repo doctor.Repository) Service {
+func NewDoctorService(repo doctor.Repository, appointments appointment.Repository, store storage.Storage, tx database.Transactor, outbox events.Outbox) Service {
 	return &doctorService{
-		repo: repo,
+		repo:         repo,
+		appointments: appointments,
+		store:        store,
+		tx:           tx,
+		outbox:       outbox,
+		now:          time.Now,
 	}
 }
 
-func (s *doctorService) ListDoctorsOffset(ctx context.Context, filters filter.DoctorQueryParam, params pagination.LimitOffsetParams) ([]medical.Doctor, int, error) {
-	totalCount, err := s.repo.Count(ctx, filters)
+func (s *doctorService) ListDoctorsOffset(ctx context.Context, filters filter.DoctorQueryParam, params pagination.LimitOffsetParams) (doctors []medical.Doctor, totalCount int, err error) {
+	ctx, span := tracing.Start(ctx, "DoctorService.ListDoctorsOffset")
+	defer func() { tracing.End(span, err) }()
+
+	totalCount, err = s.repo.Count(ctx, filters)
 	if err != nil {
 		return []medical.Doctor{}, 0, err
 	}
 
-	doctors, err := s.repo.ListOffset(ctx, filters, params)
+	doctors, err = s.repo.ListOffset(ctx, filters, params)
 	return doctors, totalCount, err
 }
 
-func (s *doctorService) GetByID(ctx context.Context, id uuid.UUID) (*medical.Doctor, error) {
+func (s *doctorService) GetByID(ctx context.Context, id uuid.UUID) (doc *medical.Doctor, err error) {
+	ctx, span := tracing.Start(ctx, "DoctorService.GetByID")
+	defer func() { tracing.End(span, err) }()
+
 	return s.repo.GetByID(ctx, id)
 }
+
+// GetByIDs loads several doctors in one round trip. Found doctors follow the
+// order of ids with duplicates dropped; ids without a doctor are returned as
+// missing.
+func (s *doctorService) GetByIDs(ctx context.Context, ids []uuid.UUID) (doctors []medical.Doctor, missing []uuid.UUID, err error) {
+	ctx, span := tracing.Start(ctx, "DoctorService.GetByIDs")
+	defer func() { tracing.End(span, err) }()
+
+	loaded, err := s.repo.GetByIDs(ctx, ids)
+	if err != nil {
+		return []medical.Doctor{}, nil, err
+	}
+
+	byID := make(map[uuid.UUID]medical.Doctor, len(loaded))
+	for _, doc := range loaded {
+		byID[doc.ID] = doc
+	}
+
+	doctors = make([]medical.Doctor, 0, len(loaded))
+	seen := make(map[uuid.UUID]struct{}, len(ids))
+	for _, id := range ids {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+
+		doc, ok := byID[id]
+		if !ok {
+			missing = append(missing, id)
+			continue
+		}
+		doctors = append(doctors, doc)
+	}
+
+	return doctors, missing, nil
+}
+
+func (s *doctorService) ListOfferings(ctx context.Context, doctorIDs []uuid.UUID) (offerings []medical.Offering, err error) {
+	ctx, span := tracing.Start(ctx, "DoctorService.ListOfferings")
+	defer func() { tracing.End(span, err) }()
+
+	return s.repo.ListOfferings(ctx, doctorIDs)
+}
+
+func (s *doctorService) ListClinics(ctx context.Context, doctorIDs []uuid.UUID) (clinics []medical.DoctorClinic, err error) {
+	ctx, span := tracing.Start(ctx, "DoctorService.ListClinics")
+	defer func() { tracing.End(span, err) }()
+
+	return s.repo.ListClinics(ctx, doctorIDs)
+}
+
+// SetAvatar uploads img and makes it the doctor's avatar, deleting the one
+// it replaces, and records a DoctorUpdated event.
+func (s *doctorService) SetAvatar(ctx context.Context, id uuid.UUID, img media.Image, ifUpdatedAt time.Time) (doc *medical.Doctor, err error) {
+	ctx, span := tracing.Start(ctx, "DoctorService.SetAvatar")
+	defer func() { tracing.End(span, err) }()
+
+	dir := "doctors/" + id.String()
+	key, err := media.Store(ctx, s.store, dir, img)
+	if err != nil {
+		return nil, err
+	}
+
+	var previous string
+	err = s.tx.InTx(ctx, func(ctx context.Context) error {
+		var err error
+		if previous, err = s.repo.UpdateAvatar(ctx, id, key, ifUpdatedAt); err != nil {
+			return err
+		}
+		event, err := events.New(events.DoctorUpdated, id, events.Doctor{DoctorID: id, Fields: []string{"avatar"}})
+		if err != nil {
+			return err
+		}
+		return s.outbox.Add(ctx, event)
+	})
+	if err != nil {
+		media.Discard(ctx, s.store, dir, key)
+		return nil, err
+	}
+	media.Discard(ctx, s.store, dir, previous)
+
+	return s.repo.GetByID(database.WithPrimary(ctx), id)
+}
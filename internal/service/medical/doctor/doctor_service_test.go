@@ -20,7 +20,7 @@ import (
 
 func setupDoctorService() Service {
 	repo := memory.NewDoctorRepositoryWithTestData()
-	return NewDoctorService(repo)
+	return NewDoctorService(repo, nil, nil, nil, nil)
 }
 
 func TestDoctorService_ListDoctorsOffset_Success(t *testing.T) {
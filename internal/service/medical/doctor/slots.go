@@ -0,0 +1,134 @@
+package doctor
+
+import (
+	"context"
+	"time"
+	// Working hours name IANA zones; embed them for hosts without zoneinfo
+	_ "time/tzdata"
+
+	"github.com/google/uuid"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/entity/medical"
+	"github.com/shayesteh1hs/DrAppointment/internal/logging"
+	"github.com/shayesteh1hs/DrAppointment/internal/tracing"
+)
+
+// slotSearchHorizon bounds how far ahead NextAvailableSlots looks for a
+// free slot.
+const slotSearchHorizon = 14 * 24 * time.Hour
+
+// maxSlotLength is the longest slot working hours may define, matching the
+// schema's check.
+const maxSlotLength = 8 * time.Hour
+
+// NextAvailableSlots returns the earliest free slot of each doctor within
+// slotSearchHorizon, loading the working hours and appointments of all
+// doctors in one lookup each. Doctors without a free slot are left out.
+func (s *doctorService) NextAvailableSlots(ctx context.Context, doctorIDs []uuid.UUID) (slots map[uuid.UUID]medical.Slot, err error) {
+	ctx, span := tracing.Start(ctx, "DoctorService.NextAvailableSlots")
+	defer func() { tracing.End(span, err) }()
+
+	hours, err := s.repo.ListWorkingHours(ctx, doctorIDs)
+	if err != nil {
+		return nil, err
+	}
+	slots = make(map[uuid.UUID]medical.Slot, len(doctorIDs))
+	if len(hours) == 0 {
+		return slots, nil
+	}
+
+	hoursByDoctor := make(map[uuid.UUID][]medical.WorkingHours, len(doctorIDs))
+	scheduled := make([]uuid.UUID, 0, len(doctorIDs))
+	for _, w := range hours {
+		if _, ok := hoursByDoctor[w.DoctorID]; !ok {
+			scheduled = append(scheduled, w.DoctorID)
+		}
+		hoursByDoctor[w.DoctorID] = append(hoursByDoctor[w.DoctorID], w)
+	}
+
+	now := s.now()
+	until := now.Add(slotSearchHorizon)
+	// Slots start before until but may end up to a slot length later
+	booked, err := s.appointments.ListActive(ctx, scheduled, now, until.Add(maxSlotLength))
+	if err != nil {
+		return nil, err
+	}
+	bookedByDoctor := make(map[uuid.UUID][]medical.Appointment, len(scheduled))
+	for _, appt := range booked {
+		bookedByDoctor[appt.DoctorID] = append(bookedByDoctor[appt.DoctorID], appt)
+	}
+
+	zones := map[string]*time.Location{}
+	for _, doctorID := range scheduled {
+		var (
+			next  medical.Slot
+			found bool
+		)
+		for _, w := range hoursByDoctor[doctorID] {
+			loc, ok := zones[w.TimeZone]
+			if !ok {
+				if loc, err = time.LoadLocation(w.TimeZone); err != nil {
+					logging.FromContext(ctx).Warn("skipping working hours with an unknown time zone", "working_hours_id", w.ID, "time_zone", w.TimeZone)
+					continue
+				}
+				zones[w.TimeZone] = loc
+			}
+
+			slot, ok := firstFreeSlot(w, loc, bookedByDoctor[doctorID], now, until)
+			if ok && (!found || slot.StartsAt.Before(next.StartsAt)) {
+				next, found = slot, true
+			}
+		}
+		if found {
+			slots[doctorID] = next
+		}
+	}
+	return slots, nil
+}
+
+// firstFreeSlot returns the first slot of w starting after after and before
+// until that no booked appointment overlaps.
+func firstFreeSlot(w medical.WorkingHours, loc *time.Location, booked []medical.Appointment, after, until time.Time) (medical.Slot, bool) {
+	length := time.Duration(w.SlotMinutes) * time.Minute
+	if length <= 0 {
+		return medical.Slot{}, false
+	}
+
+	local := after.In(loc)
+	for day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc); day.Before(until); day = day.AddDate(0, 0, 1) {
+		if day.Weekday() != w.Weekday {
+			continue
+		}
+
+		closes := atClock(day, w.Closes)
+		for start := atClock(day, w.Opens); !start.Add(length).After(closes); start = start.Add(length) {
+			if !start.After(after) {
+				continue
+			}
+			if !start.Before(until) {
+				return medical.Slot{}, false
+			}
+			end := start.Add(length)
+			if !overlapsAny(booked, start, end) {
+				return medical.Slot{DoctorID: w.DoctorID, ClinicID: w.ClinicID, StartsAt: start, EndsAt: end}, true
+			}
+		}
+	}
+	return medical.Slot{}, false
+}
+
+// atClock returns the wall-clock time offset after midnight on day, in
+// day's location.
+func atClock(day time.Time, offset time.Duration) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(),
+		int(offset/time.Hour), int(offset%time.Hour/time.Minute), int(offset%time.Minute/time.Second), 0, day.Location())
+}
+
+func overlapsAny(booked []medical.Appointment, start, end time.Time) bool {
+	for _, appt := range booked {
+		if appt.StartsAt.Before(end) && start.Before(appt.EndsAt) {
+			return true
+		}
+	}
+	return false
+}
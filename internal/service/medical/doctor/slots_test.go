@@ -0,0 +1,109 @@
+package doctor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/entity/medical"
+)
+
+func TestFirstFreeSlot(t *testing.T) {
+	tehran, err := time.LoadLocation("Asia/Tehran")
+	require.NoError(t, err)
+
+	doctorID := uuid.New()
+	clinicID := uuid.New()
+	// Saturdays 09:00-12:00 Tehran time in 30 minute slots
+	hours := medical.WorkingHours{
+		DoctorID:    doctorID,
+		ClinicID:    clinicID,
+		Weekday:     time.Saturday,
+		Opens:       9 * time.Hour,
+		Closes:      12 * time.Hour,
+		SlotMinutes: 30,
+		TimeZone:    "Asia/Tehran",
+	}
+	// Wednesday 2026-10-14 10:00 Tehran time
+	now := time.Date(2026, 10, 14, 10, 0, 0, 0, tehran)
+	saturday := func(hour, minute int) time.Time {
+		return time.Date(2026, 10, 17, hour, minute, 0, 0, tehran)
+	}
+
+	tests := []struct {
+		name   string
+		hours  medical.WorkingHours
+		booked []medical.Appointment
+		after  time.Time
+		until  time.Time
+		want   time.Time
+		found  bool
+	}{
+		{
+			name:  "first slot of the next working day",
+			hours: hours,
+			after: now,
+			until: now.Add(slotSearchHorizon),
+			want:  saturday(9, 0),
+			found: true,
+		},
+		{
+			name:  "skips booked slots",
+			hours: hours,
+			booked: []medical.Appointment{
+				{StartsAt: saturday(9, 0), EndsAt: saturday(9, 30)},
+				{StartsAt: saturday(9, 45), EndsAt: saturday(10, 15)},
+			},
+			after: now,
+			until: now.Add(slotSearchHorizon),
+			want:  saturday(10, 30),
+			found: true,
+		},
+		{
+			name:  "skips slots that already started",
+			hours: hours,
+			after: saturday(9, 10),
+			until: saturday(9, 10).Add(slotSearchHorizon),
+			want:  saturday(9, 30),
+			found: true,
+		},
+		{
+			name:  "last slot must end by closing time",
+			hours: medical.WorkingHours{DoctorID: doctorID, Weekday: time.Saturday, Opens: 9 * time.Hour, Closes: 10*time.Hour + 15*time.Minute, SlotMinutes: 60},
+			booked: []medical.Appointment{
+				{StartsAt: saturday(9, 0), EndsAt: saturday(10, 0)},
+			},
+			after: now,
+			until: saturday(23, 0),
+		},
+		{
+			name:  "nothing before the horizon",
+			hours: hours,
+			after: now,
+			until: saturday(9, 0),
+		},
+		{
+			name:  "invalid slot length",
+			hours: medical.WorkingHours{DoctorID: doctorID, Weekday: time.Saturday, Opens: 9 * time.Hour, Closes: 12 * time.Hour},
+			after: now,
+			until: now.Add(slotSearchHorizon),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slot, ok := firstFreeSlot(tt.hours, tehran, tt.booked, tt.after, tt.until)
+			require.Equal(t, tt.found, ok)
+			if !tt.found {
+				return
+			}
+			assert.True(t, tt.want.Equal(slot.StartsAt), "starts at %s, want %s", slot.StartsAt, tt.want)
+			assert.Equal(t, slot.StartsAt.Add(30*time.Minute), slot.EndsAt)
+			assert.Equal(t, doctorID, slot.DoctorID)
+			assert.Equal(t, clinicID, slot.ClinicID)
+		})
+	}
+}
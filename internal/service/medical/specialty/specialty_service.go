@@ -2,39 +2,114 @@ package specialty
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 
+	"github.com/shayesteh1hs/DrAppointment/internal/database"
 	"github.com/shayesteh1hs/DrAppointment/internal/entity/medical"
+	"github.com/shayesteh1hs/DrAppointment/internal/media"
 	"github.com/shayesteh1hs/DrAppointment/internal/pagination"
 	"github.com/shayesteh1hs/DrAppointment/internal/repository/medical/specialty"
+	"github.com/shayesteh1hs/DrAppointment/internal/storage"
+	"github.com/shayesteh1hs/DrAppointment/internal/tracing"
 )
 
 type Service interface {
 	ListSpecialtiesOffset(ctx context.Context, params pagination.LimitOffsetParams) ([]medical.Specialty, int, error)
 	GetByID(ctx context.Context, id uuid.UUID) (*medical.Specialty, error)
+	GetByIDs(ctx context.Context, ids []uuid.UUID) ([]medical.Specialty, []uuid.UUID, error)
+	// SetImage replaces the specialty's image. A non-zero ifUpdatedAt only
+	// lets it happen while the specialty is still at that version.
+	SetImage(ctx context.Context, id uuid.UUID, img media.Image, ifUpdatedAt time.Time) (*medical.Specialty, error)
 }
 
 type specialtyService struct {
-	repo specialty.Repository
+	repo  specialty.Repository
+	store storage.Storage
 }
 
-func NewSpecialtyService(repo specialty.Repository) Service {
+func NewSpecialtyService(repo specialty.Repository, store storage.Storage) Service {
 	return &specialtyService{
-		repo: repo,
+		repo:  repo,
+		store: store,
 	}
 }
 
-func (s *specialtyService) ListSpecialtiesOffset(ctx context.Context, params pagination.LimitOffsetParams) ([]medical.Specialty, int, error) {
-	totalCount, err := s.repo.Count(ctx)
+func (s *specialtyService) ListSpecialtiesOffset(ctx context.Context, params pagination.LimitOffsetParams) (specialties []medical.Specialty, totalCount int, err error) {
+	ctx, span := tracing.Start(ctx, "SpecialtyService.ListSpecialtiesOffset")
+	defer func() { tracing.End(span, err) }()
+
+	totalCount, err = s.repo.Count(ctx)
 	if err != nil {
 		return []medical.Specialty{}, 0, err
 	}
 
-	specialties, err := s.repo.ListOffset(ctx, params)
+	specialties, err = s.repo.ListOffset(ctx, params)
 	return specialties, totalCount, err
 }
 
-func (s *specialtyService) GetByID(ctx context.Context, id uuid.UUID) (*medical.Specialty, error) {
+func (s *specialtyService) GetByID(ctx context.Context, id uuid.UUID) (spec *medical.Specialty, err error) {
+	ctx, span := tracing.Start(ctx, "SpecialtyService.GetByID")
+	defer func() { tracing.End(span, err) }()
+
 	return s.repo.GetByID(ctx, id)
 }
+
+// GetByIDs loads several specialties in one round trip. Found specialties
+// follow the order of ids with duplicates dropped; ids without a specialty
+// are returned as missing.
+func (s *specialtyService) GetByIDs(ctx context.Context, ids []uuid.UUID) (specialties []medical.Specialty, missing []uuid.UUID, err error) {
+	ctx, span := tracing.Start(ctx, "SpecialtyService.GetByIDs")
+	defer func() { tracing.End(span, err) }()
+
+	loaded, err := s.repo.GetByIDs(ctx, ids)
+	if err != nil {
+		return []medical.Specialty{}, nil, err
+	}
+
+	byID := make(map[uuid.UUID]medical.Specialty, len(loaded))
+	for _, spec := range loaded {
+		byID[spec.ID] = spec
+	}
+
+	specialties = make([]medical.Specialty, 0, len(loaded))
+	seen := make(map[uuid.UUID]struct{}, len(ids))
+	for _, id := range ids {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+
+		spec, ok := byID[id]
+		if !ok {
+			missing = append(missing, id)
+			continue
+		}
+		specialties = append(specialties, spec)
+	}
+
+	return specialties, missing, nil
+}
+
+// SetImage uploads img and makes it the specialty's image, deleting the one
+// it replaces.
+func (s *specialtyService) SetImage(ctx context.Context, id uuid.UUID, img media.Image, ifUpdatedAt time.Time) (spec *medical.Specialty, err error) {
+	ctx, span := tracing.Start(ctx, "SpecialtyService.SetImage")
+	defer func() { tracing.End(span, err) }()
+
+	dir := "specialties/" + id.String()
+	key, err := media.Store(ctx, s.store, dir, img)
+	if err != nil {
+		return nil, err
+	}
+
+	previous, err := s.repo.UpdateImage(ctx, id, key, ifUpdatedAt)
+	if err != nil {
+		media.Discard(ctx, s.store, dir, key)
+		return nil, err
+	}
+	media.Discard(ctx, s.store, dir, previous)
+
+	return s.repo.GetByID(database.WithPrimary(ctx), id)
+}
@@ -18,7 +18,7 @@ import (
 
 func setupSpecialtyService() Service {
 	repo := memory.NewSpecialtyRepository()
-	return NewSpecialtyService(repo)
+	return NewSpecialtyService(repo, nil)
 }
 
 func TestSpecialtyService_ListSpecialtiesOffset_Success(t *testing.T) {
@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalStorage_RoundTrip(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "media")
+	store := NewLocalStorage(dir)
+	ctx := context.Background()
+
+	_, err := store.Get(ctx, "specialties/1/icon.png")
+	assert.ErrorIs(t, err, ErrNotFound, "missing directory reads as missing object")
+
+	require.NoError(t, store.Put(ctx, "specialties/1/icon.png", strings.NewReader("png"), 3, "image/png"))
+
+	obj, err := store.Get(ctx, "specialties/1/icon.png")
+	require.NoError(t, err)
+	_, seekable := obj.Body.(io.Seeker)
+	assert.True(t, seekable)
+	data, err := io.ReadAll(obj.Body)
+	require.NoError(t, obj.Body.Close())
+	require.NoError(t, err)
+	assert.Equal(t, "png", string(data))
+	assert.Equal(t, "image/png", obj.ContentType)
+	assert.Equal(t, int64(3), obj.Size)
+
+	entries, err := os.ReadDir(filepath.Join(dir, "specialties", "1"))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "no temporary files are left behind")
+
+	require.NoError(t, store.Delete(ctx, "specialties/1/icon.png"))
+	require.NoError(t, store.Delete(ctx, "specialties/1/icon.png"))
+	_, err = store.Get(ctx, "specialties/1/icon.png")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestLocalStorage_RejectsEscapingKeys(t *testing.T) {
+	store := NewLocalStorage(t.TempDir())
+	ctx := context.Background()
+
+	for _, key := range []string{"../evil.png", "/etc/passwd", "a/../../b", `a\b`, "", "a//b"} {
+		assert.Error(t, store.Put(ctx, key, strings.NewReader("x"), 1, ""), key)
+		_, err := store.Get(ctx, key)
+		assert.ErrorIs(t, err, ErrNotFound, key)
+	}
+}
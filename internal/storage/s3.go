@@ -0,0 +1,263 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// S3Config addresses a bucket on AWS S3 or any S3-compatible service such as
+// MinIO or Ceph.
+type S3Config struct {
+	// Endpoint is the service URL, e.g. https://s3.eu-central-1.amazonaws.com
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// PathStyle addresses the bucket as <endpoint>/<bucket> instead of
+	// <bucket>.<endpoint host>; most self-hosted services need it.
+	PathStyle bool
+}
+
+type s3Storage struct {
+	endpoint  *url.URL
+	bucket    string
+	pathStyle bool
+	client    *http.Client
+	signer    signer
+}
+
+func NewS3Storage(cfg S3Config, client *http.Client) (Storage, error) {
+	endpoint, err := url.Parse(strings.TrimSuffix(cfg.Endpoint, "/"))
+	if err != nil || !endpoint.IsAbs() || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid s3 endpoint %q", cfg.Endpoint)
+	}
+	if cfg.Bucket == "" {
+		return nil, errors.New("s3 bucket is required")
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &s3Storage{
+		endpoint:  endpoint,
+		bucket:    cfg.Bucket,
+		pathStyle: cfg.PathStyle,
+		client:    client,
+		signer: signer{
+			accessKeyID:     cfg.AccessKeyID,
+			secretAccessKey: cfg.SecretAccessKey,
+			region:          cfg.Region,
+			service:         "s3",
+			now:             time.Now,
+		},
+	}, nil
+}
+
+func (s *s3Storage) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+
+	// The payload is signed, so it has to be buffered; uploads are small
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", key, err)
+	}
+
+	req, err := s.newRequest(ctx, http.MethodPut, key, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = int64(len(data))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	s.signer.sign(req, hashHex(data))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to upload %s: %w", key, err)
+	}
+	defer closeBody(resp)
+
+	if resp.StatusCode != http.StatusOK {
+		return responseError("upload", key, resp)
+	}
+	return nil
+}
+
+func (s *s3Storage) Get(ctx context.Context, key string) (*Object, error) {
+	if err := ValidateKey(key); err != nil {
+		return nil, ErrNotFound
+	}
+
+	resp, err := s.get(ctx, key, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	modTime, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+	obj := &Object{
+		Body:        resp.Body,
+		ContentType: resp.Header.Get("Content-Type"),
+		Size:        resp.ContentLength,
+		ModTime:     modTime,
+	}
+	if resp.ContentLength >= 0 {
+		obj.Body = &s3Body{s: s, ctx: ctx, key: key, size: resp.ContentLength, body: resp.Body}
+	}
+	return obj, nil
+}
+
+// get downloads key from byte offset on.
+func (s *s3Storage) get(ctx context.Context, key string, offset int64) (*http.Response, error) {
+	req, err := s.newRequest(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	want := http.StatusOK
+	if offset > 0 {
+		req.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+		want = http.StatusPartialContent
+	}
+	s.signer.sign(req, emptyPayloadHash)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", key, err)
+	}
+
+	switch resp.StatusCode {
+	case want:
+		return resp, nil
+	case http.StatusNotFound:
+		closeBody(resp)
+		return nil, ErrNotFound
+	default:
+		defer closeBody(resp)
+		return nil, responseError("download", key, resp)
+	}
+}
+
+func (s *s3Storage) Delete(ctx context.Context, key string) error {
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+
+	req, err := s.newRequest(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+	s.signer.sign(req, emptyPayloadHash)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to delete %s: %w", key, err)
+	}
+	defer closeBody(resp)
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusNotFound:
+		return nil
+	default:
+		return responseError("delete", key, resp)
+	}
+}
+
+func (s *s3Storage) newRequest(ctx context.Context, method, key string, body io.Reader) (*http.Request, error) {
+	u := *s.endpoint
+	if s.pathStyle {
+		u.Path += "/" + s.bucket + "/" + key
+		u.RawPath = s.endpoint.EscapedPath() + "/" + uriEncode(s.bucket, true) + "/" + uriEncode(key, false)
+	} else {
+		u.Host = s.bucket + "." + u.Host
+		u.Path += "/" + key
+		u.RawPath = s.endpoint.EscapedPath() + "/" + uriEncode(key, false)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build s3 request: %w", err)
+	}
+	return req, nil
+}
+
+// s3Body is the body of a downloaded object. Seeking is free; the next Read
+// after a seek reopens the object with a Range request, so only the bytes
+// actually read are transferred.
+type s3Body struct {
+	s    *s3Storage
+	ctx  context.Context
+	key  string
+	size int64
+
+	pos     int64
+	body    io.ReadCloser
+	bodyPos int64
+}
+
+func (b *s3Body) Read(p []byte) (int, error) {
+	if b.pos >= b.size {
+		return 0, io.EOF
+	}
+	if b.body == nil || b.bodyPos != b.pos {
+		if b.body != nil {
+			_ = b.body.Close()
+			b.body = nil
+		}
+		resp, err := b.s.get(b.ctx, b.key, b.pos)
+		if err != nil {
+			return 0, err
+		}
+		b.body, b.bodyPos = resp.Body, b.pos
+	}
+
+	n, err := b.body.Read(p)
+	b.pos += int64(n)
+	b.bodyPos = b.pos
+	return n, err
+}
+
+func (b *s3Body) Seek(offset int64, whence int) (int64, error) {
+	pos := offset
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		pos += b.pos
+	case io.SeekEnd:
+		pos += b.size
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if pos < 0 {
+		return 0, errors.New("negative position")
+	}
+	b.pos = pos
+	return pos, nil
+}
+
+func (b *s3Body) Close() error {
+	if b.body == nil {
+		return nil
+	}
+	return b.body.Close()
+}
+
+func responseError(action, key string, resp *http.Response) error {
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("failed to %s %s: s3 returned %s: %s", action, key, resp.Status, strings.TrimSpace(string(detail)))
+}
+
+func closeBody(resp *http.Response) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	_ = resp.Body.Close()
+}
@@ -0,0 +1,174 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// s3Stub is a minimal in-memory S3 that checks each request is signed and
+// that the signed payload hash matches the body.
+type s3Stub struct {
+	mu      sync.Mutex
+	objects map[string]stubObject
+	paths   []string
+	ranges  []string
+}
+
+type stubObject struct {
+	data        []byte
+	contentType string
+}
+
+func (s *s3Stub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.paths = append(s.paths, r.Host+r.URL.EscapedPath())
+	s.ranges = append(s.ranges, r.Header.Get("Range"))
+
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=key-id/") {
+		http.Error(w, "AccessDenied", http.StatusForbidden)
+		return
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	sum := sha256.Sum256(body)
+	if r.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(sum[:]) {
+		http.Error(w, "XAmzContentSHA256Mismatch", http.StatusBadRequest)
+		return
+	}
+
+	key := r.URL.Path
+	switch r.Method {
+	case http.MethodPut:
+		s.objects[key] = stubObject{data: body, contentType: r.Header.Get("Content-Type")}
+	case http.MethodGet:
+		obj, ok := s.objects[key]
+		if !ok {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", obj.contentType)
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(obj.data))
+	case http.MethodDelete:
+		delete(s.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func newS3Stub(t *testing.T, pathStyle bool) (*s3Stub, Storage) {
+	t.Helper()
+	stub := &s3Stub{objects: map[string]stubObject{}}
+	server := httptest.NewServer(stub)
+	t.Cleanup(server.Close)
+
+	client := server.Client()
+	if !pathStyle {
+		// Route bucket.<host> to the stub like a wildcard DNS entry would
+		transport := client.Transport.(*http.Transport).Clone()
+		addr := strings.TrimPrefix(server.URL, "http://")
+		transport.DialContext = func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		}
+		client = &http.Client{Transport: transport}
+	}
+
+	store, err := NewS3Storage(S3Config{
+		Endpoint:        server.URL,
+		Region:          "us-east-1",
+		Bucket:          "media",
+		AccessKeyID:     "key-id",
+		SecretAccessKey: "secret",
+		PathStyle:       pathStyle,
+	}, client)
+	require.NoError(t, err)
+	return stub, store
+}
+
+func TestS3Storage_RoundTrip(t *testing.T) {
+	for _, pathStyle := range []bool{true, false} {
+		stub, store := newS3Stub(t, pathStyle)
+		ctx := context.Background()
+
+		require.NoError(t, store.Put(ctx, "doctors/1/avatar.png", strings.NewReader("png"), 3, "image/png"))
+
+		obj, err := store.Get(ctx, "doctors/1/avatar.png")
+		require.NoError(t, err)
+		data, err := io.ReadAll(obj.Body)
+		require.NoError(t, obj.Body.Close())
+		require.NoError(t, err)
+		assert.Equal(t, "png", string(data))
+		assert.Equal(t, "image/png", obj.ContentType)
+
+		require.NoError(t, store.Delete(ctx, "doctors/1/avatar.png"))
+		_, err = store.Get(ctx, "doctors/1/avatar.png")
+		assert.ErrorIs(t, err, ErrNotFound)
+
+		if pathStyle {
+			assert.True(t, strings.HasSuffix(stub.paths[0], "/media/doctors/1/avatar.png"), stub.paths[0])
+		} else {
+			assert.True(t, strings.HasPrefix(stub.paths[0], "media.127.0.0.1:"), stub.paths[0])
+		}
+	}
+}
+
+func TestS3Storage_SeekReadsRanges(t *testing.T) {
+	stub, store := newS3Stub(t, true)
+	ctx := context.Background()
+	require.NoError(t, store.Put(ctx, "labs/a.pdf", strings.NewReader("0123456789"), 10, "application/pdf"))
+
+	obj, err := store.Get(ctx, "labs/a.pdf")
+	require.NoError(t, err)
+	defer obj.Body.Close()
+	body, ok := obj.Body.(io.ReadSeeker)
+	require.True(t, ok)
+
+	// Finding the size and rewinding, as http.ServeContent does, is free
+	size, err := body.Seek(0, io.SeekEnd)
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), size)
+	_, err = body.Seek(0, io.SeekStart)
+	require.NoError(t, err)
+	buf := make([]byte, 2)
+	_, err = io.ReadFull(body, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "01", string(buf))
+
+	_, err = body.Seek(7, io.SeekStart)
+	require.NoError(t, err)
+	rest, err := io.ReadAll(body)
+	require.NoError(t, err)
+	assert.Equal(t, "789", string(rest))
+
+	// PUT, the initial GET and one ranged GET after the seek
+	assert.Equal(t, []string{"", "", "bytes=7-"}, stub.ranges)
+}
+
+func TestS3Storage_ReportsErrors(t *testing.T) {
+	_, store := newS3Stub(t, true)
+	store.(*s3Storage).signer.accessKeyID = "wrong"
+
+	err := store.Put(context.Background(), "a.png", strings.NewReader("png"), 3, "image/png")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "403")
+}
+
+func TestNewS3Storage_ValidatesConfig(t *testing.T) {
+	_, err := NewS3Storage(S3Config{Endpoint: "not a url", Bucket: "media"}, nil)
+	assert.Error(t, err)
+
+	_, err = NewS3Storage(S3Config{Endpoint: "https://s3.example.com"}, nil)
+	assert.Error(t, err)
+}
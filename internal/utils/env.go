@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"log/slog"
 	"os"
 	"strconv"
 )
@@ -20,6 +21,7 @@ func GetEnvInt(key string, defaultValue int) int {
 	}
 	intValue, err := strconv.Atoi(value)
 	if err != nil {
+		slog.Warn("invalid integer in environment variable, using default", "key", key, "value", value, "default", defaultValue)
 		return defaultValue
 	}
 	return intValue
@@ -4,11 +4,41 @@ import (
 	"fmt"
 	"net/url"
 	"strings"
+	"sync/atomic"
 
 	"github.com/shayesteh1hs/DrAppointment/internal/entity"
+	"github.com/shayesteh1hs/DrAppointment/internal/media"
 )
 
+var (
+	configuredImageBaseURL atomic.Pointer[url.URL]
+	configuredURLSigner    atomic.Pointer[media.Signer]
+)
+
+// SetImageBaseURL overrides the IMAGE_BASE_URL lookup with the value resolved
+// by the config package at startup.
+func SetImageBaseURL(baseURL string) error {
+	if !strings.HasSuffix(baseURL, "/") {
+		baseURL += "/"
+	}
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return fmt.Errorf("invalid image base url: %w", err)
+	}
+	configuredImageBaseURL.Store(u)
+	return nil
+}
+
+// SetURLSigner sets the signer of links to private images.
+func SetURLSigner(signer *media.Signer) {
+	configuredURLSigner.Store(signer)
+}
+
 func GetImageBaseURL() *url.URL {
+	if u := configuredImageBaseURL.Load(); u != nil {
+		return u
+	}
+
 	baseURL := GetEnv("IMAGE_BASE_URL", "")
 
 	// Ensure baseURL ends with a trailing slash
@@ -19,14 +49,47 @@ func GetImageBaseURL() *url.URL {
 	if err != nil {
 		return &url.URL{
 			Scheme: "http",
-			Host:   fmt.Sprintf("localhost:%d", GetEnvInt("SERVER_PORT", 8080)),
+			Host:   fmt.Sprintf("localhost:%d", GetEnvInt("PORT", 8000)),
 			Path:   "/",
 		}
 	}
 	return baseImageURL
 }
 
+// GetFullImageURL returns the permanent URL of a public image, or a signed,
+// expiring one for a private image.
 func GetFullImageURL[T entity.Image](image T) string {
-	baseURL := GetImageBaseURL()
-	return baseURL.ResolveReference(image.GetPath()).String()
+	return imageURL(image.GetPath(), image.IsPublic())
+}
+
+func imageURL(path *url.URL, public bool) string {
+	u := GetImageBaseURL().ResolveReference(path)
+	if !public && !path.IsAbs() {
+		// Without a signer the link is refused rather than left public
+		if signer := configuredURLSigner.Load(); signer != nil {
+			u = signer.Sign(u, path.Path)
+		}
+	}
+	return u.String()
+}
+
+// GetImageVariantURLs returns the URLs of image's resized variants by format
+// name and size. Images stored as external URLs have no variants and yield
+// nil.
+func GetImageVariantURLs[T entity.Image](image T) map[string]map[int]string {
+	path := image.GetPath()
+	if path.IsAbs() || !media.HasVariants(path.Path) {
+		return nil
+	}
+
+	urls := make(map[string]map[int]string, len(media.VariantFormats))
+	for _, f := range media.VariantFormats {
+		bySize := make(map[int]string, len(media.VariantSizes))
+		for _, size := range media.VariantSizes {
+			variant := url.URL{Path: media.VariantKey(path.Path, size, f)}
+			bySize[size] = imageURL(&variant, image.IsPublic())
+		}
+		urls[f.Name] = bySize
+	}
+	return urls
 }
@@ -1,11 +1,58 @@
 package utils
 
-import "github.com/gin-gonic/gin"
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"sync/atomic"
 
-func BuildBaseURL(c *gin.Context) string {
-	scheme := "http"
+	"github.com/gin-gonic/gin"
+)
+
+// Context keys set by middleware.ForwardedHeaders for requests that arrived
+// through a trusted proxy.
+const (
+	ForwardedProtoKey = "forwarded_proto"
+	ForwardedHostKey  = "forwarded_host"
+)
+
+var configuredPublicBaseURL atomic.Pointer[url.URL]
+
+// SetPublicBaseURL pins the scheme, host and path prefix of generated links
+// (e.g. pagination) regardless of the request or proxy headers.
+func SetPublicBaseURL(baseURL string) error {
+	u, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil {
+		return fmt.Errorf("invalid public base url: %w", err)
+	}
+	configuredPublicBaseURL.Store(u)
+	return nil
+}
+
+// RequestScheme is "https" for TLS requests or when a trusted proxy says the
+// client used https, "http" otherwise.
+func RequestScheme(c *gin.Context) string {
+	if proto := c.GetString(ForwardedProtoKey); proto != "" {
+		return proto
+	}
 	if c.Request.TLS != nil {
-		scheme = "https"
+		return "https"
+	}
+	return "http"
+}
+
+// RequestHost is the host the client addressed, preferring the one reported
+// by a trusted proxy.
+func RequestHost(c *gin.Context) string {
+	if host := c.GetString(ForwardedHostKey); host != "" {
+		return host
+	}
+	return c.Request.Host
+}
+
+func BuildBaseURL(c *gin.Context) string {
+	if u := configuredPublicBaseURL.Load(); u != nil {
+		return u.String() + c.Request.URL.Path
 	}
-	return scheme + "://" + c.Request.Host + c.Request.URL.Path
+	return RequestScheme(c) + "://" + RequestHost(c) + c.Request.URL.Path
 }
@@ -0,0 +1,254 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/database"
+	"github.com/shayesteh1hs/DrAppointment/internal/events"
+	"github.com/shayesteh1hs/DrAppointment/internal/jobs"
+	"github.com/shayesteh1hs/DrAppointment/internal/logging"
+)
+
+const (
+	// Subscriber is the name the Dispatcher consumes domain events under.
+	Subscriber = "webhooks"
+	// DeliverJob is the job kind making the attempts of one delivery.
+	DeliverJob = "webhook.deliver"
+	cleanupJob = "webhook.cleanup"
+
+	userAgent = "DrAppointment-Webhooks/1.0"
+)
+
+var errSubscriptionDisabled = errors.New("subscription was disabled")
+
+// Options tune deliveries. The wait between attempts is the job runner's
+// retry backoff.
+type Options struct {
+	// Timeout bounds one attempt
+	Timeout time.Duration
+	// MaxAttempts is how often a delivery is tried before it is failed
+	MaxAttempts int
+	// DisableAfter is how many attempts in a row may fail, across all of a
+	// subscription's deliveries, before the subscription is disabled
+	DisableAfter int
+	// Retention is how long deliveries stay in the log
+	Retention time.Duration
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Timeout:      10 * time.Second,
+		MaxAttempts:  8,
+		DisableAfter: 20,
+		Retention:    30 * 24 * time.Hour,
+	}
+}
+
+// Dispatcher turns domain events into deliveries and sends them.
+type Dispatcher struct {
+	store  Store
+	queue  jobs.Enqueuer
+	client *http.Client
+	opts   Options
+	now    func() time.Time
+}
+
+// NewDispatcher sends deliveries with client. Redirects are not followed;
+// endpoints must answer with a 2xx status themselves.
+func NewDispatcher(store Store, queue jobs.Enqueuer, client *http.Client, opts Options) *Dispatcher {
+	c := *client
+	c.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	return &Dispatcher{store: store, queue: queue, client: &c, opts: opts, now: time.Now}
+}
+
+// Register adds the jobs sending deliveries and trimming the log to runner.
+func (d *Dispatcher) Register(runner *jobs.Runner) {
+	runner.Register(DeliverJob, d.deliver)
+	runner.Every(cleanupJob, time.Hour, d.cleanup)
+}
+
+// HandleEvent queues a delivery of event to every active subscription
+// receiving its type. Subscribe it to the relay for every event type.
+func (d *Dispatcher) HandleEvent(ctx context.Context, event events.Event) error {
+	subs, err := d.store.ActiveSubscriptions(ctx, event.Type)
+	if err != nil {
+		return err
+	}
+	if len(subs) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return jobs.Permanent(fmt.Errorf("failed to encode %s event: %w", event.Type, err))
+	}
+	for _, sub := range subs {
+		delivery, err := d.newDelivery(sub.ID, event.ID, event.Type, payload)
+		if err != nil {
+			return err
+		}
+		if err := d.queueDelivery(ctx, delivery); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Dispatcher) newDelivery(subscriptionID, eventID uuid.UUID, eventType string, payload json.RawMessage) (Delivery, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return Delivery{}, err
+	}
+	return Delivery{
+		ID:             id,
+		SubscriptionID: subscriptionID,
+		EventID:        eventID,
+		EventType:      eventType,
+		Payload:        payload,
+		Status:         StatusPending,
+		CreatedAt:      d.now(),
+	}, nil
+}
+
+type deliverPayload struct {
+	DeliveryID uuid.UUID `json:"delivery_id"`
+}
+
+// queueDelivery logs delivery and queues the job sending it.
+func (d *Dispatcher) queueDelivery(ctx context.Context, delivery Delivery) error {
+	if err := d.store.CreateDelivery(ctx, delivery); err != nil {
+		return err
+	}
+	job, err := jobs.New(DeliverJob, deliverPayload{DeliveryID: delivery.ID})
+	if err != nil {
+		return err
+	}
+	job.MaxAttempts = d.opts.MaxAttempts
+	job.UniqueKey = DeliverJob + ":" + delivery.ID.String()
+	_, err = d.queue.Enqueue(ctx, job)
+	return err
+}
+
+// deliver makes one attempt at a delivery and records the outcome on it and
+// on its subscription. Failed attempts are retried by the job runner until
+// the job runs out of attempts or the subscription is disabled.
+func (d *Dispatcher) deliver(ctx context.Context, job jobs.Job) error {
+	var payload deliverPayload
+	if err := job.Decode(&payload); err != nil {
+		return err
+	}
+
+	// The job may run before a replica has the delivery, or the outcome of
+	// the previous attempt, and must not read either as missing or pending
+	ctx = database.WithPrimary(ctx)
+	delivery, err := d.store.GetDelivery(ctx, payload.DeliveryID)
+	if errors.Is(err, ErrDeliveryNotFound) {
+		// Deleted with its subscription
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if delivery.Status != StatusPending {
+		return nil
+	}
+	sub, err := d.store.GetSubscription(ctx, delivery.SubscriptionID)
+	if errors.Is(err, ErrSubscriptionNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !sub.Active {
+		delivery.Status, delivery.LastError = StatusFailed, errSubscriptionDisabled.Error()
+		return d.store.UpdateDelivery(ctx, delivery)
+	}
+
+	delivery.Attempts++
+	delivery.ResponseStatus, err = d.send(ctx, sub, delivery)
+	if err == nil {
+		now := d.now()
+		delivery.Status, delivery.LastError, delivery.DeliveredAt = StatusSucceeded, "", &now
+		if err := d.store.UpdateDelivery(ctx, delivery); err != nil {
+			return err
+		}
+		return d.store.RecordSuccess(ctx, sub.ID)
+	}
+
+	delivery.LastError = err.Error()
+	disabled, recordErr := d.store.RecordFailure(ctx, sub.ID, d.opts.DisableAfter)
+	if recordErr != nil {
+		return recordErr
+	}
+	if disabled {
+		logging.FromContext(ctx).Warn("disabled webhook subscription after repeated failures",
+			"subscription_id", sub.ID, "url", sub.URL, "failures", d.opts.DisableAfter)
+		err = fmt.Errorf("%w: %w", errSubscriptionDisabled, err)
+	}
+
+	final := disabled || job.Attempts >= job.MaxAttempts
+	if final {
+		delivery.Status = StatusFailed
+	}
+	if updateErr := d.store.UpdateDelivery(ctx, delivery); updateErr != nil {
+		return updateErr
+	}
+	if final {
+		return jobs.Permanent(err)
+	}
+	return err
+}
+
+// send POSTs the delivery's payload, signed, to the subscription URL and
+// returns the response status.
+func (d *Dispatcher) send(ctx context.Context, sub Subscription, delivery Delivery) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, d.opts.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("failed to build webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set(DeliveryHeader, delivery.ID.String())
+	req.Header.Set(EventHeader, delivery.EventID.String())
+	req.Header.Set(TypeHeader, delivery.EventType)
+	req.Header.Set(SignatureHeader, Sign(sub.Secret, d.now(), delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send webhook: %w", err)
+	}
+	defer func() {
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return resp.StatusCode, fmt.Errorf("endpoint returned %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return resp.StatusCode, nil
+}
+
+func (d *Dispatcher) cleanup(ctx context.Context, _ jobs.Job) error {
+	deleted, err := d.store.DeleteDeliveries(ctx, d.now().Add(-d.opts.Retention))
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		logging.FromContext(ctx).Info("deleted old webhook deliveries", "count", deleted)
+	}
+	return nil
+}
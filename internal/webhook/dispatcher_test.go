@@ -0,0 +1,288 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/events"
+	"github.com/shayesteh1hs/DrAppointment/internal/jobs"
+)
+
+const testSecret = "whsec_test_secret_0123456789"
+
+// receiver is a partner endpoint recording the requests it accepted.
+type receiver struct {
+	mu       sync.Mutex
+	status   int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func newReceiver(t *testing.T) (*receiver, *httptest.Server) {
+	t.Helper()
+	rec := &receiver{status: http.StatusOK}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := Verify(testSecret, r.Header.Get(SignatureHeader), body, 5*time.Minute, time.Now()); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		rec.mu.Lock()
+		defer rec.mu.Unlock()
+		rec.requests = append(rec.requests, r)
+		rec.bodies = append(rec.bodies, body)
+		w.WriteHeader(rec.status)
+		_, _ = w.Write([]byte("bad gateway upstream"))
+	}))
+	t.Cleanup(server.Close)
+	return rec, server
+}
+
+func (r *receiver) setStatus(status int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status = status
+}
+
+type fixture struct {
+	store      *MemoryStore
+	queue      *jobs.MemoryStore
+	dispatcher *Dispatcher
+	service    Service
+}
+
+func setup(opts Options) fixture {
+	store, queue := NewMemoryStore(), jobs.NewMemoryStore()
+	dispatcher := NewDispatcher(store, queue, http.DefaultClient, opts)
+	return fixture{store: store, queue: queue, dispatcher: dispatcher, service: NewService(store, dispatcher)}
+}
+
+func (f fixture) subscribe(t *testing.T, url string, types ...string) Subscription {
+	t.Helper()
+	sub, err := f.service.CreateSubscription(context.Background(), SubscriptionInput{URL: url, Secret: testSecret, EventTypes: types})
+	require.NoError(t, err)
+	return sub
+}
+
+func (f fixture) publish(t *testing.T, eventType string) events.Event {
+	t.Helper()
+	id := uuid.New()
+	event, err := events.New(eventType, id, events.Appointment{AppointmentID: id, Status: "booked"})
+	require.NoError(t, err)
+	require.NoError(t, f.dispatcher.HandleEvent(context.Background(), event))
+	return event
+}
+
+// run makes attempt number attempt of the job sending delivery.
+func (f fixture) run(t *testing.T, delivery Delivery, attempt int) error {
+	t.Helper()
+	job, ok := f.job(delivery.ID)
+	require.True(t, ok, "a job is queued for the delivery")
+	job.Attempts = attempt
+	return f.dispatcher.deliver(context.Background(), job)
+}
+
+func (f fixture) job(deliveryID uuid.UUID) (jobs.Job, bool) {
+	for _, job := range f.queue.Jobs() {
+		if job.UniqueKey == DeliverJob+":"+deliveryID.String() {
+			return job, true
+		}
+	}
+	return jobs.Job{}, false
+}
+
+func (f fixture) deliveries(t *testing.T, sub Subscription) []Delivery {
+	t.Helper()
+	deliveries, err := f.service.ListDeliveries(context.Background(), sub.ID, 100)
+	require.NoError(t, err)
+	return deliveries
+}
+
+func TestDispatcher_DeliversSignedEvents(t *testing.T) {
+	rec, server := newReceiver(t)
+	f := setup(DefaultOptions())
+	sub := f.subscribe(t, server.URL, events.AppointmentBooked)
+	other := f.subscribe(t, server.URL, events.AppointmentCancelled)
+
+	event := f.publish(t, events.AppointmentBooked)
+	assert.Empty(t, f.deliveries(t, other))
+	deliveries := f.deliveries(t, sub)
+	require.Len(t, deliveries, 1)
+	delivery := deliveries[0]
+	assert.Equal(t, StatusPending, delivery.Status)
+
+	require.NoError(t, f.run(t, delivery, 1))
+
+	require.Len(t, rec.requests, 1)
+	req := rec.requests[0]
+	assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+	assert.Equal(t, delivery.ID.String(), req.Header.Get(DeliveryHeader))
+	assert.Equal(t, event.ID.String(), req.Header.Get(EventHeader))
+	assert.Equal(t, events.AppointmentBooked, req.Header.Get(TypeHeader))
+
+	var received events.Event
+	require.NoError(t, json.Unmarshal(rec.bodies[0], &received))
+	assert.Equal(t, event.ID, received.ID)
+	assert.JSONEq(t, string(event.Data), string(received.Data))
+
+	delivered, err := f.store.GetDelivery(context.Background(), delivery.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusSucceeded, delivered.Status)
+	assert.Equal(t, 1, delivered.Attempts)
+	assert.Equal(t, http.StatusOK, delivered.ResponseStatus)
+	assert.NotNil(t, delivered.DeliveredAt)
+
+	// A duplicate job run sends nothing
+	require.NoError(t, f.run(t, delivery, 2))
+	assert.Len(t, rec.requests, 1)
+}
+
+func TestDispatcher_RetriesUntilOutOfAttempts(t *testing.T) {
+	rec, server := newReceiver(t)
+	rec.setStatus(http.StatusBadGateway)
+	opts := DefaultOptions()
+	opts.MaxAttempts = 3
+	f := setup(opts)
+	sub := f.subscribe(t, server.URL, events.AppointmentBooked)
+	f.publish(t, events.AppointmentBooked)
+	delivery := f.deliveries(t, sub)[0]
+
+	job, _ := f.job(delivery.ID)
+	assert.Equal(t, 3, job.MaxAttempts)
+
+	err := f.run(t, delivery, 1)
+	require.Error(t, err)
+	assert.False(t, jobs.IsPermanent(err), "the runner retries with backoff")
+	failed, _ := f.store.GetDelivery(context.Background(), delivery.ID)
+	assert.Equal(t, StatusPending, failed.Status)
+	assert.Equal(t, http.StatusBadGateway, failed.ResponseStatus)
+	assert.Contains(t, failed.LastError, "bad gateway upstream")
+
+	require.Error(t, f.run(t, delivery, 2))
+	err = f.run(t, delivery, 3)
+	assert.True(t, jobs.IsPermanent(err))
+	failed, _ = f.store.GetDelivery(context.Background(), delivery.ID)
+	assert.Equal(t, StatusFailed, failed.Status)
+	assert.Equal(t, 3, failed.Attempts)
+
+	current, _ := f.store.GetSubscription(context.Background(), sub.ID)
+	assert.True(t, current.Active)
+	assert.Equal(t, 3, current.ConsecutiveFailures)
+}
+
+func TestDispatcher_DisablesSubscriptionAfterRepeatedFailures(t *testing.T) {
+	rec, server := newReceiver(t)
+	rec.setStatus(http.StatusInternalServerError)
+	opts := DefaultOptions()
+	opts.DisableAfter = 2
+	f := setup(opts)
+	sub := f.subscribe(t, server.URL, events.AppointmentBooked)
+	f.publish(t, events.AppointmentBooked)
+	f.publish(t, events.AppointmentBooked)
+	deliveries := f.deliveries(t, sub)
+	require.Len(t, deliveries, 2)
+
+	assert.False(t, jobs.IsPermanent(f.run(t, deliveries[0], 1)))
+	assert.True(t, jobs.IsPermanent(f.run(t, deliveries[1], 1)))
+
+	disabled, err := f.service.GetSubscription(context.Background(), sub.ID)
+	require.NoError(t, err)
+	assert.False(t, disabled.Active)
+	assert.NotNil(t, disabled.DisabledAt)
+
+	// The other delivery is failed without another request, and no new
+	// deliveries are made
+	requests := len(rec.requests)
+	require.NoError(t, f.run(t, deliveries[0], 2))
+	assert.Len(t, rec.requests, requests)
+	f.publish(t, events.AppointmentBooked)
+	assert.Len(t, f.deliveries(t, sub), 2)
+
+	// Enabling it again starts counting failures afresh
+	active := true
+	enabled, err := f.service.UpdateSubscription(context.Background(), sub.ID, SubscriptionUpdate{Active: &active})
+	require.NoError(t, err)
+	assert.True(t, enabled.Active)
+	assert.Zero(t, enabled.ConsecutiveFailures)
+	assert.Nil(t, enabled.DisabledAt)
+}
+
+func TestService_Replay(t *testing.T) {
+	rec, server := newReceiver(t)
+	f := setup(DefaultOptions())
+	sub := f.subscribe(t, server.URL, events.AppointmentBooked, events.AppointmentCancelled)
+	event := f.publish(t, events.AppointmentCancelled)
+	original := f.deliveries(t, sub)[0]
+	require.NoError(t, f.run(t, original, 1))
+
+	replay, err := f.service.Replay(context.Background(), sub.ID, original.ID)
+	require.NoError(t, err)
+	assert.NotEqual(t, original.ID, replay.ID)
+	require.NotNil(t, replay.ReplayOf)
+	assert.Equal(t, original.ID, *replay.ReplayOf)
+	assert.Equal(t, StatusPending, replay.Status)
+
+	require.NoError(t, f.run(t, replay, 1))
+	require.Len(t, rec.requests, 2)
+	assert.Equal(t, rec.bodies[0], rec.bodies[1])
+	assert.Equal(t, replay.ID.String(), rec.requests[1].Header.Get(DeliveryHeader))
+	assert.Equal(t, event.ID.String(), rec.requests[1].Header.Get(EventHeader))
+	assert.Len(t, f.deliveries(t, sub), 2)
+
+	other := f.subscribe(t, server.URL, events.AppointmentBooked)
+	_, err = f.service.Replay(context.Background(), other.ID, original.ID)
+	assert.ErrorIs(t, err, ErrDeliveryNotFound)
+
+	inactive := false
+	_, err = f.service.UpdateSubscription(context.Background(), sub.ID, SubscriptionUpdate{Active: &inactive})
+	require.NoError(t, err)
+	_, err = f.service.Replay(context.Background(), sub.ID, original.ID)
+	assert.ErrorIs(t, err, ErrSubscriptionDisabled)
+}
+
+func TestService_CreateSubscription_Validates(t *testing.T) {
+	f := setup(DefaultOptions())
+	ctx := context.Background()
+
+	sub, err := f.service.CreateSubscription(ctx, SubscriptionInput{
+		URL:        "https://clinic.example.com/hooks",
+		EventTypes: []string{events.DoctorUpdated, events.AppointmentBooked, events.DoctorUpdated},
+	})
+	require.NoError(t, err)
+	assert.Regexp(t, `^whsec_[A-Za-z0-9_-]{43}$`, sub.Secret, "a secret is generated when none is given")
+	assert.Equal(t, []string{events.AppointmentBooked, events.DoctorUpdated}, sub.EventTypes)
+
+	for name, input := range map[string]SubscriptionInput{
+		"relative url":   {URL: "/hooks", EventTypes: []string{events.AppointmentBooked}},
+		"other scheme":   {URL: "ftp://clinic.example.com", EventTypes: []string{events.AppointmentBooked}},
+		"short secret":   {URL: "https://clinic.example.com", Secret: "short", EventTypes: []string{events.AppointmentBooked}},
+		"no event types": {URL: "https://clinic.example.com"},
+		"unknown type":   {URL: "https://clinic.example.com", EventTypes: []string{"patient.created"}},
+	} {
+		_, err := f.service.CreateSubscription(ctx, input)
+		assert.ErrorIs(t, err, ErrInvalidSubscription, name)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"type":"appointment.booked"}`)
+	sentAt := time.Date(2026, time.October, 18, 9, 0, 0, 0, time.UTC)
+	header := Sign(testSecret, sentAt, body)
+
+	assert.NoError(t, Verify(testSecret, header, body, 5*time.Minute, sentAt.Add(time.Minute)))
+	assert.ErrorIs(t, Verify("whsec_another_secret_000000", header, body, 5*time.Minute, sentAt), ErrInvalidSignature)
+	assert.ErrorIs(t, Verify(testSecret, header, []byte(`{"type":"appointment.cancelled"}`), 5*time.Minute, sentAt), ErrInvalidSignature)
+	assert.ErrorIs(t, Verify(testSecret, header, body, 5*time.Minute, sentAt.Add(10*time.Minute)), ErrInvalidSignature)
+	assert.ErrorIs(t, Verify(testSecret, "v1=00", body, 5*time.Minute, sentAt), ErrInvalidSignature)
+}
@@ -0,0 +1,216 @@
+package webhook
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MemoryStore keeps subscriptions and deliveries in process memory, for
+// tests and single instance deployments.
+type MemoryStore struct {
+	mu            sync.Mutex
+	subscriptions map[uuid.UUID]Subscription
+	deliveries    map[uuid.UUID]Delivery
+	now           func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		subscriptions: make(map[uuid.UUID]Subscription),
+		deliveries:    make(map[uuid.UUID]Delivery),
+		now:           time.Now,
+	}
+}
+
+func (s *MemoryStore) CreateSubscription(ctx context.Context, sub Subscription) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sub.EventTypes = slices.Clone(sub.EventTypes)
+	s.subscriptions[sub.ID] = sub
+	return nil
+}
+
+func (s *MemoryStore) GetSubscription(ctx context.Context, id uuid.UUID) (Subscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sub, ok := s.subscriptions[id]
+	if !ok {
+		return Subscription{}, ErrSubscriptionNotFound
+	}
+	return sub, nil
+}
+
+func (s *MemoryStore) ListSubscriptions(ctx context.Context) ([]Subscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.sortedSubscriptions(func(Subscription) bool { return true }), nil
+}
+
+func (s *MemoryStore) UpdateSubscription(ctx context.Context, sub Subscription) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.subscriptions[sub.ID]
+	if !ok {
+		return ErrSubscriptionNotFound
+	}
+	stored.URL = sub.URL
+	stored.Secret = sub.Secret
+	stored.EventTypes = slices.Clone(sub.EventTypes)
+	switch {
+	case sub.Active && !stored.Active:
+		stored.ConsecutiveFailures, stored.DisabledAt = 0, nil
+	case !sub.Active && stored.Active:
+		now := s.now()
+		stored.DisabledAt = &now
+	}
+	stored.Active = sub.Active
+	stored.UpdatedAt = s.now()
+	s.subscriptions[sub.ID] = stored
+	return nil
+}
+
+func (s *MemoryStore) DeleteSubscription(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.subscriptions[id]; !ok {
+		return ErrSubscriptionNotFound
+	}
+	delete(s.subscriptions, id)
+	maps.DeleteFunc(s.deliveries, func(_ uuid.UUID, d Delivery) bool { return d.SubscriptionID == id })
+	return nil
+}
+
+func (s *MemoryStore) ActiveSubscriptions(ctx context.Context, eventType string) ([]Subscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.sortedSubscriptions(func(sub Subscription) bool {
+		return sub.Active && sub.Receives(eventType)
+	}), nil
+}
+
+func (s *MemoryStore) sortedSubscriptions(keep func(Subscription) bool) []Subscription {
+	var subs []Subscription
+	for _, sub := range s.subscriptions {
+		if keep(sub) {
+			subs = append(subs, sub)
+		}
+	}
+	slices.SortFunc(subs, func(a, b Subscription) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return subs
+}
+
+func (s *MemoryStore) RecordSuccess(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sub, ok := s.subscriptions[id]
+	if !ok {
+		return ErrSubscriptionNotFound
+	}
+	sub.ConsecutiveFailures = 0
+	s.subscriptions[id] = sub
+	return nil
+}
+
+func (s *MemoryStore) RecordFailure(ctx context.Context, id uuid.UUID, disableAfter int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sub, ok := s.subscriptions[id]
+	if !ok {
+		return false, ErrSubscriptionNotFound
+	}
+	sub.ConsecutiveFailures++
+	disabled := sub.Active && sub.ConsecutiveFailures >= disableAfter
+	if disabled {
+		now := s.now()
+		sub.Active, sub.DisabledAt, sub.UpdatedAt = false, &now, now
+	}
+	s.subscriptions[id] = sub
+	return disabled, nil
+}
+
+func (s *MemoryStore) CreateDelivery(ctx context.Context, d Delivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.subscriptions[d.SubscriptionID]; !ok {
+		return ErrSubscriptionNotFound
+	}
+	s.deliveries[d.ID] = d
+	return nil
+}
+
+func (s *MemoryStore) UpdateDelivery(ctx context.Context, d Delivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.deliveries[d.ID]
+	if !ok {
+		return ErrDeliveryNotFound
+	}
+	stored.Status = d.Status
+	stored.Attempts = d.Attempts
+	stored.ResponseStatus = d.ResponseStatus
+	stored.LastError = d.LastError
+	stored.DeliveredAt = d.DeliveredAt
+	s.deliveries[d.ID] = stored
+	return nil
+}
+
+func (s *MemoryStore) GetDelivery(ctx context.Context, id uuid.UUID) (Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, ok := s.deliveries[id]
+	if !ok {
+		return Delivery{}, ErrDeliveryNotFound
+	}
+	return d, nil
+}
+
+func (s *MemoryStore) ListDeliveries(ctx context.Context, subscriptionID uuid.UUID, limit int) ([]Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var list []Delivery
+	for _, d := range s.deliveries {
+		if d.SubscriptionID == subscriptionID {
+			list = append(list, d)
+		}
+	}
+	slices.SortFunc(list, func(a, b Delivery) int {
+		return cmp.Or(b.CreatedAt.Compare(a.CreatedAt), cmp.Compare(b.ID.String(), a.ID.String()))
+	})
+	if len(list) > limit {
+		list = list[:limit]
+	}
+	return list, nil
+}
+
+func (s *MemoryStore) DeleteDeliveries(ctx context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deleted int64
+	maps.DeleteFunc(s.deliveries, func(_ uuid.UUID, d Delivery) bool {
+		if d.CreatedAt.Before(before) {
+			deleted++
+			return true
+		}
+		return false
+	})
+	return deleted, nil
+}
@@ -0,0 +1,248 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/database"
+)
+
+const (
+	subscriptionColumns = "id, url, secret, event_types, active, consecutive_failures, disabled_at, created_at, updated_at"
+	deliveryColumns     = "id, subscription_id, event_id, event_type, payload, status, attempts, response_status, last_error, replay_of, created_at, delivered_at"
+)
+
+const (
+	insertSubscriptionQuery = "INSERT INTO webhook_subscriptions (" + subscriptionColumns + ") " +
+		"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)"
+
+	selectSubscriptionQuery = "SELECT " + subscriptionColumns + " FROM webhook_subscriptions WHERE id = $1"
+
+	listSubscriptionsQuery = "SELECT " + subscriptionColumns + " FROM webhook_subscriptions ORDER BY created_at"
+
+	// updateSubscriptionQuery resets the failure count when the
+	// subscription is enabled, and stamps disabled_at when it is disabled.
+	updateSubscriptionQuery = `UPDATE webhook_subscriptions SET url = $2, secret = $3, event_types = $4, active = $5,
+    consecutive_failures = CASE WHEN $5 AND NOT active THEN 0 ELSE consecutive_failures END,
+    disabled_at = CASE WHEN $5 THEN NULL WHEN active THEN now() ELSE disabled_at END
+WHERE id = $1`
+
+	deleteSubscriptionQuery = "DELETE FROM webhook_subscriptions WHERE id = $1"
+
+	activeSubscriptionsQuery = "SELECT " + subscriptionColumns + " FROM webhook_subscriptions " +
+		"WHERE active AND $1 = ANY(event_types) ORDER BY created_at"
+
+	recordSuccessQuery = "UPDATE webhook_subscriptions SET consecutive_failures = 0 WHERE id = $1 AND consecutive_failures > 0"
+
+	// recordFailureQuery returns whether this failure disabled the
+	// subscription.
+	recordFailureQuery = `UPDATE webhook_subscriptions AS s
+SET consecutive_failures = s.consecutive_failures + 1,
+    active = s.active AND s.consecutive_failures + 1 < $2,
+    disabled_at = CASE WHEN s.active AND s.consecutive_failures + 1 >= $2 THEN now() ELSE s.disabled_at END
+FROM (SELECT id, active FROM webhook_subscriptions WHERE id = $1 FOR UPDATE) AS before
+WHERE s.id = before.id
+RETURNING before.active AND NOT s.active`
+
+	insertDeliveryQuery = "INSERT INTO webhook_deliveries (" + deliveryColumns + ") " +
+		"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)"
+
+	updateDeliveryQuery = "UPDATE webhook_deliveries SET status = $2, attempts = $3, response_status = $4, last_error = $5, delivered_at = $6 WHERE id = $1"
+
+	selectDeliveryQuery = "SELECT " + deliveryColumns + " FROM webhook_deliveries WHERE id = $1"
+
+	listDeliveriesQuery = "SELECT " + deliveryColumns + " FROM webhook_deliveries " +
+		"WHERE subscription_id = $1 ORDER BY created_at DESC, id DESC LIMIT $2"
+
+	deleteDeliveriesQuery = "DELETE FROM webhook_deliveries WHERE created_at < $1"
+)
+
+// PostgresStore shares subscriptions and the delivery log between all
+// instances.
+type PostgresStore struct {
+	db database.Querier
+}
+
+func NewPostgresStore(db database.Querier) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSubscription(row scanner) (Subscription, error) {
+	var sub Subscription
+	err := row.Scan(&sub.ID, &sub.URL, &sub.Secret, pq.Array(&sub.EventTypes), &sub.Active,
+		&sub.ConsecutiveFailures, &sub.DisabledAt, &sub.CreatedAt, &sub.UpdatedAt)
+	return sub, err
+}
+
+func scanDelivery(row scanner) (Delivery, error) {
+	var (
+		d       Delivery
+		payload []byte
+	)
+	err := row.Scan(&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &payload, &d.Status, &d.Attempts,
+		&d.ResponseStatus, &d.LastError, &d.ReplayOf, &d.CreatedAt, &d.DeliveredAt)
+	d.Payload = payload
+	return d, err
+}
+
+func (s *PostgresStore) CreateSubscription(ctx context.Context, sub Subscription) error {
+	_, err := s.db.ExecContext(ctx, insertSubscriptionQuery, sub.ID, sub.URL, sub.Secret, pq.Array(sub.EventTypes),
+		sub.Active, sub.ConsecutiveFailures, sub.DisabledAt, sub.CreatedAt, sub.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook subscription: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) GetSubscription(ctx context.Context, id uuid.UUID) (Subscription, error) {
+	sub, err := scanSubscription(s.db.QueryRowContext(ctx, selectSubscriptionQuery, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Subscription{}, ErrSubscriptionNotFound
+	}
+	if err != nil {
+		return Subscription{}, fmt.Errorf("failed to get webhook subscription: %w", err)
+	}
+	return sub, nil
+}
+
+func (s *PostgresStore) ListSubscriptions(ctx context.Context) ([]Subscription, error) {
+	return s.querySubscriptions(ctx, listSubscriptionsQuery)
+}
+
+func (s *PostgresStore) ActiveSubscriptions(ctx context.Context, eventType string) ([]Subscription, error) {
+	return s.querySubscriptions(ctx, activeSubscriptionsQuery, eventType)
+}
+
+func (s *PostgresStore) querySubscriptions(ctx context.Context, query string, args ...any) ([]Subscription, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	var subs []Subscription
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook subscription: %w", err)
+		}
+		subs = append(subs, sub)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list webhook subscriptions: %w", err)
+	}
+	return subs, nil
+}
+
+func (s *PostgresStore) UpdateSubscription(ctx context.Context, sub Subscription) error {
+	result, err := s.db.ExecContext(ctx, updateSubscriptionQuery, sub.ID, sub.URL, sub.Secret, pq.Array(sub.EventTypes), sub.Active)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook subscription: %w", err)
+	}
+	return requireRow(result, ErrSubscriptionNotFound)
+}
+
+func (s *PostgresStore) DeleteSubscription(ctx context.Context, id uuid.UUID) error {
+	result, err := s.db.ExecContext(ctx, deleteSubscriptionQuery, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook subscription: %w", err)
+	}
+	return requireRow(result, ErrSubscriptionNotFound)
+}
+
+func (s *PostgresStore) RecordSuccess(ctx context.Context, id uuid.UUID) error {
+	if _, err := s.db.ExecContext(ctx, recordSuccessQuery, id); err != nil {
+		return fmt.Errorf("failed to record webhook success: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) RecordFailure(ctx context.Context, id uuid.UUID, disableAfter int) (bool, error) {
+	var disabled bool
+	err := s.db.QueryRowContext(ctx, recordFailureQuery, id, disableAfter).Scan(&disabled)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, ErrSubscriptionNotFound
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to record webhook failure: %w", err)
+	}
+	return disabled, nil
+}
+
+func (s *PostgresStore) CreateDelivery(ctx context.Context, d Delivery) error {
+	_, err := s.db.ExecContext(ctx, insertDeliveryQuery, d.ID, d.SubscriptionID, d.EventID, d.EventType, []byte(d.Payload),
+		d.Status, d.Attempts, d.ResponseStatus, d.LastError, d.ReplayOf, d.CreatedAt, d.DeliveredAt)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook delivery: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) UpdateDelivery(ctx context.Context, d Delivery) error {
+	result, err := s.db.ExecContext(ctx, updateDeliveryQuery, d.ID, d.Status, d.Attempts, d.ResponseStatus, d.LastError, d.DeliveredAt)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook delivery: %w", err)
+	}
+	return requireRow(result, ErrDeliveryNotFound)
+}
+
+func (s *PostgresStore) GetDelivery(ctx context.Context, id uuid.UUID) (Delivery, error) {
+	d, err := scanDelivery(s.db.QueryRowContext(ctx, selectDeliveryQuery, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Delivery{}, ErrDeliveryNotFound
+	}
+	if err != nil {
+		return Delivery{}, fmt.Errorf("failed to get webhook delivery: %w", err)
+	}
+	return d, nil
+}
+
+func (s *PostgresStore) ListDeliveries(ctx context.Context, subscriptionID uuid.UUID, limit int) ([]Delivery, error) {
+	rows, err := s.db.QueryContext(ctx, listDeliveriesQuery, subscriptionID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var list []Delivery
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		list = append(list, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	return list, nil
+}
+
+func (s *PostgresStore) DeleteDeliveries(ctx context.Context, before time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx, deleteDeliveriesQuery, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete webhook deliveries: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+func requireRow(result sql.Result, notFound error) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return notFound
+	}
+	return nil
+}
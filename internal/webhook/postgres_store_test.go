@@ -0,0 +1,93 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/database"
+	"github.com/shayesteh1hs/DrAppointment/internal/jobs"
+)
+
+var testTime = time.Date(2026, time.October, 18, 9, 0, 0, 0, time.UTC)
+
+func TestPostgresStore_RecordFailure(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db := database.New(sqlDB)
+	defer db.Close()
+	store := NewPostgresStore(db)
+	id := uuid.New()
+
+	mock.ExpectQuery(regexp.QuoteMeta(recordFailureQuery)).
+		WithArgs(id, 20).
+		WillReturnRows(sqlmock.NewRows([]string{"disabled"}).AddRow(true))
+	disabled, err := store.RecordFailure(context.Background(), id, 20)
+	require.NoError(t, err)
+	assert.True(t, disabled)
+
+	mock.ExpectQuery(regexp.QuoteMeta(recordFailureQuery)).
+		WithArgs(id, 20).
+		WillReturnRows(sqlmock.NewRows([]string{"disabled"}))
+	_, err = store.RecordFailure(context.Background(), id, 20)
+	assert.ErrorIs(t, err, ErrSubscriptionNotFound)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStore_ActiveSubscriptions(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db := database.New(sqlDB)
+	defer db.Close()
+
+	id := uuid.New()
+	mock.ExpectQuery(regexp.QuoteMeta(activeSubscriptionsQuery)).
+		WithArgs("appointment.booked").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "url", "secret", "event_types", "active", "consecutive_failures", "disabled_at", "created_at", "updated_at"}).
+			AddRow(id, "https://clinic.example.com/hooks", testSecret, `{appointment.booked,doctor.updated}`, true, 0, nil, testTime, testTime))
+
+	subs, err := NewPostgresStore(db).ActiveSubscriptions(context.Background(), "appointment.booked")
+	require.NoError(t, err)
+	require.Len(t, subs, 1)
+	assert.Equal(t, id, subs[0].ID)
+	assert.Equal(t, []string{"appointment.booked", "doctor.updated"}, subs[0].EventTypes)
+	assert.Nil(t, subs[0].DisabledAt)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDispatcher_Deliver_ReadsDeliveryFromPrimary(t *testing.T) {
+	primary, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	replica, replicaMock, err := sqlmock.New()
+	require.NoError(t, err)
+	db := database.NewWithReplicas(primary, replica)
+	defer db.Close()
+	dispatcher := NewDispatcher(NewPostgresStore(db), jobs.NewMemoryStore(), http.DefaultClient, DefaultOptions())
+
+	id := uuid.New()
+	// The replica has not caught up with the delivery yet
+	replicaMock.ExpectQuery(regexp.QuoteMeta(selectDeliveryQuery)).
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows(strings.Split(deliveryColumns, ", ")))
+	// Already delivered, so the job has nothing left to do
+	mock.ExpectQuery(regexp.QuoteMeta(selectDeliveryQuery)).
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows(strings.Split(deliveryColumns, ", ")).
+			AddRow(id, uuid.New(), uuid.New(), "appointment.booked", []byte(`{}`), "succeeded", 1, 200, "", nil, testTime, testTime))
+
+	job, err := jobs.New(DeliverJob, deliverPayload{DeliveryID: id})
+	require.NoError(t, err)
+	require.NoError(t, dispatcher.deliver(context.Background(), job))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
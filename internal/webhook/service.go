@@ -0,0 +1,213 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+
+	"github.com/google/uuid"
+
+	"github.com/shayesteh1hs/DrAppointment/internal/database"
+	"github.com/shayesteh1hs/DrAppointment/internal/events"
+	"github.com/shayesteh1hs/DrAppointment/internal/tracing"
+)
+
+// ErrInvalidSubscription wraps validation errors of subscription input.
+var ErrInvalidSubscription = errors.New("invalid webhook subscription")
+
+const minSecretLength = 16
+
+// SubscriptionInput creates a subscription. A secret is generated when
+// none is given.
+type SubscriptionInput struct {
+	URL        string
+	Secret     string
+	EventTypes []string
+}
+
+// SubscriptionUpdate changes the fields that are set. Setting Active
+// re-enables a disabled subscription and resets its failure count.
+type SubscriptionUpdate struct {
+	URL        *string
+	Secret     *string
+	EventTypes []string
+	Active     *bool
+}
+
+// Service manages subscriptions and their delivery log for the admin API.
+type Service interface {
+	CreateSubscription(ctx context.Context, input SubscriptionInput) (Subscription, error)
+	ListSubscriptions(ctx context.Context) ([]Subscription, error)
+	GetSubscription(ctx context.Context, id uuid.UUID) (Subscription, error)
+	UpdateSubscription(ctx context.Context, id uuid.UUID, update SubscriptionUpdate) (Subscription, error)
+	DeleteSubscription(ctx context.Context, id uuid.UUID) error
+	ListDeliveries(ctx context.Context, subscriptionID uuid.UUID, limit int) ([]Delivery, error)
+	// Replay sends a delivery's payload again as a new delivery.
+	Replay(ctx context.Context, subscriptionID, deliveryID uuid.UUID) (Delivery, error)
+}
+
+type service struct {
+	store      Store
+	dispatcher *Dispatcher
+}
+
+func NewService(store Store, dispatcher *Dispatcher) Service {
+	return &service{store: store, dispatcher: dispatcher}
+}
+
+func (s *service) CreateSubscription(ctx context.Context, input SubscriptionInput) (sub Subscription, err error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.CreateSubscription")
+	defer func() { tracing.End(span, err) }()
+
+	if input.Secret == "" {
+		if input.Secret, err = generateSecret(); err != nil {
+			return Subscription{}, err
+		}
+	}
+	id, err := uuid.NewV7()
+	if err != nil {
+		return Subscription{}, err
+	}
+	now := s.dispatcher.now()
+	sub = Subscription{
+		ID:         id,
+		URL:        input.URL,
+		Secret:     input.Secret,
+		EventTypes: input.EventTypes,
+		Active:     true,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if err := validate(&sub); err != nil {
+		return Subscription{}, err
+	}
+
+	if err := s.store.CreateSubscription(ctx, sub); err != nil {
+		return Subscription{}, err
+	}
+	return sub, nil
+}
+
+func (s *service) ListSubscriptions(ctx context.Context) (subs []Subscription, err error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.ListSubscriptions")
+	defer func() { tracing.End(span, err) }()
+
+	return s.store.ListSubscriptions(ctx)
+}
+
+func (s *service) GetSubscription(ctx context.Context, id uuid.UUID) (sub Subscription, err error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.GetSubscription")
+	defer func() { tracing.End(span, err) }()
+
+	return s.store.GetSubscription(ctx, id)
+}
+
+func (s *service) UpdateSubscription(ctx context.Context, id uuid.UUID, update SubscriptionUpdate) (sub Subscription, err error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.UpdateSubscription")
+	defer func() { tracing.End(span, err) }()
+
+	sub, err = s.store.GetSubscription(ctx, id)
+	if err != nil {
+		return Subscription{}, err
+	}
+	if update.URL != nil {
+		sub.URL = *update.URL
+	}
+	if update.Secret != nil {
+		sub.Secret = *update.Secret
+	}
+	if update.EventTypes != nil {
+		sub.EventTypes = update.EventTypes
+	}
+	if update.Active != nil {
+		sub.Active = *update.Active
+	}
+	if err := validate(&sub); err != nil {
+		return Subscription{}, err
+	}
+
+	if err := s.store.UpdateSubscription(ctx, sub); err != nil {
+		return Subscription{}, err
+	}
+	return s.store.GetSubscription(database.WithPrimary(ctx), id)
+}
+
+func (s *service) DeleteSubscription(ctx context.Context, id uuid.UUID) (err error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.DeleteSubscription")
+	defer func() { tracing.End(span, err) }()
+
+	return s.store.DeleteSubscription(ctx, id)
+}
+
+func (s *service) ListDeliveries(ctx context.Context, subscriptionID uuid.UUID, limit int) (deliveries []Delivery, err error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.ListDeliveries")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := s.store.GetSubscription(ctx, subscriptionID); err != nil {
+		return nil, err
+	}
+	return s.store.ListDeliveries(ctx, subscriptionID, limit)
+}
+
+func (s *service) Replay(ctx context.Context, subscriptionID, deliveryID uuid.UUID) (replay Delivery, err error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.Replay")
+	defer func() { tracing.End(span, err) }()
+
+	original, err := s.store.GetDelivery(ctx, deliveryID)
+	if err != nil {
+		return Delivery{}, err
+	}
+	if original.SubscriptionID != subscriptionID {
+		return Delivery{}, ErrDeliveryNotFound
+	}
+	sub, err := s.store.GetSubscription(ctx, subscriptionID)
+	if err != nil {
+		return Delivery{}, err
+	}
+	if !sub.Active {
+		return Delivery{}, ErrSubscriptionDisabled
+	}
+
+	replay, err = s.dispatcher.newDelivery(sub.ID, original.EventID, original.EventType, original.Payload)
+	if err != nil {
+		return Delivery{}, err
+	}
+	replay.ReplayOf = &original.ID
+	if err := s.dispatcher.queueDelivery(ctx, replay); err != nil {
+		return Delivery{}, err
+	}
+	return replay, nil
+}
+
+// validate checks sub and drops duplicate event types.
+func validate(sub *Subscription) error {
+	u, err := url.Parse(sub.URL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalidSubscription)
+	}
+	if len(sub.Secret) < minSecretLength {
+		return fmt.Errorf("%w: secret must be at least %d characters", ErrInvalidSubscription, minSecretLength)
+	}
+	if len(sub.EventTypes) == 0 {
+		return fmt.Errorf("%w: at least one event type is required", ErrInvalidSubscription)
+	}
+	for _, t := range sub.EventTypes {
+		if !slices.Contains(events.Types, t) {
+			return fmt.Errorf("%w: unknown event type %q", ErrInvalidSubscription, t)
+		}
+	}
+	sub.EventTypes = slices.Compact(slices.Sorted(slices.Values(sub.EventTypes)))
+	return nil
+}
+
+func generateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return "whsec_" + base64.RawURLEncoding.EncodeToString(b), nil
+}
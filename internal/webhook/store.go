@@ -0,0 +1,41 @@
+package webhook
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Store persists subscriptions and the delivery log.
+type Store interface {
+	CreateSubscription(ctx context.Context, s Subscription) error
+	GetSubscription(ctx context.Context, id uuid.UUID) (Subscription, error)
+	// ListSubscriptions returns every subscription, oldest first.
+	ListSubscriptions(ctx context.Context) ([]Subscription, error)
+	// UpdateSubscription saves the URL, secret, event types and active
+	// state; enabling resets the failure count.
+	UpdateSubscription(ctx context.Context, s Subscription) error
+	// DeleteSubscription removes a subscription and its deliveries.
+	DeleteSubscription(ctx context.Context, id uuid.UUID) error
+	// ActiveSubscriptions returns the active subscriptions receiving
+	// eventType.
+	ActiveSubscriptions(ctx context.Context, eventType string) ([]Subscription, error)
+	// RecordSuccess resets the subscription's failure count.
+	RecordSuccess(ctx context.Context, id uuid.UUID) error
+	// RecordFailure counts a failed attempt and disables the subscription
+	// once disableAfter attempts in a row have failed, reporting whether
+	// this call disabled it.
+	RecordFailure(ctx context.Context, id uuid.UUID, disableAfter int) (bool, error)
+
+	CreateDelivery(ctx context.Context, d Delivery) error
+	// UpdateDelivery saves the outcome of an attempt: status, attempts,
+	// response status, last error and delivery time.
+	UpdateDelivery(ctx context.Context, d Delivery) error
+	GetDelivery(ctx context.Context, id uuid.UUID) (Delivery, error)
+	// ListDeliveries returns up to limit of a subscription's deliveries,
+	// newest first.
+	ListDeliveries(ctx context.Context, subscriptionID uuid.UUID, limit int) ([]Delivery, error)
+	// DeleteDeliveries removes deliveries created before the given time.
+	DeleteDeliveries(ctx context.Context, before time.Time) (int64, error)
+}
@@ -0,0 +1,76 @@
+// Package webhook sends domain events to partner systems. Partners
+// subscribe a URL to event types; every matching event is POSTed there as
+// JSON signed with the subscription's secret, retried with backoff, and
+// logged as a delivery that can be replayed.
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrSubscriptionNotFound = errors.New("webhook subscription not found")
+	ErrDeliveryNotFound     = errors.New("webhook delivery not found")
+	// ErrSubscriptionDisabled is returned when replaying to a disabled
+	// subscription; enable it first.
+	ErrSubscriptionDisabled = errors.New("webhook subscription is disabled")
+)
+
+// Subscription is a partner endpoint and the event types it receives.
+type Subscription struct {
+	ID     uuid.UUID
+	URL    string
+	Secret string
+	// EventTypes are the events.Event types sent to URL
+	EventTypes []string
+	// Active is false once the subscription was disabled, by hand or
+	// after DisableAfter failed attempts in a row
+	Active bool
+	// ConsecutiveFailures counts failed attempts since the last success
+	ConsecutiveFailures int
+	DisabledAt          *time.Time
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+}
+
+// Receives reports whether events of eventType are sent to s.
+func (s Subscription) Receives(eventType string) bool {
+	return slices.Contains(s.EventTypes, eventType)
+}
+
+type Status string
+
+const (
+	// StatusPending deliveries are waiting for their first or next attempt
+	StatusPending   Status = "pending"
+	StatusSucceeded Status = "succeeded"
+	// StatusFailed deliveries ran out of attempts, got a permanent error or
+	// were dropped because their subscription was disabled
+	StatusFailed Status = "failed"
+)
+
+// Delivery is an event sent to a subscription, with the outcome of its
+// attempts. Replaying a delivery creates a new one.
+type Delivery struct {
+	ID             uuid.UUID
+	SubscriptionID uuid.UUID
+	EventID        uuid.UUID
+	EventType      string
+	// Payload is the exact request body
+	Payload  json.RawMessage
+	Status   Status
+	Attempts int
+	// ResponseStatus is the HTTP status of the last attempt, 0 when it got
+	// no response
+	ResponseStatus int
+	LastError      string
+	// ReplayOf is the delivery this one replays
+	ReplayOf    *uuid.UUID
+	CreatedAt   time.Time
+	DeliveredAt *time.Time
+}